@@ -0,0 +1,273 @@
+package alert
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sypchal/notify"
+	"sypchal/validation"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type AlertDomain struct {
+	db        *pgx.Conn
+	validator *validation.Validator
+}
+
+func NewAlertDomain(db *pgx.Conn, validator *validation.Validator) (*AlertDomain, error) {
+	if db == nil {
+		return nil, errors.New("db is nil")
+	}
+
+	if validator == nil {
+		return nil, errors.New("validator is nil")
+	}
+
+	return &AlertDomain{db, validator}, nil
+}
+
+type SetReorderThresholdRequest struct {
+	ProductId int
+	// Threshold disables the alerts when nil.
+	Threshold *int `json:"threshold" validate:"omitempty,gte=0"`
+}
+
+// SetReorderThreshold sets the stock below which the product, or any of its
+// variants, triggers a low stock alert.
+func (a *AlertDomain) SetReorderThreshold(ctx context.Context, req SetReorderThresholdRequest) (err error) {
+	if err = a.validator.ValidateStruct(req); err != nil {
+		return
+	}
+
+	tag, err := a.db.Exec(
+		ctx,
+		"update products set reorder_threshold=$1 where id=$2 and deleted_at is null",
+		req.Threshold,
+		req.ProductId,
+	)
+	if err != nil {
+		return
+	}
+
+	if tag.RowsAffected() == 0 {
+		err = ErrProductNotFound
+	}
+
+	return
+}
+
+// LowStockItem is a product, or one of its variants when VariantId is set,
+// whose stock is below its reorder threshold.
+type LowStockItem struct {
+	ProductId int     `json:"product_id"`
+	VariantId *int    `json:"variant_id"`
+	Name      string  `json:"name"`
+	Sku       *string `json:"sku"`
+	Stock     int     `json:"stock"`
+	Threshold int     `json:"threshold"`
+}
+
+// GetLowStock lists what needs to be reordered, lowest stock first.
+func (a *AlertDomain) GetLowStock(ctx context.Context) (items []*LowStockItem, err error) {
+	rows, err := a.db.Query(
+		ctx,
+		`select products.id,product_variants.id,products.name,coalesce(product_variants.sku,products.sku),
+			coalesce(product_variants.stock,products.stock),products.reorder_threshold
+		from products left join product_variants on(product_variants.product_id=products.id)
+		where products.deleted_at is null and products.reorder_threshold is not null
+		and coalesce(product_variants.stock,products.stock) < products.reorder_threshold
+		order by 5, products.id, product_variants.id`,
+	)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	items = []*LowStockItem{}
+	for rows.Next() {
+		item := &LowStockItem{}
+		if err = rows.Scan(
+			&item.ProductId,
+			&item.VariantId,
+			&item.Name,
+			&item.Sku,
+			&item.Stock,
+			&item.Threshold,
+		); err != nil {
+			return
+		}
+		items = append(items, item)
+	}
+
+	err = rows.Err()
+	return
+}
+
+// LowStock returns the alert to send when a stock change from before to
+// after falls below the threshold. It only alerts once, when the threshold is
+// crossed, not on every sale below it.
+func LowStock(productId int, variantId *int, name string, sku *string, threshold *int, before, after int) (msg notify.Message, ok bool) {
+	if threshold == nil || before < *threshold || after >= *threshold {
+		return
+	}
+
+	label := name
+	if sku != nil && *sku != "" {
+		label = fmt.Sprintf("%s (%s)", name, *sku)
+	}
+
+	msg = notify.Message{
+		Event:   notify.EventLowStock,
+		Subject: "Low stock: " + label,
+		Body:    fmt.Sprintf("%s has %d left, below the reorder threshold of %d.", label, after, *threshold),
+		Data: map[string]any{
+			"product_id": productId,
+			"variant_id": variantId,
+			"sku":        sku,
+			"stock":      after,
+			"threshold":  *threshold,
+		},
+	}
+
+	return msg, true
+}
+
+type Subscription struct {
+	Id        int       `json:"id"`
+	ProductId int       `json:"product_id"`
+	VariantId *int      `json:"variant_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type SubscribeRequest struct {
+	UserId    int
+	ProductId int
+	VariantId *int `json:"variant_id"`
+}
+
+// Subscribe asks to be notified once the out of stock product, or variant,
+// is restocked. Subscribing again after a notification waits for the next
+// restock.
+func (a *AlertDomain) Subscribe(ctx context.Context, req SubscribeRequest) (subscription *Subscription, err error) {
+	var variantCount, stock int
+	err = a.db.QueryRow(
+		ctx,
+		`select (select count(*) from product_variants where product_id=products.id), stock
+		from products where id=$1 and deleted_at is null`,
+		req.ProductId,
+	).Scan(&variantCount, &stock)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			err = ErrProductNotFound
+		}
+		return
+	}
+
+	// products with variants are restocked per sku
+	if variantCount > 0 && req.VariantId == nil {
+		err = ErrVariantRequired
+		return
+	}
+
+	if req.VariantId != nil {
+		err = a.db.QueryRow(
+			ctx,
+			"select stock from product_variants where id=$1 and product_id=$2",
+			*req.VariantId,
+			req.ProductId,
+		).Scan(&stock)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				err = ErrVariantNotFound
+			}
+			return
+		}
+	}
+
+	if stock > 0 {
+		err = ErrInStock
+		return
+	}
+
+	subscription = &Subscription{}
+	err = a.db.QueryRow(
+		ctx,
+		`insert into stock_subscriptions(user_id,product_id,variant_id) values ($1,$2,$3)
+		on conflict (user_id,product_id,variant_id) do update set notified_at=null,created_at=now()
+		returning id,product_id,variant_id,created_at`,
+		req.UserId,
+		req.ProductId,
+		req.VariantId,
+	).Scan(
+		&subscription.Id,
+		&subscription.ProductId,
+		&subscription.VariantId,
+		&subscription.CreatedAt,
+	)
+
+	return
+}
+
+func (a *AlertDomain) Unsubscribe(ctx context.Context, req SubscribeRequest) (err error) {
+	tag, err := a.db.Exec(
+		ctx,
+		`delete from stock_subscriptions
+		where user_id=$1 and product_id=$2 and variant_id is not distinct from $3 and notified_at is null`,
+		req.UserId,
+		req.ProductId,
+		req.VariantId,
+	)
+	if err != nil {
+		return
+	}
+
+	if tag.RowsAffected() == 0 {
+		err = ErrSubscriptionNotFound
+	}
+
+	return
+}
+
+// ClaimBackInStock marks the waiting subscriptions of a restocked product, or
+// variant, as notified and returns the notifications to send once tx is
+// committed.
+func ClaimBackInStock(ctx context.Context, tx pgx.Tx, productId int, variantId *int) (msgs []notify.Message, err error) {
+	rows, err := tx.Query(
+		ctx,
+		`update stock_subscriptions set notified_at=now()
+		from users, products
+		where users.id=stock_subscriptions.user_id and products.id=stock_subscriptions.product_id
+		and stock_subscriptions.product_id=$1 and stock_subscriptions.variant_id is not distinct from $2
+		and stock_subscriptions.notified_at is null
+		returning users.email,users.full_name,products.name`,
+		productId,
+		variantId,
+	)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var email, fullName, name string
+		if err = rows.Scan(&email, &fullName, &name); err != nil {
+			return
+		}
+
+		msgs = append(msgs, notify.Message{
+			Event:   notify.EventBackInStock,
+			To:      email,
+			Subject: name + " is back in stock",
+			Body:    fmt.Sprintf("Hi %s,\n\n%s is back in stock.", fullName, name),
+			Data: map[string]any{
+				"product_id": productId,
+				"variant_id": variantId,
+			},
+		})
+	}
+
+	err = rows.Err()
+	return
+}
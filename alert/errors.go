@@ -0,0 +1,9 @@
+package alert
+
+import "errors"
+
+var ErrProductNotFound = errors.New("product not found")
+var ErrVariantNotFound = errors.New("variant not found")
+var ErrVariantRequired = errors.New("variant is required for products with variants")
+var ErrInStock = errors.New("product is in stock")
+var ErrSubscriptionNotFound = errors.New("subscription not found")
@@ -0,0 +1,25 @@
+package audit
+
+import "context"
+
+// Actor is who made a change and the request it was made in.
+type Actor struct {
+	Name      string
+	RequestId string
+}
+
+type actorCtxKey struct{}
+
+func WithActor(ctx context.Context, actor Actor) context.Context {
+	return context.WithValue(ctx, actorCtxKey{}, actor)
+}
+
+// FromContext returns the actor of the request, or "system" when the change
+// does not come from a request, e.g. a cli command.
+func FromContext(ctx context.Context) Actor {
+	if actor, ok := ctx.Value(actorCtxKey{}).(Actor); ok {
+		return actor
+	}
+
+	return Actor{Name: "system"}
+}
@@ -3,7 +3,11 @@ package cart
 import (
 	"context"
 	"errors"
+	"fmt"
+	"sypchal/exchange"
+	"sypchal/money"
 	"sypchal/product"
+	"sypchal/promotion"
 	"sypchal/validation"
 	"time"
 
@@ -13,9 +17,14 @@ import (
 type CartDomain struct {
 	db        *pgx.Conn
 	validator *validation.Validator
+	// secret signs the guest cart tokens
+	secret      []byte
+	pricePolicy PricePolicy
+	// currency is the currency of the catalog prices
+	currency money.Currency
 }
 
-func NewCartDomain(db *pgx.Conn, validator *validation.Validator) (*CartDomain, error) {
+func NewCartDomain(db *pgx.Conn, validator *validation.Validator, secret string, pricePolicy PricePolicy, currency money.Currency) (*CartDomain, error) {
 	if db == nil {
 		return nil, errors.New("db is nil")
 	}
@@ -24,22 +33,67 @@ func NewCartDomain(db *pgx.Conn, validator *validation.Validator) (*CartDomain,
 		return nil, errors.New("validator is nil")
 	}
 
-	return &CartDomain{db, validator}, nil
+	if secret == "" {
+		return nil, errors.New("secret is empty")
+	}
+
+	if err := pricePolicy.validate(); err != nil {
+		return nil, err
+	}
+
+	return &CartDomain{db, validator, []byte(secret), pricePolicy, currency}, nil
+}
+
+// Owner is who a cart belongs to, a signed in customer or a guest cart.
+type Owner struct {
+	UserId      int `json:"user_id" validate:"required_without=GuestCartId"`
+	GuestCartId int `json:"guest_cart_id" validate:"required_without=UserId"`
+}
+
+// column is the cart_items column identifying the cart of the owner and its
+// value.
+func (o Owner) column() (string, int) {
+	if o.UserId != 0 {
+		return "user_id", o.UserId
+	}
+
+	return "guest_cart_id", o.GuestCartId
+}
+
+// countItems is the quantity in the cart of the owner.
+func countItems(ctx context.Context, tx pgx.Tx, owner Owner) (count int, err error) {
+	column, id := owner.column()
+	err = tx.QueryRow(ctx, "select coalesce(sum(qty),0) from cart_items where "+column+"=$1", id).Scan(&count)
+	return
+}
+
+// touch keeps a guest cart from expiring.
+func touch(ctx context.Context, tx pgx.Tx, owner Owner) (err error) {
+	if owner.GuestCartId == 0 {
+		return
+	}
+
+	_, err = tx.Exec(ctx, "update guest_carts set updated_at=now() where id=$1", owner.GuestCartId)
+	return
 }
 
 type CartItem struct {
-	Id        int        `json:"id"`
-	UserId    int        `json:"user_id"`
-	ProductId int        `json:"product_id"`
-	Qty       int        `json:"qty"`
-	Price     int        `json:"price"`
-	CreatedAt time.Time  `json:"created_at"`
-	UpdatedAt *time.Time `json:"updated_at"`
+	Id          int         `json:"id"`
+	UserId      *int        `json:"user_id"`
+	GuestCartId *int        `json:"guest_cart_id"`
+	ProductId   int         `json:"product_id"`
+	VariantId   *int        `json:"variant_id"`
+	Qty         int         `json:"qty"`
+	Price       money.Money `json:"price"`
+	PricedAt    time.Time   `json:"priced_at"`
+	CreatedAt   time.Time   `json:"created_at"`
+	UpdatedAt   *time.Time  `json:"updated_at"`
 }
 
 type AddCartItemRequest struct {
-	UserId    int `json:"user_id" validate:"required"`
+	Owner
 	ProductId int `json:"product_id" validate:"required"`
+	VariantId int `json:"variant_id"`
 	Qty       int `json:"qty" validate:"required"`
 }
 
@@ -54,15 +108,18 @@ func (c *CartDomain) AddCartItem(ctx context.Context, req AddCartItemRequest) (c
 	}
 	defer tx.Rollback(ctx)
 
+	var variantCount int
 	product := &product.Product{}
 	err = tx.QueryRow(
 		ctx,
-		"select id, price, stock from products where id=$1",
+		`select id, row(price,currency), stock, (select count(*) from product_variants where product_id=products.id)
+		from products where id=$1 and deleted_at is null`,
 		req.ProductId,
 	).Scan(
 		&product.Id,
 		&product.Price,
 		&product.Stock,
+		&variantCount,
 	)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
@@ -73,6 +130,36 @@ func (c *CartDomain) AddCartItem(ctx context.Context, req AddCartItemRequest) (c
 		return
 	}
 
+	// products with variants are sold per sku, so the stock and price come
+	// from the chosen variant instead
+	var variantId *int
+	if req.VariantId != 0 {
+		err = tx.QueryRow(
+			ctx,
+			`select product_variants.stock, row(coalesce(product_variants.price,products.price),products.currency)
+			from product_variants inner join products on(product_id=products.id)
+			where product_variants.id=$1 and product_id=$2`,
+			req.VariantId,
+			req.ProductId,
+		).Scan(
+			&product.Stock,
+			&product.Price,
+		)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				err = ErrVariantNotFound
+				return
+			}
+
+			return
+		}
+
+		variantId = &req.VariantId
+	} else if variantCount > 0 {
+		err = ErrVariantRequired
+		return
+	}
+
 	if req.Qty > product.Stock {
 		err = ErrProductOutOfStock
 		return
@@ -81,18 +168,26 @@ func (c *CartDomain) AddCartItem(ctx context.Context, req AddCartItemRequest) (c
 	// do upsert
 	_, err = tx.Exec(
 		ctx,
-		`insert into cart_items(user_id,product_id,qty,price) values ($1,$2,$3,$4)
-		on conflict (user_id,product_id) do update set qty=excluded.qty+cart_items.qty`,
+		`insert into cart_items(user_id,guest_cart_id,product_id,variant_id,qty,price,currency)
+		values (nullif($1,0),nullif($2,0),$3,$4,$5,$6,$7)
+		on conflict (user_id,guest_cart_id,product_id,variant_id) do update set qty=excluded.qty+cart_items.qty`,
 		req.UserId,
+		req.GuestCartId,
 		req.ProductId,
+		variantId,
 		req.Qty,
-		product.Price,
+		product.Price.Amount,
+		product.Price.Currency,
 	)
 	if err != nil {
 		return
 	}
 
-	err = tx.QueryRow(ctx, "select sum(qty) from cart_items where user_id=$1", req.UserId).Scan(&count)
+	if err = touch(ctx, tx, req.Owner); err != nil {
+		return
+	}
+
+	count, err = countItems(ctx, tx, req.Owner)
 	if err != nil {
 		return
 	}
@@ -105,82 +200,214 @@ func (c *CartDomain) AddCartItem(ctx context.Context, req AddCartItemRequest) (c
 	return
 }
 
+// Cart is priced in the currency of the customer: the list prices of the
+// products in that currency, or else their prices converted at the exchange
+// rates. The price changes are those of the catalog prices.
 type Cart struct {
-	TotalPrice    int                  `json:"total_price"`
+	Currency      money.Currency       `json:"currency"`
+	TotalPrice    money.Money          `json:"total_price"`
 	ItemCount     int                  `json:"item_count"`
 	TotalQuantity int                  `json:"total_quantity"`
 	Items         []*CartItemPopulated `json:"items"`
+	// PriceChanged is true when a line price changed since it was added, and
+	// ConfirmPrices is true when they must be confirmed before ordering.
+	PriceChanged  bool `json:"price_changed"`
+	ConfirmPrices bool `json:"confirm_prices"`
+	// Discount is what the promotions take off TotalPrice, see Promotions.
+	Discount   money.Money            `json:"discount"`
+	Promotions []promotion.Allocation `json:"promotions"`
 }
 
 type CartItemPopulated struct {
-	Id         int             `json:"id"`
-	Product    CartItemProduct `json:"product"`
-	Qty        int             `json:"qty"`
-	Price      int             `json:"price"`
-	TotalPrice int             `json:"total_price"`
-	CreatedAt  time.Time       `json:"created_at"`
-	UpdatedAt  *time.Time      `json:"updated_at"`
+	Id      int              `json:"id"`
+	Product CartItemProduct  `json:"product"`
+	Variant *CartItemVariant `json:"variant"`
+	Qty     int              `json:"qty"`
+	// Price is the price the line was added at, CurrentPrice the catalog
+	// price and ChargedPrice what an order charges, see PricePolicy.
+	Price        money.Money `json:"price"`
+	CurrentPrice money.Money `json:"current_price"`
+	ChargedPrice money.Money `json:"charged_price"`
+	PriceChanged bool        `json:"price_changed"`
+	TotalPrice   money.Money `json:"total_price"`
+	Discount     money.Money `json:"discount"`
+	PricedAt     time.Time   `json:"priced_at"`
+	CreatedAt    time.Time   `json:"created_at"`
+	UpdatedAt    *time.Time  `json:"updated_at"`
 }
 
 type CartItemProduct struct {
-	Id          int    `json:"id"`
-	Name        string `json:"name"`
-	Description string `json:"description"`
-	ImageUrl    string `json:"image_url"`
-	Price       int    `json:"price"`
+	Id          int         `json:"id"`
+	Name        string      `json:"name"`
+	Description string      `json:"description"`
+	ImageUrl    string      `json:"image_url"`
+	Category    string      `json:"category"`
+	Price       money.Money `json:"price"`
 }
 
-func (c *CartDomain) GetUserCart(ctx context.Context, userId int) (cart *Cart, err error) {
+type CartItemVariant struct {
+	Id       int               `json:"id"`
+	Sku      string            `json:"sku"`
+	Options  map[string]string `json:"options"`
+	ImageUrl string            `json:"image_url"`
+	Price    money.Money       `json:"price"`
+}
+
+func (c *CartDomain) GetCart(ctx context.Context, owner Owner, currency money.Currency) (cart *Cart, err error) {
+	rates, err := exchange.LoadRates(ctx, c.db, c.currency)
+	if err != nil {
+		return
+	}
+
+	column, id := owner.column()
 	rows, err := c.db.Query(
 		ctx,
-		`select 
-			(cart_items.qty*cart_items.price) as total_price,
+		fmt.Sprintf(`select 
 			products.id,
 			products.name,
 			products.description,
 			products.image_url,
-			products.price,
+			products.category,
+			row(products.price,products.currency),
 			cart_items.id,
 			qty,
-			cart_items.price,
+			row(cart_items.price,cart_items.currency),
+			cart_items.priced_at,
 			cart_items.created_at,
-			cart_items.updated_at
-		from cart_items inner join products on(product_id=products.id and user_id=$1);`,
-		userId,
+			cart_items.updated_at,
+			product_variants.id,
+			product_variants.sku,
+			product_variants.options,
+			product_variants.image_url,
+			row(coalesce(product_variants.price,products.price),products.currency),
+			(%s)
+		from cart_items inner join products on(cart_items.product_id=products.id and %s=$1)
+		left join product_variants on(variant_id=product_variants.id)
+		where products.deleted_at is null;`, ListPriceSQL, column),
+		id,
+		currency,
 	)
 	if err != nil {
 		return
 	}
 	defer rows.Close()
 
-	cart = &Cart{}
+	cart = &Cart{Currency: currency, TotalPrice: money.Zero(currency), Discount: money.Zero(currency)}
 
 	for rows.Next() {
 		item := &CartItemPopulated{}
+		variant := struct {
+			Id       *int
+			Sku      *string
+			Options  map[string]string
+			ImageUrl *string
+			Price    money.Money
+		}{}
+		var listPrice *int64
 		rows.Scan(
-			&item.TotalPrice,
 			&item.Product.Id,
 			&item.Product.Name,
 			&item.Product.Description,
 			&item.Product.ImageUrl,
+			&item.Product.Category,
 			&item.Product.Price,
 			&item.Id,
 			&item.Qty,
 			&item.Price,
+			&item.PricedAt,
 			&item.CreatedAt,
 			&item.UpdatedAt,
+			&variant.Id,
+			&variant.Sku,
+			&variant.Options,
+			&variant.ImageUrl,
+			&variant.Price,
+			&listPrice,
 		)
+		if variant.Id != nil {
+			item.Variant = &CartItemVariant{
+				Id:       *variant.Id,
+				Sku:      *variant.Sku,
+				Options:  variant.Options,
+				ImageUrl: *variant.ImageUrl,
+				Price:    variant.Price,
+			}
+		}
+
+		item.CurrentPrice = item.Product.Price
+		if item.Variant != nil {
+			item.CurrentPrice = item.Variant.Price
+		}
+
+		var confirm bool
+		item.ChargedPrice, confirm = c.pricePolicy.Price(item.Price, item.CurrentPrice, item.PricedAt)
+		item.PriceChanged = item.Price != item.CurrentPrice
+
+		local := Localizer(rates, currency, item.CurrentPrice, listPrice)
+		for _, price := range []*money.Money{&item.Price, &item.CurrentPrice, &item.ChargedPrice, &item.Product.Price} {
+			if *price, err = local(*price); err != nil {
+				return
+			}
+		}
+		if item.Variant != nil {
+			item.Variant.Price = item.CurrentPrice
+		}
+
+		item.TotalPrice = item.ChargedPrice.Mul(item.Qty)
+		item.Discount = money.Zero(currency)
+
+		cart.PriceChanged = cart.PriceChanged || item.PriceChanged
+		cart.ConfirmPrices = cart.ConfirmPrices || confirm
 		cart.ItemCount++
-		cart.TotalPrice += item.TotalPrice
+		cart.TotalPrice = cart.TotalPrice.Add(item.TotalPrice)
 		cart.TotalQuantity += item.Qty
 		cart.Items = append(cart.Items, item)
 	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return
+	}
+
+	return cart, c.applyPromotions(ctx, cart, rates)
+}
+
+// applyPromotions discounts the cart with the running promotions, as the order
+// would.
+func (c *CartDomain) applyPromotions(ctx context.Context, cart *Cart, rates *exchange.Rates) (err error) {
+	promotions, err := promotion.Running(ctx, c.db, time.Now())
+	if err != nil {
+		return
+	}
+
+	if err = promotion.Localize(promotions, rates, cart.Currency); err != nil {
+		return
+	}
+
+	lines := make([]promotion.Line, 0, len(cart.Items))
+	byId := map[int]*CartItemPopulated{}
+	for _, item := range cart.Items {
+		lines = append(lines, promotion.Line{
+			Id:        item.Id,
+			ProductId: item.Product.Id,
+			Category:  item.Product.Category,
+			Qty:       item.Qty,
+			Price:     item.ChargedPrice,
+		})
+		byId[item.Id] = item
+	}
+
+	cart.Promotions = promotion.Evaluate(promotions, lines)
+	for _, allocation := range cart.Promotions {
+		item := byId[allocation.LineId]
+		item.Discount = item.Discount.Add(allocation.Amount)
+		cart.Discount = cart.Discount.Add(allocation.Amount)
+	}
 
 	return
 }
 
 type DeleteCartItemRequest struct {
-	UserId int
+	Owner
 	ItemId int
 }
 
@@ -192,11 +419,12 @@ func (c *CartDomain) DeleteCartItem(ctx context.Context, req DeleteCartItemReque
 	}
 	defer tx.Rollback(ctx)
 
+	column, ownerId := req.Owner.column()
 	err = tx.QueryRow(
 		ctx,
-		"delete from cart_items where id=$1 and user_id=$2 returning id",
+		"delete from cart_items where id=$1 and "+column+"=$2 returning id",
 		req.ItemId,
-		req.UserId,
+		ownerId,
 	).
 		Scan(&deletedId)
 	if err != nil {
@@ -208,7 +436,11 @@ func (c *CartDomain) DeleteCartItem(ctx context.Context, req DeleteCartItemReque
 		return
 	}
 
-	err = tx.QueryRow(ctx, "select sum(qty) from cart_items where user_id=$1", req.UserId).Scan(&count)
+	if err = touch(ctx, tx, req.Owner); err != nil {
+		return
+	}
+
+	count, err = countItems(ctx, tx, req.Owner)
 	if err != nil {
 		return
 	}
@@ -221,7 +453,7 @@ func (c *CartDomain) DeleteCartItem(ctx context.Context, req DeleteCartItemReque
 }
 
 type UpdateCartItemRequest struct {
-	UserId int
+	Owner
 	ItemId int
 	Qty    int `json:"qty" validate:"required"`
 }
@@ -238,12 +470,14 @@ func (c *CartDomain) UpdateCartItem(ctx context.Context, req UpdateCartItemReque
 	defer tx.Rollback(ctx)
 
 	var productId int
+	var variantId *int
+	column, ownerId := req.Owner.column()
 	err = tx.QueryRow(
 		ctx,
-		"select product_id from cart_items where id=$1 and user_id=$2",
+		"select product_id, variant_id from cart_items where id=$1 and "+column+"=$2",
 		req.ItemId,
-		req.UserId,
-	).Scan(&productId)
+		ownerId,
+	).Scan(&productId, &variantId)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			err = ErrCartItemNotFound
@@ -255,8 +489,11 @@ func (c *CartDomain) UpdateCartItem(ctx context.Context, req UpdateCartItemReque
 	var productStock int
 	err = tx.QueryRow(
 		ctx,
-		"select stock from products where id=$1",
+		`select coalesce(product_variants.stock,products.stock)
+		from products left join product_variants on(product_variants.id=$2)
+		where products.id=$1 and products.deleted_at is null`,
 		productId,
+		variantId,
 	).Scan(&productStock)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
@@ -278,7 +515,11 @@ func (c *CartDomain) UpdateCartItem(ctx context.Context, req UpdateCartItemReque
 		return
 	}
 
-	err = tx.QueryRow(ctx, "select sum(qty) from cart_items where user_id=$1", req.UserId).Scan(&count)
+	if err = touch(ctx, tx, req.Owner); err != nil {
+		return
+	}
+
+	count, err = countItems(ctx, tx, req.Owner)
 	if err != nil {
 		return
 	}
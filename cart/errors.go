@@ -5,3 +5,7 @@ import "errors"
 var ErrProductNotFound = errors.New("product not found")
 var ErrProductOutOfStock = errors.New("product out of stock")
 var ErrCartItemNotFound = errors.New("cart item not found")
+var ErrVariantNotFound = errors.New("variant not found")
+var ErrVariantRequired = errors.New("variant is required for this product")
+var ErrInvalidCartToken = errors.New("invalid cart token")
+var ErrInvalidPricePolicy = errors.New("price policy must be reprice, honor or confirm")
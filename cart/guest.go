@@ -0,0 +1,195 @@
+package cart
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"strconv"
+	"strings"
+	"sypchal/money"
+	"time"
+)
+
+// CreateGuestCart starts a cart for a visitor who is not signed in, the token
+// identifies the cart in the following requests.
+func (c *CartDomain) CreateGuestCart(ctx context.Context) (guestCartId int, token string, err error) {
+	err = c.db.QueryRow(ctx, "insert into guest_carts default values returning id").Scan(&guestCartId)
+	if err != nil {
+		return
+	}
+
+	token = c.signGuestCart(guestCartId)
+	return
+}
+
+// ParseGuestCartToken returns the guest cart of a token, the cart must still
+// exist, it is gone once merged or purged.
+func (c *CartDomain) ParseGuestCartToken(ctx context.Context, token string) (guestCartId int, err error) {
+	id, _, ok := strings.Cut(token, ".")
+	if !ok {
+		err = ErrInvalidCartToken
+		return
+	}
+
+	guestCartId, err = strconv.Atoi(id)
+	if err != nil || !hmac.Equal([]byte(token), []byte(c.signGuestCart(guestCartId))) {
+		err = ErrInvalidCartToken
+		return
+	}
+
+	var exists bool
+	err = c.db.QueryRow(ctx, "select exists(select 1 from guest_carts where id=$1)", guestCartId).Scan(&exists)
+	if err != nil {
+		return
+	}
+
+	if !exists {
+		err = ErrInvalidCartToken
+		return
+	}
+
+	return
+}
+
+// signGuestCart returns the token of a guest cart, its id and signature.
+func (c *CartDomain) signGuestCart(guestCartId int) string {
+	id := strconv.Itoa(guestCartId)
+	mac := hmac.New(sha256.New, c.secret)
+	mac.Write([]byte("guest_cart:" + id))
+
+	return id + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+var (
+	// MergeReasonStock is a line capped at the stock left.
+	MergeReasonStock = "stock"
+	// MergeReasonUnavailable is a line left out, the product is archived or
+	// out of stock.
+	MergeReasonUnavailable = "unavailable"
+)
+
+// MergeAdjustment is a guest cart line that could not be merged as is.
+type MergeAdjustment struct {
+	ProductId int  `json:"product_id"`
+	VariantId *int `json:"variant_id"`
+	// Requested is the guest quantity plus the quantity already in the
+	// customer cart, Qty is what the customer cart has after the merge.
+	Requested int    `json:"requested"`
+	Qty       int    `json:"qty"`
+	Reason    string `json:"reason"`
+}
+
+type MergeResult struct {
+	Merged   int               `json:"merged"`
+	Adjusted []MergeAdjustment `json:"adjusted"`
+}
+
+// MergeGuestCart moves the lines of a guest cart into the cart of a customer,
+// adding up the quantities of the lines in both carts without going over the
+// stock. The guest cart is deleted.
+func (c *CartDomain) MergeGuestCart(ctx context.Context, guestCartId int, userId int) (res *MergeResult, err error) {
+	tx, err := c.db.Begin(ctx)
+	if err != nil {
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(
+		ctx,
+		`select guest.product_id, guest.variant_id, guest.qty, row(guest.price,guest.currency), guest.priced_at, coalesce(own.qty,0),
+			coalesce(product_variants.stock,products.stock), products.deleted_at is not null
+		from cart_items guest inner join products on(guest.product_id=products.id)
+		left join product_variants on(guest.variant_id=product_variants.id)
+		left join cart_items own on(own.user_id=$2 and own.product_id=guest.product_id
+			and own.variant_id is not distinct from guest.variant_id)
+		where guest.guest_cart_id=$1
+		order by guest.id
+		for update of guest`,
+		guestCartId,
+		userId,
+	)
+	if err != nil {
+		return
+	}
+
+	type line struct {
+		productId, qty, ownQty, stock int
+		variantId                     *int
+		price                         money.Money
+		pricedAt                      time.Time
+		archived                      bool
+	}
+	lines := []line{}
+	for rows.Next() {
+		l := line{}
+		if err = rows.Scan(&l.productId, &l.variantId, &l.qty, &l.price, &l.pricedAt, &l.ownQty, &l.stock, &l.archived); err != nil {
+			rows.Close()
+			return
+		}
+		lines = append(lines, l)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return
+	}
+
+	res = &MergeResult{Adjusted: []MergeAdjustment{}}
+	for _, l := range lines {
+		requested := l.qty + l.ownQty
+		qty := min(requested, l.stock)
+
+		if l.archived || qty <= 0 {
+			res.Adjusted = append(res.Adjusted, MergeAdjustment{l.productId, l.variantId, requested, l.ownQty, MergeReasonUnavailable})
+			continue
+		}
+
+		if qty < requested {
+			res.Adjusted = append(res.Adjusted, MergeAdjustment{l.productId, l.variantId, requested, qty, MergeReasonStock})
+		}
+
+		// a line already in the customer cart keeps its price
+		_, err = tx.Exec(
+			ctx,
+			`insert into cart_items(user_id,product_id,variant_id,qty,price,currency,priced_at) values ($1,$2,$3,$4,$5,$6,$7)
+			on conflict (user_id,guest_cart_id,product_id,variant_id) do update set qty=excluded.qty,updated_at=now()`,
+			userId,
+			l.productId,
+			l.variantId,
+			qty,
+			l.price.Amount,
+			l.price.Currency,
+			l.pricedAt,
+		)
+		if err != nil {
+			return
+		}
+		res.Merged++
+	}
+
+	if _, err = tx.Exec(ctx, "delete from guest_carts where id=$1", guestCartId); err != nil {
+		return
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return
+	}
+
+	return
+}
+
+// PurgeGuestCarts deletes the guest carts left unchanged for longer than the
+// retention period, returning how many were deleted.
+func (c *CartDomain) PurgeGuestCarts(ctx context.Context, retention time.Duration) (count int64, err error) {
+	tag, err := c.db.Exec(
+		ctx,
+		"delete from guest_carts where coalesce(updated_at,created_at) < now() - $1::interval",
+		retention,
+	)
+	if err != nil {
+		return
+	}
+
+	count = tag.RowsAffected()
+	return
+}
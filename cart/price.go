@@ -0,0 +1,141 @@
+package cart
+
+import (
+	"context"
+	"fmt"
+	"sypchal/exchange"
+	"sypchal/money"
+	"time"
+)
+
+var (
+	// PricePolicyReprice always charges the current catalog price.
+	PricePolicyReprice = "reprice"
+	// PricePolicyHonor charges the price a line was added at for the honor
+	// period, then the current price.
+	PricePolicyHonor = "honor"
+	// PricePolicyConfirm charges the current price, but orders are refused
+	// until the customer confirms the lines whose price changed.
+	PricePolicyConfirm = "confirm"
+)
+
+// PricePolicy decides the price charged for cart lines whose captured price
+// differs from the current catalog price.
+type PricePolicy struct {
+	Policy      string
+	HonorPeriod time.Duration
+}
+
+func (p PricePolicy) validate() error {
+	switch p.Policy {
+	case PricePolicyReprice, PricePolicyHonor, PricePolicyConfirm:
+		return nil
+	default:
+		return fmt.Errorf("%w: %q", ErrInvalidPricePolicy, p.Policy)
+	}
+}
+
+// Price returns the price charged for a line captured at pricedAt, and whether
+// the customer must confirm it before ordering.
+func (p PricePolicy) Price(captured money.Money, current money.Money, pricedAt time.Time) (price money.Money, confirm bool) {
+	if captured == current {
+		return captured, false
+	}
+
+	switch p.Policy {
+	case PricePolicyHonor:
+		if time.Since(pricedAt) < p.HonorPeriod {
+			return captured, false
+		}
+		return current, false
+	case PricePolicyConfirm:
+		return current, true
+	default:
+		return current, false
+	}
+}
+
+// PriceChange is a cart line whose price changed since it was added.
+type PriceChange struct {
+	CartItemId    int         `json:"cart_item_id"`
+	ProductId     int         `json:"product_id"`
+	VariantId     *int        `json:"variant_id"`
+	CapturedPrice money.Money `json:"captured_price"`
+	CurrentPrice  money.Money `json:"current_price"`
+}
+
+// ConfirmPrices accepts the current price of the lines whose price changed,
+// returning them.
+func (c *CartDomain) ConfirmPrices(ctx context.Context, owner Owner) (changes []PriceChange, err error) {
+	tx, err := c.db.Begin(ctx)
+	if err != nil {
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	column, id := owner.column()
+	rows, err := tx.Query(
+		ctx,
+		fmt.Sprintf(`update cart_items set price=drift.current,currency=drift.currency,priced_at=now(),updated_at=now()
+		from (
+			select cart_items.id, cart_items.price, cart_items.currency,
+				coalesce(product_variants.price,products.price), products.currency
+			from cart_items inner join products on(cart_items.product_id=products.id)
+			left join product_variants on(cart_items.variant_id=product_variants.id)
+			where cart_items.%s=$1 for update of cart_items
+		) drift(id, captured, captured_currency, current, currency)
+		where cart_items.id=drift.id and (drift.captured<>drift.current or drift.captured_currency<>drift.currency)
+		returning cart_items.id, cart_items.product_id, cart_items.variant_id,
+			row(drift.captured,drift.captured_currency), row(drift.current,drift.currency)`, column),
+		id,
+	)
+	if err != nil {
+		return
+	}
+
+	changes = []PriceChange{}
+	for rows.Next() {
+		change := PriceChange{}
+		if err = rows.Scan(&change.CartItemId, &change.ProductId, &change.VariantId, &change.CapturedPrice, &change.CurrentPrice); err != nil {
+			rows.Close()
+			return
+		}
+		changes = append(changes, change)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return
+	}
+
+	if err = touch(ctx, tx, owner); err != nil {
+		return
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return
+	}
+
+	return
+}
+
+// ListPriceSQL selects the list price of a cart line in the currency of the
+// query parameter $2, null without one: the price of its variant, or else of
+// its product when the variant has no price of its own. It expects products
+// and product_variants in the query.
+const ListPriceSQL = `select product_prices.price from product_prices
+	where product_prices.product_id=products.id and product_prices.currency=$2
+	and (product_prices.variant_id=product_variants.id or (product_prices.variant_id is null and product_variants.price is null))
+	order by product_prices.variant_id nulls last limit 1`
+
+// Localizer returns the function pricing the amounts of a line in currency:
+// the current catalog price is the list price when there is one, any other
+// amount, e.g. an honored older price, is converted at the rates.
+func Localizer(rates *exchange.Rates, currency money.Currency, current money.Money, listPrice *int64) func(money.Money) (money.Money, error) {
+	return func(price money.Money) (money.Money, error) {
+		if listPrice != nil && price == current {
+			return money.New(*listPrice, currency), nil
+		}
+
+		return rates.Convert(price, currency)
+	}
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sypchal/cart"
+	"sypchal/exchange"
+	"sypchal/inventory"
+	"sypchal/money"
+	"sypchal/product"
+	"time"
+)
+
+// runCommand runs a cli subcommand, e.g. `sypchal import-products products.csv`.
+func runCommand(ctx context.Context, productDomain *product.ProductDomain, inventoryDomain *inventory.InventoryDomain, cartDomain *cart.CartDomain, exchangeDomain *exchange.ExchangeDomain, args []string) error {
+	switch args[0] {
+	case "import-products":
+		return importProducts(ctx, productDomain, args[1:])
+	case "export-products":
+		return exportProducts(ctx, productDomain, args[1:])
+	case "purge-archived-products":
+		return purgeArchivedProducts(ctx, productDomain, args[1:])
+	case "reconcile-stock":
+		return reconcileStock(ctx, inventoryDomain, args[1:])
+	case "purge-guest-carts":
+		return purgeGuestCarts(ctx, cartDomain, args[1:])
+	case "import-exchange-rates":
+		return importExchangeRates(ctx, exchangeDomain, args[1:])
+	default:
+		return fmt.Errorf(
+			"unknown command %q, available commands: import-products, export-products, purge-archived-products, reconcile-stock, purge-guest-carts, import-exchange-rates",
+			args[0],
+		)
+	}
+}
+
+func importProducts(ctx context.Context, productDomain *product.ProductDomain, args []string) error {
+	fs := flag.NewFlagSet("import-products", flag.ContinueOnError)
+	format := fs.String("format", "", "csv or json, guessed from the file extension when empty")
+	dryRun := fs.Bool("dry-run", false, "validate and report without saving")
+	atomic := fs.Bool("atomic", false, "save nothing when any row fails")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: import-products [-format csv|json] [-dry-run] [-atomic] <file>")
+	}
+
+	f, err := os.Open(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if *format == "" {
+		*format = strings.TrimPrefix(filepath.Ext(f.Name()), ".")
+	}
+
+	var rows []product.ImportProductRow
+	switch *format {
+	case "csv":
+		rows, err = product.ParseProductsCSV(f)
+	case "json":
+		rows, err = product.ParseProductsJSON(f)
+	default:
+		return fmt.Errorf("format must be csv or json")
+	}
+	if err != nil {
+		return err
+	}
+
+	res, err := productDomain.ImportProducts(ctx, rows, product.ImportOptions{
+		DryRun: *dryRun,
+		Atomic: *atomic,
+	})
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err = encoder.Encode(res); err != nil {
+		return err
+	}
+
+	if res.Failed > 0 {
+		return fmt.Errorf("%d of %d rows failed", res.Failed, res.Total)
+	}
+
+	return nil
+}
+
+func exportProducts(ctx context.Context, productDomain *product.ProductDomain, args []string) error {
+	fs := flag.NewFlagSet("export-products", flag.ContinueOnError)
+	format := fs.String("format", "csv", "csv or json")
+	output := fs.String("o", "", "output file, stdout when empty")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	switch *format {
+	case "csv":
+		return productDomain.ExportProductsCSV(ctx, w)
+	case "json":
+		return productDomain.ExportProductsJSON(ctx, w)
+	default:
+		return fmt.Errorf("format must be csv or json")
+	}
+}
+
+func purgeArchivedProducts(ctx context.Context, productDomain *product.ProductDomain, args []string) error {
+	fs := flag.NewFlagSet("purge-archived-products", flag.ContinueOnError)
+	retention := fs.Duration("retention", 90*24*time.Hour, "purge products archived longer than this ago")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	count, err := productDomain.PurgeArchivedProducts(ctx, *retention)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("purged %d archived products\n", count)
+	return nil
+}
+
+func purgeGuestCarts(ctx context.Context, cartDomain *cart.CartDomain, args []string) error {
+	fs := flag.NewFlagSet("purge-guest-carts", flag.ContinueOnError)
+	retention := fs.Duration("retention", 30*24*time.Hour, "purge guest carts unchanged longer than this ago")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	count, err := cartDomain.PurgeGuestCarts(ctx, *retention)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("purged %d guest carts\n", count)
+	return nil
+}
+
+func reconcileStock(ctx context.Context, inventoryDomain *inventory.InventoryDomain, args []string) error {
+	fs := flag.NewFlagSet("reconcile-stock", flag.ContinueOnError)
+	fix := fs.Bool("fix", false, "reset the stocks that differ to their ledger")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	discrepancies, err := inventoryDomain.ReconcileStock(ctx, *fix)
+	if err != nil {
+		return err
+	}
+
+	for _, d := range discrepancies {
+		variant, warehouse := "-", "all"
+		if d.VariantId != nil {
+			variant = strconv.Itoa(*d.VariantId)
+		}
+		if d.WarehouseId != nil {
+			warehouse = strconv.Itoa(*d.WarehouseId)
+		}
+		fmt.Printf(
+			"product %d variant %s warehouse %s: stock %d, ledger %d\n",
+			d.ProductId, variant, warehouse, d.Stock, d.Ledger,
+		)
+	}
+
+	if *fix {
+		fmt.Printf("fixed %d stocks\n", len(discrepancies))
+	} else {
+		fmt.Printf("%d stocks differ from their ledger\n", len(discrepancies))
+	}
+
+	return nil
+}
+
+func importExchangeRates(ctx context.Context, exchangeDomain *exchange.ExchangeDomain, args []string) error {
+	fs := flag.NewFlagSet("import-exchange-rates", flag.ContinueOnError)
+	format := fs.String("format", "", "csv or json, guessed from the file extension when empty")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: import-exchange-rates [-format csv|json] <file>")
+	}
+
+	f, err := os.Open(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if *format == "" {
+		*format = strings.TrimPrefix(filepath.Ext(f.Name()), ".")
+	}
+
+	var rates map[string]money.Rate
+	switch *format {
+	case "csv":
+		rates, err = exchange.ParseRatesCSV(f)
+	case "json":
+		rates, err = exchange.ParseRatesJSON(f, exchangeDomain.Base())
+	default:
+		return fmt.Errorf("format must be csv or json")
+	}
+	if err != nil {
+		return err
+	}
+
+	imported, err := exchangeDomain.ImportRates(ctx, rates)
+	if err != nil {
+		return err
+	}
+
+	for _, rate := range imported {
+		fmt.Printf("1 %s = %s %s\n", exchangeDomain.Base(), rate.Rate, rate.Currency)
+	}
+	fmt.Printf("imported %d exchange rates\n", len(imported))
+
+	return nil
+}
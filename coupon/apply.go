@@ -0,0 +1,192 @@
+package coupon
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sypchal/exchange"
+	"sypchal/money"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Load returns the coupon of a code if the customer can use it now, locking it
+// when lock is true so its usage limit holds until the order is saved.
+func Load(ctx context.Context, tx pgx.Tx, userId int, code string, lock bool) (coupon *Coupon, err error) {
+	query := "select " + couponColumns + " from coupons where code=upper($1) and active"
+	if lock {
+		query += " for update"
+	}
+
+	coupon = &Coupon{}
+	err = tx.QueryRow(ctx, query, code).Scan(coupon.scanFields()...)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			err = ErrCouponNotFound
+		}
+		return
+	}
+
+	now := time.Now()
+	if coupon.StartsAt != nil && now.Before(*coupon.StartsAt) {
+		err = ErrCouponNotStarted
+		return
+	}
+
+	if coupon.EndsAt != nil && !now.Before(*coupon.EndsAt) {
+		err = ErrCouponExpired
+		return
+	}
+
+	if coupon.UsageLimit != nil && coupon.Used >= *coupon.UsageLimit {
+		err = ErrCouponUsedUp
+		return
+	}
+
+	if coupon.UsageLimitPerUser != nil {
+		var used int
+		err = tx.QueryRow(
+			ctx,
+			"select count(*) from coupon_redemptions where coupon_id=$1 and user_id=$2",
+			coupon.Id,
+			userId,
+		).Scan(&used)
+		if err != nil {
+			return
+		}
+
+		if used >= *coupon.UsageLimitPerUser {
+			err = ErrCouponUserLimit
+			return
+		}
+	}
+
+	return
+}
+
+// Line is a cart line a coupon may discount, Id identifies it in
+// Discount.Lines.
+type Line struct {
+	Id        int
+	ProductId int
+	Category  string
+	Total     money.Money
+}
+
+// Localize converts the minimum order value of the coupon, and the amount of
+// a fixed coupon, into currency at the rates.
+func (coupon *Coupon) Localize(rates *exchange.Rates, currency money.Currency) (err error) {
+	if coupon.MinOrderValue, err = rates.Convert(coupon.MinOrderValue, currency); err != nil {
+		return
+	}
+
+	if coupon.Type == TypeFixed {
+		var value money.Money
+		value, err = rates.Convert(money.New(int64(coupon.Value), coupon.Currency), currency)
+		if err != nil {
+			return
+		}
+		coupon.Value = int(value.Amount)
+	}
+	coupon.Currency = currency
+
+	return
+}
+
+// Discount is what a coupon takes off a cart.
+type Discount struct {
+	CouponId     int         `json:"coupon_id"`
+	Code         string      `json:"code"`
+	Type         string      `json:"type"`
+	Amount       money.Money `json:"amount"`
+	FreeShipping bool        `json:"free_shipping"`
+	// Lines is the share of Amount of every discounted line, by line id.
+	Lines map[int]money.Money `json:"-"`
+}
+
+// Apply works out the discount of the coupon on the lines. The amount is
+// spread over the lines it applies to in proportion to their total, the
+// rounding remainder going to the first lines. Percentages round down.
+func (coupon *Coupon) Apply(lines []Line) (discount *Discount, err error) {
+	subtotal, eligible := money.Zero(coupon.Currency), money.Zero(coupon.Currency)
+	applicable := []Line{}
+	for _, line := range lines {
+		subtotal = subtotal.Add(line.Total)
+		if coupon.appliesTo(line) {
+			eligible = eligible.Add(line.Total)
+			applicable = append(applicable, line)
+		}
+	}
+
+	if subtotal.LessThan(coupon.MinOrderValue) {
+		err = ErrMinOrderValue
+		return
+	}
+
+	if len(applicable) == 0 {
+		err = ErrCouponNotApplicable
+		return
+	}
+
+	discount = &Discount{
+		CouponId: coupon.Id,
+		Code:     coupon.Code,
+		Type:     coupon.Type,
+		Amount:   money.Zero(subtotal.Currency),
+		Lines:    map[int]money.Money{},
+	}
+
+	switch coupon.Type {
+	case TypePercentage:
+		discount.Amount = eligible.Percent(coupon.Value, money.RoundDown)
+	case TypeFixed:
+		discount.Amount = money.Min(money.New(int64(coupon.Value), coupon.Currency), eligible)
+	case TypeFreeShipping:
+		discount.FreeShipping = true
+		return
+	}
+
+	if eligible.IsZero() {
+		return
+	}
+
+	allocated := money.Zero(eligible.Currency)
+	for _, line := range applicable {
+		share := discount.Amount.MulRatio(line.Total.Amount, eligible.Amount, money.RoundDown)
+		discount.Lines[line.Id] = share
+		allocated = allocated.Add(share)
+	}
+	unit := money.New(1, eligible.Currency)
+	for i := 0; allocated.LessThan(discount.Amount); i = (i + 1) % len(applicable) {
+		line := applicable[i]
+		if discount.Lines[line.Id].LessThan(line.Total) {
+			discount.Lines[line.Id] = discount.Lines[line.Id].Add(unit)
+			allocated = allocated.Add(unit)
+		}
+	}
+
+	return
+}
+
+func (coupon *Coupon) appliesTo(line Line) bool {
+	if len(coupon.Categories) == 0 && len(coupon.ProductIds) == 0 {
+		return true
+	}
+
+	return slices.Contains(coupon.Categories, line.Category) || slices.Contains(coupon.ProductIds, line.ProductId)
+}
+
+// Redeem counts a use of the coupon by an order.
+func Redeem(ctx context.Context, tx pgx.Tx, discount *Discount, userId int, orderId int) (err error) {
+	_, err = tx.Exec(
+		ctx,
+		"insert into coupon_redemptions(coupon_id,user_id,order_id,amount,currency) values ($1,$2,$3,$4,$5)",
+		discount.CouponId,
+		userId,
+		orderId,
+		discount.Amount.Amount,
+		discount.Amount.Currency,
+	)
+	return
+}
@@ -0,0 +1,260 @@
+package coupon
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strings"
+	"sypchal/money"
+	"sypchal/validation"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+type CouponDomain struct {
+	db        *pgx.Conn
+	validator *validation.Validator
+	// currency is the currency of the coupon amounts
+	currency money.Currency
+}
+
+func NewCouponDomain(db *pgx.Conn, validator *validation.Validator, currency money.Currency) (*CouponDomain, error) {
+	if db == nil {
+		return nil, errors.New("db is nil")
+	}
+
+	if validator == nil {
+		return nil, errors.New("validator is nil")
+	}
+
+	return &CouponDomain{db, validator, currency}, nil
+}
+
+var (
+	// TypePercentage takes Value percent off the items it applies to.
+	TypePercentage = "percentage"
+	// TypeFixed takes Value, in minor units of Currency, off the items it
+	// applies to, at most their price.
+	TypeFixed = "fixed"
+	// TypeFreeShipping waives the shipping cost.
+	TypeFreeShipping = "free_shipping"
+)
+
+type Coupon struct {
+	Id            int            `json:"id"`
+	Code          string         `json:"code"`
+	Type          string         `json:"type"`
+	Value         int            `json:"value"`
+	Currency      money.Currency `json:"currency"`
+	MinOrderValue money.Money    `json:"min_order_value"`
+	// UsageLimit is how many orders can use the coupon and UsageLimitPerUser
+	// how many orders of a customer, unlimited when nil.
+	UsageLimit        *int       `json:"usage_limit"`
+	UsageLimitPerUser *int       `json:"usage_limit_per_user"`
+	StartsAt          *time.Time `json:"starts_at"`
+	EndsAt            *time.Time `json:"ends_at"`
+	// Categories and ProductIds limit the items the coupon applies to, it
+	// applies to the whole cart when both are empty.
+	Categories []string   `json:"categories"`
+	ProductIds []int      `json:"product_ids"`
+	Active     bool       `json:"active"`
+	Used       int        `json:"used"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  *time.Time `json:"updated_at"`
+}
+
+const couponColumns = "id,code,type,value,currency,row(min_order_value,currency),usage_limit,usage_limit_per_user,starts_at,ends_at," +
+	"categories,product_ids,active,(select count(*) from coupon_redemptions where coupon_id=coupons.id),created_at,updated_at"
+
+// scanFields returns the destinations matching couponColumns.
+func (coupon *Coupon) scanFields() []any {
+	return []any{
+		&coupon.Id,
+		&coupon.Code,
+		&coupon.Type,
+		&coupon.Value,
+		&coupon.Currency,
+		&coupon.MinOrderValue,
+		&coupon.UsageLimit,
+		&coupon.UsageLimitPerUser,
+		&coupon.StartsAt,
+		&coupon.EndsAt,
+		&coupon.Categories,
+		&coupon.ProductIds,
+		&coupon.Active,
+		&coupon.Used,
+		&coupon.CreatedAt,
+		&coupon.UpdatedAt,
+	}
+}
+
+type CreateCouponRequest struct {
+	Code              string      `json:"code" validate:"required,max=32"`
+	Type              string      `json:"type" validate:"required,oneof=percentage fixed free_shipping"`
+	Value             int         `json:"value" validate:"gte=0"`
+	MinOrderValue     money.Money `json:"min_order_value" validate:"gte=0"`
+	UsageLimit        *int        `json:"usage_limit" validate:"omitempty,gt=0"`
+	UsageLimitPerUser *int        `json:"usage_limit_per_user" validate:"omitempty,gt=0"`
+	StartsAt          *time.Time  `json:"starts_at"`
+	EndsAt            *time.Time  `json:"ends_at"`
+	Categories        []string    `json:"categories"`
+	ProductIds        []int       `json:"product_ids"`
+	Active            bool        `json:"active"`
+}
+
+func (req *CreateCouponRequest) validate(validator *validation.Validator, currency money.Currency) (err error) {
+	if err = validator.ValidateStruct(req); err != nil {
+		return err
+	}
+
+	if req.MinOrderValue, err = validation.InCurrency("min_order_value", req.MinOrderValue, currency); err != nil {
+		return err
+	}
+
+	if (req.Type == TypePercentage && (req.Value < 1 || req.Value > 100)) || (req.Type == TypeFixed && req.Value < 1) {
+		return ErrInvalidValue
+	}
+
+	if req.StartsAt != nil && req.EndsAt != nil && !req.EndsAt.After(*req.StartsAt) {
+		return ErrInvalidWindow
+	}
+
+	// codes are matched case insensitively
+	req.Code = strings.ToUpper(strings.TrimSpace(req.Code))
+	if req.Categories == nil {
+		req.Categories = []string{}
+	}
+	if req.ProductIds == nil {
+		req.ProductIds = []int{}
+	}
+
+	return nil
+}
+
+func (c *CouponDomain) CreateCoupon(ctx context.Context, req CreateCouponRequest) (coupon *Coupon, err error) {
+	if err = req.validate(c.validator, c.currency); err != nil {
+		return
+	}
+
+	coupon = &Coupon{}
+	err = c.db.QueryRow(
+		ctx,
+		`insert into coupons(code,type,value,currency,min_order_value,usage_limit,usage_limit_per_user,starts_at,ends_at,categories,product_ids,active)
+		values ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12) returning `+couponColumns,
+		req.Code,
+		req.Type,
+		req.Value,
+		req.MinOrderValue.Currency,
+		req.MinOrderValue.Amount,
+		req.UsageLimit,
+		req.UsageLimitPerUser,
+		req.StartsAt,
+		req.EndsAt,
+		req.Categories,
+		req.ProductIds,
+		req.Active,
+	).Scan(coupon.scanFields()...)
+	if err != nil {
+		err = codeTaken(err)
+		return
+	}
+
+	return
+}
+
+type UpdateCouponRequest CreateCouponRequest
+
+// UpdateCouponById replaces every field of the coupon, orders that already
+// used it keep their discount.
+func (c *CouponDomain) UpdateCouponById(ctx context.Context, id int, req UpdateCouponRequest) (coupon *Coupon, err error) {
+	create := CreateCouponRequest(req)
+	if err = create.validate(c.validator, c.currency); err != nil {
+		return
+	}
+
+	coupon = &Coupon{}
+	err = c.db.QueryRow(
+		ctx,
+		`update coupons set code=$1,type=$2,value=$3,currency=$4,min_order_value=$5,usage_limit=$6,usage_limit_per_user=$7,
+		starts_at=$8,ends_at=$9,categories=$10,product_ids=$11,active=$12,updated_at=now()
+		where id=$13 returning `+couponColumns,
+		create.Code,
+		create.Type,
+		create.Value,
+		create.MinOrderValue.Currency,
+		create.MinOrderValue.Amount,
+		create.UsageLimit,
+		create.UsageLimitPerUser,
+		create.StartsAt,
+		create.EndsAt,
+		create.Categories,
+		create.ProductIds,
+		create.Active,
+		id,
+	).Scan(coupon.scanFields()...)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			err = ErrCouponNotFound
+			return
+		}
+		err = codeTaken(err)
+		return
+	}
+
+	return
+}
+
+// codeTaken maps the unique_violation of the coupon code to ErrCodeTaken.
+func codeTaken(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return ErrCodeTaken
+	}
+
+	return err
+}
+
+type GetCouponsRequest struct {
+	Limit  int
+	Offset int
+}
+
+type GetCouponsResponse struct {
+	Coupons []*Coupon `json:"coupons"`
+	Total   int       `json:"total"`
+	MaxPage int       `json:"max_page"`
+}
+
+// GetCoupons lists the coupons, newest first.
+func (c *CouponDomain) GetCoupons(ctx context.Context, req GetCouponsRequest) (res *GetCouponsResponse, err error) {
+	rows, err := c.db.Query(
+		ctx,
+		"select count(*) over(),"+couponColumns+" from coupons order by id desc limit $1 offset $2",
+		req.Limit,
+		req.Offset,
+	)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	var total int
+	res = &GetCouponsResponse{Coupons: []*Coupon{}}
+	for rows.Next() {
+		coupon := &Coupon{}
+		if err = rows.Scan(append([]any{&total}, coupon.scanFields()...)...); err != nil {
+			return
+		}
+		res.Coupons = append(res.Coupons, coupon)
+	}
+	if err = rows.Err(); err != nil {
+		return
+	}
+
+	res.Total = total
+	res.MaxPage = int(math.Ceil(float64(total) / float64(req.Limit)))
+
+	return
+}
@@ -0,0 +1,34 @@
+package coupon
+
+import "errors"
+
+var ErrCouponNotFound = errors.New("coupon not found")
+var ErrCodeTaken = errors.New("coupon code already exists")
+var ErrInvalidValue = errors.New("percentage coupons take 1 to 100, fixed coupons more than 0")
+var ErrInvalidWindow = errors.New("coupon must end after it starts")
+var ErrCouponNotStarted = errors.New("coupon is not valid yet")
+var ErrCouponExpired = errors.New("coupon has expired")
+var ErrCouponUsedUp = errors.New("coupon has been used up")
+var ErrCouponUserLimit = errors.New("coupon already used the maximum number of times")
+var ErrMinOrderValue = errors.New("order is below the coupon minimum")
+var ErrCouponNotApplicable = errors.New("coupon doesn't apply to any item in the cart")
+
+// IsUnusable reports whether err is a reason the coupon can't be used on the
+// cart, as opposed to a database error.
+func IsUnusable(err error) bool {
+	for _, target := range []error{
+		ErrCouponNotFound,
+		ErrCouponNotStarted,
+		ErrCouponExpired,
+		ErrCouponUsedUp,
+		ErrCouponUserLimit,
+		ErrMinOrderValue,
+		ErrCouponNotApplicable,
+	} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,8 @@
+package exchange
+
+import "errors"
+
+var ErrRateNotFound = errors.New("exchange rate not found")
+var ErrNoRate = errors.New("currency has no exchange rate")
+var ErrBaseCurrency = errors.New("the base currency has no exchange rate")
+var ErrInvalidRateFile = errors.New("invalid exchange rate file")
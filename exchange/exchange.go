@@ -0,0 +1,247 @@
+package exchange
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sypchal/money"
+	"sypchal/validation"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type ExchangeDomain struct {
+	db        *pgx.Conn
+	validator *validation.Validator
+	// base is the currency of the catalog, the rates are from it
+	base money.Currency
+}
+
+func NewExchangeDomain(db *pgx.Conn, validator *validation.Validator, base money.Currency) (*ExchangeDomain, error) {
+	if db == nil {
+		return nil, errors.New("db is nil")
+	}
+
+	if validator == nil {
+		return nil, errors.New("validator is nil")
+	}
+
+	return &ExchangeDomain{db, validator, base}, nil
+}
+
+// Base is the currency of the catalog, the currency the rates are from.
+func (e *ExchangeDomain) Base() money.Currency {
+	return e.base
+}
+
+// Rate is how many units of Currency one unit of the base currency buys.
+type Rate struct {
+	Currency  money.Currency `json:"currency"`
+	Rate      money.Rate     `json:"rate"`
+	UpdatedAt time.Time      `json:"updated_at"`
+}
+
+const rateColumns = "currency,rate::text,updated_at"
+
+// scan reads a row of rateColumns.
+func (rate *Rate) scan(row pgx.Row) error {
+	return row.Scan(&rate.Currency, &rate.Rate, &rate.UpdatedAt)
+}
+
+type SetRateRequest struct {
+	Rate money.Rate `json:"rate"`
+}
+
+// SetRate creates or replaces the rate of a currency. Placed orders keep the
+// rate they were charged at.
+func (e *ExchangeDomain) SetRate(ctx context.Context, code string, req SetRateRequest) (rate *Rate, err error) {
+	currency, err := e.quotedCurrency(code)
+	if err != nil {
+		return
+	}
+
+	if req.Rate.IsZero() {
+		err = validation.NewFieldErrors(map[string]string{"rate": money.ErrInvalidRate.Error()})
+		return
+	}
+
+	rate = &Rate{}
+	err = rate.scan(e.db.QueryRow(
+		ctx,
+		`insert into exchange_rates(currency,rate) values ($1,$2)
+		on conflict (currency) do update set rate=excluded.rate,updated_at=now()
+		returning `+rateColumns,
+		currency,
+		req.Rate.String(),
+	))
+	return
+}
+
+func (e *ExchangeDomain) DeleteRate(ctx context.Context, code string) (err error) {
+	currency, err := e.quotedCurrency(code)
+	if err != nil {
+		return
+	}
+
+	tag, err := e.db.Exec(ctx, "delete from exchange_rates where currency=$1", currency)
+	if err != nil {
+		return
+	}
+
+	if tag.RowsAffected() == 0 {
+		err = ErrRateNotFound
+		return
+	}
+
+	return
+}
+
+// GetRates lists the rates by currency.
+func (e *ExchangeDomain) GetRates(ctx context.Context) (rates []*Rate, err error) {
+	rows, err := e.db.Query(ctx, "select "+rateColumns+" from exchange_rates order by currency")
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	rates = []*Rate{}
+	for rows.Next() {
+		rate := &Rate{}
+		if err = rate.scan(rows); err != nil {
+			return
+		}
+		rates = append(rates, rate)
+	}
+	if err = rows.Err(); err != nil {
+		return
+	}
+
+	return
+}
+
+// quotedCurrency parses the currency of a rate, any currency but the base.
+func (e *ExchangeDomain) quotedCurrency(code string) (currency money.Currency, err error) {
+	currency, err = money.ParseCurrency(code)
+	if err != nil {
+		return
+	}
+
+	if currency == e.base {
+		err = ErrBaseCurrency
+		return
+	}
+
+	return
+}
+
+// Rates are the exchange rates from the base currency, loaded at once so the
+// amounts of an order are converted at the same rates.
+type Rates struct {
+	Base  money.Currency
+	rates map[money.Currency]money.Rate
+}
+
+type querier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}
+
+// LoadRates loads the current rates from base.
+func LoadRates(ctx context.Context, db querier, base money.Currency) (rates *Rates, err error) {
+	rows, err := db.Query(ctx, "select "+rateColumns+" from exchange_rates")
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	rates = &Rates{base, map[money.Currency]money.Rate{}}
+	for rows.Next() {
+		rate := &Rate{}
+		if err = rate.scan(rows); err != nil {
+			return
+		}
+		rates.rates[rate.Currency] = rate.Rate
+	}
+	if err = rows.Err(); err != nil {
+		return
+	}
+
+	return
+}
+
+// LoadRates loads the current rates from the base currency.
+func (e *ExchangeDomain) LoadRates(ctx context.Context) (*Rates, error) {
+	return LoadRates(ctx, e.db, e.base)
+}
+
+// Supports reports whether amounts can be converted into currency.
+func (r *Rates) Supports(currency money.Currency) bool {
+	if currency == r.Base {
+		return true
+	}
+
+	_, ok := r.rates[currency]
+	return ok
+}
+
+// Currencies lists the base currency and the currencies with a rate.
+func (r *Rates) Currencies() []money.Currency {
+	currencies := make([]money.Currency, 0, len(r.rates))
+	for currency := range r.rates {
+		currencies = append(currencies, currency)
+	}
+	slices.Sort(currencies)
+
+	return append([]money.Currency{r.Base}, currencies...)
+}
+
+// Rate is the rate from currency from to currency to, through the base
+// currency when neither is the base.
+func (r *Rates) Rate(from, to money.Currency) (rate money.Rate, err error) {
+	if from == to {
+		return money.ParseRate("1")
+	}
+
+	if from != r.Base {
+		fromRate, ok := r.rates[from]
+		if !ok {
+			err = fmt.Errorf("%w: %s", ErrNoRate, from)
+			return
+		}
+		if to == r.Base {
+			return fromRate.Inverse(), nil
+		}
+		rate = fromRate.Inverse()
+	}
+
+	toRate, ok := r.rates[to]
+	if !ok {
+		err = fmt.Errorf("%w: %s", ErrNoRate, to)
+		return
+	}
+	if rate.IsZero() {
+		return toRate, nil
+	}
+
+	return rate.Mul(toRate), nil
+}
+
+// Convert is m in currency, rounded half up. An amount without a currency is
+// in the base currency.
+func (r *Rates) Convert(m money.Money, currency money.Currency) (money.Money, error) {
+	if m.Currency == "" {
+		m.Currency = r.Base
+	}
+
+	if m.Currency == currency {
+		return m, nil
+	}
+
+	rate, err := r.Rate(m.Currency, currency)
+	if err != nil {
+		return m, err
+	}
+
+	return m.Convert(currency, rate, money.RoundHalfUp), nil
+}
@@ -0,0 +1,119 @@
+package exchange
+
+import (
+	"errors"
+	"sypchal/money"
+	"testing"
+)
+
+func testRates(t *testing.T) *Rates {
+	t.Helper()
+	rates := &Rates{"IDR", map[money.Currency]money.Rate{}}
+	for currency, s := range map[money.Currency]string{
+		"USD": "0.000063",
+		"SGD": "0.000085",
+		"JPY": "0.0095",
+	} {
+		rate, err := money.ParseRate(s)
+		if err != nil {
+			t.Fatalf("parse rate %q: %v", s, err)
+		}
+		rates.rates[currency] = rate
+	}
+
+	return rates
+}
+
+func TestRatesRate(t *testing.T) {
+	rates := testRates(t)
+
+	tests := []struct {
+		from, to money.Currency
+		want     string
+		err      error
+	}{
+		{"IDR", "IDR", "1", nil},
+		{"USD", "USD", "1", nil},
+		{"IDR", "USD", "0.000063", nil},
+		{"USD", "IDR", "15873.0158730159", nil},
+		// through the base, 0.000085 / 0.000063
+		{"USD", "SGD", "1.3492063492", nil},
+		{"SGD", "USD", "0.7411764706", nil},
+		{"IDR", "EUR", "", ErrNoRate},
+		{"EUR", "IDR", "", ErrNoRate},
+		{"EUR", "USD", "", ErrNoRate},
+		{"USD", "EUR", "", ErrNoRate},
+	}
+
+	for _, tt := range tests {
+		rate, err := rates.Rate(tt.from, tt.to)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("%s to %s: got error %v, want %v", tt.from, tt.to, err, tt.err)
+			continue
+		}
+		if err == nil && rate.String() != tt.want {
+			t.Errorf("%s to %s: got %s, want %s", tt.from, tt.to, rate, tt.want)
+		}
+	}
+}
+
+func TestRatesConvert(t *testing.T) {
+	rates := testRates(t)
+
+	tests := []struct {
+		name     string
+		money    money.Money
+		currency money.Currency
+		want     money.Money
+		err      error
+	}{
+		{"same currency", money.New(945, "USD"), "USD", money.New(945, "USD"), nil},
+		{"base to cents", money.New(150000, "IDR"), "USD", money.New(945, "USD"), nil},
+		// 945 cents / 0.000063 is Rp150,000 exactly
+		{"cents to base", money.New(945, "USD"), "IDR", money.New(150000, "IDR"), nil},
+		{"no currency is the base", money.Money{Amount: 150000}, "USD", money.New(945, "USD"), nil},
+		// Rp10,000 at 0.0095 is ¥95
+		{"base to no minor unit", money.New(10000, "IDR"), "JPY", money.New(95, "JPY"), nil},
+		// $1 through the base is 1.3492... SGD, rounded half up
+		{"through the base", money.New(100, "USD"), "SGD", money.New(135, "SGD"), nil},
+		// Rp7,937 at 0.000063 is 50.0031 cents
+		{"rounds to the minor unit", money.New(7937, "IDR"), "USD", money.New(50, "USD"), nil},
+		// Rp10 at 0.000063 is 0.063 cents
+		{"rounds small amounts to zero", money.New(10, "IDR"), "USD", money.New(0, "USD"), nil},
+		// $0.01 is Rp158.73
+		{"rounds up over half", money.New(1, "USD"), "IDR", money.New(159, "IDR"), nil},
+		{"unknown currency", money.New(100, "IDR"), "EUR", money.New(100, "IDR"), ErrNoRate},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := rates.Convert(tt.money, tt.currency)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("got error %v, want %v", err, tt.err)
+			}
+			if got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRatesCurrencies(t *testing.T) {
+	rates := testRates(t)
+
+	want := []money.Currency{"IDR", "JPY", "SGD", "USD"}
+	got := rates.Currencies()
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("got %v, want %v", got, want)
+			break
+		}
+	}
+
+	if !rates.Supports("IDR") || !rates.Supports("USD") || rates.Supports("EUR") {
+		t.Errorf("supports: got IDR %v, USD %v, EUR %v", rates.Supports("IDR"), rates.Supports("USD"), rates.Supports("EUR"))
+	}
+}
@@ -0,0 +1,123 @@
+package exchange
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sypchal/money"
+)
+
+// RateFile is the json rate file, the format of the common exchange rate
+// feeds, e.g. {"base": "IDR", "rates": {"USD": 0.000063}}. Base, when given,
+// must be the base currency.
+type RateFile struct {
+	Base  string                `json:"base"`
+	Rates map[string]money.Rate `json:"rates"`
+}
+
+// ParseRatesCSV reads a csv file of currency,rate lines, the first line being
+// the header.
+func ParseRatesCSV(r io.Reader) (rates map[string]money.Rate, err error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			err = ErrInvalidRateFile
+		}
+		return
+	}
+
+	if len(header) != 2 || !strings.EqualFold(strings.TrimPrefix(header[0], "\ufeff"), "currency") ||
+		!strings.EqualFold(header[1], "rate") {
+		err = fmt.Errorf("%w: the header must be currency,rate", ErrInvalidRateFile)
+		return
+	}
+
+	rates = map[string]money.Rate{}
+	for {
+		var record []string
+		record, err = reader.Read()
+		if errors.Is(err, io.EOF) {
+			return rates, nil
+		}
+		if err != nil {
+			err = fmt.Errorf("%w: %w", ErrInvalidRateFile, err)
+			return
+		}
+
+		line, _ := reader.FieldPos(0)
+		rate, perr := money.ParseRate(record[1])
+		if perr != nil {
+			err = fmt.Errorf("%w: line %d: %w", ErrInvalidRateFile, line, perr)
+			return
+		}
+		rates[record[0]] = rate
+	}
+}
+
+// ParseRatesJSON reads a json rate file, see RateFile.
+func ParseRatesJSON(r io.Reader, base money.Currency) (rates map[string]money.Rate, err error) {
+	var file RateFile
+	if err = json.NewDecoder(r).Decode(&file); err != nil {
+		err = fmt.Errorf("%w: %w", ErrInvalidRateFile, err)
+		return
+	}
+
+	if file.Base != "" && !strings.EqualFold(file.Base, string(base)) {
+		err = fmt.Errorf("%w: the rates must be from %s, not %s", ErrInvalidRateFile, base, file.Base)
+		return
+	}
+
+	return file.Rates, nil
+}
+
+// ImportRates sets the rates of the currencies at once, all of them or none.
+// The base currency is skipped, feeds often list it at 1, and the currencies
+// missing from the file keep their rate.
+func (e *ExchangeDomain) ImportRates(ctx context.Context, rates map[string]money.Rate) (imported []*Rate, err error) {
+	tx, err := e.db.Begin(ctx)
+	if err != nil {
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	imported = []*Rate{}
+	for code, value := range rates {
+		var currency money.Currency
+		currency, err = money.ParseCurrency(code)
+		if err != nil {
+			return
+		}
+		if currency == e.base {
+			continue
+		}
+
+		rate := &Rate{}
+		err = rate.scan(tx.QueryRow(
+			ctx,
+			`insert into exchange_rates(currency,rate) values ($1,$2)
+			on conflict (currency) do update set rate=excluded.rate,updated_at=now()
+			returning `+rateColumns,
+			currency,
+			value.String(),
+		))
+		if err != nil {
+			return
+		}
+		imported = append(imported, rate)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return
+	}
+
+	slices.SortFunc(imported, func(a, b *Rate) int { return strings.Compare(string(a.Currency), string(b.Currency)) })
+	return
+}
@@ -0,0 +1,12 @@
+package inventory
+
+import "errors"
+
+var ErrProductNotFound = errors.New("product not found")
+var ErrVariantNotFound = errors.New("variant not found")
+var ErrVariantRequired = errors.New("variant is required for products with variants")
+var ErrInsufficientStock = errors.New("insufficient stock")
+var ErrWarehouseNotFound = errors.New("warehouse not found")
+var ErrWarehouseCodeTaken = errors.New("warehouse code already exists")
+var ErrWarehouseInUse = errors.New("warehouse has stock or orders")
+var ErrDefaultWarehouse = errors.New("the default warehouse can't be deleted or unset")
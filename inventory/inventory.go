@@ -0,0 +1,396 @@
+package inventory
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sypchal/alert"
+	"sypchal/audit"
+	"sypchal/notify"
+	"sypchal/validation"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+type InventoryDomain struct {
+	db        *pgx.Conn
+	validator *validation.Validator
+	notifier  notify.Notifier
+}
+
+func NewInventoryDomain(db *pgx.Conn, validator *validation.Validator, notifier notify.Notifier) (*InventoryDomain, error) {
+	if db == nil {
+		return nil, errors.New("db is nil")
+	}
+
+	if validator == nil {
+		return nil, errors.New("validator is nil")
+	}
+
+	return &InventoryDomain{db, validator, notifier}, nil
+}
+
+var (
+	ReasonReceipt      = "receipt"
+	ReasonSale         = "sale"
+	ReasonCancellation = "cancellation"
+	ReasonAdjustment   = "adjustment"
+	ReasonReturn       = "return"
+)
+
+// Movement is a change of the stock of a product, or of one of its variants
+// when VariantId is set, in a warehouse. Quantity is negative when stock goes
+// out, Balance is the stock left in the warehouse.
+type Movement struct {
+	Id          int       `json:"id"`
+	WarehouseId int       `json:"warehouse_id"`
+	ProductId   int       `json:"product_id"`
+	VariantId   *int      `json:"variant_id"`
+	Quantity    int       `json:"quantity"`
+	Balance     int       `json:"balance"`
+	Reason      string    `json:"reason"`
+	Reference   string    `json:"reference"`
+	Note        string    `json:"note"`
+	Actor       string    `json:"actor"`
+	RequestId   *string   `json:"request_id"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// Move applies the movement to the total stock of the product or variant and
+// to the warehouse, then records it in the ledger. It fails with
+// ErrInsufficientStock when the stock would become negative.
+func Move(ctx context.Context, tx pgx.Tx, m *Movement) (err error) {
+	var tag pgconn.CommandTag
+	if m.VariantId != nil {
+		tag, err = tx.Exec(
+			ctx,
+			"update product_variants set stock=stock+$1,updated_at=now() where id=$2 and stock+$1 >= 0",
+			m.Quantity,
+			*m.VariantId,
+		)
+		if err == nil && tag.RowsAffected() > 0 {
+			_, err = tx.Exec(ctx, "update products set version=version+1 where id=$1", m.ProductId)
+		}
+	} else {
+		tag, err = tx.Exec(
+			ctx,
+			"update products set stock=stock+$1,version=version+1,updated_at=now() where id=$2 and stock+$1 >= 0",
+			m.Quantity,
+			m.ProductId,
+		)
+	}
+	if err != nil {
+		return
+	}
+
+	if tag.RowsAffected() == 0 {
+		err = ErrInsufficientStock
+		return
+	}
+
+	return Record(ctx, tx, m)
+}
+
+// Record applies the movement to the warehouse and adds it to the ledger
+// without changing the total stock, for changes that already set it, e.g. a
+// product update. The default warehouse is used when WarehouseId is 0.
+func Record(ctx context.Context, tx pgx.Tx, m *Movement) (err error) {
+	err = tx.QueryRow(
+		ctx,
+		`insert into warehouse_stocks(warehouse_id,product_id,variant_id,stock)
+		values (coalesce(nullif($1,0),(select id from warehouses where is_default)),$2,$3,$4)
+		on conflict (warehouse_id,product_id,variant_id) do update set stock=warehouse_stocks.stock+excluded.stock,updated_at=now()
+		returning warehouse_id,stock`,
+		m.WarehouseId,
+		m.ProductId,
+		m.VariantId,
+		m.Quantity,
+	).Scan(&m.WarehouseId, &m.Balance)
+	if err != nil {
+		// check_violation, the warehouse stock can't become negative
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23514" {
+			err = ErrInsufficientStock
+		}
+		return
+	}
+
+	actor := audit.FromContext(ctx)
+	m.Actor = actor.Name
+	if actor.RequestId != "" {
+		m.RequestId = &actor.RequestId
+	}
+
+	return tx.QueryRow(
+		ctx,
+		`insert into stock_movements(warehouse_id,product_id,variant_id,quantity,balance,reason,reference,note,actor,request_id)
+		values ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10) returning id,created_at`,
+		m.WarehouseId,
+		m.ProductId,
+		m.VariantId,
+		m.Quantity,
+		m.Balance,
+		m.Reason,
+		m.Reference,
+		m.Note,
+		m.Actor,
+		m.RequestId,
+	).Scan(&m.Id, &m.CreatedAt)
+}
+
+type AdjustStockRequest struct {
+	ProductId int
+	// WarehouseId is the default warehouse when 0.
+	WarehouseId int    `json:"warehouse_id"`
+	VariantId   *int   `json:"variant_id"`
+	Quantity    int    `json:"quantity" validate:"required"`
+	Reason      string `json:"reason" validate:"required,oneof=receipt adjustment return cancellation"`
+	Reference   string `json:"reference" validate:"max=255"`
+	Note        string `json:"note" validate:"max=1000"`
+}
+
+// AdjustStock moves stock in or out by hand, e.g. a supplier delivery or a
+// stock count. Sales are only recorded by orders.
+func (i *InventoryDomain) AdjustStock(ctx context.Context, req AdjustStockRequest) (movement *Movement, err error) {
+	if err = i.validator.ValidateStruct(req); err != nil {
+		return
+	}
+
+	tx, err := i.db.Begin(ctx)
+	if err != nil {
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	var variantCount, stock int
+	err = tx.QueryRow(
+		ctx,
+		`select (select count(*) from product_variants where product_id=products.id), stock
+		from products where id=$1 and deleted_at is null for update`,
+		req.ProductId,
+	).Scan(&variantCount, &stock)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			err = ErrProductNotFound
+		}
+		return
+	}
+
+	// products with variants keep their stock per sku
+	if variantCount > 0 && req.VariantId == nil {
+		err = ErrVariantRequired
+		return
+	}
+
+	if req.VariantId != nil {
+		err = tx.QueryRow(
+			ctx,
+			"select stock from product_variants where id=$1 and product_id=$2",
+			*req.VariantId,
+			req.ProductId,
+		).Scan(&stock)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				err = ErrVariantNotFound
+			}
+			return
+		}
+	}
+
+	if req.WarehouseId != 0 {
+		if _, err = getWarehouse(ctx, tx, req.WarehouseId); err != nil {
+			return
+		}
+	}
+
+	movement = &Movement{
+		WarehouseId: req.WarehouseId,
+		ProductId:   req.ProductId,
+		VariantId:   req.VariantId,
+		Quantity:    req.Quantity,
+		Reason:      req.Reason,
+		Reference:   req.Reference,
+		Note:        req.Note,
+	}
+	if err = Move(ctx, tx, movement); err != nil {
+		return
+	}
+
+	var msgs []notify.Message
+	if stock == 0 && req.Quantity > 0 {
+		msgs, err = alert.ClaimBackInStock(ctx, tx, req.ProductId, req.VariantId)
+		if err != nil {
+			return
+		}
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return
+	}
+	notify.SendAll(ctx, i.notifier, msgs)
+
+	return
+}
+
+type GetMovementsRequest struct {
+	ProductId int
+	// VariantId only lists the movements of that variant when not nil.
+	VariantId *int
+	// WarehouseId only lists the movements of that warehouse when not nil.
+	WarehouseId *int
+	Limit       int
+	Offset      int
+}
+
+type GetMovementsResponse struct {
+	Movements []*Movement `json:"movements"`
+	Total     int         `json:"total"`
+	MaxPage   int         `json:"max_page"`
+}
+
+// GetMovements lists the stock movements of a product, newest first.
+func (i *InventoryDomain) GetMovements(ctx context.Context, req GetMovementsRequest) (res *GetMovementsResponse, err error) {
+	rows, err := i.db.Query(
+		ctx,
+		`select count(*) over(),id,warehouse_id,product_id,variant_id,quantity,balance,reason,reference,note,actor,request_id,created_at
+		from stock_movements
+		where product_id=$1 and ($2::integer is null or variant_id=$2) and ($3::integer is null or warehouse_id=$3)
+		order by created_at desc, id desc limit $4 offset $5`,
+		req.ProductId,
+		req.VariantId,
+		req.WarehouseId,
+		req.Limit,
+		req.Offset,
+	)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	var total int
+	res = &GetMovementsResponse{Movements: []*Movement{}}
+	for rows.Next() {
+		movement := &Movement{}
+		if err = rows.Scan(
+			&total,
+			&movement.Id,
+			&movement.WarehouseId,
+			&movement.ProductId,
+			&movement.VariantId,
+			&movement.Quantity,
+			&movement.Balance,
+			&movement.Reason,
+			&movement.Reference,
+			&movement.Note,
+			&movement.Actor,
+			&movement.RequestId,
+			&movement.CreatedAt,
+		); err != nil {
+			return
+		}
+		res.Movements = append(res.Movements, movement)
+	}
+
+	res.Total = total
+	res.MaxPage = int(math.Ceil(float64(total) / float64(req.Limit)))
+
+	return
+}
+
+// Discrepancy is a stock that doesn't match the sum of its movements. The
+// stock is the total of every warehouse when WarehouseId is nil.
+type Discrepancy struct {
+	WarehouseId *int `json:"warehouse_id"`
+	ProductId   int  `json:"product_id"`
+	VariantId   *int `json:"variant_id"`
+	Stock       int  `json:"stock"`
+	Ledger      int  `json:"ledger"`
+}
+
+// ReconcileStock lists the products and variants whose total or per
+// warehouse stock differs from their ledger, e.g. after the stock was edited
+// in the database. With fix, the stock is reset to the ledger, which is the
+// source of truth.
+func (i *InventoryDomain) ReconcileStock(ctx context.Context, fix bool) (discrepancies []*Discrepancy, err error) {
+	tx, err := i.db.Begin(ctx)
+	if err != nil {
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(
+		ctx,
+		`select null::integer, products.id, null::integer, products.stock, coalesce(sum(quantity),0)
+		from products left join stock_movements on(stock_movements.product_id=products.id and variant_id is null)
+		group by products.id having products.stock <> coalesce(sum(quantity),0)
+		union all
+		select null, product_variants.product_id, product_variants.id, product_variants.stock, coalesce(sum(quantity),0)
+		from product_variants left join stock_movements on(variant_id=product_variants.id)
+		group by product_variants.id having product_variants.stock <> coalesce(sum(quantity),0)
+		union all
+		select warehouse_stocks.warehouse_id, warehouse_stocks.product_id, warehouse_stocks.variant_id, warehouse_stocks.stock,
+			coalesce(sum(quantity),0)
+		from warehouse_stocks left join stock_movements on(
+			stock_movements.warehouse_id=warehouse_stocks.warehouse_id and
+			stock_movements.product_id=warehouse_stocks.product_id and
+			stock_movements.variant_id is not distinct from warehouse_stocks.variant_id
+		)
+		group by warehouse_stocks.id having warehouse_stocks.stock <> coalesce(sum(quantity),0)
+		order by 2, 3 nulls first, 1 nulls first`,
+	)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	discrepancies = []*Discrepancy{}
+	for rows.Next() {
+		discrepancy := &Discrepancy{}
+		if err = rows.Scan(
+			&discrepancy.WarehouseId,
+			&discrepancy.ProductId,
+			&discrepancy.VariantId,
+			&discrepancy.Stock,
+			&discrepancy.Ledger,
+		); err != nil {
+			return
+		}
+		discrepancies = append(discrepancies, discrepancy)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return
+	}
+
+	if !fix || len(discrepancies) == 0 {
+		return
+	}
+
+	b := &pgx.Batch{}
+	for _, d := range discrepancies {
+		if d.WarehouseId != nil {
+			b.Queue(`update warehouse_stocks set stock=$1,updated_at=now() where warehouse_id=$2 and product_id=$3
+			and variant_id is not distinct from $4`, d.Ledger, *d.WarehouseId, d.ProductId, d.VariantId)
+			continue
+		}
+
+		if d.VariantId != nil {
+			b.Queue(`update product_variants set stock=$1,updated_at=now() where id=$2`, d.Ledger, *d.VariantId)
+			b.Queue(`update products set version=version+1 where id=$1`, d.ProductId)
+			continue
+		}
+
+		b.Queue(`update products set stock=$1,version=version+1,updated_at=now() where id=$2`, d.Ledger, d.ProductId)
+	}
+	if err = tx.SendBatch(ctx, b).Close(); err != nil {
+		return
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return
+	}
+
+	return
+}
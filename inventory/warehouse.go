@@ -0,0 +1,279 @@
+package inventory
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+type Warehouse struct {
+	Id        int        `json:"id"`
+	Code      string     `json:"code"`
+	Name      string     `json:"name"`
+	Address   string     `json:"address"`
+	Priority  int        `json:"priority"`
+	IsDefault bool       `json:"is_default"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt *time.Time `json:"updated_at"`
+}
+
+const warehouseColumns = "id,code,name,address,priority,is_default,created_at,updated_at"
+
+func scanWarehouse(row pgx.Row, warehouse *Warehouse) error {
+	return row.Scan(
+		&warehouse.Id,
+		&warehouse.Code,
+		&warehouse.Name,
+		&warehouse.Address,
+		&warehouse.Priority,
+		&warehouse.IsDefault,
+		&warehouse.CreatedAt,
+		&warehouse.UpdatedAt,
+	)
+}
+
+type CreateWarehouseRequest struct {
+	Code     string `json:"code" validate:"required,max=32"`
+	Name     string `json:"name" validate:"required"`
+	Address  string `json:"address"`
+	Priority int    `json:"priority" validate:"gte=0"`
+	// IsDefault makes the warehouse the default one instead of the current
+	// default.
+	IsDefault bool `json:"is_default"`
+}
+
+func (i *InventoryDomain) CreateWarehouse(ctx context.Context, req CreateWarehouseRequest) (warehouse *Warehouse, err error) {
+	if err = i.validator.ValidateStruct(req); err != nil {
+		return
+	}
+
+	tx, err := i.db.Begin(ctx)
+	if err != nil {
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	if err = checkWarehouseCode(ctx, tx, req.Code, 0); err != nil {
+		return
+	}
+
+	if req.IsDefault {
+		if _, err = tx.Exec(ctx, "update warehouses set is_default=false,updated_at=now() where is_default"); err != nil {
+			return
+		}
+	}
+
+	warehouse = &Warehouse{}
+	err = scanWarehouse(tx.QueryRow(
+		ctx,
+		`insert into warehouses(code,name,address,priority,is_default) values ($1,$2,$3,$4,$5)
+		returning `+warehouseColumns,
+		req.Code,
+		req.Name,
+		req.Address,
+		req.Priority,
+		req.IsDefault,
+	), warehouse)
+	if err != nil {
+		return
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return
+	}
+
+	return
+}
+
+type UpdateWarehouseRequest CreateWarehouseRequest
+
+// UpdateWarehouseById replaces every field of the warehouse. The default
+// warehouse can only be changed by making another warehouse the default.
+func (i *InventoryDomain) UpdateWarehouseById(ctx context.Context, id int, req UpdateWarehouseRequest) (warehouse *Warehouse, err error) {
+	if err = i.validator.ValidateStruct(req); err != nil {
+		return
+	}
+
+	tx, err := i.db.Begin(ctx)
+	if err != nil {
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	before, err := getWarehouse(ctx, tx, id)
+	if err != nil {
+		return
+	}
+
+	if before.IsDefault && !req.IsDefault {
+		err = ErrDefaultWarehouse
+		return
+	}
+
+	if err = checkWarehouseCode(ctx, tx, req.Code, id); err != nil {
+		return
+	}
+
+	if req.IsDefault && !before.IsDefault {
+		if _, err = tx.Exec(ctx, "update warehouses set is_default=false,updated_at=now() where is_default"); err != nil {
+			return
+		}
+	}
+
+	warehouse = &Warehouse{}
+	err = scanWarehouse(tx.QueryRow(
+		ctx,
+		`update warehouses set code=$1,name=$2,address=$3,priority=$4,is_default=$5,updated_at=now() where id=$6
+		returning `+warehouseColumns,
+		req.Code,
+		req.Name,
+		req.Address,
+		req.Priority,
+		req.IsDefault,
+		id,
+	), warehouse)
+	if err != nil {
+		return
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return
+	}
+
+	return
+}
+
+// DeleteWarehouseById deletes a warehouse that never held stock nor shipped
+// an order, the others are kept for their ledger.
+func (i *InventoryDomain) DeleteWarehouseById(ctx context.Context, id int) (err error) {
+	tx, err := i.db.Begin(ctx)
+	if err != nil {
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	warehouse, err := getWarehouse(ctx, tx, id)
+	if err != nil {
+		return
+	}
+
+	if warehouse.IsDefault {
+		err = ErrDefaultWarehouse
+		return
+	}
+
+	if _, err = tx.Exec(ctx, "delete from warehouse_stocks where warehouse_id=$1 and stock=0", id); err != nil {
+		return
+	}
+
+	if _, err = tx.Exec(ctx, "delete from warehouses where id=$1", id); err != nil {
+		// foreign_key_violation, the warehouse is referenced by stock,
+		// movements or order allocations
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+			err = ErrWarehouseInUse
+		}
+		return
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return
+	}
+
+	return
+}
+
+// GetWarehouses lists the warehouses in the order they ship from.
+func (i *InventoryDomain) GetWarehouses(ctx context.Context) (warehouses []*Warehouse, err error) {
+	rows, err := i.db.Query(ctx, "select "+warehouseColumns+" from warehouses order by priority, id")
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	warehouses = []*Warehouse{}
+	for rows.Next() {
+		warehouse := &Warehouse{}
+		if err = scanWarehouse(rows, warehouse); err != nil {
+			return
+		}
+		warehouses = append(warehouses, warehouse)
+	}
+
+	err = rows.Err()
+	return
+}
+
+// WarehouseStock is the stock of a product, or of one of its variants when
+// VariantId is set, in a warehouse.
+type WarehouseStock struct {
+	WarehouseId   int        `json:"warehouse_id"`
+	WarehouseCode string     `json:"warehouse_code"`
+	VariantId     *int       `json:"variant_id"`
+	Stock         int        `json:"stock"`
+	UpdatedAt     *time.Time `json:"updated_at"`
+}
+
+// GetProductStocks lists the stock of a product and its variants per
+// warehouse.
+func (i *InventoryDomain) GetProductStocks(ctx context.Context, productId int) (stocks []*WarehouseStock, err error) {
+	rows, err := i.db.Query(
+		ctx,
+		`select warehouses.id,warehouses.code,variant_id,stock,warehouse_stocks.updated_at
+		from warehouse_stocks inner join warehouses on(warehouse_id=warehouses.id)
+		where product_id=$1 order by variant_id nulls first, warehouses.priority, warehouses.id`,
+		productId,
+	)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	stocks = []*WarehouseStock{}
+	for rows.Next() {
+		stock := &WarehouseStock{}
+		if err = rows.Scan(
+			&stock.WarehouseId,
+			&stock.WarehouseCode,
+			&stock.VariantId,
+			&stock.Stock,
+			&stock.UpdatedAt,
+		); err != nil {
+			return
+		}
+		stocks = append(stocks, stock)
+	}
+
+	err = rows.Err()
+	return
+}
+
+func getWarehouse(ctx context.Context, tx pgx.Tx, id int) (warehouse *Warehouse, err error) {
+	warehouse = &Warehouse{}
+	err = scanWarehouse(tx.QueryRow(ctx, "select "+warehouseColumns+" from warehouses where id=$1", id), warehouse)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			err = ErrWarehouseNotFound
+		}
+		return
+	}
+
+	return
+}
+
+func checkWarehouseCode(ctx context.Context, tx pgx.Tx, code string, exceptId int) (err error) {
+	var count int
+	err = tx.QueryRow(ctx, "select count(*) from warehouses where code=$1 and id<>$2", code, exceptId).Scan(&count)
+	if err != nil {
+		return
+	}
+
+	if count > 0 {
+		err = ErrWarehouseCodeTaken
+	}
+
+	return
+}
@@ -0,0 +1,7 @@
+package invoice
+
+import "errors"
+
+var ErrInvoiceNotFound = errors.New("invoice not found")
+var ErrOrderNotFound = errors.New("order not found")
+var ErrRefundNotFound = errors.New("refund not found")
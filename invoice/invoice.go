@@ -0,0 +1,488 @@
+package invoice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"sypchal/money"
+	"sypchal/shipping"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type InvoiceDomain struct {
+	db *pgx.Conn
+}
+
+func NewInvoiceDomain(db *pgx.Conn) (*InvoiceDomain, error) {
+	if db == nil {
+		return nil, errors.New("db is nil")
+	}
+
+	return &InvoiceDomain{db}, nil
+}
+
+var (
+	// KindInvoice is issued when an order is paid.
+	KindInvoice = "invoice"
+	// KindCreditNote is issued for a refund of an order, its amounts are
+	// positive and are taken off the invoice of the order.
+	KindCreditNote = "credit_note"
+)
+
+// Party is the seller or the buyer of an invoice.
+type Party struct {
+	Name    string   `json:"name"`
+	Email   string   `json:"email"`
+	TaxId   string   `json:"tax_id"`
+	Address []string `json:"address"`
+}
+
+// Line is a line of an invoice, Total is Qty times UnitPrice less Discount,
+// without the tax in exclusive TaxMode.
+type Line struct {
+	Description string      `json:"description"`
+	Sku         string      `json:"sku"`
+	Qty         int         `json:"qty"`
+	UnitPrice   money.Money `json:"unit_price"`
+	Discount    money.Money `json:"discount"`
+	TaxRate     int         `json:"tax_rate"`
+	TaxAmount   money.Money `json:"tax_amount"`
+	Total       money.Money `json:"total"`
+}
+
+// TaxLine is the tax of the lines of a rate, in basis points, Base is the
+// amount taxed without the tax.
+type TaxLine struct {
+	Rate   int         `json:"rate"`
+	Base   money.Money `json:"base"`
+	Amount money.Money `json:"amount"`
+}
+
+// Invoice is a snapshot of an order, or of a refund for a credit note, when it
+// was issued. Numbers are sequential per kind and never skip.
+type Invoice struct {
+	Id      int    `json:"id"`
+	Number  string `json:"number"`
+	Kind    string `json:"kind"`
+	OrderId int    `json:"order_id"`
+	// RefundId and InvoiceId are the refund and the invoice a credit note is
+	// for.
+	RefundId  *int           `json:"refund_id"`
+	InvoiceId *int           `json:"invoice_id"`
+	Seller    Party          `json:"seller"`
+	Buyer     Party          `json:"buyer"`
+	Lines     []Line         `json:"lines"`
+	Taxes     []TaxLine      `json:"taxes"`
+	Currency  money.Currency `json:"currency"`
+	Subtotal  money.Money    `json:"subtotal"`
+	// Discount is the discount of the whole order, e.g. a free shipping
+	// coupon, the discounts of the lines are in their Discount.
+	Discount      money.Money `json:"discount"`
+	ShippingTotal money.Money `json:"shipping_total"`
+	TaxTotal      money.Money `json:"tax_total"`
+	TaxMode       string      `json:"tax_mode"`
+	Total         money.Money `json:"total"`
+	IssuedAt      time.Time   `json:"issued_at"`
+}
+
+const invoiceColumns = "id,number,kind,order_id,refund_id,invoice_id,seller,buyer,lines,taxes,currency," +
+	"row(subtotal,currency),row(discount,currency),row(shipping_total,currency),row(tax_total,currency),tax_mode," +
+	"row(total,currency),issued_at"
+
+// scanFields returns the destinations matching invoiceColumns.
+func (invoice *Invoice) scanFields() []any {
+	return []any{
+		&invoice.Id,
+		&invoice.Number,
+		&invoice.Kind,
+		&invoice.OrderId,
+		&invoice.RefundId,
+		&invoice.InvoiceId,
+		&invoice.Seller,
+		&invoice.Buyer,
+		&invoice.Lines,
+		&invoice.Taxes,
+		&invoice.Currency,
+		&invoice.Subtotal,
+		&invoice.Discount,
+		&invoice.ShippingTotal,
+		&invoice.TaxTotal,
+		&invoice.TaxMode,
+		&invoice.Total,
+		&invoice.IssuedAt,
+	}
+}
+
+// Issuer issues the invoices and the credit notes in the transactions paying
+// the orders and recording the refunds, a rolled back transaction gives its
+// number back.
+type Issuer struct {
+	Seller           Party
+	InvoicePrefix    string
+	CreditNotePrefix string
+}
+
+// Invoice issues the invoice of a paid order.
+func (issuer *Issuer) Invoice(ctx context.Context, tx pgx.Tx, orderId int) (invoice *Invoice, err error) {
+	invoice = &Invoice{Kind: KindInvoice, OrderId: orderId, Seller: issuer.Seller}
+	err = tx.QueryRow(
+		ctx,
+		`select currency,tax_mode,row(shipping_total,currency),row(tax_total,currency),row(total_price,currency),
+		row(coalesce((select -sum(amount) from order_adjustments where order_id=orders.id and order_item_id is null),0),currency)
+		from orders where id=$1`,
+		orderId,
+	).Scan(&invoice.Currency, &invoice.TaxMode, &invoice.ShippingTotal, &invoice.TaxTotal, &invoice.Total, &invoice.Discount)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			err = ErrOrderNotFound
+		}
+		return
+	}
+
+	if invoice.Buyer, err = buyer(ctx, tx, orderId); err != nil {
+		return
+	}
+
+	rows, err := tx.Query(
+		ctx,
+		`select
+			coalesce(products.name,order_items.sku,'item'),
+			coalesce(order_items.sku,''),
+			order_items.qty,
+			row(order_items.price,orders.currency),
+			row(coalesce((select -sum(amount) from order_adjustments where order_item_id=order_items.id),0),orders.currency),
+			order_items.tax_rate,
+			row(order_items.tax_amount,orders.currency)
+		from order_items inner join orders on(order_id=orders.id)
+		left join products on(product_id=products.id)
+		where order_id=$1 order by order_items.id`,
+		orderId,
+	)
+	if err != nil {
+		return
+	}
+
+	invoice.Lines = []Line{}
+	for rows.Next() {
+		line := Line{}
+		if err = rows.Scan(
+			&line.Description,
+			&line.Sku,
+			&line.Qty,
+			&line.UnitPrice,
+			&line.Discount,
+			&line.TaxRate,
+			&line.TaxAmount,
+		); err != nil {
+			rows.Close()
+			return
+		}
+		line.Total = line.UnitPrice.Mul(line.Qty).Sub(line.Discount)
+		invoice.Lines = append(invoice.Lines, line)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return
+	}
+
+	invoice.summarize()
+
+	err = issuer.insert(ctx, tx, invoice, issuer.InvoicePrefix)
+	return
+}
+
+// CreditNote issues the credit note of a refund, with the returned lines when
+// the refund is for a return.
+func (issuer *Issuer) CreditNote(ctx context.Context, tx pgx.Tx, refundId int) (invoice *Invoice, err error) {
+	var returnId *int
+	invoice = &Invoice{Kind: KindCreditNote, RefundId: &refundId, Seller: issuer.Seller}
+	err = tx.QueryRow(
+		ctx,
+		`select refunds.order_id,refunds.return_id,refunds.currency,row(refunds.amount,refunds.currency),orders.tax_mode,
+		(select id from invoices where order_id=orders.id and kind='invoice')
+		from refunds inner join orders on(order_id=orders.id) where refunds.id=$1`,
+		refundId,
+	).Scan(&invoice.OrderId, &returnId, &invoice.Currency, &invoice.Total, &invoice.TaxMode, &invoice.InvoiceId)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			err = ErrRefundNotFound
+		}
+		return
+	}
+
+	if invoice.Buyer, err = buyer(ctx, tx, invoice.OrderId); err != nil {
+		return
+	}
+
+	invoice.Lines = []Line{}
+	if returnId == nil {
+		invoice.Lines = append(invoice.Lines, Line{
+			Description: "Refund",
+			Qty:         1,
+			UnitPrice:   invoice.Total,
+			Discount:    money.Zero(invoice.Currency),
+			TaxAmount:   money.Zero(invoice.Currency),
+			Total:       invoice.Total,
+		})
+		invoice.summarize()
+		err = issuer.insert(ctx, tx, invoice, issuer.CreditNotePrefix)
+		return
+	}
+
+	// the refund of a returned line is its share of what the line was paid,
+	// with the exclusive tax
+	rows, err := tx.Query(
+		ctx,
+		`select
+			coalesce(products.name,order_items.sku,'item'),
+			coalesce(order_items.sku,''),
+			return_items.qty,
+			row(order_items.price,orders.currency),
+			order_items.tax_rate,
+			row(order_items.tax_amount*return_items.qty/order_items.qty,orders.currency),
+			row(return_items.amount,orders.currency)
+		from return_items
+			inner join order_items on(order_item_id=order_items.id)
+			inner join orders on(order_items.order_id=orders.id)
+			left join products on(product_id=products.id)
+		where return_id=$1 order by return_items.id`,
+		*returnId,
+	)
+	if err != nil {
+		return
+	}
+
+	for rows.Next() {
+		var amount money.Money
+		line := Line{}
+		if err = rows.Scan(
+			&line.Description,
+			&line.Sku,
+			&line.Qty,
+			&line.UnitPrice,
+			&line.TaxRate,
+			&line.TaxAmount,
+			&amount,
+		); err != nil {
+			rows.Close()
+			return
+		}
+
+		line.Total = amount
+		if invoice.TaxMode == "exclusive" {
+			line.Total = line.Total.Sub(line.TaxAmount)
+		}
+		line.Discount = line.UnitPrice.Mul(line.Qty).Sub(line.Total)
+		invoice.Lines = append(invoice.Lines, line)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return
+	}
+
+	invoice.summarize()
+
+	err = issuer.insert(ctx, tx, invoice, issuer.CreditNotePrefix)
+	return
+}
+
+// summarize sums the lines into the subtotal and the taxes by rate.
+func (invoice *Invoice) summarize() {
+	invoice.Subtotal = money.Zero(invoice.Currency)
+	invoice.Taxes = []TaxLine{}
+	taxTotal := money.Zero(invoice.Currency)
+	for _, line := range invoice.Lines {
+		invoice.Subtotal = invoice.Subtotal.Add(line.Total)
+		taxTotal = taxTotal.Add(line.TaxAmount)
+		if line.TaxRate == 0 && line.TaxAmount.IsZero() {
+			continue
+		}
+
+		base := line.Total
+		if invoice.TaxMode != "exclusive" {
+			base = base.Sub(line.TaxAmount)
+		}
+
+		i := 0
+		for i < len(invoice.Taxes) && invoice.Taxes[i].Rate != line.TaxRate {
+			i++
+		}
+		if i == len(invoice.Taxes) {
+			invoice.Taxes = append(invoice.Taxes, TaxLine{line.TaxRate, money.Zero(invoice.Currency), money.Zero(invoice.Currency)})
+		}
+		invoice.Taxes[i].Base = invoice.Taxes[i].Base.Add(base)
+		invoice.Taxes[i].Amount = invoice.Taxes[i].Amount.Add(line.TaxAmount)
+	}
+
+	// the order keeps its own tax total, a credit note sums its lines
+	if invoice.Kind == KindCreditNote {
+		invoice.TaxTotal = taxTotal
+		invoice.Discount = money.Zero(invoice.Currency)
+		invoice.ShippingTotal = money.Zero(invoice.Currency)
+	}
+}
+
+// insert numbers the invoice with the next number of its kind and saves it.
+func (issuer *Issuer) insert(ctx context.Context, tx pgx.Tx, invoice *Invoice, prefix string) (err error) {
+	var next int
+	err = tx.QueryRow(ctx, "update invoice_sequences set last=last+1 where kind=$1 returning last", invoice.Kind).
+		Scan(&next)
+	if err != nil {
+		return
+	}
+	invoice.Number = fmt.Sprintf("%s-%06d", prefix, next)
+
+	err = tx.QueryRow(
+		ctx,
+		`insert into invoices(number,kind,order_id,refund_id,invoice_id,seller,buyer,lines,taxes,currency,subtotal,discount,
+		shipping_total,tax_total,tax_mode,total)
+		values ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16) returning `+invoiceColumns,
+		invoice.Number,
+		invoice.Kind,
+		invoice.OrderId,
+		invoice.RefundId,
+		invoice.InvoiceId,
+		invoice.Seller,
+		invoice.Buyer,
+		invoice.Lines,
+		invoice.Taxes,
+		invoice.Currency,
+		invoice.Subtotal.Amount,
+		invoice.Discount.Amount,
+		invoice.ShippingTotal.Amount,
+		invoice.TaxTotal.Amount,
+		invoice.TaxMode,
+		invoice.Total.Amount,
+	).Scan(invoice.scanFields()...)
+	return
+}
+
+// buyer returns the customer of an order at the address the order ships to.
+func buyer(ctx context.Context, tx pgx.Tx, orderId int) (party Party, err error) {
+	var address *shipping.Address
+	err = tx.QueryRow(
+		ctx,
+		`select users.full_name,users.email,orders.shipping_address
+		from orders inner join users on(user_id=users.id) where orders.id=$1`,
+		orderId,
+	).Scan(&party.Name, &party.Email, &address)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			err = ErrOrderNotFound
+		}
+		return
+	}
+
+	party.Address = []string{}
+	if address == nil {
+		return
+	}
+
+	if address.RecipientName != "" {
+		party.Name = address.RecipientName
+	}
+	for _, line := range []string{
+		address.Line1,
+		address.Line2,
+		strings.TrimSpace(address.City + " " + address.PostalCode),
+		strings.Trim(address.Region+", "+address.Country, ", "),
+	} {
+		if line != "" {
+			party.Address = append(party.Address, line)
+		}
+	}
+
+	return
+}
+
+type GetInvoicesRequest struct {
+	Kind    string
+	OrderId int
+	UserId  int
+	Limit   int
+	Offset  int
+}
+
+type GetInvoicesResponse struct {
+	Invoices []*Invoice `json:"invoices"`
+	Total    int        `json:"total"`
+	MaxPage  int        `json:"max_page"`
+}
+
+// GetInvoices lists the invoices and credit notes, newest first, of a kind, an
+// order or a customer when set.
+func (i *InvoiceDomain) GetInvoices(ctx context.Context, req GetInvoicesRequest) (res *GetInvoicesResponse, err error) {
+	rows, err := i.db.Query(
+		ctx,
+		`select count(*) over(),`+invoiceColumns+` from invoices
+		where ($1='' or kind=$1) and ($2=0 or order_id=$2)
+		and ($3=0 or order_id in (select id from orders where user_id=$3))
+		order by id desc limit $4 offset $5`,
+		req.Kind,
+		req.OrderId,
+		req.UserId,
+		req.Limit,
+		req.Offset,
+	)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	var total int
+	res = &GetInvoicesResponse{Invoices: []*Invoice{}}
+	for rows.Next() {
+		invoice := &Invoice{}
+		if err = rows.Scan(append([]any{&total}, invoice.scanFields()...)...); err != nil {
+			return
+		}
+		res.Invoices = append(res.Invoices, invoice)
+	}
+	if err = rows.Err(); err != nil {
+		return
+	}
+
+	res.Total = total
+	res.MaxPage = int(math.Ceil(float64(total) / float64(req.Limit)))
+
+	return
+}
+
+// GetInvoiceById returns an invoice or a credit note, of the customer when
+// userId isn't 0.
+func (i *InvoiceDomain) GetInvoiceById(ctx context.Context, userId int, id int) (invoice *Invoice, err error) {
+	invoice = &Invoice{}
+	err = i.db.QueryRow(
+		ctx,
+		`select `+invoiceColumns+` from invoices
+		where id=$1 and ($2=0 or order_id in (select id from orders where user_id=$2))`,
+		id,
+		userId,
+	).Scan(invoice.scanFields()...)
+	if errors.Is(err, pgx.ErrNoRows) {
+		err = ErrInvoiceNotFound
+	}
+
+	return
+}
+
+// GetOrderInvoice returns the invoice of an order of the customer.
+func (i *InvoiceDomain) GetOrderInvoice(ctx context.Context, userId int, orderId int) (invoice *Invoice, err error) {
+	invoice = &Invoice{}
+	err = i.db.QueryRow(
+		ctx,
+		`select `+invoiceColumns+` from invoices
+		where order_id=$1 and kind='invoice' and order_id in (select id from orders where user_id=$2)`,
+		orderId,
+		userId,
+	).Scan(invoice.scanFields()...)
+	if errors.Is(err, pgx.ErrNoRows) {
+		err = ErrInvoiceNotFound
+	}
+
+	return
+}
@@ -0,0 +1,169 @@
+package invoice
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"sypchal/money"
+)
+
+const (
+	marginLeft   = 50
+	marginRight  = pageWidth - 50
+	marginBottom = 80
+	lineHeight   = 14
+)
+
+// the right edges of the amount columns
+var (
+	colQty      = 330.0
+	colPrice    = 395.0
+	colDiscount = 455.0
+	colTax      = 495.0
+	colTotal    = float64(marginRight)
+)
+
+// WritePDF renders the invoice, or credit note, as an A4 PDF.
+func (invoice *Invoice) WritePDF(w io.Writer) (err error) {
+	d := newPDF()
+
+	title := "INVOICE"
+	if invoice.Kind == KindCreditNote {
+		title = "CREDIT NOTE"
+	}
+	d.text(marginLeft, 780, fontBold, 20, title)
+
+	y := 755.0
+	details := [][2]string{
+		{"Number", invoice.Number},
+		{"Date", invoice.IssuedAt.Format("2 January 2006")},
+		{"Order", "#" + strconv.Itoa(invoice.OrderId)},
+	}
+	if invoice.Kind == KindCreditNote && invoice.InvoiceId != nil {
+		details = append(details, [2]string{"Invoice", "#" + strconv.Itoa(*invoice.InvoiceId)})
+	}
+	for _, detail := range details {
+		d.text(marginLeft, y, fontBold, 9, detail[0])
+		d.text(marginLeft+60, y, fontRegular, 9, detail[1])
+		y -= lineHeight - 2
+	}
+
+	y -= 20
+	sellerEnd := writeParty(d, marginLeft, y, "From", invoice.Seller)
+	buyerEnd := writeParty(d, 310, y, "Bill to", invoice.Buyer)
+	y = min(sellerEnd, buyerEnd) - 20
+
+	header := func() {
+		d.text(marginLeft, y, fontBold, 9, "Description")
+		d.textRight(colQty, y, 8, "Qty")
+		d.textRight(colPrice, y, 8, "Unit price")
+		d.textRight(colDiscount, y, 8, "Discount")
+		d.textRight(colTax, y, 8, "Tax")
+		d.textRight(colTotal, y, 8, "Total")
+		d.line(marginLeft, y-4, marginRight, y-4)
+		y -= lineHeight + 4
+	}
+	header()
+
+	for _, line := range invoice.Lines {
+		if y < marginBottom+lineHeight {
+			d.addPage()
+			y = pageHeight - 60
+			header()
+		}
+
+		description := line.Description
+		if line.Sku != "" {
+			description += " (" + line.Sku + ")"
+		}
+		d.text(marginLeft, y, fontRegular, 9, truncate(description, 48))
+		d.textRight(colQty, y, 8, strconv.Itoa(line.Qty))
+		d.textRight(colPrice, y, 8, formatAmount(line.UnitPrice))
+		d.textRight(colDiscount, y, 8, formatAmount(line.Discount))
+		d.textRight(colTax, y, 8, formatRate(line.TaxRate))
+		d.textRight(colTotal, y, 8, formatAmount(line.Total))
+		y -= lineHeight
+	}
+
+	totals := [][2]string{{"Subtotal", formatAmount(invoice.Subtotal)}}
+	if !invoice.Discount.IsZero() {
+		totals = append(totals, [2]string{"Discount", formatAmount(invoice.Discount.Neg())})
+	}
+	if !invoice.ShippingTotal.IsZero() {
+		totals = append(totals, [2]string{"Shipping", formatAmount(invoice.ShippingTotal)})
+	}
+	for _, tax := range invoice.Taxes {
+		totals = append(totals, [2]string{
+			fmt.Sprintf("Tax %s of %s", formatRate(tax.Rate), formatAmount(tax.Base)),
+			formatAmount(tax.Amount),
+		})
+	}
+	if invoice.TaxMode != "exclusive" && !invoice.TaxTotal.IsZero() {
+		totals = append(totals, [2]string{"Prices include tax of", formatAmount(invoice.TaxTotal)})
+	}
+
+	if y < marginBottom+float64(len(totals)+2)*lineHeight {
+		d.addPage()
+		y = pageHeight - 60
+	}
+
+	d.line(310, y+4, marginRight, y+4)
+	y -= lineHeight - 4
+	for _, total := range totals {
+		d.text(310, y, fontRegular, 9, total[0])
+		d.textRight(colTotal, y, 8, total[1])
+		y -= lineHeight
+	}
+	d.text(310, y-2, fontBold, 11, "Total")
+	d.textRight(colTotal, y-2, 10, invoice.Total.String())
+
+	_, err = d.WriteTo(w)
+	return
+}
+
+// writeParty writes the name and address of a party from y and returns where
+// it ended.
+func writeParty(d *pdfDocument, x, y float64, label string, party Party) float64 {
+	d.text(x, y, fontBold, 9, label)
+	y -= lineHeight
+	d.text(x, y, fontRegular, 10, party.Name)
+	y -= lineHeight
+
+	lines := append([]string{}, party.Address...)
+	if party.Email != "" {
+		lines = append(lines, party.Email)
+	}
+	if party.TaxId != "" {
+		lines = append(lines, "Tax id "+party.TaxId)
+	}
+	for _, line := range lines {
+		d.text(x, y, fontRegular, 9, truncate(line, 45))
+		y -= lineHeight - 2
+	}
+
+	return y
+}
+
+// formatAmount formats an amount without its currency, which the total shows,
+// e.g. 1,250,000.
+func formatAmount(amount money.Money) string {
+	return amount.Number()
+}
+
+// formatRate formats a rate in basis points as a percent, e.g. 11%.
+func formatRate(rate int) string {
+	if rate%100 == 0 {
+		return strconv.Itoa(rate/100) + "%"
+	}
+
+	return fmt.Sprintf("%.2f%%", float64(rate)/100)
+}
+
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+
+	return string(runes[:n-3]) + "..."
+}
@@ -4,14 +4,32 @@ import (
 	"context"
 	"errors"
 	"net/http"
+	"os"
+	"strings"
 
+	"sypchal/alert"
 	"sypchal/cart"
+	"sypchal/coupon"
+	"sypchal/exchange"
+	"sypchal/inventory"
+	"sypchal/invoice"
+	"sypchal/money"
+	"sypchal/notify"
 	"sypchal/order"
+	"sypchal/payment"
 	"sypchal/postgres"
 	"sypchal/product"
+	"sypchal/promotion"
+	"sypchal/returns"
+	"sypchal/review"
 	"sypchal/server"
+	"sypchal/shipping"
+	"sypchal/storage"
+	"sypchal/tax"
+	"sypchal/upload"
 	"sypchal/user"
 	"sypchal/validation"
+	"sypchal/wishlist"
 
 	"github.com/rs/zerolog/log"
 )
@@ -26,43 +44,200 @@ func main() {
 
 	validator := validation.NewValidator()
 
+	currency, err := money.ParseCurrency(config.Currency)
+	if err != nil {
+		log.Error().Err(err).Msg("parse currency")
+	}
+
 	db, err := postgres.NewPostgresClient(ctx, config.DatabaseUrl)
 	if err != nil {
 		log.Error().Err(err).Msg("new postgres client")
 	}
 
+	// the constructors return typed nils on error, only assign the notifier
+	// when it was made so a nil check on the interface still works
+	var notifier notify.Notifier = notify.NewLogNotifier()
+	switch config.Notifier.Driver {
+	case "webhook":
+		webhook, err := notify.NewWebhookNotifier(config.Notifier.WebhookUrl, config.Notifier.WebhookSecret)
+		if err != nil {
+			log.Error().Err(err).Msg("new notifier, falling back to the log notifier")
+			break
+		}
+		notifier = webhook
+	case "email":
+		email, err := notify.NewEmailNotifier(notify.EmailConfig{
+			Host:     config.Notifier.Smtp.Host,
+			Port:     config.Notifier.Smtp.Port,
+			Username: config.Notifier.Smtp.Username,
+			Password: config.Notifier.Smtp.Password,
+			From:     config.Notifier.Smtp.From,
+			AdminTo:  config.Notifier.AdminEmail,
+		})
+		if err != nil {
+			log.Error().Err(err).Msg("new notifier, falling back to the log notifier")
+			break
+		}
+		notifier = email
+	}
+
 	userDomain, err := user.NewUserDomain(db.Conn, validator, config.JwtSecret)
 	if err != nil {
 		log.Error().Err(err).Msg("new user domain")
 	}
 
-	productDomain, err := product.NewProductDomain(db.Conn, validator)
+	productDomain, err := product.NewProductDomain(db.Conn, validator, notifier, currency)
 	if err != nil {
 		log.Error().Err(err).Msg("new product domain")
 	}
 
-	cartDomain, err := cart.NewCartDomain(db.Conn, validator)
+	cartDomain, err := cart.NewCartDomain(db.Conn, validator, config.CartSecret, cart.PricePolicy(config.PriceDrift), currency)
 	if err != nil {
 		log.Error().Err(err).Msg("new cart domain")
 	}
 
-	orderDomain, err := order.NewOrderDomain(db.Conn, validator)
+	invoices := &invoice.Issuer{
+		Seller: invoice.Party{
+			Name:    config.Invoice.SellerName,
+			Email:   config.Invoice.SellerEmail,
+			TaxId:   config.Invoice.SellerTaxId,
+			Address: []string{},
+		},
+		InvoicePrefix:    config.Invoice.Prefix,
+		CreditNotePrefix: config.Invoice.CreditNotePrefix,
+	}
+	if config.Invoice.SellerAddress != "" {
+		invoices.Seller.Address = strings.Split(config.Invoice.SellerAddress, ";")
+	}
+
+	orderDomain, err := order.NewOrderDomain(db.Conn, validator, notifier, order.Config{
+		Currency:            currency,
+		AllowSplitShipments: config.Order.AllowSplitShipments,
+		MaxItemQty:          config.Order.MaxItemQty,
+		PricePolicy:         cart.PricePolicy(config.PriceDrift),
+		TaxCalculator:       tax.NewLocalCalculator(),
+		TaxMode:             config.Tax.Mode,
+		TaxRegion:           config.Tax.Region,
+		Invoices:            invoices,
+	})
 	if err != nil {
 		log.Error().Err(err).Msg("new order domain")
 	}
 
+	inventoryDomain, err := inventory.NewInventoryDomain(db.Conn, validator, notifier)
+	if err != nil {
+		log.Error().Err(err).Msg("new inventory domain")
+	}
+
+	alertDomain, err := alert.NewAlertDomain(db.Conn, validator)
+	if err != nil {
+		log.Error().Err(err).Msg("new alert domain")
+	}
+
+	reviewDomain, err := review.NewReviewDomain(db.Conn, validator)
+	if err != nil {
+		log.Error().Err(err).Msg("new review domain")
+	}
+
+	couponDomain, err := coupon.NewCouponDomain(db.Conn, validator, currency)
+	if err != nil {
+		log.Error().Err(err).Msg("new coupon domain")
+	}
+
+	promotionDomain, err := promotion.NewPromotionDomain(db.Conn, validator, currency)
+	if err != nil {
+		log.Error().Err(err).Msg("new promotion domain")
+	}
+
+	taxDomain, err := tax.NewTaxDomain(db.Conn, validator)
+	if err != nil {
+		log.Error().Err(err).Msg("new tax domain")
+	}
+
+	shippingDomain, err := shipping.NewShippingDomain(db.Conn, validator, currency)
+	if err != nil {
+		log.Error().Err(err).Msg("new shipping domain")
+	}
+
+	returnDomain, err := returns.NewReturnDomain(db.Conn, validator, payment.NewManualGateway(), invoices, config.Return.Window)
+	if err != nil {
+		log.Error().Err(err).Msg("new return domain")
+	}
+
+	invoiceDomain, err := invoice.NewInvoiceDomain(db.Conn)
+	if err != nil {
+		log.Error().Err(err).Msg("new invoice domain")
+	}
+
+	exchangeDomain, err := exchange.NewExchangeDomain(db.Conn, validator, currency)
+	if err != nil {
+		log.Error().Err(err).Msg("new exchange domain")
+	}
+
+	wishlistDomain, err := wishlist.NewWishlistDomain(db.Conn, validator, currency)
+	if err != nil {
+		log.Error().Err(err).Msg("new wishlist domain")
+	}
+
+	if len(os.Args) > 1 {
+		if err := runCommand(ctx, productDomain, inventoryDomain, cartDomain, exchangeDomain, os.Args[1:]); err != nil {
+			log.Error().Err(err).Msg(os.Args[1])
+			notify.Wait()
+			os.Exit(1)
+		}
+		notify.Wait()
+		return
+	}
+
+	// like the notifier, a failed store is a typed nil, uploads can't work
+	// without one
+	var blobStore storage.BlobStore
+	if config.Storage.Driver == "s3" {
+		s3Store, err := storage.NewS3Store(storage.S3Config(config.Storage.S3))
+		if err != nil {
+			log.Fatal().Err(err).Msg("new blob store")
+		}
+		blobStore = s3Store
+	} else {
+		localStore, err := storage.NewLocalStore(config.Storage.LocalDir)
+		if err != nil {
+			log.Fatal().Err(err).Msg("new blob store")
+		}
+		blobStore = localStore
+	}
+
+	uploadDomain, err := upload.NewUploadDomain(blobStore, config.Storage.MaxUploadSize, config.Storage.MaxImagePixels, config.PublicUrl)
+	if err != nil {
+		log.Error().Err(err).Msg("new upload domain")
+	}
+
 	httpServer, err := server.NewServer(server.ServerConfig{
 		Environment: config.Environment,
 		Hostname:    config.Hostname,
 		Port:        config.Port,
+		TrustProxy:  config.TrustProxy,
 		Admin: struct {
 			Username string
 			Password string
 		}(config.Admin),
-		UserDomain:    userDomain,
-		ProductDomain: productDomain,
-		CartDomain:    cartDomain,
-		OrderDomain:   orderDomain,
+		CatalogCache:    server.CatalogCacheConfig(config.CatalogCache),
+		RateLimit:       server.RateLimitConfig(config.RateLimit),
+		UserDomain:      userDomain,
+		ProductDomain:   productDomain,
+		CartDomain:      cartDomain,
+		OrderDomain:     orderDomain,
+		UploadDomain:    uploadDomain,
+		InventoryDomain: inventoryDomain,
+		AlertDomain:     alertDomain,
+		ReviewDomain:    reviewDomain,
+		CouponDomain:    couponDomain,
+		PromotionDomain: promotionDomain,
+		TaxDomain:       taxDomain,
+		ShippingDomain:  shippingDomain,
+		ReturnDomain:    returnDomain,
+		InvoiceDomain:   invoiceDomain,
+		ExchangeDomain:  exchangeDomain,
+		WishlistDomain:  wishlistDomain,
 	})
 	if err != nil {
 		log.Error().Err(err).Msg("new server")
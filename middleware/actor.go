@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"net/http"
+	"sypchal/audit"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/jwtauth/v5"
+)
+
+// AdminActor records the basic auth admin and the request id as the actor of
+// the changes made in the request. It must run after BasicAuth and RequestID.
+func AdminActor(next http.Handler) http.Handler {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		username, _, _ := r.BasicAuth()
+		ctx := audit.WithActor(r.Context(), audit.Actor{
+			Name:      "admin:" + username,
+			RequestId: middleware.GetReqID(r.Context()),
+		})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	}
+	return http.HandlerFunc(fn)
+}
+
+// UserActor records the authenticated customer and the request id as the actor
+// of the changes made in the request, e.g. the stock sold by an order. It must
+// run after the jwt Authenticator and RequestID.
+func UserActor(next http.Handler) http.Handler {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		_, claims, _ := jwtauth.FromContext(r.Context())
+		uid, _ := claims["uid"].(string)
+		ctx := audit.WithActor(r.Context(), audit.Actor{
+			Name:      "user:" + uid,
+			RequestId: middleware.GetReqID(r.Context()),
+		})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	}
+	return http.HandlerFunc(fn)
+}
@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/jwtauth/v5"
+)
+
+// OptionalAuthenticator lets requests without a token through as anonymous,
+// but rejects invalid or expired tokens like jwtauth.Authenticator. It must
+// run after jwtauth.Verifier.
+func OptionalAuthenticator(next http.Handler) http.Handler {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		_, _, err := jwtauth.FromContext(r.Context())
+		if err != nil && !errors.Is(err, jwtauth.ErrNoTokenFound) {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	}
+	return http.HandlerFunc(fn)
+}
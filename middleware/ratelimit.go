@@ -0,0 +1,110 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-chi/jwtauth/v5"
+)
+
+// RateLimiter allows a number of requests per client in fixed time windows.
+type RateLimiter struct {
+	limit  int
+	window time.Duration
+
+	mu      sync.Mutex
+	start   time.Time
+	counts  map[string]int
+	nowFunc func() time.Time
+}
+
+func NewRateLimiter(limit int, window time.Duration) *RateLimiter {
+	return &RateLimiter{
+		limit:   limit,
+		window:  window,
+		counts:  map[string]int{},
+		nowFunc: time.Now,
+	}
+}
+
+// allow counts a request of the client, returning how many requests are left
+// in the window and when the window resets.
+func (l *RateLimiter) allow(key string) (ok bool, remaining int, reset time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.nowFunc()
+	if now.Sub(l.start) >= l.window {
+		// every client starts over, which also drops the clients gone quiet
+		l.start = now.Truncate(l.window)
+		l.counts = map[string]int{}
+	}
+
+	reset = l.start.Add(l.window)
+	if l.counts[key] >= l.limit {
+		return false, 0, reset
+	}
+
+	l.counts[key]++
+	return true, l.limit - l.counts[key], reset
+}
+
+// RateLimit limits anonymous clients by ip address and signed in customers
+// by user id, each with their own limiter. It must run after jwtauth.Verifier.
+// A nil limiter doesn't limit.
+func RateLimit(anonymous *RateLimiter, authenticated *RateLimiter) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			limiter := anonymous
+			key := clientIp(r)
+
+			_, claims, err := jwtauth.FromContext(r.Context())
+			if uid, ok := claims["uid"].(string); err == nil && ok {
+				limiter = authenticated
+				key = "user:" + uid
+			}
+
+			if limiter == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ok, remaining, reset := limiter.allow(key)
+			w.Header().Set("X-RateLimit-Limit", strconv.Itoa(limiter.limit))
+			w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+			w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+
+			if !ok {
+				retryAfter := int(time.Until(reset).Seconds()) + 1
+				w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusTooManyRequests)
+				_ = json.NewEncoder(w).Encode(map[string]any{
+					"error": map[string]any{
+						"code":    http.StatusTooManyRequests,
+						"message": "too many requests",
+					},
+				})
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		}
+		return http.HandlerFunc(fn)
+	}
+}
+
+// clientIp is the address the request comes from, behind a proxy it's only
+// the client address when the RealIP middleware runs first.
+func clientIp(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
@@ -0,0 +1,55 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE "product_options" (
+  "id" INTEGER GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  "product_id" integer NOT NULL,
+  "name" varchar NOT NULL,
+  "values" varchar[] NOT NULL,
+  "position" integer NOT NULL DEFAULT 0,
+  "created_at" timestamp DEFAULT 'now()',
+  "updated_at" timestamp,
+  UNIQUE ("product_id", "name")
+);
+
+CREATE TABLE "product_variants" (
+  "id" INTEGER GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  "product_id" integer NOT NULL,
+  "sku" varchar UNIQUE NOT NULL,
+  "options" jsonb NOT NULL DEFAULT '{}',
+  "stock" integer NOT NULL,
+  "price" integer,
+  "image_url" varchar,
+  "created_at" timestamp DEFAULT 'now()',
+  "updated_at" timestamp
+);
+
+COMMENT ON COLUMN "product_variants"."options" IS 'option name to value, e.g. {"size": "M", "color": "red"}';
+COMMENT ON COLUMN "product_variants"."price" IS 'overrides products.price when not null';
+
+ALTER TABLE "product_options" ADD FOREIGN KEY ("product_id") REFERENCES "products" ("id") ON DELETE CASCADE ON UPDATE CASCADE;
+
+ALTER TABLE "product_variants" ADD FOREIGN KEY ("product_id") REFERENCES "products" ("id") ON DELETE CASCADE ON UPDATE CASCADE;
+
+ALTER TABLE "cart_items" ADD COLUMN "variant_id" integer;
+ALTER TABLE "cart_items" ADD FOREIGN KEY ("variant_id") REFERENCES "product_variants" ("id") ON DELETE CASCADE ON UPDATE CASCADE;
+ALTER TABLE "cart_items" DROP CONSTRAINT cart_items_user_id_product_id_key;
+ALTER TABLE "cart_items" ADD CONSTRAINT cart_items_user_id_product_id_variant_id_key UNIQUE NULLS NOT DISTINCT (user_id, product_id, variant_id);
+
+ALTER TABLE "order_items" ADD COLUMN "variant_id" integer;
+ALTER TABLE "order_items" ADD COLUMN "sku" varchar;
+ALTER TABLE "order_items" ADD FOREIGN KEY ("variant_id") REFERENCES "product_variants" ("id") ON DELETE SET NULL ON UPDATE CASCADE;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE "order_items" DROP COLUMN "sku";
+ALTER TABLE "order_items" DROP COLUMN "variant_id";
+
+DELETE FROM "cart_items" WHERE "variant_id" IS NOT NULL;
+ALTER TABLE "cart_items" DROP CONSTRAINT cart_items_user_id_product_id_variant_id_key;
+ALTER TABLE "cart_items" DROP COLUMN "variant_id";
+ALTER TABLE "cart_items" ADD CONSTRAINT cart_items_user_id_product_id_key UNIQUE (user_id, product_id);
+
+DROP TABLE "product_variants";
+DROP TABLE "product_options";
+-- +goose StatementEnd
@@ -0,0 +1,9 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE "products" ADD COLUMN "sku" VARCHAR UNIQUE;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE "products" DROP COLUMN "sku";
+-- +goose StatementEnd
@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE "products" ADD COLUMN "deleted_at" timestamp;
+CREATE INDEX products_deleted_at_idx ON "products" ("deleted_at");
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX products_deleted_at_idx;
+ALTER TABLE "products" DROP COLUMN "deleted_at";
+-- +goose StatementEnd
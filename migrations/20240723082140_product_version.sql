@@ -0,0 +1,10 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE "products" ADD COLUMN "version" integer NOT NULL DEFAULT 1;
+COMMENT ON COLUMN "products"."version" IS 'incremented on every change, used as the product etag';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE "products" DROP COLUMN "version";
+-- +goose StatementEnd
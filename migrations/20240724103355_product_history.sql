@@ -0,0 +1,57 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE "product_history" (
+  "id" INTEGER GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  "product_id" integer NOT NULL,
+  "variant_id" integer,
+  "action" varchar NOT NULL,
+  "changes" jsonb NOT NULL DEFAULT '{}',
+  "actor" varchar NOT NULL,
+  "request_id" varchar,
+  "created_at" timestamp NOT NULL DEFAULT now()
+);
+
+COMMENT ON TABLE "product_history" IS 'append-only, not linked to products so it outlives purged products';
+COMMENT ON COLUMN "product_history"."changes" IS 'field to {"from": ..., "to": ...}';
+
+CREATE INDEX product_history_product_id_idx ON "product_history" ("product_id", "created_at");
+
+CREATE FUNCTION product_history_append_only() RETURNS trigger AS $$
+BEGIN
+  RAISE EXCEPTION 'product_history is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER product_history_append_only BEFORE UPDATE OR DELETE ON "product_history"
+FOR EACH ROW EXECUTE FUNCTION product_history_append_only();
+
+-- products created before the history existed start with their current price
+INSERT INTO "product_history" ("product_id", "action", "changes", "actor", "created_at")
+SELECT "id", 'snapshot', jsonb_build_object('price', jsonb_build_object('from', null, 'to', "price")), 'system', "created_at"
+FROM "products";
+
+INSERT INTO "product_history" ("product_id", "variant_id", "action", "changes", "actor", "created_at")
+SELECT "product_id", "id", 'snapshot', jsonb_build_object('price', jsonb_build_object('from', null, 'to', "price")), 'system', "created_at"
+FROM "product_variants";
+
+-- the price of a product, or a variant price override (null when the variant
+-- uses the product price), from valid_from until valid_to
+CREATE VIEW "product_price_history" AS
+SELECT
+  "product_id",
+  "variant_id",
+  ("changes"->'price'->>'to')::integer AS "price",
+  "created_at" AS "valid_from",
+  lead("created_at") OVER (PARTITION BY "product_id", "variant_id" ORDER BY "created_at", "id") AS "valid_to",
+  "actor",
+  "request_id"
+FROM "product_history"
+WHERE "changes" ? 'price';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP VIEW "product_price_history";
+DROP TABLE "product_history";
+DROP FUNCTION product_history_append_only;
+-- +goose StatementEnd
@@ -0,0 +1,39 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE "stock_movements" (
+  "id" INTEGER GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  "product_id" integer NOT NULL,
+  "variant_id" integer,
+  "quantity" integer NOT NULL,
+  "balance" integer NOT NULL,
+  "reason" varchar NOT NULL,
+  "reference" varchar NOT NULL DEFAULT '',
+  "note" varchar NOT NULL DEFAULT '',
+  "actor" varchar NOT NULL,
+  "request_id" varchar,
+  "created_at" timestamp NOT NULL DEFAULT now()
+);
+
+COMMENT ON COLUMN "stock_movements"."quantity" IS 'positive when stock comes in, negative when it goes out';
+COMMENT ON COLUMN "stock_movements"."balance" IS 'stock of the product, or of the variant when variant_id is set, after the movement';
+COMMENT ON COLUMN "stock_movements"."reference" IS 'what caused the movement, e.g. order:12';
+
+CREATE INDEX stock_movements_product_id_idx ON "stock_movements" ("product_id", "variant_id", "created_at");
+
+ALTER TABLE "stock_movements" ADD FOREIGN KEY ("product_id") REFERENCES "products" ("id") ON DELETE CASCADE ON UPDATE CASCADE;
+ALTER TABLE "stock_movements" ADD FOREIGN KEY ("variant_id") REFERENCES "product_variants" ("id") ON DELETE CASCADE ON UPDATE CASCADE;
+
+-- the ledger starts from the current stock
+INSERT INTO "stock_movements" ("product_id", "quantity", "balance", "reason", "reference", "actor")
+SELECT "id", "stock", "stock", 'adjustment', 'opening balance', 'system'
+FROM "products";
+
+INSERT INTO "stock_movements" ("product_id", "variant_id", "quantity", "balance", "reason", "reference", "actor")
+SELECT "product_id", "id", "stock", "stock", 'adjustment', 'opening balance', 'system'
+FROM "product_variants";
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE "stock_movements";
+-- +goose StatementEnd
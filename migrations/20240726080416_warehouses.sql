@@ -0,0 +1,74 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE "warehouses" (
+  "id" INTEGER GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  "code" varchar UNIQUE NOT NULL,
+  "name" varchar NOT NULL,
+  "address" varchar NOT NULL DEFAULT '',
+  "priority" integer NOT NULL DEFAULT 0,
+  "is_default" boolean NOT NULL DEFAULT false,
+  "created_at" timestamp NOT NULL DEFAULT now(),
+  "updated_at" timestamp
+);
+
+COMMENT ON COLUMN "warehouses"."priority" IS 'lower ships first';
+COMMENT ON COLUMN "warehouses"."is_default" IS 'receives stock set on products and variants without a warehouse';
+
+CREATE UNIQUE INDEX warehouses_is_default_idx ON "warehouses" ("is_default") WHERE "is_default";
+
+CREATE TABLE "warehouse_stocks" (
+  "id" INTEGER GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  "warehouse_id" integer NOT NULL,
+  "product_id" integer NOT NULL,
+  "variant_id" integer,
+  "stock" integer NOT NULL DEFAULT 0 CHECK ("stock" >= 0),
+  "updated_at" timestamp,
+  CONSTRAINT warehouse_stocks_warehouse_id_product_id_variant_id_key UNIQUE NULLS NOT DISTINCT ("warehouse_id", "product_id", "variant_id")
+);
+
+ALTER TABLE "warehouse_stocks" ADD FOREIGN KEY ("warehouse_id") REFERENCES "warehouses" ("id") ON DELETE RESTRICT ON UPDATE CASCADE;
+ALTER TABLE "warehouse_stocks" ADD FOREIGN KEY ("product_id") REFERENCES "products" ("id") ON DELETE CASCADE ON UPDATE CASCADE;
+ALTER TABLE "warehouse_stocks" ADD FOREIGN KEY ("variant_id") REFERENCES "product_variants" ("id") ON DELETE CASCADE ON UPDATE CASCADE;
+
+CREATE TABLE "order_item_allocations" (
+  "id" INTEGER GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  "order_item_id" integer NOT NULL,
+  "warehouse_id" integer NOT NULL,
+  "qty" integer NOT NULL
+);
+
+ALTER TABLE "order_item_allocations" ADD FOREIGN KEY ("order_item_id") REFERENCES "order_items" ("id") ON DELETE CASCADE ON UPDATE CASCADE;
+ALTER TABLE "order_item_allocations" ADD FOREIGN KEY ("warehouse_id") REFERENCES "warehouses" ("id") ON DELETE RESTRICT ON UPDATE CASCADE;
+
+-- the stock so far was kept in a single place
+INSERT INTO "warehouses" ("code", "name", "is_default") VALUES ('main', 'Main warehouse', true);
+
+INSERT INTO "warehouse_stocks" ("warehouse_id", "product_id", "stock")
+SELECT (SELECT "id" FROM "warehouses" WHERE "is_default"), "id", "stock"
+FROM "products";
+
+INSERT INTO "warehouse_stocks" ("warehouse_id", "product_id", "variant_id", "stock")
+SELECT (SELECT "id" FROM "warehouses" WHERE "is_default"), "product_id", "id", "stock"
+FROM "product_variants";
+
+ALTER TABLE "stock_movements" ADD COLUMN "warehouse_id" integer;
+UPDATE "stock_movements" SET "warehouse_id" = (SELECT "id" FROM "warehouses" WHERE "is_default");
+ALTER TABLE "stock_movements" ALTER COLUMN "warehouse_id" SET NOT NULL;
+ALTER TABLE "stock_movements" ADD FOREIGN KEY ("warehouse_id") REFERENCES "warehouses" ("id") ON DELETE RESTRICT ON UPDATE CASCADE;
+
+COMMENT ON COLUMN "stock_movements"."balance" IS 'stock of the product, or of the variant when variant_id is set, in the warehouse after the movement';
+COMMENT ON COLUMN "products"."stock" IS 'sum of the stock in every warehouse';
+COMMENT ON COLUMN "product_variants"."stock" IS 'sum of the stock in every warehouse';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+COMMENT ON COLUMN "product_variants"."stock" IS NULL;
+COMMENT ON COLUMN "products"."stock" IS NULL;
+COMMENT ON COLUMN "stock_movements"."balance" IS 'stock of the product, or of the variant when variant_id is set, after the movement';
+ALTER TABLE "stock_movements" DROP COLUMN "warehouse_id";
+
+DROP TABLE "order_item_allocations";
+DROP TABLE "warehouse_stocks";
+DROP TABLE "warehouses";
+-- +goose StatementEnd
@@ -0,0 +1,30 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE "products" ADD COLUMN "reorder_threshold" integer;
+
+COMMENT ON COLUMN "products"."reorder_threshold" IS 'alert when the stock, or the stock of a variant, falls below it, no alert when null';
+
+CREATE TABLE "stock_subscriptions" (
+  "id" INTEGER GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  "user_id" integer NOT NULL,
+  "product_id" integer NOT NULL,
+  "variant_id" integer,
+  "created_at" timestamp NOT NULL DEFAULT now(),
+  "notified_at" timestamp,
+  CONSTRAINT stock_subscriptions_user_id_product_id_variant_id_key UNIQUE NULLS NOT DISTINCT ("user_id", "product_id", "variant_id")
+);
+
+COMMENT ON TABLE "stock_subscriptions" IS 'customers waiting for an out of stock product to be restocked';
+
+CREATE INDEX stock_subscriptions_product_id_idx ON "stock_subscriptions" ("product_id", "variant_id") WHERE "notified_at" IS NULL;
+
+ALTER TABLE "stock_subscriptions" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON DELETE CASCADE ON UPDATE CASCADE;
+ALTER TABLE "stock_subscriptions" ADD FOREIGN KEY ("product_id") REFERENCES "products" ("id") ON DELETE CASCADE ON UPDATE CASCADE;
+ALTER TABLE "stock_subscriptions" ADD FOREIGN KEY ("variant_id") REFERENCES "product_variants" ("id") ON DELETE CASCADE ON UPDATE CASCADE;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE "stock_subscriptions";
+ALTER TABLE "products" DROP COLUMN "reorder_threshold";
+-- +goose StatementEnd
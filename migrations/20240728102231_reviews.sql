@@ -0,0 +1,39 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE "reviews" (
+  "id" INTEGER GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  "product_id" integer NOT NULL,
+  "user_id" integer NOT NULL,
+  "order_id" integer,
+  "rating" smallint NOT NULL CHECK ("rating" BETWEEN 1 AND 5),
+  "body" text NOT NULL,
+  "status" varchar NOT NULL DEFAULT 'pending',
+  "moderation_note" varchar NOT NULL DEFAULT '',
+  "moderated_at" timestamp,
+  "created_at" timestamp NOT NULL DEFAULT now(),
+  "updated_at" timestamp,
+  UNIQUE ("product_id", "user_id")
+);
+
+COMMENT ON COLUMN "reviews"."order_id" IS 'the order the product was bought in';
+COMMENT ON COLUMN "reviews"."status" IS 'pending, approved or rejected, only approved reviews are shown and rated';
+
+CREATE INDEX reviews_status_idx ON "reviews" ("status", "created_at");
+
+ALTER TABLE "reviews" ADD FOREIGN KEY ("product_id") REFERENCES "products" ("id") ON DELETE CASCADE ON UPDATE CASCADE;
+ALTER TABLE "reviews" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON DELETE CASCADE ON UPDATE CASCADE;
+ALTER TABLE "reviews" ADD FOREIGN KEY ("order_id") REFERENCES "orders" ("id") ON DELETE SET NULL ON UPDATE CASCADE;
+
+ALTER TABLE "products" ADD COLUMN "rating_average" numeric(3,2) NOT NULL DEFAULT 0;
+ALTER TABLE "products" ADD COLUMN "rating_count" integer NOT NULL DEFAULT 0;
+
+COMMENT ON COLUMN "products"."rating_average" IS 'average of the approved reviews';
+COMMENT ON COLUMN "products"."rating_count" IS 'number of approved reviews';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE "products" DROP COLUMN "rating_count";
+ALTER TABLE "products" DROP COLUMN "rating_average";
+DROP TABLE "reviews";
+-- +goose StatementEnd
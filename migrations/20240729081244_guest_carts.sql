@@ -0,0 +1,28 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE "guest_carts" (
+  "id" INTEGER GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  "created_at" timestamp NOT NULL DEFAULT now(),
+  "updated_at" timestamp
+);
+
+COMMENT ON TABLE "guest_carts" IS 'carts of visitors who are not signed in, merged into the customer cart on login';
+
+ALTER TABLE "cart_items" ALTER COLUMN "user_id" DROP NOT NULL;
+ALTER TABLE "cart_items" ADD COLUMN "guest_cart_id" integer;
+ALTER TABLE "cart_items" ADD CONSTRAINT cart_items_owner_check CHECK (("user_id" IS NULL) <> ("guest_cart_id" IS NULL));
+ALTER TABLE "cart_items" DROP CONSTRAINT cart_items_user_id_product_id_variant_id_key;
+ALTER TABLE "cart_items" ADD CONSTRAINT cart_items_user_id_guest_cart_id_product_id_variant_id_key UNIQUE NULLS NOT DISTINCT ("user_id", "guest_cart_id", "product_id", "variant_id");
+ALTER TABLE "cart_items" ADD FOREIGN KEY ("guest_cart_id") REFERENCES "guest_carts" ("id") ON DELETE CASCADE ON UPDATE CASCADE;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DELETE FROM "cart_items" WHERE "guest_cart_id" IS NOT NULL;
+ALTER TABLE "cart_items" DROP CONSTRAINT cart_items_user_id_guest_cart_id_product_id_variant_id_key;
+ALTER TABLE "cart_items" ADD CONSTRAINT cart_items_user_id_product_id_variant_id_key UNIQUE NULLS NOT DISTINCT ("user_id", "product_id", "variant_id");
+ALTER TABLE "cart_items" DROP CONSTRAINT cart_items_owner_check;
+ALTER TABLE "cart_items" DROP COLUMN "guest_cart_id";
+ALTER TABLE "cart_items" ALTER COLUMN "user_id" SET NOT NULL;
+DROP TABLE "guest_carts";
+-- +goose StatementEnd
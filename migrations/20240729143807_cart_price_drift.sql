@@ -0,0 +1,12 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE "cart_items" ADD COLUMN "priced_at" timestamp NOT NULL DEFAULT now();
+UPDATE "cart_items" SET "priced_at" = "created_at" WHERE "created_at" IS NOT NULL;
+
+COMMENT ON COLUMN "cart_items"."priced_at" IS 'when the price was captured, on add or confirmation';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE "cart_items" DROP COLUMN "priced_at";
+-- +goose StatementEnd
@@ -0,0 +1,73 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE "coupons" (
+  "id" INTEGER GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  "code" varchar(32) UNIQUE NOT NULL,
+  "type" varchar NOT NULL,
+  "value" integer NOT NULL DEFAULT 0,
+  "min_order_value" integer NOT NULL DEFAULT 0,
+  "usage_limit" integer,
+  "usage_limit_per_user" integer,
+  "starts_at" timestamp,
+  "ends_at" timestamp,
+  "categories" varchar[] NOT NULL DEFAULT '{}',
+  "product_ids" integer[] NOT NULL DEFAULT '{}',
+  "active" boolean NOT NULL DEFAULT true,
+  "created_at" timestamp NOT NULL DEFAULT now(),
+  "updated_at" timestamp
+);
+
+COMMENT ON COLUMN "coupons"."code" IS 'upper case, matched case insensitively';
+COMMENT ON COLUMN "coupons"."type" IS 'percentage, fixed or free_shipping';
+
+CREATE TABLE "coupon_redemptions" (
+  "id" INTEGER GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  "coupon_id" integer NOT NULL,
+  "user_id" integer NOT NULL,
+  "order_id" integer NOT NULL,
+  "amount" integer NOT NULL,
+  "created_at" timestamp NOT NULL DEFAULT now()
+);
+
+CREATE INDEX coupon_redemptions_coupon_id_user_id_idx ON "coupon_redemptions" ("coupon_id", "user_id");
+
+ALTER TABLE "coupon_redemptions" ADD FOREIGN KEY ("coupon_id") REFERENCES "coupons" ("id") ON DELETE RESTRICT ON UPDATE CASCADE;
+ALTER TABLE "coupon_redemptions" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON DELETE CASCADE ON UPDATE CASCADE;
+ALTER TABLE "coupon_redemptions" ADD FOREIGN KEY ("order_id") REFERENCES "orders" ("id") ON DELETE CASCADE ON UPDATE CASCADE;
+
+CREATE TABLE "cart_coupons" (
+  "user_id" integer PRIMARY KEY,
+  "coupon_id" integer NOT NULL,
+  "created_at" timestamp NOT NULL DEFAULT now()
+);
+
+ALTER TABLE "cart_coupons" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON DELETE CASCADE ON UPDATE CASCADE;
+ALTER TABLE "cart_coupons" ADD FOREIGN KEY ("coupon_id") REFERENCES "coupons" ("id") ON DELETE CASCADE ON UPDATE CASCADE;
+
+CREATE TABLE "order_adjustments" (
+  "id" INTEGER GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  "order_id" integer NOT NULL,
+  "order_item_id" integer,
+  "type" varchar NOT NULL,
+  "code" varchar NOT NULL DEFAULT '',
+  "description" varchar NOT NULL DEFAULT '',
+  "amount" integer NOT NULL,
+  "created_at" timestamp NOT NULL DEFAULT now()
+);
+
+COMMENT ON TABLE "order_adjustments" IS 'orders.total_price is the sum of the order items and adjustments';
+COMMENT ON COLUMN "order_adjustments"."amount" IS 'negative for discounts';
+
+CREATE INDEX order_adjustments_order_id_idx ON "order_adjustments" ("order_id");
+
+ALTER TABLE "order_adjustments" ADD FOREIGN KEY ("order_id") REFERENCES "orders" ("id") ON DELETE CASCADE ON UPDATE CASCADE;
+ALTER TABLE "order_adjustments" ADD FOREIGN KEY ("order_item_id") REFERENCES "order_items" ("id") ON DELETE CASCADE ON UPDATE CASCADE;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE "order_adjustments";
+DROP TABLE "cart_coupons";
+DROP TABLE "coupon_redemptions";
+DROP TABLE "coupons";
+-- +goose StatementEnd
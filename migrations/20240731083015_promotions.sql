@@ -0,0 +1,34 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE "promotions" (
+  "id" INTEGER GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  "name" varchar NOT NULL,
+  "type" varchar NOT NULL,
+  "priority" integer NOT NULL DEFAULT 0,
+  "exclusive" boolean NOT NULL DEFAULT false,
+  "starts_at" timestamp,
+  "ends_at" timestamp,
+  "active" boolean NOT NULL DEFAULT true,
+  "categories" varchar[] NOT NULL DEFAULT '{}',
+  "product_ids" integer[] NOT NULL DEFAULT '{}',
+  "buy_qty" integer NOT NULL DEFAULT 0,
+  "get_qty" integer NOT NULL DEFAULT 0,
+  "bundle_price" integer NOT NULL DEFAULT 0,
+  "percent" integer NOT NULL DEFAULT 0,
+  "tiers" jsonb NOT NULL DEFAULT '[]',
+  "created_at" timestamp NOT NULL DEFAULT now(),
+  "updated_at" timestamp
+);
+
+COMMENT ON COLUMN "promotions"."type" IS 'buy_x_get_y, bundle, volume_tier or sale';
+COMMENT ON COLUMN "promotions"."priority" IS 'lower applies first';
+COMMENT ON COLUMN "promotions"."exclusive" IS 'skips the lines already discounted, and no later promotion discounts its lines';
+COMMENT ON COLUMN "promotions"."tiers" IS 'volume tiers, e.g. [{"min_qty": 3, "percent": 10}]';
+
+CREATE INDEX promotions_active_priority_idx ON "promotions" ("active", "priority");
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE "promotions";
+-- +goose StatementEnd
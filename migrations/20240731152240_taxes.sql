@@ -0,0 +1,43 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE "tax_rates" (
+  "id" INTEGER GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  "region" varchar(16) NOT NULL,
+  "tax_class" varchar(32) NOT NULL DEFAULT 'standard',
+  "name" varchar NOT NULL,
+  "rate" integer NOT NULL CHECK ("rate" BETWEEN 0 AND 10000),
+  "created_at" timestamp NOT NULL DEFAULT now(),
+  "updated_at" timestamp
+);
+
+COMMENT ON COLUMN "tax_rates"."region" IS 'country code like ID, or subdivision like ID-JK falling back to its country';
+COMMENT ON COLUMN "tax_rates"."rate" IS 'basis points, 1000 is 10%';
+
+CREATE UNIQUE INDEX tax_rates_region_tax_class_idx ON "tax_rates" ("region", "tax_class");
+
+ALTER TABLE "products" ADD COLUMN "tax_class" varchar(32) NOT NULL DEFAULT 'standard';
+
+ALTER TABLE "orders" ADD COLUMN "tax_total" integer NOT NULL DEFAULT 0;
+ALTER TABLE "orders" ADD COLUMN "tax_mode" varchar NOT NULL DEFAULT 'exclusive';
+ALTER TABLE "orders" ADD COLUMN "tax_region" varchar NOT NULL DEFAULT '';
+
+COMMENT ON COLUMN "orders"."tax_total" IS 'added to total_price in exclusive tax_mode, part of it in inclusive mode';
+
+ALTER TABLE "order_items" ADD COLUMN "tax_class" varchar(32) NOT NULL DEFAULT 'standard';
+ALTER TABLE "order_items" ADD COLUMN "tax_rate" integer NOT NULL DEFAULT 0;
+ALTER TABLE "order_items" ADD COLUMN "tax_amount" integer NOT NULL DEFAULT 0;
+
+COMMENT ON COLUMN "order_items"."tax_amount" IS 'tax of the line after its discounts';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE "order_items" DROP COLUMN "tax_amount";
+ALTER TABLE "order_items" DROP COLUMN "tax_rate";
+ALTER TABLE "order_items" DROP COLUMN "tax_class";
+ALTER TABLE "orders" DROP COLUMN "tax_region";
+ALTER TABLE "orders" DROP COLUMN "tax_mode";
+ALTER TABLE "orders" DROP COLUMN "tax_total";
+ALTER TABLE "products" DROP COLUMN "tax_class";
+DROP TABLE "tax_rates";
+-- +goose StatementEnd
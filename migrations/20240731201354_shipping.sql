@@ -0,0 +1,96 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE "addresses" (
+  "id" INTEGER GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  "user_id" integer NOT NULL,
+  "label" varchar NOT NULL DEFAULT '',
+  "recipient_name" varchar NOT NULL,
+  "phone" varchar(32) NOT NULL,
+  "line1" varchar NOT NULL,
+  "line2" varchar NOT NULL DEFAULT '',
+  "city" varchar NOT NULL,
+  "region" varchar(16) NOT NULL DEFAULT '',
+  "postal_code" varchar(16) NOT NULL DEFAULT '',
+  "country" char(2) NOT NULL,
+  "is_default" boolean NOT NULL DEFAULT false,
+  "created_at" timestamp NOT NULL DEFAULT now(),
+  "updated_at" timestamp
+);
+
+COMMENT ON COLUMN "addresses"."region" IS 'subdivision code like ID-JK';
+COMMENT ON COLUMN "addresses"."country" IS 'ISO 3166-1 alpha-2 code';
+
+CREATE INDEX addresses_user_id_idx ON "addresses" ("user_id");
+CREATE UNIQUE INDEX addresses_user_id_default_idx ON "addresses" ("user_id") WHERE "is_default";
+
+ALTER TABLE "addresses" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON DELETE CASCADE ON UPDATE CASCADE;
+
+CREATE TABLE "shipping_methods" (
+  "id" INTEGER GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  "code" varchar(32) UNIQUE NOT NULL,
+  "name" varchar NOT NULL,
+  "zones" varchar[] NOT NULL DEFAULT '{}',
+  "rate_type" varchar NOT NULL,
+  "base_rate" integer NOT NULL DEFAULT 0,
+  "brackets" jsonb NOT NULL DEFAULT '[]',
+  "free_over" integer,
+  "position" integer NOT NULL DEFAULT 0,
+  "active" boolean NOT NULL DEFAULT true,
+  "created_at" timestamp NOT NULL DEFAULT now(),
+  "updated_at" timestamp
+);
+
+COMMENT ON COLUMN "shipping_methods"."zones" IS 'countries and regions the method ships to, everywhere when empty';
+COMMENT ON COLUMN "shipping_methods"."rate_type" IS 'flat, weight or order_value';
+COMMENT ON COLUMN "shipping_methods"."brackets" IS 'price from a weight in grams or an order value, e.g. [{"min": 1000, "price": 5000}], added to base_rate';
+COMMENT ON COLUMN "shipping_methods"."free_over" IS 'order value from which shipping is free';
+
+CREATE TABLE "cart_shipping" (
+  "user_id" integer PRIMARY KEY,
+  "address_id" integer NOT NULL,
+  "shipping_method_id" integer,
+  "created_at" timestamp NOT NULL DEFAULT now(),
+  "updated_at" timestamp
+);
+
+COMMENT ON TABLE "cart_shipping" IS 'the address and shipping method chosen for the cart of a customer';
+
+ALTER TABLE "cart_shipping" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON DELETE CASCADE ON UPDATE CASCADE;
+ALTER TABLE "cart_shipping" ADD FOREIGN KEY ("address_id") REFERENCES "addresses" ("id") ON DELETE CASCADE ON UPDATE CASCADE;
+ALTER TABLE "cart_shipping" ADD FOREIGN KEY ("shipping_method_id") REFERENCES "shipping_methods" ("id") ON DELETE SET NULL ON UPDATE CASCADE;
+
+ALTER TABLE "products" ADD COLUMN "weight" integer NOT NULL DEFAULT 0;
+ALTER TABLE "products" ADD COLUMN "length" integer NOT NULL DEFAULT 0;
+ALTER TABLE "products" ADD COLUMN "width" integer NOT NULL DEFAULT 0;
+ALTER TABLE "products" ADD COLUMN "height" integer NOT NULL DEFAULT 0;
+
+COMMENT ON COLUMN "products"."weight" IS 'grams';
+COMMENT ON COLUMN "products"."length" IS 'millimeters, like width and height';
+
+ALTER TABLE "orders" ADD COLUMN "shipping_total" integer NOT NULL DEFAULT 0;
+ALTER TABLE "orders" ADD COLUMN "shipping_method_id" integer;
+ALTER TABLE "orders" ADD COLUMN "shipping_method" varchar NOT NULL DEFAULT '';
+ALTER TABLE "orders" ADD COLUMN "shipping_address" jsonb;
+
+COMMENT ON COLUMN "orders"."shipping_address" IS 'copy of the address when the order was placed';
+
+ALTER TABLE "orders" ADD FOREIGN KEY ("shipping_method_id") REFERENCES "shipping_methods" ("id") ON DELETE SET NULL ON UPDATE CASCADE;
+
+COMMENT ON TABLE "order_adjustments" IS 'orders.total_price is the sum of the order items, adjustments, shipping_total and the exclusive tax_total';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+COMMENT ON TABLE "order_adjustments" IS 'orders.total_price is the sum of the order items and adjustments';
+ALTER TABLE "orders" DROP COLUMN "shipping_address";
+ALTER TABLE "orders" DROP COLUMN "shipping_method";
+ALTER TABLE "orders" DROP COLUMN "shipping_method_id";
+ALTER TABLE "orders" DROP COLUMN "shipping_total";
+ALTER TABLE "products" DROP COLUMN "height";
+ALTER TABLE "products" DROP COLUMN "width";
+ALTER TABLE "products" DROP COLUMN "length";
+ALTER TABLE "products" DROP COLUMN "weight";
+DROP TABLE "cart_shipping";
+DROP TABLE "shipping_methods";
+DROP TABLE "addresses";
+-- +goose StatementEnd
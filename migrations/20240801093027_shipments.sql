@@ -0,0 +1,55 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TYPE "order_status" ADD VALUE 'partially_shipped';
+ALTER TYPE "order_status" ADD VALUE 'shipped';
+ALTER TYPE "order_status" ADD VALUE 'delivered';
+
+CREATE TABLE "shipments" (
+  "id" INTEGER GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  "order_id" integer NOT NULL,
+  "carrier" varchar NOT NULL,
+  "tracking_number" varchar NOT NULL DEFAULT '',
+  "tracking_url" varchar NOT NULL DEFAULT '',
+  "status" varchar NOT NULL DEFAULT 'pending',
+  "shipped_at" timestamp,
+  "delivered_at" timestamp,
+  "created_at" timestamp NOT NULL DEFAULT now(),
+  "updated_at" timestamp
+);
+
+COMMENT ON COLUMN "shipments"."status" IS 'pending, shipped, in_transit, delivered or cancelled';
+
+CREATE INDEX shipments_order_id_idx ON "shipments" ("order_id");
+
+ALTER TABLE "shipments" ADD FOREIGN KEY ("order_id") REFERENCES "orders" ("id") ON DELETE CASCADE ON UPDATE CASCADE;
+
+CREATE TABLE "shipment_items" (
+  "id" INTEGER GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  "shipment_id" integer NOT NULL,
+  "order_item_id" integer NOT NULL,
+  "qty" integer NOT NULL CHECK ("qty" > 0)
+);
+
+CREATE INDEX shipment_items_shipment_id_idx ON "shipment_items" ("shipment_id");
+
+ALTER TABLE "shipment_items" ADD FOREIGN KEY ("shipment_id") REFERENCES "shipments" ("id") ON DELETE CASCADE ON UPDATE CASCADE;
+ALTER TABLE "shipment_items" ADD FOREIGN KEY ("order_item_id") REFERENCES "order_items" ("id") ON DELETE CASCADE ON UPDATE CASCADE;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE "shipment_items";
+DROP TABLE "shipments";
+
+-- enum values can't be dropped, the type is recreated without them
+UPDATE "orders" SET "status"='paid' WHERE "status" IN ('partially_shipped','shipped','delivered');
+ALTER TABLE "orders" ALTER COLUMN "status" DROP DEFAULT;
+ALTER TYPE "order_status" RENAME TO "order_status_old";
+CREATE TYPE "order_status" AS ENUM (
+  'unpaid',
+  'paid'
+);
+ALTER TABLE "orders" ALTER COLUMN "status" TYPE "order_status" USING "status"::text::"order_status";
+ALTER TABLE "orders" ALTER COLUMN "status" SET DEFAULT 'unpaid';
+DROP TYPE "order_status_old";
+-- +goose StatementEnd
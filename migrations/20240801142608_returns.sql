@@ -0,0 +1,77 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE "returns" (
+  "id" INTEGER GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  "order_id" integer NOT NULL,
+  "user_id" integer NOT NULL,
+  "status" varchar NOT NULL DEFAULT 'requested',
+  "reason" text NOT NULL,
+  "refund_amount" integer NOT NULL CHECK ("refund_amount" >= 0),
+  "refund_id" integer,
+  "created_at" timestamp NOT NULL DEFAULT now(),
+  "updated_at" timestamp
+);
+
+COMMENT ON COLUMN "returns"."status" IS 'requested, approved, rejected or received';
+
+CREATE INDEX returns_order_id_idx ON "returns" ("order_id");
+CREATE INDEX returns_status_idx ON "returns" ("status");
+
+ALTER TABLE "returns" ADD FOREIGN KEY ("order_id") REFERENCES "orders" ("id") ON DELETE CASCADE ON UPDATE CASCADE;
+ALTER TABLE "returns" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON DELETE CASCADE ON UPDATE CASCADE;
+
+CREATE TABLE "return_items" (
+  "id" INTEGER GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  "return_id" integer NOT NULL,
+  "order_item_id" integer NOT NULL,
+  "qty" integer NOT NULL CHECK ("qty" > 0),
+  "amount" integer NOT NULL,
+  "restocked" boolean NOT NULL DEFAULT false
+);
+
+CREATE INDEX return_items_return_id_idx ON "return_items" ("return_id");
+CREATE INDEX return_items_order_item_id_idx ON "return_items" ("order_item_id");
+
+ALTER TABLE "return_items" ADD FOREIGN KEY ("return_id") REFERENCES "returns" ("id") ON DELETE CASCADE ON UPDATE CASCADE;
+ALTER TABLE "return_items" ADD FOREIGN KEY ("order_item_id") REFERENCES "order_items" ("id") ON DELETE CASCADE ON UPDATE CASCADE;
+
+CREATE TABLE "return_history" (
+  "id" INTEGER GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  "return_id" integer NOT NULL,
+  "status" varchar NOT NULL,
+  "note" text NOT NULL DEFAULT '',
+  "actor" varchar NOT NULL,
+  "created_at" timestamp NOT NULL DEFAULT now()
+);
+
+CREATE INDEX return_history_return_id_idx ON "return_history" ("return_id");
+
+ALTER TABLE "return_history" ADD FOREIGN KEY ("return_id") REFERENCES "returns" ("id") ON DELETE CASCADE ON UPDATE CASCADE;
+
+CREATE TABLE "refunds" (
+  "id" INTEGER GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  "order_id" integer NOT NULL,
+  "payment_id" integer NOT NULL,
+  "return_id" integer,
+  "amount" integer NOT NULL CHECK ("amount" >= 0),
+  "reference" varchar NOT NULL,
+  "note" text NOT NULL DEFAULT '',
+  "created_at" timestamp NOT NULL DEFAULT now()
+);
+
+CREATE INDEX refunds_order_id_idx ON "refunds" ("order_id");
+
+ALTER TABLE "refunds" ADD FOREIGN KEY ("order_id") REFERENCES "orders" ("id") ON DELETE CASCADE ON UPDATE CASCADE;
+ALTER TABLE "refunds" ADD FOREIGN KEY ("payment_id") REFERENCES "payments" ("id") ON DELETE CASCADE ON UPDATE CASCADE;
+ALTER TABLE "refunds" ADD FOREIGN KEY ("return_id") REFERENCES "returns" ("id") ON DELETE SET NULL ON UPDATE CASCADE;
+ALTER TABLE "returns" ADD FOREIGN KEY ("refund_id") REFERENCES "refunds" ("id") ON DELETE SET NULL ON UPDATE CASCADE;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE "returns" DROP COLUMN "refund_id";
+DROP TABLE "refunds";
+DROP TABLE "return_history";
+DROP TABLE "return_items";
+DROP TABLE "returns";
+-- +goose StatementEnd
@@ -0,0 +1,46 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE "invoice_sequences" (
+  "kind" varchar PRIMARY KEY,
+  "last" integer NOT NULL DEFAULT 0
+);
+
+COMMENT ON TABLE "invoice_sequences" IS 'last number of each kind, taken in the issuing transaction so numbers never skip';
+
+INSERT INTO "invoice_sequences" ("kind") VALUES ('invoice'), ('credit_note');
+
+CREATE TABLE "invoices" (
+  "id" INTEGER GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  "number" varchar UNIQUE NOT NULL,
+  "kind" varchar NOT NULL,
+  "order_id" integer NOT NULL,
+  "refund_id" integer UNIQUE,
+  "invoice_id" integer,
+  "seller" jsonb NOT NULL,
+  "buyer" jsonb NOT NULL,
+  "lines" jsonb NOT NULL,
+  "taxes" jsonb NOT NULL,
+  "subtotal" integer NOT NULL,
+  "discount" integer NOT NULL DEFAULT 0,
+  "shipping_total" integer NOT NULL DEFAULT 0,
+  "tax_total" integer NOT NULL DEFAULT 0,
+  "tax_mode" varchar NOT NULL,
+  "total" integer NOT NULL,
+  "issued_at" timestamp NOT NULL DEFAULT now()
+);
+
+COMMENT ON COLUMN "invoices"."kind" IS 'invoice or credit_note';
+
+CREATE INDEX invoices_order_id_idx ON "invoices" ("order_id");
+CREATE UNIQUE INDEX invoices_order_id_invoice_key ON "invoices" ("order_id") WHERE "kind" = 'invoice';
+
+ALTER TABLE "invoices" ADD FOREIGN KEY ("order_id") REFERENCES "orders" ("id") ON UPDATE CASCADE;
+ALTER TABLE "invoices" ADD FOREIGN KEY ("refund_id") REFERENCES "refunds" ("id") ON UPDATE CASCADE;
+ALTER TABLE "invoices" ADD FOREIGN KEY ("invoice_id") REFERENCES "invoices" ("id") ON UPDATE CASCADE;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE "invoices";
+DROP TABLE "invoice_sequences";
+-- +goose StatementEnd
@@ -0,0 +1,132 @@
+-- +goose Up
+-- +goose StatementBegin
+-- amounts are in the minor unit of their currency, the amounts stored before
+-- were whole rupiah
+ALTER TABLE "products" ALTER COLUMN "price" TYPE bigint;
+ALTER TABLE "products" ADD COLUMN "currency" varchar(3) NOT NULL DEFAULT 'IDR';
+ALTER TABLE "product_variants" ALTER COLUMN "price" TYPE bigint;
+
+ALTER TABLE "cart_items" ALTER COLUMN "price" TYPE bigint;
+ALTER TABLE "cart_items" ADD COLUMN "currency" varchar(3) NOT NULL DEFAULT 'IDR';
+
+ALTER TABLE "orders" ALTER COLUMN "total_price" TYPE bigint;
+ALTER TABLE "orders" ALTER COLUMN "tax_total" TYPE bigint;
+ALTER TABLE "orders" ALTER COLUMN "shipping_total" TYPE bigint;
+ALTER TABLE "orders" ADD COLUMN "currency" varchar(3) NOT NULL DEFAULT 'IDR';
+ALTER TABLE "order_items" ALTER COLUMN "price" TYPE bigint;
+ALTER TABLE "order_items" ALTER COLUMN "tax_amount" TYPE bigint;
+ALTER TABLE "order_adjustments" ALTER COLUMN "amount" TYPE bigint;
+
+ALTER TABLE "payments" ALTER COLUMN "amount" TYPE bigint;
+ALTER TABLE "payments" ADD COLUMN "currency" varchar(3) NOT NULL DEFAULT 'IDR';
+
+ALTER TABLE "coupons" ALTER COLUMN "value" TYPE bigint;
+ALTER TABLE "coupons" ALTER COLUMN "min_order_value" TYPE bigint;
+ALTER TABLE "coupons" ADD COLUMN "currency" varchar(3) NOT NULL DEFAULT 'IDR';
+ALTER TABLE "coupon_redemptions" ALTER COLUMN "amount" TYPE bigint;
+ALTER TABLE "coupon_redemptions" ADD COLUMN "currency" varchar(3) NOT NULL DEFAULT 'IDR';
+
+ALTER TABLE "promotions" ALTER COLUMN "bundle_price" TYPE bigint;
+ALTER TABLE "promotions" ADD COLUMN "currency" varchar(3) NOT NULL DEFAULT 'IDR';
+
+ALTER TABLE "shipping_methods" ALTER COLUMN "base_rate" TYPE bigint;
+ALTER TABLE "shipping_methods" ALTER COLUMN "free_over" TYPE bigint;
+ALTER TABLE "shipping_methods" ADD COLUMN "currency" varchar(3) NOT NULL DEFAULT 'IDR';
+
+ALTER TABLE "returns" ALTER COLUMN "refund_amount" TYPE bigint;
+ALTER TABLE "returns" ADD COLUMN "currency" varchar(3) NOT NULL DEFAULT 'IDR';
+ALTER TABLE "return_items" ALTER COLUMN "amount" TYPE bigint;
+ALTER TABLE "refunds" ALTER COLUMN "amount" TYPE bigint;
+ALTER TABLE "refunds" ADD COLUMN "currency" varchar(3) NOT NULL DEFAULT 'IDR';
+
+ALTER TABLE "invoices" ALTER COLUMN "subtotal" TYPE bigint;
+ALTER TABLE "invoices" ALTER COLUMN "discount" TYPE bigint;
+ALTER TABLE "invoices" ALTER COLUMN "shipping_total" TYPE bigint;
+ALTER TABLE "invoices" ALTER COLUMN "tax_total" TYPE bigint;
+ALTER TABLE "invoices" ALTER COLUMN "total" TYPE bigint;
+ALTER TABLE "invoices" ADD COLUMN "currency" varchar(3) NOT NULL DEFAULT 'IDR';
+
+COMMENT ON COLUMN "products"."currency" IS 'ISO 4217 code of price and of the variant prices';
+COMMENT ON COLUMN "orders"."currency" IS 'ISO 4217 code of every amount of the order, its items and adjustments';
+COMMENT ON COLUMN "coupons"."value" IS 'percent, or minor units of currency for a fixed coupon';
+COMMENT ON COLUMN "returns"."currency" IS 'ISO 4217 code of refund_amount and of the return items';
+
+-- prices are now recorded as {"amount": ..., "currency": ...}, the history
+-- recorded before holds bare rupiah amounts
+DROP VIEW "product_price_history";
+CREATE VIEW "product_price_history" AS
+SELECT
+  "product_id",
+  "variant_id",
+  CASE jsonb_typeof("changes"->'price'->'to')
+    WHEN 'object' THEN ("changes"->'price'->'to'->>'amount')::bigint
+    ELSE ("changes"->'price'->>'to')::bigint
+  END AS "price",
+  "created_at" AS "valid_from",
+  lead("created_at") OVER (PARTITION BY "product_id", "variant_id" ORDER BY "created_at", "id") AS "valid_to",
+  "actor",
+  "request_id",
+  coalesce("changes"->'price'->'to'->>'currency', 'IDR')::varchar AS "currency"
+FROM "product_history"
+WHERE "changes" ? 'price';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP VIEW "product_price_history";
+CREATE VIEW "product_price_history" AS
+SELECT
+  "product_id",
+  "variant_id",
+  ("changes"->'price'->>'to')::integer AS "price",
+  "created_at" AS "valid_from",
+  lead("created_at") OVER (PARTITION BY "product_id", "variant_id" ORDER BY "created_at", "id") AS "valid_to",
+  "actor",
+  "request_id"
+FROM "product_history"
+WHERE "changes" ? 'price';
+
+ALTER TABLE "invoices" DROP COLUMN "currency";
+ALTER TABLE "invoices" ALTER COLUMN "total" TYPE integer;
+ALTER TABLE "invoices" ALTER COLUMN "tax_total" TYPE integer;
+ALTER TABLE "invoices" ALTER COLUMN "shipping_total" TYPE integer;
+ALTER TABLE "invoices" ALTER COLUMN "discount" TYPE integer;
+ALTER TABLE "invoices" ALTER COLUMN "subtotal" TYPE integer;
+
+ALTER TABLE "refunds" DROP COLUMN "currency";
+ALTER TABLE "refunds" ALTER COLUMN "amount" TYPE integer;
+ALTER TABLE "return_items" ALTER COLUMN "amount" TYPE integer;
+ALTER TABLE "returns" DROP COLUMN "currency";
+ALTER TABLE "returns" ALTER COLUMN "refund_amount" TYPE integer;
+
+ALTER TABLE "shipping_methods" DROP COLUMN "currency";
+ALTER TABLE "shipping_methods" ALTER COLUMN "free_over" TYPE integer;
+ALTER TABLE "shipping_methods" ALTER COLUMN "base_rate" TYPE integer;
+
+ALTER TABLE "promotions" DROP COLUMN "currency";
+ALTER TABLE "promotions" ALTER COLUMN "bundle_price" TYPE integer;
+
+ALTER TABLE "coupon_redemptions" DROP COLUMN "currency";
+ALTER TABLE "coupon_redemptions" ALTER COLUMN "amount" TYPE integer;
+ALTER TABLE "coupons" DROP COLUMN "currency";
+ALTER TABLE "coupons" ALTER COLUMN "min_order_value" TYPE integer;
+ALTER TABLE "coupons" ALTER COLUMN "value" TYPE integer;
+
+ALTER TABLE "payments" DROP COLUMN "currency";
+ALTER TABLE "payments" ALTER COLUMN "amount" TYPE integer;
+
+ALTER TABLE "order_adjustments" ALTER COLUMN "amount" TYPE integer;
+ALTER TABLE "order_items" ALTER COLUMN "tax_amount" TYPE integer;
+ALTER TABLE "order_items" ALTER COLUMN "price" TYPE integer;
+ALTER TABLE "orders" DROP COLUMN "currency";
+ALTER TABLE "orders" ALTER COLUMN "shipping_total" TYPE integer;
+ALTER TABLE "orders" ALTER COLUMN "tax_total" TYPE integer;
+ALTER TABLE "orders" ALTER COLUMN "total_price" TYPE integer;
+
+ALTER TABLE "cart_items" DROP COLUMN "currency";
+ALTER TABLE "cart_items" ALTER COLUMN "price" TYPE integer;
+
+ALTER TABLE "product_variants" ALTER COLUMN "price" TYPE integer;
+ALTER TABLE "products" DROP COLUMN "currency";
+ALTER TABLE "products" ALTER COLUMN "price" TYPE integer;
+-- +goose StatementEnd
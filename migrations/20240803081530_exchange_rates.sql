@@ -0,0 +1,62 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE "exchange_rates" (
+  "currency" varchar(3) PRIMARY KEY,
+  "rate" numeric(24,12) NOT NULL CHECK ("rate" > 0),
+  "updated_at" timestamp NOT NULL DEFAULT now()
+);
+
+COMMENT ON TABLE "exchange_rates" IS 'how many units of currency one unit of the base currency buys, the base currency has no row';
+
+CREATE TABLE "product_prices" (
+  "id" INTEGER GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  "product_id" integer NOT NULL,
+  "variant_id" integer,
+  "currency" varchar(3) NOT NULL,
+  "price" bigint NOT NULL CHECK ("price" > 0),
+  "created_at" timestamp NOT NULL DEFAULT now(),
+  "updated_at" timestamp
+);
+
+COMMENT ON TABLE "product_prices" IS 'list prices in other currencies than the base, the others are converted at the exchange rates';
+
+CREATE UNIQUE INDEX product_prices_product_variant_currency_key ON "product_prices" ("product_id", coalesce("variant_id", 0), "currency");
+
+ALTER TABLE "product_prices" ADD FOREIGN KEY ("product_id") REFERENCES "products" ("id") ON DELETE CASCADE ON UPDATE CASCADE;
+ALTER TABLE "product_prices" ADD FOREIGN KEY ("variant_id") REFERENCES "product_variants" ("id") ON DELETE CASCADE ON UPDATE CASCADE;
+
+ALTER TABLE "users" ADD COLUMN "currency" varchar(3);
+
+COMMENT ON COLUMN "users"."currency" IS 'currency the customer prefers to see prices in, the base currency when null';
+
+-- the orders placed before were charged in the base currency
+ALTER TABLE "orders" ADD COLUMN "base_currency" varchar(3);
+ALTER TABLE "orders" ADD COLUMN "base_total_price" bigint;
+ALTER TABLE "orders" ADD COLUMN "exchange_rate" numeric(24,12) NOT NULL DEFAULT 1;
+UPDATE "orders" SET "base_currency" = "currency", "base_total_price" = "total_price";
+ALTER TABLE "orders" ALTER COLUMN "base_currency" SET NOT NULL;
+ALTER TABLE "orders" ALTER COLUMN "base_total_price" SET NOT NULL;
+
+COMMENT ON COLUMN "orders"."exchange_rate" IS 'rate from base_currency to currency when the order was placed';
+
+ALTER TABLE "payments" ADD COLUMN "base_currency" varchar(3);
+ALTER TABLE "payments" ADD COLUMN "base_amount" bigint;
+UPDATE "payments" SET "base_currency" = "currency", "base_amount" = "amount";
+ALTER TABLE "payments" ALTER COLUMN "base_currency" SET NOT NULL;
+ALTER TABLE "payments" ALTER COLUMN "base_amount" SET NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE "payments" DROP COLUMN "base_amount";
+ALTER TABLE "payments" DROP COLUMN "base_currency";
+
+ALTER TABLE "orders" DROP COLUMN "exchange_rate";
+ALTER TABLE "orders" DROP COLUMN "base_total_price";
+ALTER TABLE "orders" DROP COLUMN "base_currency";
+
+ALTER TABLE "users" DROP COLUMN "currency";
+
+DROP TABLE IF EXISTS "product_prices";
+DROP TABLE IF EXISTS "exchange_rates";
+-- +goose StatementEnd
@@ -0,0 +1,59 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE "wishlists" (
+  "id" INTEGER GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  "user_id" integer NOT NULL,
+  "name" varchar NOT NULL,
+  "share_token" varchar UNIQUE,
+  "created_at" timestamp NOT NULL DEFAULT now(),
+  "updated_at" timestamp
+);
+
+COMMENT ON COLUMN "wishlists"."share_token" IS 'token of the read-only link of the wishlist, null when not shared';
+
+ALTER TABLE "wishlists" ADD CONSTRAINT wishlists_user_id_name_key UNIQUE ("user_id", "name");
+ALTER TABLE "wishlists" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON DELETE CASCADE ON UPDATE CASCADE;
+
+CREATE TABLE "wishlist_items" (
+  "id" INTEGER GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  "wishlist_id" integer NOT NULL,
+  "product_id" integer NOT NULL,
+  "variant_id" integer,
+  "price" bigint NOT NULL,
+  "currency" varchar(3) NOT NULL,
+  "created_at" timestamp NOT NULL DEFAULT now()
+);
+
+COMMENT ON COLUMN "wishlist_items"."price" IS 'catalog price when the item was added, to tell how it changed';
+
+ALTER TABLE "wishlist_items" ADD CONSTRAINT wishlist_items_wishlist_id_product_id_variant_id_key UNIQUE NULLS NOT DISTINCT ("wishlist_id", "product_id", "variant_id");
+ALTER TABLE "wishlist_items" ADD FOREIGN KEY ("wishlist_id") REFERENCES "wishlists" ("id") ON DELETE CASCADE ON UPDATE CASCADE;
+ALTER TABLE "wishlist_items" ADD FOREIGN KEY ("product_id") REFERENCES "products" ("id") ON DELETE CASCADE ON UPDATE CASCADE;
+ALTER TABLE "wishlist_items" ADD FOREIGN KEY ("variant_id") REFERENCES "product_variants" ("id") ON DELETE CASCADE ON UPDATE CASCADE;
+
+CREATE TABLE "saved_items" (
+  "id" INTEGER GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  "user_id" integer NOT NULL,
+  "product_id" integer NOT NULL,
+  "variant_id" integer,
+  "qty" integer NOT NULL,
+  "price" bigint NOT NULL,
+  "currency" varchar(3) NOT NULL,
+  "priced_at" timestamp NOT NULL,
+  "created_at" timestamp NOT NULL DEFAULT now()
+);
+
+COMMENT ON TABLE "saved_items" IS 'cart lines saved for later, they keep the price they were added to the cart at';
+
+ALTER TABLE "saved_items" ADD CONSTRAINT saved_items_user_id_product_id_variant_id_key UNIQUE NULLS NOT DISTINCT ("user_id", "product_id", "variant_id");
+ALTER TABLE "saved_items" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON DELETE CASCADE ON UPDATE CASCADE;
+ALTER TABLE "saved_items" ADD FOREIGN KEY ("product_id") REFERENCES "products" ("id") ON DELETE CASCADE ON UPDATE CASCADE;
+ALTER TABLE "saved_items" ADD FOREIGN KEY ("variant_id") REFERENCES "product_variants" ("id") ON DELETE CASCADE ON UPDATE CASCADE;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS "saved_items";
+DROP TABLE IF EXISTS "wishlist_items";
+DROP TABLE IF EXISTS "wishlists";
+-- +goose StatementEnd
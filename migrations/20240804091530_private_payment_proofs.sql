@@ -0,0 +1,12 @@
+-- +goose Up
+-- +goose StatementBegin
+-- payment proofs moved from the public /files route to an authenticated one
+UPDATE "payments" SET "proof_url" = replace("proof_url", '/files/proofs/', '/api/payment-proofs/')
+WHERE "proof_url" LIKE '%/files/proofs/%';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+UPDATE "payments" SET "proof_url" = replace("proof_url", '/api/payment-proofs/', '/files/proofs/')
+WHERE "proof_url" LIKE '%/api/payment-proofs/%';
+-- +goose StatementEnd
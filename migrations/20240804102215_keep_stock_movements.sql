@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+-- the ledger outlives purged products and deleted variants, like product_history
+ALTER TABLE "stock_movements" DROP CONSTRAINT "stock_movements_product_id_fkey";
+ALTER TABLE "stock_movements" DROP CONSTRAINT "stock_movements_variant_id_fkey";
+
+COMMENT ON TABLE "stock_movements" IS 'not linked to products so it outlives purged products';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+COMMENT ON TABLE "stock_movements" IS NULL;
+
+DELETE FROM "stock_movements" WHERE "product_id" NOT IN (SELECT "id" FROM "products");
+DELETE FROM "stock_movements" WHERE "variant_id" NOT IN (SELECT "id" FROM "product_variants");
+
+ALTER TABLE "stock_movements" ADD FOREIGN KEY ("product_id") REFERENCES "products" ("id") ON DELETE CASCADE ON UPDATE CASCADE;
+ALTER TABLE "stock_movements" ADD FOREIGN KEY ("variant_id") REFERENCES "product_variants" ("id") ON DELETE CASCADE ON UPDATE CASCADE;
+-- +goose StatementEnd
@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+-- refunds are recorded pending before the gateway pays them back
+ALTER TABLE "refunds" ADD COLUMN "status" varchar NOT NULL DEFAULT 'completed';
+ALTER TABLE "refunds" ALTER COLUMN "status" DROP DEFAULT;
+ALTER TABLE "refunds" ADD COLUMN "idempotency_key" varchar;
+UPDATE "refunds" SET "idempotency_key" = 'refund:' || "id";
+ALTER TABLE "refunds" ALTER COLUMN "idempotency_key" SET NOT NULL;
+ALTER TABLE "refunds" ADD CONSTRAINT refunds_idempotency_key_key UNIQUE ("idempotency_key");
+ALTER TABLE "refunds" ADD COLUMN "completed_at" timestamp;
+UPDATE "refunds" SET "completed_at" = "created_at";
+ALTER TABLE "refunds" ALTER COLUMN "reference" SET DEFAULT '';
+
+COMMENT ON COLUMN "refunds"."status" IS 'pending until the payment gateway pays it back, then completed';
+COMMENT ON COLUMN "refunds"."idempotency_key" IS 'sent to the payment gateway so a retried refund is paid once, e.g. return:12';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DELETE FROM "refunds" WHERE "status" = 'pending';
+ALTER TABLE "refunds" ALTER COLUMN "reference" DROP DEFAULT;
+ALTER TABLE "refunds" DROP COLUMN "completed_at";
+ALTER TABLE "refunds" DROP COLUMN "idempotency_key";
+ALTER TABLE "refunds" DROP COLUMN "status";
+-- +goose StatementEnd
@@ -0,0 +1,59 @@
+package money
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Currency is an ISO 4217 currency code, e.g. IDR.
+type Currency string
+
+type currencyInfo struct {
+	// exponent is the number of digits of the minor unit, 2 for cents.
+	exponent int
+	symbol   string
+}
+
+// currencies are the currencies the store can price in. The exponent of IDR
+// is 0 instead of the ISO 2, the sen has not been used in decades and rupiah
+// amounts were always whole.
+var currencies = map[Currency]currencyInfo{
+	"AUD": {2, "A$"},
+	"CNY": {2, "CN¥"},
+	"EUR": {2, "€"},
+	"GBP": {2, "£"},
+	"IDR": {0, "Rp"},
+	"INR": {2, "₹"},
+	"JPY": {0, "¥"},
+	"KRW": {0, "₩"},
+	"MYR": {2, "RM"},
+	"PHP": {2, "₱"},
+	"SGD": {2, "S$"},
+	"THB": {2, "฿"},
+	"USD": {2, "$"},
+	"VND": {0, "₫"},
+}
+
+// ParseCurrency returns the currency of a code, in any case.
+func ParseCurrency(code string) (Currency, error) {
+	currency := Currency(strings.ToUpper(strings.TrimSpace(code)))
+	if _, ok := currencies[currency]; !ok {
+		return "", fmt.Errorf("%w: %q", ErrUnknownCurrency, code)
+	}
+
+	return currency, nil
+}
+
+// Exponent is the number of digits of the minor unit of the currency.
+func (c Currency) Exponent() int {
+	return currencies[c].exponent
+}
+
+// Symbol is the sign of the currency, its code when it has none.
+func (c Currency) Symbol() string {
+	if info, ok := currencies[c]; ok {
+		return info.symbol
+	}
+
+	return string(c)
+}
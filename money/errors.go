@@ -0,0 +1,9 @@
+package money
+
+import "errors"
+
+var ErrUnknownCurrency = errors.New("unknown currency")
+var ErrCurrencyMismatch = errors.New("currencies don't match")
+var ErrOverflow = errors.New("amount overflows")
+var ErrInvalidAmount = errors.New("amount must be a number of minor units or an object of amount and currency")
+var ErrInvalidRate = errors.New("rate must be a positive decimal")
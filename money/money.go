@@ -0,0 +1,310 @@
+package money
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Money is an amount in the minor unit of its currency, e.g. cents.
+//
+// The zero value has no currency and takes the currency of the amounts it is
+// added to, so sums can start from it. Mixing two currencies is a bug and
+// panics with ErrCurrencyMismatch, like an amount out of int64 panics with
+// ErrOverflow.
+type Money struct {
+	Amount   int64
+	Currency Currency
+}
+
+func New(amount int64, currency Currency) Money {
+	return Money{amount, currency}
+}
+
+// Zero is no money in currency.
+func Zero(currency Currency) Money {
+	return Money{0, currency}
+}
+
+// Rounding rounds the results of MulRatio, Percent and BasisPoints.
+type Rounding int
+
+const (
+	// RoundDown drops the remainder, toward zero.
+	RoundDown Rounding = iota
+	// RoundHalfUp rounds half away from zero.
+	RoundHalfUp
+	// RoundHalfEven rounds half to the even amount, the banker's rounding.
+	RoundHalfEven
+)
+
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+func (m Money) IsNegative() bool {
+	return m.Amount < 0
+}
+
+func (m Money) IsPositive() bool {
+	return m.Amount > 0
+}
+
+// SameCurrency reports whether m and o can be added together.
+func (m Money) SameCurrency(o Money) bool {
+	return m.Currency == o.Currency || m.Currency == "" || o.Currency == ""
+}
+
+// currency returns the currency of an operation on m and o.
+func (m Money) currency(o Money) Currency {
+	if !m.SameCurrency(o) {
+		panic(fmt.Errorf("%w: %s and %s", ErrCurrencyMismatch, m.Currency, o.Currency))
+	}
+
+	if m.Currency == "" {
+		return o.Currency
+	}
+	return m.Currency
+}
+
+func (m Money) Add(o Money) Money {
+	currency := m.currency(o)
+	sum := m.Amount + o.Amount
+	if (sum > m.Amount) != (o.Amount > 0) {
+		panic(ErrOverflow)
+	}
+
+	return Money{sum, currency}
+}
+
+func (m Money) Sub(o Money) Money {
+	return m.Add(o.Neg())
+}
+
+func (m Money) Neg() Money {
+	if m.Amount == math.MinInt64 {
+		panic(ErrOverflow)
+	}
+
+	return Money{-m.Amount, m.Currency}
+}
+
+// Mul is m times n, e.g. the total of a quantity.
+func (m Money) Mul(n int) Money {
+	if n == 0 || m.Amount == 0 {
+		return Money{0, m.Currency}
+	}
+
+	product := m.Amount * int64(n)
+	if product/int64(n) != m.Amount {
+		panic(ErrOverflow)
+	}
+
+	return Money{product, m.Currency}
+}
+
+// MulRatio is m times num divided by den, rounded, e.g. the share of a line
+// in a discount. It panics when den is 0.
+func (m Money) MulRatio(num, den int64, rounding Rounding) Money {
+	if den == 0 {
+		panic("money: division by zero")
+	}
+
+	n := new(big.Int).Mul(big.NewInt(m.Amount), big.NewInt(num))
+	return Money{quo(n, big.NewInt(den), rounding), m.Currency}
+}
+
+// quo is n divided by d, rounded. It panics with ErrOverflow when the result
+// is out of int64.
+func quo(n, d *big.Int, rounding Rounding) int64 {
+	if d.Sign() < 0 {
+		n = new(big.Int).Neg(n)
+		d = new(big.Int).Neg(d)
+	}
+
+	q, r := new(big.Int).QuoRem(n, d, new(big.Int))
+	if r.Sign() != 0 && rounding != RoundDown {
+		// twice the remainder against the divisor tells below, at or over half
+		half := new(big.Int).Abs(r)
+		half.Lsh(half, 1)
+		cmp := half.Cmp(d)
+
+		away := cmp > 0 || (cmp == 0 && (rounding == RoundHalfUp || q.Bit(0) == 1))
+		if away {
+			q.Add(q, big.NewInt(int64(n.Sign())))
+		}
+	}
+
+	if !q.IsInt64() {
+		panic(ErrOverflow)
+	}
+
+	return q.Int64()
+}
+
+// Percent is percent of m, e.g. 10 for 10%.
+func (m Money) Percent(percent int, rounding Rounding) Money {
+	return m.MulRatio(int64(percent), 100, rounding)
+}
+
+// BasisPoints is bp hundredths of a percent of m, e.g. 1100 for 11%.
+func (m Money) BasisPoints(bp int, rounding Rounding) Money {
+	return m.MulRatio(int64(bp), 10000, rounding)
+}
+
+// Cmp compares m and o, -1 when m is less, 0 when equal and +1 when more.
+func (m Money) Cmp(o Money) int {
+	m.currency(o)
+
+	switch {
+	case m.Amount < o.Amount:
+		return -1
+	case m.Amount > o.Amount:
+		return 1
+	}
+	return 0
+}
+
+func (m Money) LessThan(o Money) bool {
+	return m.Cmp(o) < 0
+}
+
+func (m Money) GreaterThan(o Money) bool {
+	return m.Cmp(o) > 0
+}
+
+// Min returns the smaller of a and b.
+func Min(a, b Money) Money {
+	if b.LessThan(a) {
+		return b
+	}
+	return a
+}
+
+// Max returns the bigger of a and b.
+func Max(a, b Money) Money {
+	if b.GreaterThan(a) {
+		return b
+	}
+	return a
+}
+
+// Number formats the amount in the major unit with thousands separators,
+// without the currency, e.g. 1,250.50.
+func (m Money) Number() string {
+	amount := m.Amount
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+	}
+
+	digits := strconv.FormatUint(uint64(amount), 10)
+	if amount < 0 {
+		digits = strconv.FormatUint(uint64(-(amount+1))+1, 10)
+	}
+
+	exponent := m.Currency.Exponent()
+	if len(digits) <= exponent {
+		digits = strings.Repeat("0", exponent-len(digits)+1) + digits
+	}
+
+	whole, fraction := digits[:len(digits)-exponent], digits[len(digits)-exponent:]
+	for i := len(whole) - 3; i > 0; i -= 3 {
+		whole = whole[:i] + "," + whole[i:]
+	}
+
+	if fraction == "" {
+		return sign + whole
+	}
+	return sign + whole + "." + fraction
+}
+
+// String formats m with its currency code, e.g. USD 1,250.50.
+func (m Money) String() string {
+	return strings.TrimSpace(string(m.Currency) + " " + m.Number())
+}
+
+// Format formats m with the symbol of its currency, e.g. $1,250.50.
+func (m Money) Format() string {
+	if m.Amount < 0 {
+		return "-" + m.Currency.Symbol() + m.Neg().Number()
+	}
+	return m.Currency.Symbol() + m.Number()
+}
+
+type moneyJSON struct {
+	Amount    int64    `json:"amount"`
+	Currency  Currency `json:"currency"`
+	Formatted string   `json:"formatted"`
+}
+
+// MarshalJSON encodes m as its amount in minor units, its currency and the
+// amount formatted for display.
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(moneyJSON{m.Amount, m.Currency, m.Format()})
+}
+
+// UnmarshalJSON decodes the object MarshalJSON encodes, the formatted amount
+// being ignored, or a bare number of minor units without a currency.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] != '{' {
+		var amount int64
+		if err := json.Unmarshal(data, &amount); err != nil {
+			return ErrInvalidAmount
+		}
+		*m = Money{Amount: amount}
+		return nil
+	}
+
+	var v moneyJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return ErrInvalidAmount
+	}
+
+	*m = Money{Amount: v.Amount}
+	if v.Currency != "" {
+		currency, err := ParseCurrency(string(v.Currency))
+		if err != nil {
+			return err
+		}
+		m.Currency = currency
+	}
+
+	return nil
+}
+
+// As returns m in currency, an amount without a currency, e.g. decoded from a
+// bare number, taking it.
+func (m Money) As(currency Currency) (Money, error) {
+	if m.Currency == "" {
+		m.Currency = currency
+	}
+
+	if m.Currency != currency {
+		return m, fmt.Errorf("%w: %s, expected %s", ErrCurrencyMismatch, m.Currency, currency)
+	}
+
+	return m, nil
+}
+
+// ScanNull and ScanIndex scan a row(amount,currency) record, select the amount
+// columns as such to scan them into Money.
+func (m *Money) ScanNull() error {
+	return errors.New("money: cannot scan NULL into Money")
+}
+
+func (m *Money) ScanIndex(i int) any {
+	switch i {
+	case 0:
+		return &m.Amount
+	case 1:
+		return &m.Currency
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestMulRatioRounding(t *testing.T) {
+	tests := []struct {
+		amount   int64
+		num, den int64
+		rounding Rounding
+		want     int64
+	}{
+		{10, 1, 4, RoundDown, 2},
+		{10, 1, 4, RoundHalfUp, 3},
+		{10, 1, 4, RoundHalfEven, 2},
+		{14, 1, 4, RoundHalfEven, 4},
+		{11, 1, 4, RoundHalfUp, 3},
+		{9, 1, 4, RoundHalfUp, 2},
+		{9, 1, 4, RoundHalfEven, 2},
+		{-10, 1, 4, RoundDown, -2},
+		{-10, 1, 4, RoundHalfUp, -3},
+		{-10, 1, 4, RoundHalfEven, -2},
+		{-14, 1, 4, RoundHalfEven, -4},
+		{10, 1, -4, RoundHalfUp, -3},
+		{12, 1, 4, RoundHalfUp, 3},
+		{0, 7, 3, RoundHalfUp, 0},
+	}
+
+	for _, tt := range tests {
+		got := New(tt.amount, "USD").MulRatio(tt.num, tt.den, tt.rounding)
+		if got != New(tt.want, "USD") {
+			t.Errorf("%d * %d/%d rounding %d: got %d, want %d", tt.amount, tt.num, tt.den, tt.rounding, got.Amount, tt.want)
+		}
+	}
+}
+
+func TestPercentAndBasisPoints(t *testing.T) {
+	tests := []struct {
+		name string
+		got  Money
+		want int64
+	}{
+		{"10% of 1,005", New(1005, "USD").Percent(10, RoundHalfUp), 101},
+		{"10% of 1,005 down", New(1005, "USD").Percent(10, RoundDown), 100},
+		{"15% of 50 half even", New(50, "USD").Percent(15, RoundHalfEven), 8},
+		{"25% of 50 half even", New(50, "USD").Percent(25, RoundHalfEven), 12},
+		{"11% of 150,000", New(150000, "IDR").BasisPoints(1100, RoundHalfUp), 16500},
+		{"0.5% of 99", New(99, "USD").BasisPoints(50, RoundHalfUp), 0},
+		{"0.5% of 100", New(100, "USD").BasisPoints(50, RoundHalfUp), 1},
+	}
+
+	for _, tt := range tests {
+		if tt.got.Amount != tt.want {
+			t.Errorf("%s: got %d, want %d", tt.name, tt.got.Amount, tt.want)
+		}
+	}
+}
+
+func TestMulRatioOverflow(t *testing.T) {
+	defer func() {
+		if r := recover(); r != ErrOverflow {
+			t.Errorf("got panic %v, want ErrOverflow", r)
+		}
+	}()
+
+	New(1<<62, "USD").MulRatio(4, 1, RoundHalfUp)
+}
+
+func TestParseCurrency(t *testing.T) {
+	tests := []struct {
+		code string
+		want Currency
+		err  error
+	}{
+		{"IDR", "IDR", nil},
+		{"usd", "USD", nil},
+		{" sgd ", "SGD", nil},
+		{"XYZ", "", ErrUnknownCurrency},
+		{"", "", ErrUnknownCurrency},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseCurrency(tt.code)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("%q: got error %v, want %v", tt.code, err, tt.err)
+		}
+		if got != tt.want {
+			t.Errorf("%q: got %q, want %q", tt.code, got, tt.want)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		money  Money
+		number string
+		format string
+	}{
+		{New(125050, "USD"), "1,250.50", "$1,250.50"},
+		{New(5, "USD"), "0.05", "$0.05"},
+		{New(-5, "USD"), "-0.05", "-$0.05"},
+		{New(1500000, "IDR"), "1,500,000", "Rp1,500,000"},
+		{New(-1000, "JPY"), "-1,000", "-¥1,000"},
+		{New(0, "EUR"), "0.00", "€0.00"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.money.Number(); got != tt.number {
+			t.Errorf("%d %s: got number %q, want %q", tt.money.Amount, tt.money.Currency, got, tt.number)
+		}
+		if got := tt.money.Format(); got != tt.format {
+			t.Errorf("%d %s: got format %q, want %q", tt.money.Amount, tt.money.Currency, got, tt.format)
+		}
+	}
+}
+
+func TestUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		data string
+		want Money
+		err  error
+	}{
+		{`1500`, Money{Amount: 1500}, nil},
+		{`{"amount": 1250, "currency": "usd", "formatted": "ignored"}`, New(1250, "USD"), nil},
+		{`{"amount": 1250}`, Money{Amount: 1250}, nil},
+		{`{"amount": 1, "currency": "XYZ"}`, Money{}, ErrUnknownCurrency},
+		{`"1500"`, Money{}, ErrInvalidAmount},
+		{`12.5`, Money{}, ErrInvalidAmount},
+	}
+
+	for _, tt := range tests {
+		var got Money
+		err := json.Unmarshal([]byte(tt.data), &got)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("%s: got error %v, want %v", tt.data, err, tt.err)
+			continue
+		}
+		if err == nil && got != tt.want {
+			t.Errorf("%s: got %+v, want %+v", tt.data, got, tt.want)
+		}
+	}
+}
+
+func TestMarshalJSON(t *testing.T) {
+	data, err := json.Marshal(New(125050, "USD"))
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+
+	want := `{"amount":125050,"currency":"USD","formatted":"$1,250.50"}`
+	if string(data) != want {
+		t.Errorf("got %s, want %s", data, want)
+	}
+}
@@ -0,0 +1,127 @@
+package money
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// Rate is an exchange rate, how many units of a currency one unit of another
+// buys, e.g. 0.000063 USD for 1 IDR. The zero value is no rate.
+type Rate struct {
+	rat *big.Rat
+}
+
+// ParseRate parses a positive decimal rate, e.g. 0.000063.
+func ParseRate(s string) (Rate, error) {
+	rat, ok := new(big.Rat).SetString(strings.TrimSpace(s))
+	if !ok || rat.Sign() <= 0 {
+		return Rate{}, fmt.Errorf("%w: %q", ErrInvalidRate, s)
+	}
+
+	return Rate{rat}, nil
+}
+
+func (r Rate) IsZero() bool {
+	return r.rat == nil
+}
+
+// Inverse is the rate of the other way, 1 divided by r.
+func (r Rate) Inverse() Rate {
+	if r.rat == nil {
+		return r
+	}
+
+	return Rate{new(big.Rat).Inv(r.rat)}
+}
+
+// String formats r as a decimal of up to 10 digits after the point.
+func (r Rate) String() string {
+	if r.rat == nil {
+		return "0"
+	}
+
+	s := r.rat.FloatString(10)
+	s = strings.TrimRight(s, "0")
+	return strings.TrimSuffix(s, ".")
+}
+
+// MarshalJSON encodes r as a decimal string, which keeps its precision.
+func (r Rate) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.String())
+}
+
+// UnmarshalJSON decodes a decimal string or number.
+func (r *Rate) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		var n json.Number
+		if err := json.Unmarshal(data, &n); err != nil {
+			return fmt.Errorf("%w: %s", ErrInvalidRate, data)
+		}
+		s = n.String()
+	}
+
+	rate, err := ParseRate(s)
+	if err != nil {
+		return err
+	}
+
+	*r = rate
+	return nil
+}
+
+// Convert is m in currency at rate, rounded to the minor unit of currency.
+func (m Money) Convert(currency Currency, rate Rate, rounding Rounding) Money {
+	if rate.rat == nil {
+		panic(fmt.Errorf("%w: no rate from %s to %s", ErrInvalidRate, m.Currency, currency))
+	}
+
+	n := new(big.Int).Mul(big.NewInt(m.Amount), rate.rat.Num())
+	d := new(big.Int).Set(rate.rat.Denom())
+
+	// the minor units differ when the exponents of the currencies do
+	exp := currency.Exponent() - m.Currency.Exponent()
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(max(exp, -exp))), nil)
+	if exp > 0 {
+		n.Mul(n, scale)
+	} else {
+		d.Mul(d, scale)
+	}
+
+	return Money{quo(n, d, rounding), currency}
+}
+
+// Mul is the rate of r then o, e.g. from IDR to USD then from USD to SGD.
+func (r Rate) Mul(o Rate) Rate {
+	if r.rat == nil || o.rat == nil {
+		return Rate{}
+	}
+
+	return Rate{new(big.Rat).Mul(r.rat, o.rat)}
+}
+
+// Scan reads a rate selected as text, e.g. rate::text, null is no rate.
+func (r *Rate) Scan(src any) error {
+	var s string
+	switch src := src.(type) {
+	case nil:
+		*r = Rate{}
+		return nil
+	case string:
+		s = src
+	case []byte:
+		s = string(src)
+	default:
+		return fmt.Errorf("%w: cannot scan %T", ErrInvalidRate, src)
+	}
+
+	rate, err := ParseRate(s)
+	if err != nil {
+		return err
+	}
+
+	*r = rate
+	return nil
+}
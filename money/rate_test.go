@@ -0,0 +1,137 @@
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestParseRate(t *testing.T) {
+	tests := []struct {
+		s    string
+		want string
+		err  error
+	}{
+		{"0.000063", "0.000063", nil},
+		{" 15873.5 ", "15873.5", nil},
+		{"1", "1", nil},
+		{"2/3", "0.6666666667", nil},
+		{"0", "", ErrInvalidRate},
+		{"-1.5", "", ErrInvalidRate},
+		{"abc", "", ErrInvalidRate},
+		{"", "", ErrInvalidRate},
+	}
+
+	for _, tt := range tests {
+		rate, err := ParseRate(tt.s)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("%q: got error %v, want %v", tt.s, err, tt.err)
+			continue
+		}
+		if err == nil && rate.String() != tt.want {
+			t.Errorf("%q: got %s, want %s", tt.s, rate, tt.want)
+		}
+	}
+}
+
+func mustRate(t *testing.T, s string) Rate {
+	t.Helper()
+	rate, err := ParseRate(s)
+	if err != nil {
+		t.Fatalf("parse rate %q: %v", s, err)
+	}
+	return rate
+}
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		name     string
+		money    Money
+		currency Currency
+		rate     string
+		rounding Rounding
+		want     int64
+	}{
+		// Rp150,000 at 0.000063 is $9.45
+		{"no minor unit to cents", New(150000, "IDR"), "USD", "0.000063", RoundHalfUp, 945},
+		// $9.45 at 15873 is Rp150,000, rounded from 149,999.85
+		{"cents to no minor unit", New(945, "USD"), "IDR", "15873", RoundHalfUp, 150000},
+		{"cents to no minor unit down", New(945, "USD"), "IDR", "15873", RoundDown, 149999},
+		{"same exponent", New(1000, "USD"), "SGD", "1.345", RoundHalfUp, 1345},
+		// $0.01 at 0.5 is half a cent
+		{"half up", New(1, "USD"), "EUR", "0.5", RoundHalfUp, 1},
+		{"half even", New(1, "USD"), "EUR", "0.5", RoundHalfEven, 0},
+		{"negative half up", New(-1, "USD"), "EUR", "0.5", RoundHalfUp, -1},
+		{"inverse", New(100, "USD"), "SGD", "4/3", RoundHalfUp, 133},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.money.Convert(tt.currency, mustRate(t, tt.rate), tt.rounding)
+			if got != New(tt.want, tt.currency) {
+				t.Errorf("got %s, want %s", got, New(tt.want, tt.currency))
+			}
+		})
+	}
+}
+
+func TestConvertWithoutRate(t *testing.T) {
+	defer func() {
+		err, _ := recover().(error)
+		if !errors.Is(err, ErrInvalidRate) {
+			t.Errorf("got panic %v, want ErrInvalidRate", err)
+		}
+	}()
+
+	New(100, "USD").Convert("IDR", Rate{}, RoundHalfUp)
+}
+
+func TestRateInverseAndMul(t *testing.T) {
+	rate := mustRate(t, "0.000063")
+
+	if got := rate.Inverse().Inverse().String(); got != "0.000063" {
+		t.Errorf("inverse of inverse: got %s, want 0.000063", got)
+	}
+	if got := rate.Mul(rate.Inverse()).String(); got != "1" {
+		t.Errorf("rate times its inverse: got %s, want 1", got)
+	}
+	if got := mustRate(t, "2").Mul(mustRate(t, "0.25")).String(); got != "0.5" {
+		t.Errorf("2 times 0.25: got %s, want 0.5", got)
+	}
+	if !rate.Mul(Rate{}).IsZero() {
+		t.Errorf("rate times no rate is a rate")
+	}
+}
+
+func TestRateJSON(t *testing.T) {
+	data, err := json.Marshal(mustRate(t, "0.000063"))
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	if string(data) != `"0.000063"` {
+		t.Errorf("got %s, want \"0.000063\"", data)
+	}
+
+	tests := []struct {
+		data string
+		want string
+		err  error
+	}{
+		{`"0.000063"`, "0.000063", nil},
+		{`15873.5`, "15873.5", nil},
+		{`"0"`, "", ErrInvalidRate},
+		{`true`, "", ErrInvalidRate},
+	}
+
+	for _, tt := range tests {
+		var rate Rate
+		err := json.Unmarshal([]byte(tt.data), &rate)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("%s: got error %v, want %v", tt.data, err, tt.err)
+			continue
+		}
+		if err == nil && rate.String() != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.data, rate, tt.want)
+		}
+	}
+}
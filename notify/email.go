@@ -0,0 +1,80 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+type EmailConfig struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+	// AdminTo receives the messages meant for the shop admins.
+	AdminTo string
+}
+
+// EmailNotifier sends the messages as plain text emails over smtp.
+type EmailNotifier struct {
+	config EmailConfig
+}
+
+func NewEmailNotifier(config EmailConfig) (*EmailNotifier, error) {
+	if config.Host == "" || config.From == "" {
+		return nil, errors.New("smtp host and from address are required")
+	}
+
+	return &EmailNotifier{config}, nil
+}
+
+func (n *EmailNotifier) Notify(ctx context.Context, msg Message) error {
+	to := msg.To
+	if to == "" {
+		to = n.config.AdminTo
+	}
+	if to == "" {
+		return errors.New("no recipient for " + msg.Event)
+	}
+
+	var auth smtp.Auth
+	if n.config.Username != "" {
+		auth = smtp.PlainAuth("", n.config.Username, n.config.Password, n.config.Host)
+	}
+
+	body := strings.Join([]string{
+		"From: " + n.config.From,
+		"To: " + headerValue(to),
+		"Subject: " + headerValue(msg.Subject),
+		"Date: " + time.Now().Format(time.RFC1123Z),
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=utf-8",
+		"",
+		msg.Body,
+	}, "\r\n")
+
+	done := make(chan error, 1)
+	go func() {
+		done <- smtp.SendMail(net.JoinHostPort(n.config.Host, n.config.Port), auth, n.config.From, []string{to}, []byte(body))
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			return fmt.Errorf("send email: %w", err)
+		}
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// headerValue keeps a value on a single header line.
+func headerValue(value string) string {
+	return strings.NewReplacer("\r", " ", "\n", " ").Replace(value)
+}
@@ -0,0 +1,24 @@
+package notify
+
+import (
+	"context"
+
+	"github.com/rs/zerolog/log"
+)
+
+// LogNotifier writes the messages to the application log.
+type LogNotifier struct{}
+
+func NewLogNotifier() *LogNotifier {
+	return &LogNotifier{}
+}
+
+func (n *LogNotifier) Notify(ctx context.Context, msg Message) error {
+	log.Info().
+		Str("event", msg.Event).
+		Str("to", msg.To).
+		Interface("data", msg.Data).
+		Msg(msg.Subject)
+
+	return nil
+}
@@ -0,0 +1,58 @@
+package notify
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	EventLowStock    = "low_stock"
+	EventBackInStock = "back_in_stock"
+)
+
+// Message is a notification for the shop admins, or for a customer when To
+// is set.
+type Message struct {
+	Event   string         `json:"event"`
+	To      string         `json:"to,omitempty"`
+	Subject string         `json:"subject"`
+	Body    string         `json:"body"`
+	Data    map[string]any `json:"data"`
+}
+
+// Notifier delivers messages, e.g. by email or to a webhook.
+type Notifier interface {
+	Notify(ctx context.Context, msg Message) error
+}
+
+var pending sync.WaitGroup
+
+// SendAll delivers the messages in the background, so a slow notifier doesn't
+// hold the request that triggered them. Failures are logged.
+func SendAll(ctx context.Context, notifier Notifier, msgs []Message) {
+	if notifier == nil || len(msgs) == 0 {
+		return
+	}
+
+	ctx = context.WithoutCancel(ctx)
+	pending.Add(1)
+	go func() {
+		defer pending.Done()
+		for _, msg := range msgs {
+			ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+			if err := notifier.Notify(ctx, msg); err != nil {
+				log.Error().Err(err).Str("event", msg.Event).Msg("notify")
+			}
+			cancel()
+		}
+	}()
+}
+
+// Wait blocks until the messages being sent are delivered, e.g. before a cli
+// command exits.
+func Wait() {
+	pending.Wait()
+}
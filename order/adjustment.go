@@ -0,0 +1,93 @@
+package order
+
+import (
+	"context"
+	"sypchal/money"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	AdjustmentCoupon    = "coupon"
+	AdjustmentPromotion = "promotion"
+)
+
+// Adjustment is an amount added to, or taken off when negative, the items of
+// an order, e.g. a coupon or promotion discount. The order total is the sum of its items
+// and adjustments. Adjustments of a single line have its OrderItemId.
+type Adjustment struct {
+	Id          int         `json:"id"`
+	OrderItemId *int        `json:"order_item_id"`
+	Type        string      `json:"type"`
+	Code        string      `json:"code"`
+	Description string      `json:"description"`
+	Amount      money.Money `json:"amount"`
+	CreatedAt   time.Time   `json:"created_at"`
+}
+
+// pendingAdjustment is an adjustment found by the checkout, of the line of
+// item when item is set.
+type pendingAdjustment struct {
+	item *CartItem
+	Adjustment
+}
+
+// saveAdjustments inserts the adjustments of a placed order.
+func saveAdjustments(ctx context.Context, tx pgx.Tx, orderId int, adjustments []*pendingAdjustment, orderItemIds map[stockKey]int) (err error) {
+	_, err = tx.CopyFrom(
+		ctx,
+		pgx.Identifier{"order_adjustments"},
+		[]string{"order_id", "order_item_id", "type", "code", "description", "amount"},
+		pgx.CopyFromSlice(len(adjustments), func(i int) ([]any, error) {
+			var orderItemId *int
+			if item := adjustments[i].item; item != nil {
+				id := orderItemIds[item.stockKey()]
+				orderItemId = &id
+			}
+
+			return []any{
+				orderId,
+				orderItemId,
+				adjustments[i].Type,
+				adjustments[i].Code,
+				adjustments[i].Description,
+				adjustments[i].Amount.Amount,
+			}, nil
+		}),
+	)
+	return
+}
+
+func getAdjustments(ctx context.Context, db *pgx.Conn, orderId int) (adjustments []*Adjustment, err error) {
+	rows, err := db.Query(
+		ctx,
+		`select order_adjustments.id,order_item_id,type,code,description,row(amount,orders.currency),order_adjustments.created_at
+		from order_adjustments inner join orders on(order_id=orders.id) where order_id=$1 order by order_adjustments.id`,
+		orderId,
+	)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	adjustments = []*Adjustment{}
+	for rows.Next() {
+		adjustment := &Adjustment{}
+		if err = rows.Scan(
+			&adjustment.Id,
+			&adjustment.OrderItemId,
+			&adjustment.Type,
+			&adjustment.Code,
+			&adjustment.Description,
+			&adjustment.Amount,
+			&adjustment.CreatedAt,
+		); err != nil {
+			return
+		}
+		adjustments = append(adjustments, adjustment)
+	}
+
+	err = rows.Err()
+	return
+}
@@ -0,0 +1,236 @@
+package order
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Allocation is the quantity of an order line shipped from a warehouse.
+type Allocation struct {
+	WarehouseId   int    `json:"warehouse_id"`
+	WarehouseCode string `json:"warehouse_code"`
+	Qty           int    `json:"qty"`
+}
+
+type stockKey struct {
+	productId int
+	// variantId is 0 for products without variants
+	variantId int
+}
+
+func (item *CartItem) stockKey() stockKey {
+	key := stockKey{productId: item.ProductId}
+	if item.VariantId != nil {
+		key.variantId = *item.VariantId
+	}
+
+	return key
+}
+
+// warehouseStocks is the stock available in a warehouse.
+type warehouseStocks struct {
+	id     int
+	code   string
+	stocks map[stockKey]int
+}
+
+// getWarehouseStocks returns the stock of the items in every warehouse, in the
+// order the warehouses ship from, locking the stocks when lock is true.
+func getWarehouseStocks(ctx context.Context, tx pgx.Tx, items []*CartItem, lock bool) (warehouses []*warehouseStocks, err error) {
+	productIds := make([]int, 0, len(items))
+	for _, item := range items {
+		productIds = append(productIds, item.ProductId)
+	}
+
+	rows, err := tx.Query(ctx, "select id,code from warehouses order by priority, id")
+	if err != nil {
+		return
+	}
+
+	byId := map[int]*warehouseStocks{}
+	for rows.Next() {
+		warehouse := &warehouseStocks{stocks: map[stockKey]int{}}
+		if err = rows.Scan(&warehouse.id, &warehouse.code); err != nil {
+			rows.Close()
+			return
+		}
+		byId[warehouse.id] = warehouse
+		warehouses = append(warehouses, warehouse)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return
+	}
+
+	query := "select warehouse_id,product_id,coalesce(variant_id,0),stock from warehouse_stocks where product_id=any($1) and stock > 0"
+	if lock {
+		query += " for update"
+	}
+
+	rows, err = tx.Query(ctx, query, productIds)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var warehouseId, stock int
+		var key stockKey
+		if err = rows.Scan(&warehouseId, &key.productId, &key.variantId, &stock); err != nil {
+			return
+		}
+		byId[warehouseId].stocks[key] = stock
+	}
+
+	err = rows.Err()
+	return
+}
+
+// allocate chooses the warehouses shipping every line. The first warehouse
+// that has every line in stock ships the whole order. Otherwise, when split
+// shipments are allowed, each line ships from a warehouse already shipping
+// the order or else the first one that has it in stock, and lines no
+// warehouse has enough of are split across warehouses. warehouses must be in
+// the order they ship from.
+func allocate(items []*CartItem, warehouses []*warehouseStocks, split bool) (allocations [][]*Allocation, err error) {
+	allocations = make([][]*Allocation, len(items))
+
+	for _, warehouse := range warehouses {
+		if !canShip(warehouse, items) {
+			continue
+		}
+
+		for i, item := range items {
+			allocations[i] = []*Allocation{{warehouse.id, warehouse.code, item.Qty}}
+		}
+		return
+	}
+
+	if !split {
+		err = ErrSplitShipment
+		return
+	}
+
+	used := map[int]bool{}
+	for i, item := range items {
+		key := item.stockKey()
+
+		// warehouses already shipping the order first, keeping the priority
+		ordered := make([]*warehouseStocks, 0, len(warehouses))
+		for _, warehouse := range warehouses {
+			if used[warehouse.id] {
+				ordered = append(ordered, warehouse)
+			}
+		}
+		for _, warehouse := range warehouses {
+			if !used[warehouse.id] {
+				ordered = append(ordered, warehouse)
+			}
+		}
+
+		whole := false
+		for _, warehouse := range ordered {
+			if warehouse.stocks[key] >= item.Qty {
+				allocations[i] = []*Allocation{{warehouse.id, warehouse.code, item.Qty}}
+				warehouse.stocks[key] -= item.Qty
+				used[warehouse.id] = true
+				whole = true
+				break
+			}
+		}
+		if whole {
+			continue
+		}
+
+		remaining := item.Qty
+		for _, warehouse := range ordered {
+			qty := min(remaining, warehouse.stocks[key])
+			if qty == 0 {
+				continue
+			}
+
+			allocations[i] = append(allocations[i], &Allocation{warehouse.id, warehouse.code, qty})
+			warehouse.stocks[key] -= qty
+			used[warehouse.id] = true
+			remaining -= qty
+			if remaining == 0 {
+				break
+			}
+		}
+
+		if remaining > 0 {
+			err = ErrItemOutOfStock
+			return
+		}
+	}
+
+	return
+}
+
+func canShip(warehouse *warehouseStocks, items []*CartItem) bool {
+	for _, item := range items {
+		if warehouse.stocks[item.stockKey()] < item.Qty {
+			return false
+		}
+	}
+
+	return true
+}
+
+// getOrderItemIds maps the product and variant of every line of the order to
+// the line id.
+func getOrderItemIds(ctx context.Context, tx pgx.Tx, orderId int) (ids map[stockKey]int, err error) {
+	rows, err := tx.Query(
+		ctx,
+		"select id,product_id,coalesce(variant_id,0) from order_items where order_id=$1",
+		orderId,
+	)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	ids = map[stockKey]int{}
+	for rows.Next() {
+		var id int
+		var key stockKey
+		if err = rows.Scan(&id, &key.productId, &key.variantId); err != nil {
+			return
+		}
+		ids[key] = id
+	}
+
+	err = rows.Err()
+	return
+}
+
+// getAllocations returns the allocations of the order lines by line id.
+func getAllocations(ctx context.Context, db *pgx.Conn, orderId int) (allocations map[int][]*Allocation, err error) {
+	rows, err := db.Query(
+		ctx,
+		`select order_item_id,warehouses.id,warehouses.code,qty
+		from order_item_allocations
+		inner join order_items on(order_item_id=order_items.id)
+		inner join warehouses on(warehouse_id=warehouses.id)
+		where order_id=$1 order by order_item_allocations.id`,
+		orderId,
+	)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	allocations = map[int][]*Allocation{}
+	for rows.Next() {
+		var orderItemId int
+		allocation := &Allocation{}
+		if err = rows.Scan(&orderItemId, &allocation.WarehouseId, &allocation.WarehouseCode, &allocation.Qty); err != nil {
+			return
+		}
+		allocations[orderItemId] = append(allocations[orderItemId], allocation)
+	}
+
+	err = rows.Err()
+	return
+}
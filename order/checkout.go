@@ -0,0 +1,490 @@
+package order
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sypchal/cart"
+	"sypchal/coupon"
+	"sypchal/exchange"
+	"sypchal/money"
+	"sypchal/promotion"
+	"sypchal/shipping"
+	"sypchal/tax"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	IssueCartEmpty       = "cart_empty"
+	IssueOutOfStock      = "out_of_stock"
+	IssueQtyLimit        = "quantity_limit"
+	IssuePriceChanged    = "price_changed"
+	IssueArchived        = "archived"
+	IssueSplitShipment   = "split_shipment"
+	IssueCoupon          = "coupon"
+	IssueShippingAddress = "shipping_address"
+	IssueShippingMethod  = "shipping_method"
+)
+
+// CheckoutIssue is a problem found in the cart. Blocking issues make the order
+// fail, the others only inform, e.g. a price change the policy accepts.
+type CheckoutIssue struct {
+	Code     string `json:"code"`
+	Message  string `json:"message"`
+	Blocking bool   `json:"blocking"`
+}
+
+type CheckoutLine struct {
+	CartItemId  int     `json:"cart_item_id"`
+	ProductId   int     `json:"product_id"`
+	ProductName string  `json:"product_name"`
+	VariantId   *int    `json:"variant_id"`
+	Sku         *string `json:"sku"`
+	Qty         int     `json:"qty"`
+	Stock       int     `json:"stock"`
+	// Price is what the order charges, see cart.PricePolicy.
+	Price         money.Money     `json:"price"`
+	CapturedPrice money.Money     `json:"captured_price"`
+	CurrentPrice  money.Money     `json:"current_price"`
+	TotalPrice    money.Money     `json:"total_price"`
+	Discount      money.Money     `json:"discount"`
+	TaxRate       int             `json:"tax_rate"`
+	Tax           money.Money     `json:"tax"`
+	Issues        []CheckoutIssue `json:"issues"`
+}
+
+// Checkout is what placing the order would do with the cart. Lines of
+// archived products are listed but not ordered.
+type Checkout struct {
+	// Currency is what the order is charged in, the prices are its list
+	// prices or else are converted at ExchangeRate, the rate from the base
+	// currency. BaseTotal is Total in the base currency.
+	Currency     money.Currency  `json:"currency"`
+	ExchangeRate money.Rate      `json:"exchange_rate"`
+	BaseTotal    money.Money     `json:"base_total"`
+	Lines        []*CheckoutLine `json:"lines"`
+	Issues       []CheckoutIssue `json:"issues"`
+	Subtotal     money.Money     `json:"subtotal"`
+	Discount     money.Money     `json:"discount"`
+	Tax          money.Money     `json:"tax"`
+	// TaxMode tells whether Tax is added to the total, exclusive, or already
+	// part of the prices, inclusive.
+	TaxMode  string      `json:"tax_mode"`
+	Shipping money.Money `json:"shipping"`
+	// ShippingAddress is where the order ships, ShippingMethod how, out of
+	// the ShippingOptions to the address. Weight is in grams.
+	ShippingAddress *shipping.Address `json:"shipping_address"`
+	ShippingMethod  *shipping.Quote   `json:"shipping_method"`
+	ShippingOptions []shipping.Quote  `json:"shipping_options"`
+	Weight          int               `json:"weight"`
+	Total           money.Money       `json:"total"`
+	// Promotions are the discounts of the running promotions by line.
+	Promotions []promotion.Allocation `json:"promotions"`
+	// Coupon is the discount of the coupon applied to the cart.
+	Coupon *coupon.Discount `json:"coupon"`
+	// CanPlaceOrder is false when any issue is blocking.
+	CanPlaceOrder bool `json:"can_place_order"`
+
+	// items are the lines to order and allocations where they ship from
+	items       []*CartItem
+	allocations [][]*Allocation
+	adjustments []*pendingAdjustment
+	// lines are the ordered lines by cart item id
+	lines map[int]*CheckoutLine
+	rates *exchange.Rates
+	// err is the error of the first blocking issue, couponErr the reason the
+	// coupon of the cart can't be used and shippingErr the reason the cart
+	// can't be shipped
+	err         error
+	couponErr   error
+	shippingErr error
+}
+
+// PreviewCheckout runs the checks of PlaceOrder on the cart of the customer
+// and prices it in currency, without changing anything.
+func (o *OrderDomain) PreviewCheckout(ctx context.Context, userId int, currency money.Currency) (checkout *Checkout, err error) {
+	tx, err := o.db.Begin(ctx)
+	if err != nil {
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	return o.checkout(ctx, tx, userId, currency, false)
+}
+
+// checkout checks and prices the cart of the customer in currency, locking
+// the warehouse stocks when lock is true. The price changes are those of the
+// catalog prices, in the base currency.
+func (o *OrderDomain) checkout(ctx context.Context, tx pgx.Tx, userId int, currency money.Currency, lock bool) (checkout *Checkout, err error) {
+	rates, err := exchange.LoadRates(ctx, tx, o.config.Currency)
+	if err != nil {
+		return
+	}
+
+	exchangeRate, err := rates.Rate(o.config.Currency, currency)
+	if err != nil {
+		return
+	}
+
+	rows, err := tx.Query(
+		ctx,
+		`select 
+			cart_items.id,
+			products.id,
+			products.name,
+			product_variants.id,
+			product_variants.sku,
+			coalesce(product_variants.stock,products.stock),
+			products.reorder_threshold,
+			products.category,
+			products.tax_class,
+			products.weight,
+			cart_items.qty,
+			row(cart_items.price,cart_items.currency),
+			row(coalesce(product_variants.price,products.price),products.currency),
+			cart_items.priced_at,
+			products.deleted_at is not null,
+			(`+cart.ListPriceSQL+`)
+		from cart_items inner join products on(cart_items.product_id=products.id and user_id=$1)
+		left join product_variants on(variant_id=product_variants.id)
+		order by cart_items.id`,
+		userId,
+		currency,
+	)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	zero := money.Zero(currency)
+	checkout = &Checkout{
+		Currency:        currency,
+		ExchangeRate:    exchangeRate,
+		Subtotal:        zero,
+		Discount:        zero,
+		Tax:             zero,
+		Shipping:        zero,
+		Lines:           []*CheckoutLine{},
+		Issues:          []CheckoutIssue{},
+		Promotions:      []promotion.Allocation{},
+		ShippingOptions: []shipping.Quote{},
+		TaxMode:         o.config.TaxMode,
+		items:           []*CartItem{},
+		lines:           map[int]*CheckoutLine{},
+		rates:           rates,
+	}
+	changes := []cart.PriceChange{}
+	for rows.Next() {
+		item := &CartItem{}
+		var archived bool
+		var listPrice *int64
+		err = rows.Scan(
+			&item.Id,
+			&item.ProductId,
+			&item.ProductName,
+			&item.VariantId,
+			&item.Sku,
+			&item.ProductStock,
+			&item.ReorderThreshold,
+			&item.Category,
+			&item.TaxClass,
+			&item.Weight,
+			&item.Qty,
+			&item.Price,
+			&item.CurrentPrice,
+			&item.PricedAt,
+			&archived,
+			&listPrice,
+		)
+		if err != nil {
+			return
+		}
+
+		local := cart.Localizer(rates, currency, item.CurrentPrice, listPrice)
+		var capturedPrice, currentPrice money.Money
+		if capturedPrice, err = local(item.Price); err != nil {
+			return
+		}
+		if currentPrice, err = local(item.CurrentPrice); err != nil {
+			return
+		}
+
+		line := &CheckoutLine{
+			CartItemId:    item.Id,
+			ProductId:     item.ProductId,
+			ProductName:   item.ProductName,
+			VariantId:     item.VariantId,
+			Sku:           item.Sku,
+			Qty:           item.Qty,
+			Stock:         item.ProductStock,
+			CapturedPrice: capturedPrice,
+			CurrentPrice:  currentPrice,
+			Discount:      zero,
+			Tax:           zero,
+			Issues:        []CheckoutIssue{},
+		}
+		checkout.Lines = append(checkout.Lines, line)
+
+		if archived {
+			line.Issues = append(line.Issues, CheckoutIssue{IssueArchived, "product is no longer sold, the line is left in the cart", false})
+			continue
+		}
+
+		if item.Qty > item.ProductStock {
+			line.Issues = append(line.Issues, CheckoutIssue{IssueOutOfStock, "only " + strconv.Itoa(item.ProductStock) + " left in stock", true})
+			checkout.fail(ErrItemOutOfStock)
+		}
+
+		if o.config.MaxItemQty > 0 && item.Qty > o.config.MaxItemQty {
+			line.Issues = append(line.Issues, CheckoutIssue{IssueQtyLimit, "at most " + strconv.Itoa(o.config.MaxItemQty) + " per order", true})
+			checkout.fail(ErrItemQtyLimit)
+		}
+
+		price, confirm := o.config.PricePolicy.Price(item.Price, item.CurrentPrice, item.PricedAt)
+		if item.Price != item.CurrentPrice {
+			message := "price changed from " + capturedPrice.String() + " to " + currentPrice.String()
+			if confirm {
+				message += ", confirm it before ordering"
+			}
+			line.Issues = append(line.Issues, CheckoutIssue{IssuePriceChanged, message, confirm})
+		}
+		if confirm {
+			changes = append(changes, cart.PriceChange{
+				CartItemId:    item.Id,
+				ProductId:     item.ProductId,
+				VariantId:     item.VariantId,
+				CapturedPrice: item.Price,
+				CurrentPrice:  item.CurrentPrice,
+			})
+		}
+
+		if item.Price, err = local(price); err != nil {
+			return
+		}
+		item.TotalPrice = item.Price.Mul(item.Qty)
+		line.Price = item.Price
+		line.TotalPrice = item.TotalPrice
+		checkout.Subtotal = checkout.Subtotal.Add(item.TotalPrice)
+		checkout.items = append(checkout.items, item)
+		checkout.lines[item.Id] = line
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return
+	}
+
+	if len(checkout.items) > 0 {
+		if err = checkout.applyPromotions(ctx, tx); err != nil {
+			return
+		}
+
+		if err = checkout.applyCoupon(ctx, tx, userId, lock); err != nil {
+			return
+		}
+
+		if err = checkout.applyShipping(ctx, tx, userId); err != nil {
+			return
+		}
+
+		var rates map[string]int
+		if rates, err = tax.LoadRates(ctx, tx, checkout.taxRegion(o.config)); err != nil {
+			return
+		}
+
+		if err = checkout.applyTax(ctx, rates, o.config); err != nil {
+			return
+		}
+	}
+
+	if len(changes) > 0 {
+		checkout.fail(&PriceChangedError{changes})
+	}
+
+	if len(checkout.items) == 0 {
+		checkout.Issues = append(checkout.Issues, CheckoutIssue{IssueCartEmpty, "cart is empty", true})
+		checkout.fail(ErrCartEmpty)
+	} else if checkout.err == nil {
+		// choose the warehouses shipping the lines
+		var warehouses []*warehouseStocks
+		warehouses, err = getWarehouseStocks(ctx, tx, checkout.items, lock)
+		if err != nil {
+			return
+		}
+
+		var allocErr error
+		checkout.allocations, allocErr = allocate(checkout.items, warehouses, o.config.AllowSplitShipments)
+		if errors.Is(allocErr, ErrSplitShipment) {
+			checkout.Issues = append(checkout.Issues, CheckoutIssue{IssueSplitShipment, allocErr.Error(), true})
+		} else if errors.Is(allocErr, ErrItemOutOfStock) {
+			checkout.Issues = append(checkout.Issues, CheckoutIssue{IssueOutOfStock, "not enough stock in the warehouses", true})
+		}
+		checkout.fail(allocErr)
+	}
+
+	checkout.total()
+	if checkout.BaseTotal, err = rates.Convert(checkout.Total, o.config.Currency); err != nil {
+		return
+	}
+	checkout.CanPlaceOrder = checkout.err == nil
+
+	return
+}
+
+// fail records the error of a blocking issue, the first one is kept.
+func (checkout *Checkout) fail(err error) {
+	if checkout.err == nil {
+		checkout.err = err
+	}
+}
+
+// applyPromotions discounts the lines with the running promotions, before
+// the coupon.
+func (checkout *Checkout) applyPromotions(ctx context.Context, tx pgx.Tx) (err error) {
+	promotions, err := promotion.Running(ctx, tx, time.Now())
+	if err != nil {
+		return
+	}
+
+	if err = promotion.Localize(promotions, checkout.rates, checkout.Currency); err != nil {
+		return
+	}
+
+	lines := make([]promotion.Line, 0, len(checkout.items))
+	items := map[int]*CartItem{}
+	for _, item := range checkout.items {
+		lines = append(lines, promotion.Line{
+			Id:        item.Id,
+			ProductId: item.ProductId,
+			Category:  item.Category,
+			Qty:       item.Qty,
+			Price:     item.Price,
+		})
+		items[item.Id] = item
+	}
+
+	checkout.Promotions = promotion.Evaluate(promotions, lines)
+	for _, allocation := range checkout.Promotions {
+		line := checkout.lines[allocation.LineId]
+		line.Discount = line.Discount.Add(allocation.Amount)
+		checkout.Discount = checkout.Discount.Add(allocation.Amount)
+		checkout.adjustments = append(checkout.adjustments, &pendingAdjustment{items[allocation.LineId], Adjustment{
+			Type:        AdjustmentPromotion,
+			Code:        strconv.Itoa(allocation.PromotionId),
+			Description: allocation.Name,
+			Amount:      allocation.Amount.Neg(),
+		}})
+	}
+
+	return
+}
+
+// applyCoupon discounts the lines with the coupon of the cart, what the
+// promotions left of them. A coupon that can't be used is a blocking issue,
+// the customer removes it or fixes the cart.
+func (checkout *Checkout) applyCoupon(ctx context.Context, tx pgx.Tx, userId int, lock bool) (err error) {
+	code, err := cartCoupon(ctx, tx, userId)
+	if err != nil || code == "" {
+		return
+	}
+
+	lines := make([]coupon.Line, 0, len(checkout.items))
+	for _, item := range checkout.items {
+		lines = append(lines, coupon.Line{
+			Id:        item.Id,
+			ProductId: item.ProductId,
+			Category:  item.Category,
+			Total:     item.TotalPrice.Sub(checkout.lines[item.Id].Discount),
+		})
+	}
+
+	c, err := coupon.Load(ctx, tx, userId, code, lock)
+	var discount *coupon.Discount
+	if err == nil {
+		err = c.Localize(checkout.rates, checkout.Currency)
+	}
+	if err == nil {
+		discount, err = c.Apply(lines)
+	}
+	if coupon.IsUnusable(err) {
+		checkout.Issues = append(checkout.Issues, CheckoutIssue{IssueCoupon, code + ": " + err.Error(), true})
+		checkout.couponErr = err
+		checkout.fail(err)
+		return nil
+	}
+	if err != nil {
+		return
+	}
+
+	checkout.Coupon = discount
+	checkout.Discount = checkout.Discount.Add(discount.Amount)
+	for _, item := range checkout.items {
+		amount := discount.Lines[item.Id]
+		if amount.IsZero() {
+			continue
+		}
+
+		line := checkout.lines[item.Id]
+		line.Discount = line.Discount.Add(amount)
+		checkout.adjustments = append(checkout.adjustments, &pendingAdjustment{item, Adjustment{
+			Type:        AdjustmentCoupon,
+			Code:        discount.Code,
+			Description: "coupon " + discount.Code,
+			Amount:      amount.Neg(),
+		}})
+	}
+
+	return
+}
+
+// taxRegion is the region of the shipping address, the configured region
+// without one.
+func (checkout *Checkout) taxRegion(config Config) string {
+	if checkout.ShippingAddress != nil {
+		return checkout.ShippingAddress.Zone()
+	}
+
+	return config.TaxRegion
+}
+
+// applyTax taxes what the lines cost after their discounts, rates are the
+// local rates read in the transaction of the checkout.
+func (checkout *Checkout) applyTax(ctx context.Context, rates map[string]int, config Config) (err error) {
+	lines := make([]tax.Line, 0, len(checkout.items))
+	for _, item := range checkout.items {
+		lines = append(lines, tax.Line{
+			Id:       item.Id,
+			TaxClass: item.TaxClass,
+			Amount:   item.TotalPrice.Sub(checkout.lines[item.Id].Discount),
+		})
+	}
+
+	res, err := config.TaxCalculator.Calculate(ctx, tax.Request{
+		Region: checkout.taxRegion(config),
+		Mode:   config.TaxMode,
+		Lines:  lines,
+		Rates:  rates,
+	})
+	if err != nil {
+		return
+	}
+
+	for _, lineTax := range res.Lines {
+		line := checkout.lines[lineTax.Id]
+		line.TaxRate = lineTax.Rate
+		line.Tax = lineTax.Amount
+	}
+	checkout.Tax = checkout.Tax.Add(res.Total)
+
+	return
+}
+
+// total is what the order charges, the tax is only added to prices excluding
+// it.
+func (checkout *Checkout) total() {
+	checkout.Total = checkout.Subtotal.Sub(checkout.Discount).Add(checkout.Shipping)
+	if checkout.TaxMode == tax.ModeExclusive {
+		checkout.Total = checkout.Total.Add(checkout.Tax)
+	}
+}
@@ -0,0 +1,219 @@
+package order
+
+import (
+	"context"
+	"sypchal/money"
+	"sypchal/shipping"
+	"sypchal/tax"
+	"testing"
+)
+
+// testLine is a cart line costing total, discounted by discount, in cents.
+type testLine struct {
+	total    int64
+	discount int64
+}
+
+// newTestCheckout is a checkout of lines in USD about to be taxed, the way
+// checkout leaves it before applyTax.
+func newTestCheckout(mode string, address *shipping.Address, shippingCost int64, lines []testLine) *Checkout {
+	zero := money.Zero("USD")
+	checkout := &Checkout{
+		Currency:        "USD",
+		Subtotal:        zero,
+		Discount:        zero,
+		Tax:             zero,
+		Shipping:        money.New(shippingCost, "USD"),
+		TaxMode:         mode,
+		ShippingAddress: address,
+		lines:           map[int]*CheckoutLine{},
+	}
+
+	for i, l := range lines {
+		item := &CartItem{Id: i + 1, TaxClass: tax.ClassStandard, TotalPrice: money.New(l.total, "USD")}
+		line := &CheckoutLine{
+			CartItemId: item.Id,
+			TotalPrice: item.TotalPrice,
+			Discount:   money.New(l.discount, "USD"),
+			Tax:        zero,
+		}
+		checkout.items = append(checkout.items, item)
+		checkout.lines[item.Id] = line
+		checkout.Lines = append(checkout.Lines, line)
+		checkout.Subtotal = checkout.Subtotal.Add(item.TotalPrice)
+		checkout.Discount = checkout.Discount.Add(line.Discount)
+	}
+
+	return checkout
+}
+
+func TestCheckoutTax(t *testing.T) {
+	calculator := tax.NewStubCalculator(map[string]int{
+		"ID":    1100,
+		"ID-JK": 1200,
+		"SG":    900,
+	})
+
+	tests := []struct {
+		name    string
+		mode    string
+		region  string
+		address *shipping.Address
+		// shipping is not taxed
+		shipping int64
+		lines    []testLine
+		// rates and taxes are those of the lines
+		rates     []int
+		taxes     []int64
+		wantTax   int64
+		wantTotal int64
+	}{
+		{
+			name:      "exclusive adds the tax to the total",
+			mode:      tax.ModeExclusive,
+			region:    "ID",
+			lines:     []testLine{{1000, 0}, {2500, 0}},
+			rates:     []int{1100, 1100},
+			taxes:     []int64{110, 275},
+			wantTax:   385,
+			wantTotal: 3885,
+		},
+		{
+			name:      "inclusive takes the tax out of the prices",
+			mode:      tax.ModeInclusive,
+			region:    "ID-JK",
+			lines:     []testLine{{1120, 0}, {1000, 0}},
+			rates:     []int{1200, 1200},
+			taxes:     []int64{120, 107},
+			wantTax:   227,
+			wantTotal: 2120,
+		},
+		{
+			name:      "exclusive rounds half up per line",
+			mode:      tax.ModeExclusive,
+			region:    "SG",
+			lines:     []testLine{{50, 0}, {150, 0}, {333, 0}},
+			rates:     []int{900, 900, 900},
+			taxes:     []int64{5, 14, 30},
+			wantTax:   49,
+			wantTotal: 582,
+		},
+		{
+			name:      "inclusive rounds half up per line",
+			mode:      tax.ModeInclusive,
+			region:    "ID-JK",
+			lines:     []testLine{{14, 0}, {42, 0}, {1000, 0}},
+			rates:     []int{1200, 1200, 1200},
+			taxes:     []int64{2, 5, 107},
+			wantTax:   114,
+			wantTotal: 1056,
+		},
+		{
+			name:      "discounts are taken off before the tax",
+			mode:      tax.ModeExclusive,
+			region:    "ID",
+			shipping:  500,
+			lines:     []testLine{{1000, 200}, {2000, 0}},
+			rates:     []int{1100, 1100},
+			taxes:     []int64{88, 220},
+			wantTax:   308,
+			wantTotal: 3608,
+		},
+		{
+			name:      "region of the shipping address",
+			mode:      tax.ModeExclusive,
+			region:    "SG",
+			address:   &shipping.Address{Region: "ID-JK", Country: "ID"},
+			lines:     []testLine{{1000, 0}},
+			rates:     []int{1200},
+			taxes:     []int64{120},
+			wantTax:   120,
+			wantTotal: 1120,
+		},
+		{
+			name:      "region falls back to its country",
+			mode:      tax.ModeExclusive,
+			address:   &shipping.Address{Region: "ID-BA", Country: "ID"},
+			lines:     []testLine{{1000, 0}},
+			rates:     []int{1100},
+			taxes:     []int64{110},
+			wantTax:   110,
+			wantTotal: 1110,
+		},
+		{
+			name:      "country of an address without region",
+			mode:      tax.ModeExclusive,
+			region:    "ID",
+			address:   &shipping.Address{Country: "SG"},
+			lines:     []testLine{{1000, 0}},
+			rates:     []int{900},
+			taxes:     []int64{90},
+			wantTax:   90,
+			wantTotal: 1090,
+		},
+		{
+			name:      "region without a rate is not taxed",
+			mode:      tax.ModeExclusive,
+			region:    "US",
+			lines:     []testLine{{1000, 0}},
+			rates:     []int{0},
+			taxes:     []int64{0},
+			wantTax:   0,
+			wantTotal: 1000,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkout := newTestCheckout(tt.mode, tt.address, tt.shipping, tt.lines)
+			config := Config{TaxCalculator: calculator, TaxMode: tt.mode, TaxRegion: tt.region}
+
+			if err := checkout.applyTax(context.Background(), nil, config); err != nil {
+				t.Fatalf("apply tax: %v", err)
+			}
+			checkout.total()
+
+			for i, line := range checkout.Lines {
+				if line.TaxRate != tt.rates[i] {
+					t.Errorf("line %d: got rate %d, want %d", i+1, line.TaxRate, tt.rates[i])
+				}
+				if want := money.New(tt.taxes[i], "USD"); line.Tax != want {
+					t.Errorf("line %d: got tax %s, want %s", i+1, line.Tax, want)
+				}
+			}
+
+			if want := money.New(tt.wantTax, "USD"); checkout.Tax != want {
+				t.Errorf("got tax %s, want %s", checkout.Tax, want)
+			}
+			if want := money.New(tt.wantTotal, "USD"); checkout.Total != want {
+				t.Errorf("got total %s, want %s", checkout.Total, want)
+			}
+		})
+	}
+}
+
+func TestCheckoutLocalTax(t *testing.T) {
+	checkout := newTestCheckout(tax.ModeExclusive, nil, 0, []testLine{{1000, 0}, {2000, 0}, {500, 0}})
+	checkout.items[1].TaxClass = "reduced"
+	checkout.items[2].TaxClass = "exempt"
+	config := Config{TaxCalculator: tax.NewLocalCalculator(), TaxMode: tax.ModeExclusive, TaxRegion: "ID"}
+
+	rates := map[string]int{tax.ClassStandard: 1100, "reduced": 500}
+	if err := checkout.applyTax(context.Background(), rates, config); err != nil {
+		t.Fatalf("apply tax: %v", err)
+	}
+
+	wantRates := []int{1100, 500, 0}
+	wantTaxes := []int64{110, 100, 0}
+	for i, line := range checkout.Lines {
+		if line.TaxRate != wantRates[i] {
+			t.Errorf("line %d: got rate %d, want %d", i+1, line.TaxRate, wantRates[i])
+		}
+		if want := money.New(wantTaxes[i], "USD"); line.Tax != want {
+			t.Errorf("line %d: got tax %s, want %s", i+1, line.Tax, want)
+		}
+	}
+	if want := money.New(210, "USD"); checkout.Tax != want {
+		t.Errorf("got tax %s, want %s", checkout.Tax, want)
+	}
+}
@@ -0,0 +1,81 @@
+package order
+
+import (
+	"context"
+	"errors"
+	"sypchal/coupon"
+	"sypchal/money"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ApplyCoupon sets the coupon of the cart of the customer, replacing the
+// previous one. The coupon must be usable and apply to the cart, priced in
+// currency.
+func (o *OrderDomain) ApplyCoupon(ctx context.Context, userId int, currency money.Currency, code string) (checkout *Checkout, err error) {
+	tx, err := o.db.Begin(ctx)
+	if err != nil {
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	c, err := coupon.Load(ctx, tx, userId, code, false)
+	if err != nil {
+		return
+	}
+
+	_, err = tx.Exec(
+		ctx,
+		`insert into cart_coupons(user_id,coupon_id) values ($1,$2)
+		on conflict (user_id) do update set coupon_id=excluded.coupon_id,created_at=now()`,
+		userId,
+		c.Id,
+	)
+	if err != nil {
+		return
+	}
+
+	checkout, err = o.checkout(ctx, tx, userId, currency, false)
+	if err != nil {
+		return
+	}
+
+	if checkout.couponErr != nil {
+		err = checkout.couponErr
+		return
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return
+	}
+
+	return
+}
+
+// RemoveCoupon takes the coupon off the cart of the customer.
+func (o *OrderDomain) RemoveCoupon(ctx context.Context, userId int) (err error) {
+	tag, err := o.db.Exec(ctx, "delete from cart_coupons where user_id=$1", userId)
+	if err != nil {
+		return
+	}
+
+	if tag.RowsAffected() == 0 {
+		err = ErrNoCoupon
+	}
+
+	return
+}
+
+// cartCoupon returns the code of the coupon of the cart, empty without one.
+func cartCoupon(ctx context.Context, tx pgx.Tx, userId int) (code string, err error) {
+	err = tx.QueryRow(
+		ctx,
+		"select coupons.code from cart_coupons inner join coupons on(coupon_id=coupons.id) where user_id=$1",
+		userId,
+	).Scan(&code)
+	if errors.Is(err, pgx.ErrNoRows) {
+		err = nil
+	}
+
+	return
+}
@@ -1,9 +1,42 @@
 package order
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"sypchal/cart"
+)
 
 var ErrItemOutOfStock = errors.New("item out of stock")
 var ErrOrderNotFound = errors.New("order not found")
 var ErrPayAmountNotMatch = errors.New("pay amount not match")
+var ErrPayCurrencyMismatch = errors.New("pay currency doesn't match the order currency")
 var ErrPaymentIdMismatch = errors.New("pay_id mismatch")
 var ErrOrderIsPaid = errors.New("order is paid")
+var ErrCartEmpty = errors.New("cart is empty")
+var ErrSplitShipment = errors.New("order can't be shipped from a single warehouse")
+var ErrItemQtyLimit = errors.New("item quantity over the order limit")
+var ErrNoCoupon = errors.New("cart has no coupon")
+var ErrPriceChanged = errors.New("cart prices changed since they were added")
+var ErrNoShippingAddress = errors.New("order needs a shipping address")
+var ErrNoShippingMethod = errors.New("no shipping method ships the order to the address")
+var ErrShippingMethodUnavailable = errors.New("shipping method doesn't ship the order to the address")
+var ErrOrderNotPaid = errors.New("order is not paid")
+var ErrOrderItemNotFound = errors.New("order item not found")
+var ErrShipmentNotFound = errors.New("shipment not found")
+var ErrShipmentQty = errors.New("shipment quantity over the quantity left to ship")
+var ErrNothingToShip = errors.New("every item of the order is already shipped")
+var ErrShipmentTransition = errors.New("shipment status can't change")
+
+// PriceChangedError lists the cart lines whose price must be confirmed, it
+// matches ErrPriceChanged.
+type PriceChangedError struct {
+	Changes []cart.PriceChange
+}
+
+func (e *PriceChangedError) Error() string {
+	return fmt.Sprintf("%s: %d lines to confirm", ErrPriceChanged, len(e.Changes))
+}
+
+func (e *PriceChangedError) Unwrap() error {
+	return ErrPriceChanged
+}
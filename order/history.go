@@ -0,0 +1,201 @@
+package order
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sypchal/money"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type OrderItem struct {
+	Id         int               `json:"id"`
+	Product    *OrderItemProduct `json:"product"`
+	VariantId  *int              `json:"variant_id"`
+	Sku        *string           `json:"sku"`
+	Qty        int               `json:"qty"`
+	Price      money.Money       `json:"price"`
+	TotalPrice money.Money       `json:"total_price"`
+	// TaxAmount is the tax of the line at TaxRate, in basis points.
+	TaxClass  string      `json:"tax_class"`
+	TaxRate   int         `json:"tax_rate"`
+	TaxAmount money.Money `json:"tax_amount"`
+	CreatedAt time.Time   `json:"created_at"`
+	// Allocations are the warehouses the line ships from.
+	Allocations []*Allocation `json:"allocations"`
+}
+
+// OrderItemProduct is the product an order line was bought as. Archived
+// products are still returned, flagged with Archived.
+type OrderItemProduct struct {
+	Id       int    `json:"id"`
+	Name     string `json:"name"`
+	ImageUrl string `json:"image_url"`
+	Archived bool   `json:"archived"`
+}
+
+type OrderDetail struct {
+	*Order
+	Items []*OrderItem `json:"items"`
+	// Adjustments explain the difference between the items and the total,
+	// e.g. discounts.
+	Adjustments []*Adjustment `json:"adjustments"`
+	// Shipments track the parcels the items ship in.
+	Shipments []*Shipment `json:"shipments"`
+}
+
+type GetOrdersRequest struct {
+	UserId int
+	Limit  int
+	Offset int
+}
+
+type GetOrdersResponse struct {
+	Orders  []*Order `json:"orders"`
+	Total   int      `json:"total"`
+	MaxPage int      `json:"max_page"`
+}
+
+func (o *OrderDomain) GetUserOrders(ctx context.Context, req GetOrdersRequest) (res *GetOrdersResponse, err error) {
+	rows, err := o.db.Query(
+		ctx,
+		`select count(*) over(),`+orderColumns+`
+		from orders where user_id=$1 order by id desc limit $2 offset $3`,
+		req.UserId,
+		req.Limit,
+		req.Offset,
+	)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	var total int
+	res = &GetOrdersResponse{Orders: []*Order{}}
+	for rows.Next() {
+		order := &Order{}
+		if err = rows.Scan(append([]any{&total}, order.scanFields()...)...); err != nil {
+			return
+		}
+		res.Orders = append(res.Orders, order)
+	}
+
+	res.Total = total
+	res.MaxPage = int(math.Ceil(float64(total) / float64(req.Limit)))
+
+	return
+}
+
+func (o *OrderDomain) GetUserOrderById(ctx context.Context, userId int, orderId int) (detail *OrderDetail, err error) {
+	order := &Order{}
+	err = o.db.QueryRow(
+		ctx,
+		"select "+orderColumns+" from orders where id=$1 and user_id=$2",
+		orderId,
+		userId,
+	).Scan(order.scanFields()...)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			err = ErrOrderNotFound
+		}
+
+		return
+	}
+
+	rows, err := o.db.Query(
+		ctx,
+		`select
+			order_items.id,
+			products.id,
+			products.name,
+			products.image_url,
+			products.deleted_at is not null,
+			order_items.variant_id,
+			order_items.sku,
+			order_items.qty,
+			row(order_items.price,orders.currency),
+			row(order_items.qty*order_items.price,orders.currency),
+			order_items.tax_class,
+			order_items.tax_rate,
+			row(order_items.tax_amount,orders.currency),
+			order_items.created_at
+		from order_items inner join orders on(order_id=orders.id)
+		left join products on(product_id=products.id)
+		where order_id=$1 order by order_items.id`,
+		orderId,
+	)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	detail = &OrderDetail{Order: order, Items: []*OrderItem{}}
+	for rows.Next() {
+		item := &OrderItem{}
+		product := struct {
+			Id       *int
+			Name     *string
+			ImageUrl *string
+			Archived *bool
+		}{}
+		if err = rows.Scan(
+			&item.Id,
+			&product.Id,
+			&product.Name,
+			&product.ImageUrl,
+			&product.Archived,
+			&item.VariantId,
+			&item.Sku,
+			&item.Qty,
+			&item.Price,
+			&item.TotalPrice,
+			&item.TaxClass,
+			&item.TaxRate,
+			&item.TaxAmount,
+			&item.CreatedAt,
+		); err != nil {
+			return
+		}
+
+		// the product is gone when it was purged
+		if product.Id != nil {
+			item.Product = &OrderItemProduct{
+				Id:       *product.Id,
+				Name:     *product.Name,
+				ImageUrl: *product.ImageUrl,
+				Archived: *product.Archived,
+			}
+		}
+		detail.Items = append(detail.Items, item)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return
+	}
+
+	allocations, err := getAllocations(ctx, o.db, orderId)
+	if err != nil {
+		return
+	}
+
+	for _, item := range detail.Items {
+		item.Allocations = allocations[item.Id]
+		if item.Allocations == nil {
+			item.Allocations = []*Allocation{}
+		}
+	}
+
+	detail.Adjustments, err = getAdjustments(ctx, o.db, orderId)
+	if err != nil {
+		return
+	}
+
+	detail.Shipments, err = getShipments(ctx, o.db, orderId)
+	if err != nil {
+		return
+	}
+
+	return
+}
@@ -3,6 +3,17 @@ package order
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strconv"
+	"sypchal/alert"
+	"sypchal/cart"
+	"sypchal/coupon"
+	"sypchal/inventory"
+	"sypchal/invoice"
+	"sypchal/money"
+	"sypchal/notify"
+	"sypchal/shipping"
+	"sypchal/tax"
 	"sypchal/validation"
 	"time"
 
@@ -12,9 +23,31 @@ import (
 type OrderDomain struct {
 	db        *pgx.Conn
 	validator *validation.Validator
+	notifier  notify.Notifier
+	config    Config
 }
 
-func NewOrderDomain(db *pgx.Conn, validator *validation.Validator) (*OrderDomain, error) {
+type Config struct {
+	// AllowSplitShipments ships orders from several warehouses when no
+	// warehouse has every line in stock.
+	AllowSplitShipments bool
+	// MaxItemQty is the most of a product, or a variant, an order can take,
+	// 0 is unlimited.
+	MaxItemQty int
+	// PricePolicy decides the price of the cart lines whose price changed.
+	PricePolicy cart.PricePolicy
+	// TaxCalculator taxes the orders of TaxRegion, with prices excluding or
+	// including the tax by TaxMode.
+	TaxCalculator tax.TaxCalculator
+	TaxMode       string
+	TaxRegion     string
+	// Invoices issues the invoice of an order when it is paid.
+	Invoices *invoice.Issuer
+	// Currency is the currency the orders are charged in.
+	Currency money.Currency
+}
+
+func NewOrderDomain(db *pgx.Conn, validator *validation.Validator, notifier notify.Notifier, config Config) (*OrderDomain, error) {
 	if db == nil {
 		return nil, errors.New("db is nil")
 	}
@@ -23,107 +56,157 @@ func NewOrderDomain(db *pgx.Conn, validator *validation.Validator) (*OrderDomain
 		return nil, errors.New("validator is nil")
 	}
 
-	return &OrderDomain{db, validator}, nil
+	if config.TaxCalculator == nil {
+		return nil, errors.New("tax calculator is nil")
+	}
+
+	if config.Invoices == nil {
+		return nil, errors.New("invoice issuer is nil")
+	}
+
+	if err := tax.ValidateMode(config.TaxMode); err != nil {
+		return nil, err
+	}
+
+	return &OrderDomain{db, validator, notifier, config}, nil
 }
 
 var (
-	OrderStatusUnpaid = "unpaid"
-	OrderStatusPaid   = "paid"
+	OrderStatusUnpaid           = "unpaid"
+	OrderStatusPaid             = "paid"
+	OrderStatusPartiallyShipped = "partially_shipped"
+	OrderStatusShipped          = "shipped"
+	OrderStatusDelivered        = "delivered"
 )
 
 type Order struct {
-	Id         int        `json:"id"`
-	UserId     int        `json:"user_id"`
-	TotalPrice int        `json:"total_price"`
-	Status     string     `json:"status"`
-	PayId      string     `json:"pay_id"`
-	CreatedAt  time.Time  `json:"created_at"`
-	UpdatedAt  *time.Time `json:"updated_at"`
+	Id         int         `json:"id"`
+	UserId     int         `json:"user_id"`
+	TotalPrice money.Money `json:"total_price"`
+	// BaseTotalPrice is TotalPrice in the base currency at ExchangeRate, the
+	// rate from the base currency when the order was placed.
+	BaseTotalPrice money.Money `json:"base_total_price"`
+	ExchangeRate   money.Rate  `json:"exchange_rate"`
+	// TaxTotal is the tax of the items, added to TotalPrice in exclusive
+	// TaxMode and part of it in inclusive mode.
+	TaxTotal  money.Money `json:"tax_total"`
+	TaxMode   string      `json:"tax_mode"`
+	TaxRegion string      `json:"tax_region"`
+	// ShippingTotal is the shipping cost, included in TotalPrice, of the
+	// ShippingMethod to ShippingAddress, a copy of the address when the order
+	// was placed.
+	ShippingTotal    money.Money       `json:"shipping_total"`
+	ShippingMethodId *int              `json:"shipping_method_id"`
+	ShippingMethod   string            `json:"shipping_method"`
+	ShippingAddress  *shipping.Address `json:"shipping_address"`
+	Status           string            `json:"status"`
+	PayId            string            `json:"pay_id"`
+	CreatedAt        time.Time         `json:"created_at"`
+	UpdatedAt        *time.Time        `json:"updated_at"`
+}
+
+const orderColumns = "id,user_id,row(total_price,currency),row(base_total_price,base_currency),exchange_rate::text," +
+	"row(tax_total,currency),tax_mode,tax_region," +
+	"row(shipping_total,currency),shipping_method_id," +
+	"shipping_method,shipping_address,status,pay_id,created_at,updated_at"
+
+// scanFields returns the destinations matching orderColumns.
+func (order *Order) scanFields() []any {
+	return []any{
+		&order.Id,
+		&order.UserId,
+		&order.TotalPrice,
+		&order.BaseTotalPrice,
+		&order.ExchangeRate,
+		&order.TaxTotal,
+		&order.TaxMode,
+		&order.TaxRegion,
+		&order.ShippingTotal,
+		&order.ShippingMethodId,
+		&order.ShippingMethod,
+		&order.ShippingAddress,
+		&order.Status,
+		&order.PayId,
+		&order.CreatedAt,
+		&order.UpdatedAt,
+	}
 }
 
 type Payment struct {
-	Id        int        `json:"id"`
-	OrderId   int        `json:"order_id"`
-	UserId    int        `json:"user_id"`
-	ProofUrl  string     `json:"proof_url"`
-	Amount    int        `json:"amount"`
-	Method    string     `json:"method"`
-	CreatedAt time.Time  `json:"created_at"`
-	UpdatedAt *time.Time `json:"updated_at"`
+	Id       int         `json:"id"`
+	OrderId  int         `json:"order_id"`
+	UserId   int         `json:"user_id"`
+	ProofUrl string      `json:"proof_url"`
+	Amount   money.Money `json:"amount"`
+	// BaseAmount is Amount in the base currency at the rate of the order.
+	BaseAmount money.Money `json:"base_amount"`
+	Method     string      `json:"method"`
+	CreatedAt  time.Time   `json:"created_at"`
+	UpdatedAt  *time.Time  `json:"updated_at"`
 }
 
 type CartItem struct {
-	TotalPrice   int
-	ProductId    int
-	ProductStock int
-	Qty          int
-	Price        int
+	Id               int
+	TotalPrice       money.Money
+	ProductId        int
+	ProductName      string
+	VariantId        *int
+	Sku              *string
+	ProductStock     int
+	ReorderThreshold *int
+	Category         string
+	TaxClass         string
+	Weight           int
+	Qty              int
+	Price            money.Money
+	CurrentPrice     money.Money
+	PricedAt         time.Time
 }
 
-func (o *OrderDomain) PlaceOrder(ctx context.Context, userId int) (order *Order, err error) {
+// PlaceOrder orders the cart of the customer, charged in currency.
+func (o *OrderDomain) PlaceOrder(ctx context.Context, userId int, currency money.Currency) (order *Order, err error) {
 	tx, err := o.db.Begin(ctx)
 	if err != nil {
 		return
 	}
 	defer tx.Rollback(ctx)
 
-	// get cart items and products detail
-	rows, err := tx.Query(
-		ctx,
-		`select 
-			(cart_items.qty*cart_items.price) as total_price,
-			products.id,
-			products.stock,
-			cart_items.qty,
-			cart_items.price
-		from cart_items inner join products on(product_id=products.id and user_id=$1);`,
-		userId,
-	)
+	checkout, err := o.checkout(ctx, tx, userId, currency, true)
 	if err != nil {
 		return
 	}
-	defer rows.Close()
 
-	var orderTotalPrice int
-	items := []*CartItem{}
-	for rows.Next() {
-		item := &CartItem{}
-		rows.Scan(
-			&item.TotalPrice,
-			&item.ProductId,
-			&item.ProductStock,
-			&item.Qty,
-			&item.Price,
-		)
-		items = append(items, item)
-
-		if item.Qty > item.ProductStock {
-			err = ErrItemOutOfStock
-			return
-		}
-
-		orderTotalPrice += item.TotalPrice
+	if checkout.err != nil {
+		err = checkout.err
+		return
 	}
+	items, allocations := checkout.items, checkout.allocations
 
 	// create order entry
 	payId := randStr(8)
 	order = &Order{}
 	err = tx.QueryRow(
 		ctx,
-		`insert into orders (user_id,total_price,status,pay_id) values ($1,$2,$3,$4) 
-		returning id,user_id,total_price,status,created_at,updated_at`,
+		`insert into orders (user_id,currency,total_price,base_currency,base_total_price,exchange_rate,tax_total,tax_mode,
+		tax_region,shipping_total,shipping_method_id,shipping_method,shipping_address,status,pay_id)
+		values ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15) 
+		returning `+orderColumns,
 		userId,
-		orderTotalPrice,
+		checkout.Total.Currency,
+		checkout.Total.Amount,
+		checkout.BaseTotal.Currency,
+		checkout.BaseTotal.Amount,
+		checkout.ExchangeRate.String(),
+		checkout.Tax.Amount,
+		checkout.TaxMode,
+		checkout.taxRegion(o.config),
+		checkout.Shipping.Amount,
+		checkout.ShippingMethod.MethodId,
+		checkout.ShippingMethod.Name,
+		checkout.ShippingAddress,
 		OrderStatusUnpaid,
 		payId,
-	).Scan(
-		&order.Id,
-		&order.UserId,
-		&order.TotalPrice,
-		&order.Status,
-		&order.CreatedAt,
-		&order.UpdatedAt,
-	)
+	).Scan(order.scanFields()...)
 	if err != nil {
 		return
 	}
@@ -132,13 +215,19 @@ func (o *OrderDomain) PlaceOrder(ctx context.Context, userId int) (order *Order,
 	_, err = tx.CopyFrom(
 		ctx,
 		pgx.Identifier{"order_items"},
-		[]string{"order_id", "product_id", "qty", "price"},
+		[]string{"order_id", "product_id", "variant_id", "sku", "qty", "price", "tax_class", "tax_rate", "tax_amount"},
 		pgx.CopyFromSlice(len(items), func(i int) ([]any, error) {
+			line := checkout.lines[items[i].Id]
 			return []any{
 				order.Id,
 				items[i].ProductId,
+				items[i].VariantId,
+				items[i].Sku,
 				items[i].Qty,
-				items[i].Price,
+				items[i].Price.Amount,
+				items[i].TaxClass,
+				line.TaxRate,
+				line.Tax.Amount,
 			}, nil
 		}),
 	)
@@ -146,34 +235,105 @@ func (o *OrderDomain) PlaceOrder(ctx context.Context, userId int) (order *Order,
 		return
 	}
 
-	// delete user cart items
-	if _, err = tx.Exec(ctx, "delete from cart_items where user_id=$1", userId); err != nil {
+	// delete the ordered cart items, lines of archived products stay in case
+	// the product is restored
+	itemIds := make([]int, 0, len(items))
+	for _, item := range items {
+		itemIds = append(itemIds, item.Id)
+	}
+	if _, err = tx.Exec(ctx, "delete from cart_items where id=any($1)", itemIds); err != nil {
 		return
 	}
 
-	// update products stock
-	b := &pgx.Batch{}
-	for _, item := range items {
-		q := `update products set stock=stock-$1,updated_at=now() where id=$2`
-		b.Queue(q, item.Qty, item.ProductId)
+	orderItemIds, err := getOrderItemIds(ctx, tx, order.Id)
+	if err != nil {
+		return
+	}
+
+	// take the sold quantities out of the allocated warehouses, variants keep
+	// their own stock per sku
+	allocationRows := [][]any{}
+	for i, item := range items {
+		for _, allocation := range allocations[i] {
+			err = inventory.Move(ctx, tx, &inventory.Movement{
+				WarehouseId: allocation.WarehouseId,
+				ProductId:   item.ProductId,
+				VariantId:   item.VariantId,
+				Quantity:    -allocation.Qty,
+				Reason:      inventory.ReasonSale,
+				Reference:   "order:" + strconv.Itoa(order.Id),
+			})
+			if err != nil {
+				if errors.Is(err, inventory.ErrInsufficientStock) {
+					err = ErrItemOutOfStock
+				}
+				return
+			}
+
+			allocationRows = append(allocationRows, []any{
+				orderItemIds[item.stockKey()],
+				allocation.WarehouseId,
+				allocation.Qty,
+			})
+		}
+	}
+
+	_, err = tx.CopyFrom(
+		ctx,
+		pgx.Identifier{"order_item_allocations"},
+		[]string{"order_item_id", "warehouse_id", "qty"},
+		pgx.CopyFromRows(allocationRows),
+	)
+	if err != nil {
+		return
 	}
-	if err = tx.SendBatch(ctx, b).Close(); err != nil {
+
+	// explain the difference between the items and the total
+	if err = saveAdjustments(ctx, tx, order.Id, checkout.adjustments, orderItemIds); err != nil {
 		return
 	}
 
+	if checkout.Coupon != nil {
+		if err = coupon.Redeem(ctx, tx, checkout.Coupon, userId, order.Id); err != nil {
+			return
+		}
+
+		if _, err = tx.Exec(ctx, "delete from cart_coupons where user_id=$1", userId); err != nil {
+			return
+		}
+	}
+
 	if err = tx.Commit(ctx); err != nil {
 		return
 	}
 
+	// alert the products that fell below their reorder threshold
+	msgs := []notify.Message{}
+	for _, item := range items {
+		msg, ok := alert.LowStock(
+			item.ProductId,
+			item.VariantId,
+			item.ProductName,
+			item.Sku,
+			item.ReorderThreshold,
+			item.ProductStock,
+			item.ProductStock-item.Qty,
+		)
+		if ok {
+			msgs = append(msgs, msg)
+		}
+	}
+	notify.SendAll(ctx, o.notifier, msgs)
+
 	return
 }
 
 type PayOrderRequest struct {
 	PayId    string
-	OrderId  int    `json:"order_id" validate:"required"`
-	ProofUrl string `json:"proof_url" validate:"required,http_url"`
-	Amount   int    `json:"amount" validate:"required"`
-	Method   string `json:"method" validate:"required"`
+	OrderId  int         `json:"order_id" validate:"required"`
+	ProofUrl string      `json:"proof_url" validate:"required,http_url"`
+	Amount   money.Money `json:"amount" validate:"required"`
+	Method   string      `json:"method" validate:"required"`
 }
 
 func (o *OrderDomain) PayOrder(ctx context.Context, userId int, req PayOrderRequest) (payment *Payment, err error) {
@@ -187,11 +347,16 @@ func (o *OrderDomain) PayOrder(ctx context.Context, userId int, req PayOrderRequ
 	}
 	defer tx.Rollback(ctx)
 
-	var totalPrice int
+	var totalPrice money.Money
+	var baseCurrency money.Currency
+	var exchangeRate money.Rate
 	var payIdDb string
 	var orderStatus string
-	err = tx.QueryRow(ctx, "select total_price,pay_id,status from orders where id=$1", req.OrderId).
-		Scan(&totalPrice, &payIdDb, &orderStatus)
+	err = tx.QueryRow(
+		ctx,
+		"select row(total_price,currency),base_currency,exchange_rate::text,pay_id,status from orders where id=$1",
+		req.OrderId,
+	).Scan(&totalPrice, &baseCurrency, &exchangeRate, &payIdDb, &orderStatus)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			err = ErrOrderNotFound
@@ -210,20 +375,32 @@ func (o *OrderDomain) PayOrder(ctx context.Context, userId int, req PayOrderRequ
 		return
 	}
 
-	if totalPrice > req.Amount {
+	// an amount without a currency is in the currency of the order
+	amount, err := req.Amount.As(totalPrice.Currency)
+	if err != nil {
+		err = fmt.Errorf("%w: %w", ErrPayCurrencyMismatch, err)
+		return
+	}
+
+	if amount.LessThan(totalPrice) {
 		err = ErrPayAmountNotMatch
 		return
 	}
+	baseAmount := amount.Convert(baseCurrency, exchangeRate.Inverse(), money.RoundHalfUp)
 
 	payment = &Payment{}
 	err = tx.QueryRow(
 		ctx,
-		`insert into payments (order_id,user_id,proof_url,amount,method)
-		values ($1,$2,$3,$4,$5) returning *`,
+		`insert into payments (order_id,user_id,proof_url,amount,currency,base_amount,base_currency,method)
+		values ($1,$2,$3,$4,$5,$6,$7,$8) returning id,order_id,user_id,proof_url,row(amount,currency),
+		row(base_amount,base_currency),method,created_at,updated_at`,
 		req.OrderId,
 		userId,
 		req.ProofUrl,
-		req.Amount,
+		amount.Amount,
+		amount.Currency,
+		baseAmount.Amount,
+		baseAmount.Currency,
 		req.Method,
 	).Scan(
 		&payment.Id,
@@ -231,6 +408,7 @@ func (o *OrderDomain) PayOrder(ctx context.Context, userId int, req PayOrderRequ
 		&payment.UserId,
 		&payment.ProofUrl,
 		&payment.Amount,
+		&payment.BaseAmount,
 		&payment.Method,
 		&payment.CreatedAt,
 		&payment.UpdatedAt,
@@ -244,6 +422,10 @@ func (o *OrderDomain) PayOrder(ctx context.Context, userId int, req PayOrderRequ
 		return
 	}
 
+	if _, err = o.config.Invoices.Invoice(ctx, tx, req.OrderId); err != nil {
+		return
+	}
+
 	if err = tx.Commit(ctx); err != nil {
 		return
 	}
@@ -0,0 +1,402 @@
+package order
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ShipmentStatusPending   = "pending"
+	ShipmentStatusShipped   = "shipped"
+	ShipmentStatusInTransit = "in_transit"
+	ShipmentStatusDelivered = "delivered"
+	ShipmentStatusCancelled = "cancelled"
+)
+
+// shipmentTransitions are the statuses a shipment can move to from each
+// status, delivered and cancelled shipments are final.
+var shipmentTransitions = map[string][]string{
+	ShipmentStatusPending:   {ShipmentStatusShipped, ShipmentStatusInTransit, ShipmentStatusDelivered, ShipmentStatusCancelled},
+	ShipmentStatusShipped:   {ShipmentStatusInTransit, ShipmentStatusDelivered, ShipmentStatusCancelled},
+	ShipmentStatusInTransit: {ShipmentStatusDelivered, ShipmentStatusCancelled},
+}
+
+// Shipment is a parcel of an order, carrying some quantities of its items.
+type Shipment struct {
+	Id             int             `json:"id"`
+	OrderId        int             `json:"order_id"`
+	Carrier        string          `json:"carrier"`
+	TrackingNumber string          `json:"tracking_number"`
+	TrackingUrl    string          `json:"tracking_url"`
+	Status         string          `json:"status"`
+	Items          []*ShipmentItem `json:"items"`
+	ShippedAt      *time.Time      `json:"shipped_at"`
+	DeliveredAt    *time.Time      `json:"delivered_at"`
+	CreatedAt      time.Time       `json:"created_at"`
+	UpdatedAt      *time.Time      `json:"updated_at"`
+}
+
+type ShipmentItem struct {
+	OrderItemId int `json:"order_item_id" validate:"required"`
+	Qty         int `json:"qty" validate:"required,gt=0"`
+}
+
+const shipmentColumns = "id,order_id,carrier,tracking_number,tracking_url,status,shipped_at,delivered_at,created_at,updated_at"
+
+// scanFields returns the destinations matching shipmentColumns.
+func (shipment *Shipment) scanFields() []any {
+	return []any{
+		&shipment.Id,
+		&shipment.OrderId,
+		&shipment.Carrier,
+		&shipment.TrackingNumber,
+		&shipment.TrackingUrl,
+		&shipment.Status,
+		&shipment.ShippedAt,
+		&shipment.DeliveredAt,
+		&shipment.CreatedAt,
+		&shipment.UpdatedAt,
+	}
+}
+
+type CreateShipmentRequest struct {
+	Carrier        string `json:"carrier" validate:"required"`
+	TrackingNumber string `json:"tracking_number"`
+	TrackingUrl    string `json:"tracking_url" validate:"omitempty,http_url"`
+	Status         string `json:"status" validate:"omitempty,oneof=pending shipped in_transit delivered"`
+	// Items are the quantities shipped, every quantity not shipped yet when
+	// empty.
+	Items []ShipmentItem `json:"items" validate:"dive"`
+}
+
+// CreateShipment ships some quantities of the items of a paid order, pending
+// unless a status is given. The order status follows its shipments.
+func (o *OrderDomain) CreateShipment(ctx context.Context, orderId int, req CreateShipmentRequest) (shipment *Shipment, err error) {
+	if err = o.validator.ValidateStruct(req); err != nil {
+		return
+	}
+	if req.Status == "" {
+		req.Status = ShipmentStatusPending
+	}
+
+	tx, err := o.db.Begin(ctx)
+	if err != nil {
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	var status string
+	err = tx.QueryRow(ctx, "select status from orders where id=$1 for update", orderId).Scan(&status)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			err = ErrOrderNotFound
+		}
+		return
+	}
+
+	if status == OrderStatusUnpaid {
+		err = ErrOrderNotPaid
+		return
+	}
+
+	unshipped, err := unshippedQty(ctx, tx, orderId)
+	if err != nil {
+		return
+	}
+
+	items := req.Items
+	if len(items) == 0 {
+		for orderItemId, qty := range unshipped {
+			if qty > 0 {
+				items = append(items, ShipmentItem{orderItemId, qty})
+			}
+		}
+		slices.SortFunc(items, func(a, b ShipmentItem) int { return a.OrderItemId - b.OrderItemId })
+	}
+	if len(items) == 0 {
+		err = ErrNothingToShip
+		return
+	}
+
+	for _, item := range items {
+		left, ok := unshipped[item.OrderItemId]
+		if !ok {
+			err = fmt.Errorf("%w: order item %d", ErrOrderItemNotFound, item.OrderItemId)
+			return
+		}
+		if item.Qty > left {
+			err = fmt.Errorf("%w: %d of order item %d left", ErrShipmentQty, left, item.OrderItemId)
+			return
+		}
+		unshipped[item.OrderItemId] -= item.Qty
+	}
+
+	shipment = &Shipment{}
+	err = tx.QueryRow(
+		ctx,
+		`insert into shipments(order_id,carrier,tracking_number,tracking_url,status,shipped_at,delivered_at)
+		values ($1,$2,$3,$4,$5,
+		case when $5 in ('shipped','in_transit','delivered') then now() end,
+		case when $5='delivered' then now() end)
+		returning `+shipmentColumns,
+		orderId,
+		req.Carrier,
+		req.TrackingNumber,
+		req.TrackingUrl,
+		req.Status,
+	).Scan(shipment.scanFields()...)
+	if err != nil {
+		return
+	}
+
+	_, err = tx.CopyFrom(
+		ctx,
+		pgx.Identifier{"shipment_items"},
+		[]string{"shipment_id", "order_item_id", "qty"},
+		pgx.CopyFromSlice(len(items), func(i int) ([]any, error) {
+			return []any{shipment.Id, items[i].OrderItemId, items[i].Qty}, nil
+		}),
+	)
+	if err != nil {
+		return
+	}
+	shipment.Items = make([]*ShipmentItem, 0, len(items))
+	for i := range items {
+		shipment.Items = append(shipment.Items, &items[i])
+	}
+
+	if err = updateShippingStatus(ctx, tx, orderId); err != nil {
+		return
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return
+	}
+
+	return
+}
+
+type UpdateShipmentRequest struct {
+	Carrier        string `json:"carrier" validate:"required"`
+	TrackingNumber string `json:"tracking_number"`
+	TrackingUrl    string `json:"tracking_url" validate:"omitempty,http_url"`
+	Status         string `json:"status" validate:"required,oneof=pending shipped in_transit delivered cancelled"`
+}
+
+// UpdateShipmentById changes the tracking of a shipment and moves it forward,
+// see shipmentTransitions. Cancelled shipments give their quantities back to
+// ship again. The order status follows its shipments.
+func (o *OrderDomain) UpdateShipmentById(ctx context.Context, id int, req UpdateShipmentRequest) (shipment *Shipment, err error) {
+	if err = o.validator.ValidateStruct(req); err != nil {
+		return
+	}
+
+	tx, err := o.db.Begin(ctx)
+	if err != nil {
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	// the order status follows all its shipments, so it is locked before the
+	// shipment like CreateShipment does
+	var status string
+	err = tx.QueryRow(ctx, "select status from orders where id=(select order_id from shipments where id=$1) for update", id).Scan(&status)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			err = ErrShipmentNotFound
+		}
+		return
+	}
+
+	err = tx.QueryRow(ctx, "select status from shipments where id=$1 for update", id).Scan(&status)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			err = ErrShipmentNotFound
+		}
+		return
+	}
+
+	if req.Status != status && !slices.Contains(shipmentTransitions[status], req.Status) {
+		err = fmt.Errorf("%w: from %s to %s", ErrShipmentTransition, status, req.Status)
+		return
+	}
+
+	shipment = &Shipment{}
+	err = tx.QueryRow(
+		ctx,
+		`update shipments set carrier=$1,tracking_number=$2,tracking_url=$3,status=$4,
+		shipped_at=case when $4 in ('shipped','in_transit','delivered') then coalesce(shipped_at,now()) end,
+		delivered_at=case when $4='delivered' then coalesce(delivered_at,now()) end,
+		updated_at=now()
+		where id=$5 returning `+shipmentColumns,
+		req.Carrier,
+		req.TrackingNumber,
+		req.TrackingUrl,
+		req.Status,
+		id,
+	).Scan(shipment.scanFields()...)
+	if err != nil {
+		return
+	}
+
+	items, err := getShipmentItems(ctx, tx, []int{id})
+	if err != nil {
+		return
+	}
+	shipment.Items = items[id]
+
+	if err = updateShippingStatus(ctx, tx, shipment.OrderId); err != nil {
+		return
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return
+	}
+
+	return
+}
+
+// unshippedQty is the quantity of every item of the order that no shipment,
+// but the cancelled ones, carries yet.
+func unshippedQty(ctx context.Context, tx pgx.Tx, orderId int) (unshipped map[int]int, err error) {
+	rows, err := tx.Query(
+		ctx,
+		`select order_items.id,order_items.qty-coalesce(sum(shipment_items.qty),0)
+		from order_items
+		left join shipment_items on(shipment_items.order_item_id=order_items.id
+			and shipment_id in (select id from shipments where order_id=$1 and status<>'cancelled'))
+		where order_items.order_id=$1 group by order_items.id`,
+		orderId,
+	)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	unshipped = map[int]int{}
+	for rows.Next() {
+		var orderItemId, qty int
+		if err = rows.Scan(&orderItemId, &qty); err != nil {
+			return
+		}
+		unshipped[orderItemId] = qty
+	}
+
+	err = rows.Err()
+	return
+}
+
+// updateShippingStatus moves a paid order to partially_shipped, shipped or
+// delivered depending on the quantities its shipments carry, and back when
+// shipments are cancelled.
+func updateShippingStatus(ctx context.Context, tx pgx.Tx, orderId int) (err error) {
+	var ordered, shipped, delivered int
+	err = tx.QueryRow(
+		ctx,
+		`select
+			(select coalesce(sum(qty),0) from order_items where order_id=$1),
+			coalesce(sum(shipment_items.qty) filter (where status in ('shipped','in_transit','delivered')),0),
+			coalesce(sum(shipment_items.qty) filter (where status='delivered'),0)
+		from shipments inner join shipment_items on(shipment_id=shipments.id)
+		where order_id=$1`,
+		orderId,
+	).Scan(&ordered, &shipped, &delivered)
+	if err != nil {
+		return
+	}
+
+	status := OrderStatusPaid
+	switch {
+	case delivered >= ordered:
+		status = OrderStatusDelivered
+	case shipped >= ordered:
+		status = OrderStatusShipped
+	case shipped > 0:
+		status = OrderStatusPartiallyShipped
+	}
+
+	_, err = tx.Exec(
+		ctx,
+		"update orders set status=$1,updated_at=now() where id=$2 and status<>'unpaid' and status<>$1",
+		status,
+		orderId,
+	)
+	return
+}
+
+// GetShipments lists the shipments of an order, oldest first.
+func (o *OrderDomain) GetShipments(ctx context.Context, orderId int) (shipments []*Shipment, err error) {
+	return getShipments(ctx, o.db, orderId)
+}
+
+type querier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}
+
+func getShipments(ctx context.Context, db querier, orderId int) (shipments []*Shipment, err error) {
+	rows, err := db.Query(ctx, "select "+shipmentColumns+" from shipments where order_id=$1 order by id", orderId)
+	if err != nil {
+		return
+	}
+
+	shipments = []*Shipment{}
+	ids := []int{}
+	for rows.Next() {
+		shipment := &Shipment{}
+		if err = rows.Scan(shipment.scanFields()...); err != nil {
+			rows.Close()
+			return
+		}
+		shipments = append(shipments, shipment)
+		ids = append(ids, shipment.Id)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return
+	}
+
+	items, err := getShipmentItems(ctx, db, ids)
+	if err != nil {
+		return
+	}
+
+	for _, shipment := range shipments {
+		shipment.Items = items[shipment.Id]
+	}
+
+	return
+}
+
+// getShipmentItems returns the items of the shipments by shipment id.
+func getShipmentItems(ctx context.Context, db querier, shipmentIds []int) (items map[int][]*ShipmentItem, err error) {
+	rows, err := db.Query(
+		ctx,
+		"select shipment_id,order_item_id,qty from shipment_items where shipment_id=any($1) order by id",
+		shipmentIds,
+	)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	items = map[int][]*ShipmentItem{}
+	for _, id := range shipmentIds {
+		items[id] = []*ShipmentItem{}
+	}
+	for rows.Next() {
+		var shipmentId int
+		item := &ShipmentItem{}
+		if err = rows.Scan(&shipmentId, &item.OrderItemId, &item.Qty); err != nil {
+			return
+		}
+		items[shipmentId] = append(items[shipmentId], item)
+	}
+
+	err = rows.Err()
+	return
+}
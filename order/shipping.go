@@ -0,0 +1,148 @@
+package order
+
+import (
+	"context"
+	"errors"
+	"sypchal/money"
+	"sypchal/shipping"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+type SetShippingRequest struct {
+	AddressId int `json:"address_id" validate:"required"`
+	// MethodId is the chosen shipping method, the cheapest one when 0.
+	MethodId int `json:"method_id"`
+}
+
+// SetShipping chooses the address the cart of the customer ships to, and
+// optionally the shipping method. The method must ship the cart there. The
+// checkout is priced in currency.
+func (o *OrderDomain) SetShipping(ctx context.Context, userId int, currency money.Currency, req SetShippingRequest) (checkout *Checkout, err error) {
+	if err = o.validator.ValidateStruct(req); err != nil {
+		return
+	}
+
+	tx, err := o.db.Begin(ctx)
+	if err != nil {
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err = shipping.LoadAddress(ctx, tx, userId, req.AddressId); err != nil {
+		return
+	}
+
+	_, err = tx.Exec(
+		ctx,
+		`insert into cart_shipping(user_id,address_id,shipping_method_id) values ($1,$2,nullif($3,0))
+		on conflict (user_id) do update set address_id=excluded.address_id,
+		shipping_method_id=excluded.shipping_method_id,updated_at=now()`,
+		userId,
+		req.AddressId,
+		req.MethodId,
+	)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+			err = shipping.ErrMethodNotFound
+		}
+		return
+	}
+
+	checkout, err = o.checkout(ctx, tx, userId, currency, false)
+	if err != nil {
+		return
+	}
+
+	if checkout.shippingErr != nil {
+		err = checkout.shippingErr
+		return
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return
+	}
+
+	return
+}
+
+// cartShipping returns the address and shipping method chosen for the cart,
+// 0 when not chosen.
+func cartShipping(ctx context.Context, tx pgx.Tx, userId int) (addressId int, methodId int, err error) {
+	err = tx.QueryRow(
+		ctx,
+		"select address_id,coalesce(shipping_method_id,0) from cart_shipping where user_id=$1",
+		userId,
+	).Scan(&addressId, &methodId)
+	if errors.Is(err, pgx.ErrNoRows) {
+		err = nil
+	}
+
+	return
+}
+
+// applyShipping prices the shipping to the chosen address, or the default
+// address of the customer, with the chosen method, or the cheapest one. A
+// missing address or method is a blocking issue.
+func (checkout *Checkout) applyShipping(ctx context.Context, tx pgx.Tx, userId int) (err error) {
+	addressId, methodId, err := cartShipping(ctx, tx, userId)
+	if err != nil {
+		return
+	}
+
+	address, err := shipping.LoadAddress(ctx, tx, userId, addressId)
+	if errors.Is(err, shipping.ErrAddressNotFound) {
+		checkout.Issues = append(checkout.Issues, CheckoutIssue{IssueShippingAddress, ErrNoShippingAddress.Error(), true})
+		checkout.shippingErr = ErrNoShippingAddress
+		checkout.fail(ErrNoShippingAddress)
+		return nil
+	}
+	if err != nil {
+		return
+	}
+	checkout.ShippingAddress = address
+
+	for _, item := range checkout.items {
+		checkout.Weight += item.Qty * item.Weight
+	}
+
+	checkout.ShippingOptions, err = shipping.Quotes(ctx, tx, address, checkout.Weight, checkout.Subtotal.Sub(checkout.Discount), checkout.rates)
+	if err != nil {
+		return
+	}
+
+	for i, quote := range checkout.ShippingOptions {
+		if (methodId == 0 && i == 0) || quote.MethodId == methodId {
+			checkout.ShippingMethod = &checkout.ShippingOptions[i]
+			break
+		}
+	}
+
+	if checkout.ShippingMethod == nil {
+		shippingErr := ErrNoShippingMethod
+		if methodId != 0 {
+			shippingErr = ErrShippingMethodUnavailable
+		}
+		checkout.Issues = append(checkout.Issues, CheckoutIssue{IssueShippingMethod, shippingErr.Error(), true})
+		checkout.shippingErr = shippingErr
+		checkout.fail(shippingErr)
+		return nil
+	}
+	checkout.Shipping = checkout.ShippingMethod.Price
+
+	// a free shipping coupon takes the shipping off the order
+	if checkout.Coupon != nil && checkout.Coupon.FreeShipping && checkout.Shipping.IsPositive() {
+		checkout.Coupon.Amount = checkout.Shipping
+		checkout.Discount = checkout.Discount.Add(checkout.Shipping)
+		checkout.adjustments = append(checkout.adjustments, &pendingAdjustment{nil, Adjustment{
+			Type:        AdjustmentCoupon,
+			Code:        checkout.Coupon.Code,
+			Description: "coupon " + checkout.Coupon.Code + ", free shipping",
+			Amount:      checkout.Shipping.Neg(),
+		}})
+	}
+
+	return
+}
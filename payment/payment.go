@@ -0,0 +1,38 @@
+package payment
+
+import (
+	"context"
+	"strconv"
+	"sypchal/money"
+)
+
+type RefundRequest struct {
+	OrderId   int
+	PaymentId int
+	Amount    money.Money
+	Reason    string
+	// IdempotencyKey is the same every time a refund is tried again, the
+	// gateway pays it back once.
+	IdempotencyKey string
+}
+
+// Gateway moves money back to the customers, e.g. through a payment
+// provider.
+type Gateway interface {
+	// Refund pays back Amount of a payment and returns the reference of the
+	// refund at the gateway. It's called outside of any transaction and must
+	// not pay twice for the same IdempotencyKey.
+	Refund(ctx context.Context, req RefundRequest) (reference string, err error)
+}
+
+// ManualGateway is for payments made by transfer, the refunds are recorded
+// and paid back by hand.
+type ManualGateway struct{}
+
+func NewManualGateway() *ManualGateway {
+	return &ManualGateway{}
+}
+
+func (g *ManualGateway) Refund(ctx context.Context, req RefundRequest) (string, error) {
+	return "manual:payment:" + strconv.Itoa(req.PaymentId), nil
+}
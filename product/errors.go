@@ -3,3 +3,16 @@ package product
 import "errors"
 
 var ErrProductNotFound = errors.New("product not found")
+var ErrVariantNotFound = errors.New("variant not found")
+var ErrSkuAlreadyExists = errors.New("sku already exists")
+var ErrInvalidVariantOptions = errors.New("invalid variant options")
+var ErrDuplicateVariantOptions = errors.New("variant options already used by another variant")
+var ErrOptionsInUse = errors.New("options are used by existing variants")
+var ErrInvalidImportFile = errors.New("invalid import file")
+var ErrProductNotArchived = errors.New("product not found or not archived")
+var ErrInvalidPatch = errors.New("patch must be a json object")
+var ErrVersionMismatch = errors.New("product version mismatch")
+var ErrDefaultWarehouseStock = errors.New("not enough stock in the default warehouse to lower the stock")
+var ErrInvalidSort = errors.New("invalid sort")
+var ErrListPriceNotFound = errors.New("list price not found")
+var ErrListPriceCurrency = errors.New("list price must be in another currency than the catalog")
@@ -0,0 +1,225 @@
+package product
+
+import (
+	"context"
+	"math"
+	"sypchal/audit"
+	"sypchal/money"
+	"time"
+)
+
+var (
+	HistoryActionCreate        = "create"
+	HistoryActionUpdate        = "update"
+	HistoryActionArchive       = "archive"
+	HistoryActionRestore       = "restore"
+	HistoryActionImport        = "import"
+	HistoryActionOptions       = "options"
+	HistoryActionVariantCreate = "variant_create"
+	HistoryActionVariantUpdate = "variant_update"
+	HistoryActionVariantDelete = "variant_delete"
+)
+
+type Change struct {
+	From any `json:"from"`
+	To   any `json:"to"`
+}
+
+type HistoryEntry struct {
+	Id        int               `json:"id"`
+	ProductId int               `json:"product_id"`
+	VariantId *int              `json:"variant_id"`
+	Action    string            `json:"action"`
+	Changes   map[string]Change `json:"changes"`
+	Actor     string            `json:"actor"`
+	RequestId *string           `json:"request_id"`
+	CreatedAt time.Time         `json:"created_at"`
+}
+
+// recordHistory appends a change of the product, made by the actor of ctx.
+func recordHistory(ctx context.Context, db execer, productId int, variantId *int, action string, changes map[string]Change) error {
+	if changes == nil {
+		changes = map[string]Change{}
+	}
+
+	actor := audit.FromContext(ctx)
+	var requestId *string
+	if actor.RequestId != "" {
+		requestId = &actor.RequestId
+	}
+
+	_, err := db.Exec(
+		ctx,
+		`insert into product_history(product_id,variant_id,action,changes,actor,request_id)
+		values ($1,$2,$3,$4,$5,$6)`,
+		productId,
+		variantId,
+		action,
+		changes,
+		actor.Name,
+		requestId,
+	)
+
+	return err
+}
+
+// diffProducts lists the fields that differ, before is nil for a new product.
+func diffProducts(before, after *Product) map[string]Change {
+	if before == nil {
+		before = &Product{}
+	}
+
+	changes := map[string]Change{}
+	diff(changes, "sku", before.Sku, after.Sku)
+	diff(changes, "name", before.Name, after.Name)
+	diff(changes, "description", before.Description, after.Description)
+	diff(changes, "image_url", before.ImageUrl, after.ImageUrl)
+	diff(changes, "category", before.Category, after.Category)
+	diff(changes, "tax_class", before.TaxClass, after.TaxClass)
+	diff(changes, "stock", before.Stock, after.Stock)
+	diff(changes, "price", before.Price, after.Price)
+	diff(changes, "weight", before.Weight, after.Weight)
+	diff(changes, "length", before.Length, after.Length)
+	diff(changes, "width", before.Width, after.Width)
+	diff(changes, "height", before.Height, after.Height)
+
+	return changes
+}
+
+// diffVariants lists the fields that differ, before is nil for a new variant
+// and after is nil for a deleted one.
+func diffVariants(before, after *Variant) map[string]Change {
+	changes := map[string]Change{}
+	if before == nil {
+		before = &Variant{}
+	}
+	if after == nil {
+		after = &Variant{}
+	}
+
+	diff(changes, "sku", before.Sku, after.Sku)
+	diff(changes, "stock", before.Stock, after.Stock)
+	diff(changes, "image_url", before.ImageUrl, after.ImageUrl)
+	if !sameOptions(before.Options, after.Options) {
+		changes["options"] = Change{before.Options, after.Options}
+	}
+	if (before.Price == nil) != (after.Price == nil) ||
+		(before.Price != nil && *before.Price != *after.Price) {
+		changes["price"] = Change{before.Price, after.Price}
+	}
+
+	return changes
+}
+
+func diff[T comparable](changes map[string]Change, field string, from, to T) {
+	if from != to {
+		changes[field] = Change{from, to}
+	}
+}
+
+type GetProductHistoryRequest struct {
+	ProductId int
+	Limit     int
+	Offset    int
+}
+
+type GetProductHistoryResponse struct {
+	History []*HistoryEntry `json:"history"`
+	Total   int             `json:"total"`
+	MaxPage int             `json:"max_page"`
+}
+
+// GetProductHistory lists the changes of a product, newest first. It works for
+// archived and purged products too.
+func (p *ProductDomain) GetProductHistory(ctx context.Context, req GetProductHistoryRequest) (res *GetProductHistoryResponse, err error) {
+	rows, err := p.db.Query(
+		ctx,
+		`select count(*) over(),id,product_id,variant_id,action,changes,actor,request_id,created_at
+		from product_history where product_id=$1 order by created_at desc, id desc limit $2 offset $3`,
+		req.ProductId,
+		req.Limit,
+		req.Offset,
+	)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	var total int
+	res = &GetProductHistoryResponse{History: []*HistoryEntry{}}
+	for rows.Next() {
+		entry := &HistoryEntry{}
+		if err = rows.Scan(
+			&total,
+			&entry.Id,
+			&entry.ProductId,
+			&entry.VariantId,
+			&entry.Action,
+			&entry.Changes,
+			&entry.Actor,
+			&entry.RequestId,
+			&entry.CreatedAt,
+		); err != nil {
+			return
+		}
+		res.History = append(res.History, entry)
+	}
+
+	res.Total = total
+	res.MaxPage = int(math.Ceil(float64(total) / float64(req.Limit)))
+
+	return
+}
+
+// PricePeriod is a price that was in effect from ValidFrom until ValidTo, or
+// until now when ValidTo is nil. A variant period with a nil Price means the
+// variant used the product price.
+type PricePeriod struct {
+	VariantId *int         `json:"variant_id"`
+	Price     *money.Money `json:"price"`
+	ValidFrom time.Time    `json:"valid_from"`
+	ValidTo   *time.Time   `json:"valid_to"`
+	Actor     string       `json:"actor"`
+	RequestId *string      `json:"request_id"`
+}
+
+type GetPriceHistoryRequest struct {
+	ProductId int
+	// At only returns the prices in effect at that time when not nil.
+	At *time.Time
+}
+
+func (p *ProductDomain) GetPriceHistory(ctx context.Context, req GetPriceHistoryRequest) (periods []*PricePeriod, err error) {
+	rows, err := p.db.Query(
+		ctx,
+		`select variant_id,case when price is null then null else row(price,currency) end,valid_from,valid_to,actor,request_id
+		from product_price_history
+		where product_id=$1 and ($2::timestamp is null or (valid_from <= $2 and (valid_to is null or valid_to > $2)))
+		order by variant_id nulls first, valid_from`,
+		req.ProductId,
+		req.At,
+	)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	periods = []*PricePeriod{}
+	for rows.Next() {
+		period := &PricePeriod{}
+		if err = rows.Scan(
+			&period.VariantId,
+			&period.Price,
+			&period.ValidFrom,
+			&period.ValidTo,
+			&period.Actor,
+			&period.RequestId,
+		); err != nil {
+			return
+		}
+		periods = append(periods, period)
+	}
+
+	err = rows.Err()
+	return
+}
@@ -0,0 +1,491 @@
+package product
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sypchal/inventory"
+	"sypchal/money"
+	"sypchal/notify"
+	"sypchal/validation"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/rs/zerolog/log"
+)
+
+// ProductCsvHeader names the csv columns, the price is in minor units of the
+// currency, the catalog currency when the column is empty. The id only
+// matters for products without sku.
+var ProductCsvHeader = []string{"id", "sku", "name", "description", "image_url", "category", "stock", "price", "currency"}
+
+type ImportProductRow struct {
+	// Id matches a product without sku, rows with a sku are matched by sku.
+	Id          int         `json:"id" validate:"gte=0"`
+	Sku         string      `json:"sku" validate:"max=64"`
+	Name        string      `json:"name" validate:"required"`
+	Description string      `json:"description" validate:"required"`
+	ImageUrl    string      `json:"image_url"`
+	Category    string      `json:"category"`
+	Stock       int         `json:"stock" validate:"gte=0"`
+	Price       money.Money `json:"price" validate:"required,gt=0"`
+
+	// line is the csv line or json array position (1-based) of the row
+	line int
+	// parseErrors holds values that could not be parsed, e.g. a non numeric stock
+	parseErrors map[string]string
+}
+
+// ParseProductsCSV reads a csv file whose first line names the columns, see
+// ProductCsvHeader. Unknown columns are ignored.
+func ParseProductsCSV(r io.Reader) (rows []ImportProductRow, err error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			err = ErrInvalidImportFile
+		}
+		return
+	}
+
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+
+	if _, ok := columns["sku"]; !ok {
+		err = fmt.Errorf("%w: sku column is required", ErrInvalidImportFile)
+		return
+	}
+
+	rows = []ImportProductRow{}
+	for {
+		var record []string
+		record, err = reader.Read()
+		if errors.Is(err, io.EOF) {
+			err = nil
+			break
+		}
+		if err != nil {
+			err = fmt.Errorf("%w: %w", ErrInvalidImportFile, err)
+			return
+		}
+
+		line, _ := reader.FieldPos(0)
+		get := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		row := ImportProductRow{
+			Sku:         get("sku"),
+			Name:        get("name"),
+			Description: get("description"),
+			ImageUrl:    get("image_url"),
+			Category:    get("category"),
+			line:        line,
+			parseErrors: map[string]string{},
+		}
+
+		if value := get("id"); value != "" {
+			n, perr := strconv.Atoi(value)
+			if perr != nil {
+				row.parseErrors["id"] = "id must be a number"
+			}
+			row.Id = n
+		}
+
+		if value := get("stock"); value != "" {
+			n, perr := strconv.Atoi(value)
+			if perr != nil {
+				row.parseErrors["stock"] = "stock must be a number"
+			}
+			row.Stock = n
+		}
+
+		if value := get("price"); value != "" {
+			n, perr := strconv.ParseInt(value, 10, 64)
+			if perr != nil {
+				row.parseErrors["price"] = "price must be a number"
+			}
+			row.Price.Amount = n
+		}
+
+		if value := get("currency"); value != "" {
+			currency, perr := money.ParseCurrency(value)
+			if perr != nil {
+				row.parseErrors["currency"] = "currency is unknown"
+			}
+			row.Price.Currency = currency
+		}
+
+		rows = append(rows, row)
+	}
+
+	return
+}
+
+// ParseProductsJSON reads a json array of products.
+func ParseProductsJSON(r io.Reader) (rows []ImportProductRow, err error) {
+	if err = json.NewDecoder(r).Decode(&rows); err != nil {
+		err = fmt.Errorf("%w: %w", ErrInvalidImportFile, err)
+		return
+	}
+
+	for i := range rows {
+		rows[i].line = i + 1
+	}
+
+	return
+}
+
+type ImportOptions struct {
+	// DryRun validates and reports what would change without saving anything.
+	DryRun bool
+	// Atomic saves nothing when any row fails.
+	Atomic bool
+}
+
+type ImportRowError struct {
+	Row    int               `json:"row"`
+	Sku    string            `json:"sku"`
+	Errors map[string]string `json:"errors"`
+}
+
+type ImportResult struct {
+	Total   int              `json:"total"`
+	Created int              `json:"created"`
+	Updated int              `json:"updated"`
+	Failed  int              `json:"failed"`
+	Applied bool             `json:"applied"`
+	DryRun  bool             `json:"dry_run"`
+	Atomic  bool             `json:"atomic"`
+	Errors  []ImportRowError `json:"errors"`
+}
+
+// ImportProducts creates or updates products matched by sku, rows without sku
+// update the product of their id. Invalid rows are reported and skipped,
+// unless the import is atomic in which case nothing is saved when any row
+// fails. Errors that are not caused by a row abort the whole import.
+func (p *ProductDomain) ImportProducts(ctx context.Context, rows []ImportProductRow, opts ImportOptions) (res *ImportResult, err error) {
+	res = &ImportResult{
+		Total:  len(rows),
+		DryRun: opts.DryRun,
+		Atomic: opts.Atomic,
+		Errors: []ImportRowError{},
+	}
+
+	valid, err := p.validateImportRows(rows, res)
+	if err != nil {
+		return
+	}
+
+	if opts.Atomic && res.Failed > 0 {
+		return
+	}
+
+	tx, err := p.db.Begin(ctx)
+	if err != nil {
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	var msgs []notify.Message
+	for _, row := range valid {
+		// savepoint, so a failing row does not abort the whole transaction
+		sp, spErr := tx.Begin(ctx)
+		if spErr != nil {
+			err = spErr
+			return
+		}
+
+		var inserted bool
+		rowMsgs, rowErr := p.importRow(ctx, sp, row, &inserted)
+		if rowErr != nil {
+			_ = sp.Rollback(ctx)
+
+			errs := importRowErrors(rowErr)
+			if errs == nil {
+				log.Error().Err(rowErr).Int("line", row.line).Msg("import products")
+				err = rowErr
+				return
+			}
+
+			res.Failed++
+			res.Errors = append(res.Errors, ImportRowError{row.line, row.Sku, errs})
+			continue
+		}
+
+		if err = sp.Commit(ctx); err != nil {
+			return
+		}
+		msgs = append(msgs, rowMsgs...)
+
+		if inserted {
+			res.Created++
+		} else {
+			res.Updated++
+		}
+	}
+
+	if opts.DryRun || (opts.Atomic && res.Failed > 0) {
+		return
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return
+	}
+	res.Applied = true
+	notify.SendAll(ctx, p.notifier, msgs)
+
+	return
+}
+
+// importRowErrors reports the errors of a row that could not be saved by
+// field, nil when the error is not the row's fault.
+func importRowErrors(err error) map[string]string {
+	var pgErr *pgconn.PgError
+	switch {
+	case errors.Is(err, ErrProductNotFound):
+		return map[string]string{"id": "product not found"}
+	case errors.Is(err, ErrDefaultWarehouseStock):
+		return map[string]string{"stock": ErrDefaultWarehouseStock.Error()}
+	case errors.As(err, &pgErr) && pgErr.Code == "23505":
+		return map[string]string{"sku": ErrSkuAlreadyExists.Error()}
+	}
+
+	return nil
+}
+
+// validateImportRows returns the valid rows, counting and reporting the
+// invalid ones in res.
+func (p *ProductDomain) validateImportRows(rows []ImportProductRow, res *ImportResult) (valid []*ImportProductRow, err error) {
+	valid = make([]*ImportProductRow, 0, len(rows))
+	seen := map[string]int{}
+	seenIds := map[int]int{}
+	for i := range rows {
+		row := &rows[i]
+		errs := map[string]string{}
+		for field, msg := range row.parseErrors {
+			errs[field] = msg
+		}
+
+		if verr := p.validator.ValidateStruct(row); verr != nil {
+			var ve *validation.ValidationErrors
+			if !errors.As(verr, &ve) {
+				err = verr
+				return
+			}
+
+			for field, msg := range ve.Transform() {
+				if _, ok := errs[field]; !ok {
+					errs[field] = msg
+				}
+			}
+		}
+
+		if price, perr := row.Price.As(p.currency); perr != nil {
+			errs["price"] = "price must be in " + string(p.currency)
+		} else {
+			row.Price = price
+		}
+
+		switch {
+		case row.Sku != "":
+			if prev, ok := seen[row.Sku]; ok {
+				errs["sku"] = fmt.Sprintf("sku is already used on row %d", prev)
+			} else {
+				seen[row.Sku] = row.line
+			}
+		case row.Id == 0:
+			// a row without sku only updates the product of its id
+			if _, ok := errs["sku"]; !ok {
+				errs["sku"] = "sku is required"
+			}
+		default:
+			if prev, ok := seenIds[row.Id]; ok {
+				errs["id"] = fmt.Sprintf("id is already used on row %d", prev)
+			} else {
+				seenIds[row.Id] = row.line
+			}
+		}
+
+		if len(errs) > 0 {
+			res.Failed++
+			res.Errors = append(res.Errors, ImportRowError{row.line, row.Sku, errs})
+			continue
+		}
+
+		valid = append(valid, row)
+	}
+
+	return
+}
+
+// importUpsertSQL creates or updates the product of the sku $1, returning
+// whether it was created.
+const importUpsertSQL = `insert into products(sku,name,description,image_url,category,stock,price,currency) values ($1,$2,$3,$4,$5,$6,$7,$8)
+	on conflict (sku) do update set name=excluded.name,description=excluded.description,
+	image_url=excluded.image_url,category=excluded.category,stock=excluded.stock,price=excluded.price,
+	currency=excluded.currency,
+	version=products.version+1,updated_at=now()
+	returning (xmax = 0),` + productColumns
+
+// importUpdateSQL updates the product of the id $1, with the arguments of
+// importUpsertSQL.
+const importUpdateSQL = `update products set name=$2,description=$3,image_url=$4,category=$5,
+	stock=$6,price=$7,currency=$8,version=version+1,updated_at=now()
+	where id=$1
+	returning false,` + productColumns
+
+// importRow upserts a row by sku, or updates the product of its id when it
+// has no sku, and records the change in the product history. It returns the
+// back in stock notifications to send once the import is saved.
+func (p *ProductDomain) importRow(ctx context.Context, tx pgx.Tx, row *ImportProductRow, inserted *bool) (msgs []notify.Message, err error) {
+	where, key, query := "sku = $1", any(row.Sku), importUpsertSQL
+	if row.Sku == "" {
+		where, key, query = "id = $1", any(row.Id), importUpdateSQL
+	}
+
+	var before *Product
+	existing := &Product{}
+	err = tx.QueryRow(ctx, "select "+productColumns+" from products where "+where+" for update", key).
+		Scan(existing.scanFields()...)
+	if err == nil {
+		before = existing
+	} else if !errors.Is(err, pgx.ErrNoRows) {
+		return
+	} else if row.Sku == "" {
+		err = ErrProductNotFound
+		return
+	}
+
+	product := &Product{}
+	err = tx.QueryRow(
+		ctx,
+		query,
+		key,
+		row.Name,
+		row.Description,
+		row.ImageUrl,
+		row.Category,
+		row.Stock,
+		row.Price.Amount,
+		row.Price.Currency,
+	).Scan(append([]any{inserted}, product.scanFields()...)...)
+	if err != nil {
+		return
+	}
+
+	if err = recordHistory(ctx, tx, product.Id, nil, HistoryActionImport, diffProducts(before, product)); err != nil {
+		return
+	}
+
+	reason, stock := inventory.ReasonReceipt, 0
+	if before != nil {
+		reason, stock = inventory.ReasonAdjustment, before.Stock
+	}
+
+	if err = recordStockChange(ctx, tx, product.Id, nil, stock, product.Stock, reason, "product import"); err != nil {
+		return
+	}
+
+	if before == nil {
+		return
+	}
+
+	return restocked(ctx, tx, product.Id, nil, before.Stock, product.Stock)
+}
+
+// ExportProducts calls fn for every live product in the catalog, ordered by id,
+// without loading the whole catalog in memory.
+func (p *ProductDomain) ExportProducts(ctx context.Context, fn func(*Product) error) (err error) {
+	rows, err := p.db.Query(ctx, "select "+productColumns+" from products where deleted_at is null order by id")
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		product := &Product{}
+		if err = rows.Scan(product.scanFields()...); err != nil {
+			return
+		}
+
+		if err = fn(product); err != nil {
+			return
+		}
+	}
+
+	return rows.Err()
+}
+
+// ExportProductsCSV writes the catalog in the format read by ParseProductsCSV.
+func (p *ProductDomain) ExportProductsCSV(ctx context.Context, w io.Writer) (err error) {
+	writer := csv.NewWriter(w)
+	if err = writer.Write(ProductCsvHeader); err != nil {
+		return
+	}
+
+	err = p.ExportProducts(ctx, func(product *Product) error {
+		return writer.Write(productCsvRecord(product))
+	})
+	if err != nil {
+		return
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// productCsvRecord is the csv line of a product, see ProductCsvHeader.
+func productCsvRecord(product *Product) []string {
+	return []string{
+		strconv.Itoa(product.Id),
+		product.Sku,
+		product.Name,
+		product.Description,
+		product.ImageUrl,
+		product.Category,
+		strconv.Itoa(product.Stock),
+		strconv.FormatInt(product.Price.Amount, 10),
+		string(product.Price.Currency),
+	}
+}
+
+// ExportProductsJSON writes the catalog as a json array, which
+// ParseProductsJSON reads back.
+func (p *ProductDomain) ExportProductsJSON(ctx context.Context, w io.Writer) (err error) {
+	if _, err = io.WriteString(w, "["); err != nil {
+		return
+	}
+
+	first := true
+	encoder := json.NewEncoder(w)
+	err = p.ExportProducts(ctx, func(product *Product) error {
+		if !first {
+			if _, err := io.WriteString(w, ","); err != nil {
+				return err
+			}
+		}
+		first = false
+
+		return encoder.Encode(product)
+	})
+	if err != nil {
+		return
+	}
+
+	_, err = io.WriteString(w, "]\n")
+	return
+}
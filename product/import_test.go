@@ -0,0 +1,205 @@
+package product
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sypchal/money"
+	"sypchal/validation"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func testProducts() []*Product {
+	return []*Product{
+		{
+			Id:          1,
+			Sku:         "TEE-001",
+			Name:        "Tee",
+			Description: "cotton tee",
+			ImageUrl:    "https://example.com/files/products/tee.jpg",
+			Category:    "apparel",
+			Stock:       12,
+			Price:       money.New(150000, "IDR"),
+		},
+		{
+			// created before skus were required by the import
+			Id:          2,
+			Name:        `Mug "large", blue`,
+			Description: "ceramic\n350 ml",
+			Category:    "kitchen",
+			Price:       money.New(45000, "IDR"),
+		},
+		{
+			Id:          7,
+			Sku:         "CAFÉ-7",
+			Name:        "Kopi",
+			Description: "arabica beans",
+			Stock:       3,
+			Price:       money.New(99000, "IDR"),
+		},
+	}
+}
+
+func exportCSV(t *testing.T, products []*Product) io.Reader {
+	buf := &bytes.Buffer{}
+	writer := csv.NewWriter(buf)
+	if err := writer.Write(ProductCsvHeader); err != nil {
+		t.Fatalf("write header: %v", err)
+	}
+	for _, product := range products {
+		if err := writer.Write(productCsvRecord(product)); err != nil {
+			t.Fatalf("write product: %v", err)
+		}
+	}
+	writer.Flush()
+
+	return buf
+}
+
+func exportJSON(t *testing.T, products []*Product) io.Reader {
+	data, err := json.Marshal(products)
+	if err != nil {
+		t.Fatalf("marshal products: %v", err)
+	}
+
+	return bytes.NewReader(data)
+}
+
+func TestExportImportRoundTrip(t *testing.T) {
+	p := &ProductDomain{validator: validation.NewValidator(), currency: "IDR"}
+
+	tests := []struct {
+		name   string
+		export func(*testing.T, []*Product) io.Reader
+		parse  func(io.Reader) ([]ImportProductRow, error)
+	}{
+		{"csv", exportCSV, ParseProductsCSV},
+		{"json", exportJSON, ParseProductsJSON},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			products := testProducts()
+
+			rows, err := tt.parse(tt.export(t, products))
+			if err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+
+			res := &ImportResult{Total: len(rows), Errors: []ImportRowError{}}
+			valid, err := p.validateImportRows(rows, res)
+			if err != nil {
+				t.Fatalf("validate: %v", err)
+			}
+			if res.Failed != 0 {
+				t.Fatalf("got %d failed rows: %+v", res.Failed, res.Errors)
+			}
+			if len(valid) != len(products) {
+				t.Fatalf("got %d valid rows, want %d", len(valid), len(products))
+			}
+
+			for i, row := range valid {
+				product := products[i]
+				got := []any{row.Id, row.Sku, row.Name, row.Description, row.ImageUrl, row.Category, row.Stock, row.Price}
+				want := []any{product.Id, product.Sku, product.Name, product.Description, product.ImageUrl, product.Category, product.Stock, product.Price}
+				for j := range got {
+					if got[j] != want[j] {
+						t.Errorf("row %d field %d: got %v, want %v", i+1, j, got[j], want[j])
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestValidateImportRows(t *testing.T) {
+	p := &ProductDomain{validator: validation.NewValidator(), currency: "IDR"}
+
+	tests := []struct {
+		name  string
+		csv   string
+		field string
+		msg   string
+	}{
+		{
+			name:  "no sku and no id",
+			csv:   "sku,name,description,price\n,Mug,ceramic,45000\n",
+			field: "sku",
+			msg:   "sku is required",
+		},
+		{
+			name:  "same id twice",
+			csv:   "id,sku,name,description,price\n2,,Mug,ceramic,45000\n2,,Mug,ceramic,46000\n",
+			field: "id",
+			msg:   "id is already used on row 2",
+		},
+		{
+			name:  "same sku twice",
+			csv:   "id,sku,name,description,price\n1,TEE-001,Tee,cotton,150000\n,TEE-001,Tee,cotton,150000\n",
+			field: "sku",
+			msg:   "sku is already used on row 2",
+		},
+		{
+			name:  "id not a number",
+			csv:   "id,sku,name,description,price\nabc,,Mug,ceramic,45000\n",
+			field: "id",
+			msg:   "id must be a number",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, err := ParseProductsCSV(strings.NewReader(tt.csv))
+			if err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+
+			res := &ImportResult{Total: len(rows), Errors: []ImportRowError{}}
+			if _, err := p.validateImportRows(rows, res); err != nil {
+				t.Fatalf("validate: %v", err)
+			}
+			if res.Failed != 1 {
+				t.Fatalf("got %d failed rows, want 1: %+v", res.Failed, res.Errors)
+			}
+			if got := res.Errors[0].Errors[tt.field]; got != tt.msg {
+				t.Errorf("got %s error %q, want %q", tt.field, got, tt.msg)
+			}
+		})
+	}
+}
+
+func TestImportRowErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		err   error
+		field string
+		msg   string
+	}{
+		{"product not found", fmt.Errorf("row: %w", ErrProductNotFound), "id", "product not found"},
+		{"not enough stock", ErrDefaultWarehouseStock, "stock", ErrDefaultWarehouseStock.Error()},
+		{"sku taken", &pgconn.PgError{Code: "23505"}, "sku", ErrSkuAlreadyExists.Error()},
+		{"other database error", &pgconn.PgError{Code: "23503"}, "", ""},
+		{"connection lost", errors.New("conn closed"), "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := importRowErrors(tt.err)
+			if tt.field == "" {
+				if errs != nil {
+					t.Fatalf("got %v, want the import to abort", errs)
+				}
+				return
+			}
+			if got := errs[tt.field]; got != tt.msg {
+				t.Errorf("got %s error %q, want %q", tt.field, got, tt.msg)
+			}
+		})
+	}
+}
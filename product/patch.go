@@ -0,0 +1,106 @@
+package product
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"sypchal/validation"
+)
+
+type patchTarget struct {
+	field    string // go field name, for partial validation
+	dst      any
+	nullable bool
+}
+
+// PatchProductById applies an RFC 7396 JSON merge patch to the product: fields
+// missing from the patch are left alone, null clears optional fields and only
+// the fields present in the patch are validated.
+func (p *ProductDomain) PatchProductById(ctx context.Context, id int, version int, patch []byte) (product *Product, err error) {
+	var members map[string]json.RawMessage
+	if err = json.Unmarshal(patch, &members); err != nil || members == nil {
+		// a merge patch that is not an object replaces the whole resource,
+		// which makes no sense for a product
+		err = ErrInvalidPatch
+		return
+	}
+
+	current, err := p.GetProductById(ctx, id)
+	if err != nil {
+		return
+	}
+
+	if version != AnyVersion && current.Version != version {
+		err = ErrVersionMismatch
+		return
+	}
+
+	req := UpdateProductRequest{
+		Sku:         current.Sku,
+		Name:        current.Name,
+		Description: current.Description,
+		ImageUrl:    current.ImageUrl,
+		Category:    current.Category,
+		TaxClass:    current.TaxClass,
+		Stock:       &current.Stock,
+		Price:       &current.Price,
+		Weight:      current.Weight,
+		Length:      current.Length,
+		Width:       current.Width,
+		Height:      current.Height,
+	}
+
+	targets := map[string]patchTarget{
+		"sku":         {"Sku", &req.Sku, true},
+		"name":        {"Name", &req.Name, false},
+		"description": {"Description", &req.Description, false},
+		"image_url":   {"ImageUrl", &req.ImageUrl, true},
+		"category":    {"Category", &req.Category, true},
+		"tax_class":   {"TaxClass", &req.TaxClass, true},
+		"stock":       {"Stock", req.Stock, false},
+		"price":       {"Price", req.Price, false},
+		"weight":      {"Weight", &req.Weight, false},
+		"length":      {"Length", &req.Length, false},
+		"width":       {"Width", &req.Width, false},
+		"height":      {"Height", &req.Height, false},
+	}
+
+	fields := make([]string, 0, len(members))
+	fieldErrs := map[string]string{}
+	for key, raw := range members {
+		target, ok := targets[key]
+		if !ok {
+			fieldErrs[key] = key + " is not a product field"
+			continue
+		}
+
+		if bytes.Equal(bytes.TrimSpace(raw), []byte("null")) {
+			if !target.nullable {
+				fieldErrs[key] = key + " can not be null"
+				continue
+			}
+
+			// every nullable field is a string, empty means cleared
+			*target.dst.(*string) = ""
+			continue
+		}
+
+		if err := json.Unmarshal(raw, target.dst); err != nil {
+			fieldErrs[key] = key + " has an invalid value"
+			continue
+		}
+
+		fields = append(fields, target.field)
+	}
+
+	if len(fieldErrs) > 0 {
+		err = validation.NewFieldErrors(fieldErrs)
+		return
+	}
+
+	if err = p.validator.ValidatePartial(req, fields...); err != nil {
+		return
+	}
+
+	return p.replaceProduct(ctx, id, current.Version, req)
+}
@@ -0,0 +1,216 @@
+package product
+
+import (
+	"context"
+	"errors"
+	"sypchal/exchange"
+	"sypchal/money"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// ListPrice is the price of a product, or of one of its variants, in another
+// currency than the catalog. Without one the catalog price is converted at the
+// exchange rate.
+type ListPrice struct {
+	Id        int         `json:"id"`
+	ProductId int         `json:"product_id"`
+	VariantId *int        `json:"variant_id"`
+	Price     money.Money `json:"price"`
+	CreatedAt time.Time   `json:"created_at"`
+	UpdatedAt *time.Time  `json:"updated_at"`
+}
+
+const listPriceColumns = "id,product_id,variant_id,row(price,currency),created_at,updated_at"
+
+// scanFields returns the destinations matching listPriceColumns.
+func (price *ListPrice) scanFields() []any {
+	return []any{
+		&price.Id,
+		&price.ProductId,
+		&price.VariantId,
+		&price.Price,
+		&price.CreatedAt,
+		&price.UpdatedAt,
+	}
+}
+
+type SetListPriceRequest struct {
+	VariantId *int        `json:"variant_id"`
+	Price     money.Money `json:"price" validate:"gt=0"`
+}
+
+// SetListPrice creates or replaces the price of the product, or of a variant,
+// in the currency of the price.
+func (p *ProductDomain) SetListPrice(ctx context.Context, productId int, req SetListPriceRequest) (price *ListPrice, err error) {
+	if err = p.validator.ValidateStruct(req); err != nil {
+		return
+	}
+
+	if req.Price.Currency == "" || req.Price.Currency == p.currency {
+		err = ErrListPriceCurrency
+		return
+	}
+
+	if !p.IsProductExists(ctx, productId) {
+		err = ErrProductNotFound
+		return
+	}
+
+	if req.VariantId != nil {
+		if _, err = getVariant(ctx, p.db, productId, *req.VariantId); err != nil {
+			return
+		}
+	}
+
+	price = &ListPrice{}
+	err = p.db.QueryRow(
+		ctx,
+		`insert into product_prices(product_id,variant_id,currency,price) values ($1,$2,$3,$4)
+		on conflict (product_id,coalesce(variant_id,0),currency) do update set price=excluded.price,updated_at=now()
+		returning `+listPriceColumns,
+		productId,
+		req.VariantId,
+		req.Price.Currency,
+		req.Price.Amount,
+	).Scan(price.scanFields()...)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+			err = ErrVariantNotFound
+		}
+		return
+	}
+
+	return
+}
+
+func (p *ProductDomain) DeleteListPrice(ctx context.Context, productId int, priceId int) (err error) {
+	tag, err := p.db.Exec(ctx, "delete from product_prices where id=$1 and product_id=$2", priceId, productId)
+	if err != nil {
+		return
+	}
+
+	if tag.RowsAffected() == 0 {
+		err = ErrListPriceNotFound
+		return
+	}
+
+	return
+}
+
+// GetListPrices lists the prices of the product and its variants by currency.
+func (p *ProductDomain) GetListPrices(ctx context.Context, productId int) (prices []*ListPrice, err error) {
+	rows, err := p.db.Query(
+		ctx,
+		"select "+listPriceColumns+" from product_prices where product_id=$1 order by currency,variant_id nulls first",
+		productId,
+	)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	prices = []*ListPrice{}
+	for rows.Next() {
+		price := &ListPrice{}
+		if err = rows.Scan(price.scanFields()...); err != nil {
+			return
+		}
+		prices = append(prices, price)
+	}
+	if err = rows.Err(); err != nil {
+		return
+	}
+
+	return
+}
+
+// Localize sets the LocalPrice of the products and their variants in currency:
+// their list price, or else their price converted at the rates. A variant
+// without a price of its own takes the list price of its product. Nothing is
+// set in the catalog currency.
+func (p *ProductDomain) Localize(ctx context.Context, rates *exchange.Rates, currency money.Currency, products ...*Product) (err error) {
+	if currency == p.currency || len(products) == 0 {
+		return
+	}
+
+	ids := make([]int, 0, len(products))
+	for _, product := range products {
+		ids = append(ids, product.Id)
+	}
+
+	rows, err := p.db.Query(
+		ctx,
+		"select product_id,coalesce(variant_id,0),price from product_prices where currency=$1 and product_id=any($2)",
+		currency,
+		ids,
+	)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	// list prices by product then variant id, 0 for the product
+	listPrices := map[int]map[int]money.Money{}
+	for rows.Next() {
+		var productId, variantId int
+		var amount int64
+		if err = rows.Scan(&productId, &variantId, &amount); err != nil {
+			return
+		}
+		if listPrices[productId] == nil {
+			listPrices[productId] = map[int]money.Money{}
+		}
+		listPrices[productId][variantId] = money.New(amount, currency)
+	}
+	if err = rows.Err(); err != nil {
+		return
+	}
+
+	for _, product := range products {
+		local, ok := listPrices[product.Id][0]
+		if !ok {
+			if local, err = rates.Convert(product.Price, currency); err != nil {
+				return
+			}
+		}
+		product.LocalPrice = &local
+
+		for _, variant := range product.Variants {
+			variantLocal, ok := listPrices[product.Id][variant.Id]
+			if !ok && variant.Price == nil {
+				variantLocal, ok = local, true
+			}
+			if !ok {
+				if variantLocal, err = rates.Convert(variant.UnitPrice, currency); err != nil {
+					return
+				}
+			}
+			variant.LocalPrice = &variantLocal
+		}
+	}
+
+	return
+}
+
+// LocalizeVariants sets the LocalPrice of variants of a product in currency,
+// see Localize.
+func (p *ProductDomain) LocalizeVariants(ctx context.Context, rates *exchange.Rates, currency money.Currency, productId int, variants []*Variant) (err error) {
+	if currency == p.currency || len(variants) == 0 {
+		return
+	}
+
+	product := &Product{Id: productId, Variants: variants}
+	err = p.db.QueryRow(ctx, "select row(price,currency) from products where id=$1", productId).Scan(&product.Price)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			err = ErrProductNotFound
+		}
+		return
+	}
+
+	return p.Localize(ctx, rates, currency, product)
+}
@@ -5,9 +5,11 @@ import (
 	"errors"
 	"fmt"
 	"math"
-	"reflect"
 	"strconv"
 	"strings"
+	"sypchal/inventory"
+	"sypchal/money"
+	"sypchal/notify"
 	"sypchal/validation"
 	"time"
 
@@ -17,35 +19,94 @@ import (
 type ProductDomain struct {
 	db        *pgx.Conn
 	validator *validation.Validator
+	notifier  notify.Notifier
+	// currency is the currency of the catalog prices
+	currency money.Currency
 }
 
-func NewProductDomain(db *pgx.Conn, validator *validation.Validator) (*ProductDomain, error) {
+func NewProductDomain(db *pgx.Conn, validator *validation.Validator, notifier notify.Notifier, currency money.Currency) (*ProductDomain, error) {
 	if db == nil {
 		return nil, errors.New("db is nil")
 	}
 
-	return &ProductDomain{db, validator}, nil
+	return &ProductDomain{db, validator, notifier, currency}, nil
 }
 
 type Product struct {
-	Id          int        `json:"id"`
-	Name        string     `json:"name"`
-	Description string     `json:"description"`
-	ImageUrl    string     `json:"image_url"`
-	Category    string     `json:"category"`
-	Stock       int        `json:"stock"`
-	Price       int        `json:"price"`
-	CreatedAt   time.Time  `json:"created_at"`
-	UpdatedAt   *time.Time `json:"updated_at"`
+	Id          int         `json:"id"`
+	Sku         string      `json:"sku"`
+	Name        string      `json:"name"`
+	Description string      `json:"description"`
+	ImageUrl    string      `json:"image_url"`
+	Category    string      `json:"category"`
+	TaxClass    string      `json:"tax_class"`
+	Stock       int         `json:"stock"`
+	Price       money.Money `json:"price"`
+	// LocalPrice is the price in the currency of the customer, see Localize.
+	LocalPrice *money.Money `json:"local_price,omitempty"`
+	// Weight, in grams, prices the shipping. Length, Width and Height are in
+	// millimeters.
+	Weight    int        `json:"weight"`
+	Length    int        `json:"length"`
+	Width     int        `json:"width"`
+	Height    int        `json:"height"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt *time.Time `json:"updated_at"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	Version   int        `json:"version"`
+	// RatingAverage and RatingCount summarize the approved reviews.
+	RatingAverage float64 `json:"rating_average"`
+	RatingCount   int     `json:"rating_count"`
+
+	Options  []*ProductOption `json:"options,omitempty"`
+	Variants []*Variant       `json:"variants,omitempty"`
+	Viewer   *Viewer          `json:"viewer,omitempty"`
+}
+
+const productColumns = "id,coalesce(sku,''),name,description,image_url,category,tax_class,stock,row(price,currency),weight,length,width,height,created_at,updated_at,deleted_at,version," +
+	"rating_average::float8,rating_count"
+
+// AnyVersion skips the version check of product changes.
+const AnyVersion = 0
+
+// scanFields returns the destinations matching productColumns.
+func (product *Product) scanFields() []any {
+	return []any{
+		&product.Id,
+		&product.Sku,
+		&product.Name,
+		&product.Description,
+		&product.ImageUrl,
+		&product.Category,
+		&product.TaxClass,
+		&product.Stock,
+		&product.Price,
+		&product.Weight,
+		&product.Length,
+		&product.Width,
+		&product.Height,
+		&product.CreatedAt,
+		&product.UpdatedAt,
+		&product.DeletedAt,
+		&product.Version,
+		&product.RatingAverage,
+		&product.RatingCount,
+	}
 }
 
 type CreateProductRequest struct {
-	Name        string `json:"name" validate:"required"`
-	Description string `json:"description" validate:"required"`
-	ImageUrl    string `json:"image_url"`
-	Category    string `json:"category"`
-	Stock       int    `json:"stock" validate:"required"`
-	Price       int    `json:"price" validate:"required"`
+	Sku         string      `json:"sku" validate:"max=64"`
+	Name        string      `json:"name" validate:"required"`
+	Description string      `json:"description" validate:"required"`
+	ImageUrl    string      `json:"image_url"`
+	Category    string      `json:"category"`
+	TaxClass    string      `json:"tax_class" validate:"max=32"` // standard when empty
+	Stock       int         `json:"stock" validate:"required"`
+	Price       money.Money `json:"price" validate:"required"`
+	Weight      int         `json:"weight" validate:"gte=0"` // grams
+	Length      int         `json:"length" validate:"gte=0"` // millimeters
+	Width       int         `json:"width" validate:"gte=0"`
+	Height      int         `json:"height" validate:"gte=0"`
 }
 
 func (p *ProductDomain) CreateProduct(ctx context.Context, req CreateProductRequest) (product *Product, err error) {
@@ -53,45 +114,82 @@ func (p *ProductDomain) CreateProduct(ctx context.Context, req CreateProductRequ
 		return
 	}
 
+	if p.isSkuTaken(ctx, req.Sku, 0) {
+		err = ErrSkuAlreadyExists
+		return
+	}
+
+	price, err := validation.InCurrency("price", req.Price, p.currency)
+	if err != nil {
+		return
+	}
+
+	tx, err := p.db.Begin(ctx)
+	if err != nil {
+		return
+	}
+	defer tx.Rollback(ctx)
+
 	product = &Product{}
-	err = p.db.QueryRow(
+	err = tx.QueryRow(
 		ctx,
-		`insert into products(name,description,image_url,category,stock,price) values ($1,$2,$3,$4,$5,$6) 
-		returning id,name,description,image_url,category,stock,price,created_at,updated_at`,
+		`insert into products(sku,name,description,image_url,category,tax_class,stock,price,currency,weight,length,width,height)
+		values (nullif($1,''),$2,$3,$4,$5,coalesce(nullif($6,''),'standard'),$7,$8,$9,$10,$11,$12,$13) 
+		returning `+productColumns,
+		req.Sku,
 		req.Name,
 		req.Description,
 		req.ImageUrl,
 		req.Category,
+		req.TaxClass,
 		req.Stock,
-		req.Price,
-	).Scan(
-		&product.Id,
-		&product.Name,
-		&product.Description,
-		&product.ImageUrl,
-		&product.Category,
-		&product.Stock,
-		&product.Price,
-		&product.CreatedAt,
-		&product.UpdatedAt,
-	)
+		price.Amount,
+		price.Currency,
+		req.Weight,
+		req.Length,
+		req.Width,
+		req.Height,
+	).Scan(product.scanFields()...)
+	if err != nil {
+		return
+	}
+
+	err = recordHistory(ctx, tx, product.Id, nil, HistoryActionCreate, diffProducts(nil, product))
+	if err != nil {
+		return
+	}
+
+	err = recordStockChange(ctx, tx, product.Id, nil, 0, product.Stock, inventory.ReasonReceipt, "product create")
 	if err != nil {
 		return
 	}
 
+	if err = tx.Commit(ctx); err != nil {
+		return
+	}
+
 	return
 }
 
 type UpdateProductRequest struct {
-	Name        string `json:"name,omitempty"`
-	Description string `json:"description,omitempty"`
-	ImageUrl    string `json:"image_url,omitempty"`
-	Category    string `json:"category,omitempty"`
-	Stock       int    `json:"stock,omitempty"`
-	Price       int    `json:"price,omitempty"`
+	Sku         string       `json:"sku" validate:"max=64"`
+	Name        string       `json:"name" validate:"required"`
+	Description string       `json:"description" validate:"required"`
+	ImageUrl    string       `json:"image_url"`
+	Category    string       `json:"category"`
+	TaxClass    string       `json:"tax_class" validate:"max=32"` // standard when empty
+	Stock       *int         `json:"stock" validate:"required,gte=0"`
+	Price       *money.Money `json:"price" validate:"required,gt=0"`
+	Weight      int          `json:"weight" validate:"gte=0"` // grams
+	Length      int          `json:"length" validate:"gte=0"` // millimeters
+	Width       int          `json:"width" validate:"gte=0"`
+	Height      int          `json:"height" validate:"gte=0"`
 }
 
-func (p *ProductDomain) UpdateProductById(ctx context.Context, id int, req UpdateProductRequest) (product *Product, err error) {
+// UpdateProductById replaces every field of the product, optional fields left
+// empty are cleared. Use PatchProductById to change only some fields. It fails
+// with ErrVersionMismatch when the product is no longer at version.
+func (p *ProductDomain) UpdateProductById(ctx context.Context, id int, version int, req UpdateProductRequest) (product *Product, err error) {
 	if err = p.validator.ValidateStruct(req); err != nil {
 		return
 	}
@@ -102,93 +200,201 @@ func (p *ProductDomain) UpdateProductById(ctx context.Context, id int, req Updat
 		return
 	}
 
-	product = &Product{}
-	fields := make([]string, 0, 6)
-	args := make([]interface{}, 0, 7) // +1 for id
-	rv := reflect.ValueOf(req)
+	return p.replaceProduct(ctx, id, version, req)
+}
+
+// replaceProduct writes every field of an already validated request.
+func (p *ProductDomain) replaceProduct(ctx context.Context, id int, version int, req UpdateProductRequest) (product *Product, err error) {
+	if p.isSkuTaken(ctx, req.Sku, id) {
+		err = ErrSkuAlreadyExists
+		return
+	}
+
+	price, err := validation.InCurrency("price", *req.Price, p.currency)
+	if err != nil {
+		return
+	}
 
-	if rv.NumField() < 1 {
-		err = errors.New("please fill atleast one field")
+	tx, err := p.db.Begin(ctx)
+	if err != nil {
 		return
 	}
+	defer tx.Rollback(ctx)
 
-	if req.Name != "" {
-		fields = append(fields, "name=$"+strconv.Itoa(len(fields)+1))
-		args = append(args, req.Name)
+	before, err := lockProduct(ctx, tx, id, version)
+	if err != nil {
+		return
 	}
 
-	if req.Description != "" {
-		fields = append(fields, "description=$"+strconv.Itoa(len(fields)+1))
-		args = append(args, req.Description)
+	product = &Product{}
+	err = tx.QueryRow(
+		ctx,
+		`update products set sku=nullif($1,''),name=$2,description=$3,image_url=$4,category=$5,
+		tax_class=coalesce(nullif($6,''),'standard'),stock=$7,price=$8,currency=$9,weight=$10,length=$11,width=$12,height=$13,
+		version=version+1,updated_at=now() where id = $14
+		returning `+productColumns,
+		req.Sku,
+		req.Name,
+		req.Description,
+		req.ImageUrl,
+		req.Category,
+		req.TaxClass,
+		*req.Stock,
+		price.Amount,
+		price.Currency,
+		req.Weight,
+		req.Length,
+		req.Width,
+		req.Height,
+		id,
+	).Scan(product.scanFields()...)
+	if err != nil {
+		return
 	}
 
-	if req.ImageUrl != "" {
-		fields = append(fields, "image_url=$"+strconv.Itoa(len(fields)+1))
-		args = append(args, req.ImageUrl)
+	err = recordHistory(ctx, tx, id, nil, HistoryActionUpdate, diffProducts(before, product))
+	if err != nil {
+		return
 	}
 
-	if req.Category != "" {
-		fields = append(fields, "category=$"+strconv.Itoa(len(fields)+1))
-		args = append(args, req.Category)
+	err = recordStockChange(ctx, tx, id, nil, before.Stock, product.Stock, inventory.ReasonAdjustment, "product update")
+	if err != nil {
+		return
 	}
 
-	if req.Stock != 0 {
-		fields = append(fields, "stock=$"+strconv.Itoa(len(fields)+1))
-		args = append(args, req.Stock)
+	msgs, err := restocked(ctx, tx, id, nil, before.Stock, product.Stock)
+	if err != nil {
+		return
 	}
 
-	if req.Price != 0 {
-		fields = append(fields, "price=$"+strconv.Itoa(len(fields)+1))
-		args = append(args, req.Price)
+	if err = tx.Commit(ctx); err != nil {
+		return
 	}
+	notify.SendAll(ctx, p.notifier, msgs)
 
-	args = append(args, id)
-	err = p.db.QueryRow(
+	return
+}
+
+// lockProduct reads a live product for update, making sure it is still at
+// version.
+func lockProduct(ctx context.Context, tx pgx.Tx, id int, version int) (product *Product, err error) {
+	product = &Product{}
+	err = tx.QueryRow(
 		ctx,
-		fmt.Sprintf(
-			`update products set %s,updated_at=now() where id = $%d
-			returning id,name,description,image_url,category,stock,price,created_at,updated_at`,
-			strings.Join(fields, ","),
-			len(args),
-		),
-		args...,
-	).Scan(
-		&product.Id,
-		&product.Name,
-		&product.Description,
-		&product.ImageUrl,
-		&product.Category,
-		&product.Stock,
-		&product.Price,
-		&product.CreatedAt,
-		&product.UpdatedAt,
-	)
+		"select "+productColumns+" from products where id = $1 and deleted_at is null for update",
+		id,
+	).Scan(product.scanFields()...)
 	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			err = ErrProductNotFound
+		}
+		return
+	}
+
+	if version != AnyVersion && product.Version != version {
+		err = ErrVersionMismatch
 		return
 	}
 
 	return
 }
 
+// IsProductExists reports whether the product exists and is not archived.
 func (p *ProductDomain) IsProductExists(ctx context.Context, id int) bool {
 	var count int
-	_ = p.db.QueryRow(ctx, "select count(*) from products where id = $1", id).Scan(&count)
+	_ = p.db.QueryRow(ctx, "select count(*) from products where id = $1 and deleted_at is null", id).Scan(&count)
 
 	return count > 0
 }
 
-func (p *ProductDomain) DeleteProductById(ctx context.Context, id int) (err error) {
-	exists := p.IsProductExists(ctx, id)
-	if !exists {
-		err = ErrProductNotFound
+// isSkuTaken reports whether another product than exceptId already uses sku.
+func (p *ProductDomain) isSkuTaken(ctx context.Context, sku string, exceptId int) bool {
+	if sku == "" {
+		return false
+	}
+
+	var count int
+	_ = p.db.QueryRow(ctx, "select count(*) from products where sku = $1 and id <> $2", sku, exceptId).Scan(&count)
+
+	return count > 0
+}
+
+// DeleteProductById archives the product. It disappears from listings and
+// carts but stays linked to the orders it was bought in until it is purged.
+func (p *ProductDomain) DeleteProductById(ctx context.Context, id int, version int) (err error) {
+	tx, err := p.db.Begin(ctx)
+	if err != nil {
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err = lockProduct(ctx, tx, id, version); err != nil {
+		return
+	}
+
+	_, err = tx.Exec(ctx, "update products set deleted_at=now(),version=version+1 where id = $1", id)
+	if err != nil {
+		return
+	}
+
+	if err = recordHistory(ctx, tx, id, nil, HistoryActionArchive, nil); err != nil {
+		return
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return
+	}
+
+	return
+}
+
+func (p *ProductDomain) RestoreProductById(ctx context.Context, id int) (product *Product, err error) {
+	tx, err := p.db.Begin(ctx)
+	if err != nil {
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	product = &Product{}
+	err = tx.QueryRow(
+		ctx,
+		`update products set deleted_at=null,version=version+1,updated_at=now() where id = $1 and deleted_at is not null
+		returning `+productColumns,
+		id,
+	).Scan(product.scanFields()...)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			err = ErrProductNotArchived
+		}
+		return
+	}
+
+	if err = recordHistory(ctx, tx, id, nil, HistoryActionRestore, nil); err != nil {
+		return
+	}
+
+	if err = tx.Commit(ctx); err != nil {
 		return
 	}
 
-	_, err = p.db.Exec(ctx, "delete from products where id = $1", id)
+	return
+}
+
+// PurgeArchivedProducts permanently deletes products archived before the
+// retention period, returning how many were deleted. Orders keep their lines,
+// but the lines no longer link to the product. The product history and the
+// stock movements are kept.
+func (p *ProductDomain) PurgeArchivedProducts(ctx context.Context, retention time.Duration) (count int64, err error) {
+	tag, err := p.db.Exec(
+		ctx,
+		"delete from products where deleted_at < now() - $1::interval",
+		retention,
+	)
 	if err != nil {
 		return
 	}
 
+	count = tag.RowsAffected()
 	return
 }
 
@@ -196,10 +402,23 @@ type ProductList []*Product
 
 type GetProductFilter struct {
 	Category string
+	// Archived lists archived products instead of live ones.
+	Archived bool
+}
+
+// SortRating lists the best rated products first.
+const SortRating = "rating"
+
+// productSorts maps the sorts of GetProductsRequest to their order by.
+var productSorts = map[string]string{
+	"":         "id",
+	SortRating: "rating_average desc, rating_count desc, id",
 }
 
 type GetProductsRequest struct {
 	Filter *GetProductFilter
+	// Sort is empty to list by id, or SortRating.
+	Sort   string
 	Limit  int
 	Offset int
 }
@@ -213,17 +432,29 @@ type GetProductResponse struct {
 func (p *ProductDomain) GetProducts(ctx context.Context, req GetProductsRequest) (res *GetProductResponse, err error) {
 	args := make([]interface{}, 0, 3)
 	args = append(args, req.Limit, req.Offset)
-	whereClause := ""
+	conditions := []string{"deleted_at is null"}
+
+	if req.Filter != nil && req.Filter.Archived {
+		conditions[0] = "deleted_at is not null"
+	}
 
 	if req.Filter != nil && req.Filter.Category != "" {
-		whereClause = "where category=$" + strconv.Itoa(len(args)+1)
+		conditions = append(conditions, "category=$"+strconv.Itoa(len(args)+1))
 		args = append(args, req.Filter.Category)
 	}
 
+	whereClause := "where " + strings.Join(conditions, " and ")
+
+	orderBy, ok := productSorts[req.Sort]
+	if !ok {
+		err = ErrInvalidSort
+		return
+	}
+
 	rows, err := p.db.Query(
 		ctx,
-		fmt.Sprintf(`select count(*) over(), id,name,description,image_url,category,stock,price,created_at,updated_at 
-		from products %s order by id limit $1 offset $2`, whereClause),
+		fmt.Sprintf(`select count(*) over(), %s 
+		from products %s order by %s limit $1 offset $2`, productColumns, whereClause, orderBy),
 		args...,
 	)
 	if err != nil {
@@ -236,18 +467,7 @@ func (p *ProductDomain) GetProducts(ctx context.Context, req GetProductsRequest)
 	productList := ProductList{}
 	for rows.Next() {
 		product := &Product{}
-		rows.Scan(
-			&total,
-			&product.Id,
-			&product.Name,
-			&product.Description,
-			&product.ImageUrl,
-			&product.Category,
-			&product.Stock,
-			&product.Price,
-			&product.CreatedAt,
-			&product.UpdatedAt,
-		)
+		rows.Scan(append([]any{&total}, product.scanFields()...)...)
 		productList = append(productList, product)
 	}
 
@@ -262,20 +482,10 @@ func (p *ProductDomain) GetProductById(ctx context.Context, id int) (product *Pr
 	product = &Product{}
 	err = p.db.QueryRow(
 		ctx,
-		`select id,name,description,image_url,category,stock,price,created_at,updated_at 
-		from products where id = $1`,
+		`select `+productColumns+` 
+		from products where id = $1 and deleted_at is null`,
 		id,
-	).Scan(
-		&product.Id,
-		&product.Name,
-		&product.Description,
-		&product.ImageUrl,
-		&product.Category,
-		&product.Stock,
-		&product.Price,
-		&product.CreatedAt,
-		&product.UpdatedAt,
-	)
+	).Scan(product.scanFields()...)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			err = ErrProductNotFound
@@ -284,5 +494,15 @@ func (p *ProductDomain) GetProductById(ctx context.Context, id int) (product *Pr
 		return
 	}
 
+	product.Options, err = getProductOptions(ctx, p.db, id)
+	if err != nil {
+		return
+	}
+
+	product.Variants, err = getProductVariants(ctx, p.db, id)
+	if err != nil {
+		return
+	}
+
 	return
 }
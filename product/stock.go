@@ -0,0 +1,44 @@
+package product
+
+import (
+	"context"
+	"errors"
+	"sypchal/alert"
+	"sypchal/inventory"
+	"sypchal/notify"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// recordStockChange moves the difference between the stock before and after a
+// product or variant change in or out of the default warehouse and adds it to
+// the inventory ledger.
+func recordStockChange(ctx context.Context, tx pgx.Tx, productId int, variantId *int, from, to int, reason, reference string) error {
+	if from == to {
+		return nil
+	}
+
+	err := inventory.Record(ctx, tx, &inventory.Movement{
+		ProductId: productId,
+		VariantId: variantId,
+		Quantity:  to - from,
+		Reason:    reason,
+		Reference: reference,
+	})
+	if errors.Is(err, inventory.ErrInsufficientStock) {
+		err = ErrDefaultWarehouseStock
+	}
+
+	return err
+}
+
+// restocked returns the back in stock notifications to send once tx is
+// committed, when a product or variant that was out of stock is in stock
+// again.
+func restocked(ctx context.Context, tx pgx.Tx, productId int, variantId *int, from, to int) ([]notify.Message, error) {
+	if from > 0 || to <= 0 {
+		return nil, nil
+	}
+
+	return alert.ClaimBackInStock(ctx, tx, productId, variantId)
+}
@@ -0,0 +1,576 @@
+package product
+
+import (
+	"context"
+	"errors"
+	"sypchal/inventory"
+	"sypchal/money"
+	"sypchal/notify"
+	"sypchal/validation"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+type ProductOption struct {
+	Id        int        `json:"id"`
+	ProductId int        `json:"product_id"`
+	Name      string     `json:"name"`
+	Values    []string   `json:"values"`
+	Position  int        `json:"position"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt *time.Time `json:"updated_at"`
+}
+
+type Variant struct {
+	Id        int               `json:"id"`
+	ProductId int               `json:"product_id"`
+	Sku       string            `json:"sku"`
+	Options   map[string]string `json:"options"`
+	Stock     int               `json:"stock"`
+	// Price overrides the product price when not nil.
+	Price *money.Money `json:"price"`
+	// UnitPrice is the price actually charged for this variant.
+	UnitPrice money.Money `json:"unit_price"`
+	// LocalPrice is UnitPrice in the currency of the customer, see Localize.
+	LocalPrice *money.Money `json:"local_price,omitempty"`
+	ImageUrl   string       `json:"image_url"`
+	CreatedAt  time.Time    `json:"created_at"`
+	UpdatedAt  *time.Time   `json:"updated_at"`
+}
+
+type ProductOptionInput struct {
+	Name   string   `json:"name" validate:"required"`
+	Values []string `json:"values" validate:"required,min=1,dive,required"`
+}
+
+type SetProductOptionsRequest struct {
+	Options []ProductOptionInput `json:"options" validate:"dive"`
+}
+
+// SetProductOptions replaces the option axes (size, color, ...) of a product.
+// It fails with ErrOptionsInUse when an existing variant would no longer match
+// the new axes.
+func (p *ProductDomain) SetProductOptions(ctx context.Context, productId int, req SetProductOptionsRequest) (options []*ProductOption, err error) {
+	if err = p.validator.ValidateStruct(req); err != nil {
+		return
+	}
+
+	seen := map[string]bool{}
+	for _, opt := range req.Options {
+		if seen[opt.Name] {
+			err = ErrInvalidVariantOptions
+			return
+		}
+		seen[opt.Name] = true
+	}
+
+	exists := p.IsProductExists(ctx, productId)
+	if !exists {
+		err = ErrProductNotFound
+		return
+	}
+
+	tx, err := p.db.Begin(ctx)
+	if err != nil {
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	previous, err := getProductOptions(ctx, tx, productId)
+	if err != nil {
+		return
+	}
+
+	if _, err = tx.Exec(ctx, "delete from product_options where product_id=$1", productId); err != nil {
+		return
+	}
+
+	options = []*ProductOption{}
+	for i, opt := range req.Options {
+		option := &ProductOption{}
+		err = tx.QueryRow(
+			ctx,
+			`insert into product_options(product_id,name,values,position) values ($1,$2,$3,$4)
+			returning id,product_id,name,values,position,created_at,updated_at`,
+			productId,
+			opt.Name,
+			opt.Values,
+			i,
+		).Scan(
+			&option.Id,
+			&option.ProductId,
+			&option.Name,
+			&option.Values,
+			&option.Position,
+			&option.CreatedAt,
+			&option.UpdatedAt,
+		)
+		if err != nil {
+			return
+		}
+		options = append(options, option)
+	}
+
+	variants, err := getProductVariants(ctx, tx, productId)
+	if err != nil {
+		return
+	}
+
+	for _, variant := range variants {
+		if !matchOptions(options, variant.Options) {
+			err = ErrOptionsInUse
+			return
+		}
+	}
+
+	if err = bumpVersion(ctx, tx, productId); err != nil {
+		return
+	}
+
+	err = recordHistory(ctx, tx, productId, nil, HistoryActionOptions, map[string]Change{
+		"options": {optionValues(previous), optionValues(options)},
+	})
+	if err != nil {
+		return
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return
+	}
+
+	return
+}
+
+func (p *ProductDomain) GetProductOptions(ctx context.Context, productId int) (options []*ProductOption, err error) {
+	return getProductOptions(ctx, p.db, productId)
+}
+
+type CreateVariantRequest struct {
+	Sku      string            `json:"sku" validate:"required,max=64"`
+	Options  map[string]string `json:"options"`
+	Stock    int               `json:"stock" validate:"gte=0"`
+	Price    *money.Money      `json:"price" validate:"omitempty,gt=0"`
+	ImageUrl string            `json:"image_url"`
+}
+
+func (p *ProductDomain) CreateVariant(ctx context.Context, productId int, req CreateVariantRequest) (variant *Variant, err error) {
+	if err = p.validator.ValidateStruct(req); err != nil {
+		return
+	}
+
+	tx, err := p.db.Begin(ctx)
+	if err != nil {
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	if err = p.checkVariant(ctx, tx, productId, 0, req.Sku, req.Options); err != nil {
+		return
+	}
+
+	if req.Options == nil {
+		req.Options = map[string]string{}
+	}
+
+	price, err := p.variantPrice(req.Price)
+	if err != nil {
+		return
+	}
+
+	var id int
+	err = tx.QueryRow(
+		ctx,
+		`insert into product_variants(product_id,sku,options,stock,price,image_url) values ($1,$2,$3,$4,$5,$6)
+		returning id`,
+		productId,
+		req.Sku,
+		req.Options,
+		req.Stock,
+		price,
+		req.ImageUrl,
+	).Scan(&id)
+	if err != nil {
+		return
+	}
+
+	variant, err = getVariant(ctx, tx, productId, id)
+	if err != nil {
+		return
+	}
+
+	if err = bumpVersion(ctx, tx, productId); err != nil {
+		return
+	}
+
+	err = recordHistory(ctx, tx, productId, &variant.Id, HistoryActionVariantCreate, diffVariants(nil, variant))
+	if err != nil {
+		return
+	}
+
+	err = recordStockChange(ctx, tx, productId, &variant.Id, 0, variant.Stock, inventory.ReasonReceipt, "variant create")
+	if err != nil {
+		return
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return
+	}
+
+	return
+}
+
+type UpdateVariantRequest CreateVariantRequest
+
+// UpdateVariantById replaces every field of the variant.
+func (p *ProductDomain) UpdateVariantById(ctx context.Context, productId int, variantId int, req UpdateVariantRequest) (variant *Variant, err error) {
+	if err = p.validator.ValidateStruct(req); err != nil {
+		return
+	}
+
+	tx, err := p.db.Begin(ctx)
+	if err != nil {
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	before, err := getVariant(ctx, tx, productId, variantId)
+	if err != nil {
+		return
+	}
+
+	if err = p.checkVariant(ctx, tx, productId, variantId, req.Sku, req.Options); err != nil {
+		return
+	}
+
+	if req.Options == nil {
+		req.Options = map[string]string{}
+	}
+
+	price, err := p.variantPrice(req.Price)
+	if err != nil {
+		return
+	}
+
+	_, err = tx.Exec(
+		ctx,
+		`update product_variants set sku=$1,options=$2,stock=$3,price=$4,image_url=$5,updated_at=now()
+		where id=$6 and product_id=$7`,
+		req.Sku,
+		req.Options,
+		req.Stock,
+		price,
+		req.ImageUrl,
+		variantId,
+		productId,
+	)
+	if err != nil {
+		return
+	}
+
+	variant, err = getVariant(ctx, tx, productId, variantId)
+	if err != nil {
+		return
+	}
+
+	if err = bumpVersion(ctx, tx, productId); err != nil {
+		return
+	}
+
+	err = recordHistory(ctx, tx, productId, &variantId, HistoryActionVariantUpdate, diffVariants(before, variant))
+	if err != nil {
+		return
+	}
+
+	err = recordStockChange(ctx, tx, productId, &variantId, before.Stock, variant.Stock, inventory.ReasonAdjustment, "variant update")
+	if err != nil {
+		return
+	}
+
+	msgs, err := restocked(ctx, tx, productId, &variantId, before.Stock, variant.Stock)
+	if err != nil {
+		return
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return
+	}
+	notify.SendAll(ctx, p.notifier, msgs)
+
+	return
+}
+
+func (p *ProductDomain) DeleteVariantById(ctx context.Context, productId int, variantId int) (err error) {
+	tx, err := p.db.Begin(ctx)
+	if err != nil {
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	before, err := getVariant(ctx, tx, productId, variantId)
+	if err != nil {
+		return
+	}
+
+	_, err = tx.Exec(ctx, "delete from product_variants where id=$1 and product_id=$2", variantId, productId)
+	if err != nil {
+		return
+	}
+
+	if err = bumpVersion(ctx, tx, productId); err != nil {
+		return
+	}
+
+	err = recordHistory(ctx, tx, productId, &variantId, HistoryActionVariantDelete, diffVariants(before, nil))
+	if err != nil {
+		return
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return
+	}
+
+	return
+}
+
+type execer interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+}
+
+// bumpVersion changes the product etag after its options or variants changed.
+func bumpVersion(ctx context.Context, db execer, productId int) error {
+	_, err := db.Exec(ctx, "update products set version=version+1,updated_at=now() where id=$1", productId)
+	return err
+}
+
+func (p *ProductDomain) GetProductVariants(ctx context.Context, productId int) (variants []*Variant, err error) {
+	exists := p.IsProductExists(ctx, productId)
+	if !exists {
+		err = ErrProductNotFound
+		return
+	}
+
+	return getProductVariants(ctx, p.db, productId)
+}
+
+// variantPrice returns the amount of a variant price override in the catalog
+// currency, nil when the variant uses the product price.
+func (p *ProductDomain) variantPrice(price *money.Money) (amount *int64, err error) {
+	if price == nil {
+		return
+	}
+
+	m, err := validation.InCurrency("price", *price, p.currency)
+	if err != nil {
+		return
+	}
+
+	return &m.Amount, nil
+}
+
+// checkVariant makes sure the sku is not taken by another variant and that the
+// options name exactly one value for every option axis of the product, in a
+// combination no other variant uses.
+func (p *ProductDomain) checkVariant(ctx context.Context, tx pgx.Tx, productId int, variantId int, sku string, opts map[string]string) (err error) {
+	var count int
+	err = tx.QueryRow(ctx, "select count(*) from products where id=$1 and deleted_at is null", productId).Scan(&count)
+	if err != nil {
+		return
+	}
+
+	if count < 1 {
+		err = ErrProductNotFound
+		return
+	}
+
+	err = tx.QueryRow(ctx, "select count(*) from product_variants where sku=$1 and id<>$2", sku, variantId).Scan(&count)
+	if err != nil {
+		return
+	}
+
+	if count > 0 {
+		err = ErrSkuAlreadyExists
+		return
+	}
+
+	options, err := getProductOptions(ctx, tx, productId)
+	if err != nil {
+		return
+	}
+
+	if !matchOptions(options, opts) {
+		err = ErrInvalidVariantOptions
+		return
+	}
+
+	variants, err := getProductVariants(ctx, tx, productId)
+	if err != nil {
+		return
+	}
+
+	for _, variant := range variants {
+		if variant.Id != variantId && sameOptions(variant.Options, opts) {
+			err = ErrDuplicateVariantOptions
+			return
+		}
+	}
+
+	return
+}
+
+func matchOptions(options []*ProductOption, opts map[string]string) bool {
+	if len(options) != len(opts) {
+		return false
+	}
+
+	for _, option := range options {
+		value, ok := opts[option.Name]
+		if !ok {
+			return false
+		}
+
+		found := false
+		for _, v := range option.Values {
+			if v == value {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+func optionValues(options []*ProductOption) map[string][]string {
+	values := map[string][]string{}
+	for _, option := range options {
+		values[option.Name] = option.Values
+	}
+
+	return values
+}
+
+func sameOptions(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for k, v := range a {
+		if b[k] != v {
+			return false
+		}
+	}
+
+	return true
+}
+
+type querier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+func getProductOptions(ctx context.Context, db querier, productId int) (options []*ProductOption, err error) {
+	rows, err := db.Query(
+		ctx,
+		`select id,product_id,name,values,position,created_at,updated_at
+		from product_options where product_id=$1 order by position`,
+		productId,
+	)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	options = []*ProductOption{}
+	for rows.Next() {
+		option := &ProductOption{}
+		if err = rows.Scan(
+			&option.Id,
+			&option.ProductId,
+			&option.Name,
+			&option.Values,
+			&option.Position,
+			&option.CreatedAt,
+			&option.UpdatedAt,
+		); err != nil {
+			return
+		}
+		options = append(options, option)
+	}
+
+	err = rows.Err()
+	return
+}
+
+const variantColumns = `product_variants.id,product_variants.product_id,product_variants.sku,
+	product_variants.options,product_variants.stock,
+	case when product_variants.price is null then null else row(product_variants.price,products.currency) end,
+	row(coalesce(product_variants.price,products.price),products.currency),product_variants.image_url,
+	product_variants.created_at,product_variants.updated_at`
+
+func scanVariant(row pgx.Row, variant *Variant) error {
+	return row.Scan(
+		&variant.Id,
+		&variant.ProductId,
+		&variant.Sku,
+		&variant.Options,
+		&variant.Stock,
+		&variant.Price,
+		&variant.UnitPrice,
+		&variant.ImageUrl,
+		&variant.CreatedAt,
+		&variant.UpdatedAt,
+	)
+}
+
+func getProductVariants(ctx context.Context, db querier, productId int) (variants []*Variant, err error) {
+	rows, err := db.Query(
+		ctx,
+		`select `+variantColumns+`
+		from product_variants inner join products on(product_id=products.id)
+		where product_id=$1 order by product_variants.id`,
+		productId,
+	)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	variants = []*Variant{}
+	for rows.Next() {
+		variant := &Variant{}
+		if err = scanVariant(rows, variant); err != nil {
+			return
+		}
+		variants = append(variants, variant)
+	}
+
+	err = rows.Err()
+	return
+}
+
+func getVariant(ctx context.Context, db querier, productId int, variantId int) (variant *Variant, err error) {
+	variant = &Variant{}
+	err = scanVariant(db.QueryRow(
+		ctx,
+		`select `+variantColumns+`
+		from product_variants inner join products on(product_id=products.id)
+		where product_variants.id=$1 and product_id=$2`,
+		variantId,
+		productId,
+	), variant)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			err = ErrVariantNotFound
+		}
+
+		return
+	}
+
+	return
+}
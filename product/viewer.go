@@ -0,0 +1,34 @@
+package product
+
+import (
+	"context"
+)
+
+// Viewer is what the signed in customer looking at a product already did
+// with it. The catalog is public, anonymous visitors get no viewer.
+type Viewer struct {
+	// CartQty is the quantity in the cart, summed over the variants.
+	CartQty int `json:"cart_qty"`
+	// NotifyMe is true while waiting for the product, or one of its
+	// variants, to be restocked.
+	NotifyMe  bool `json:"notify_me"`
+	Purchased bool `json:"purchased"`
+	Reviewed  bool `json:"reviewed"`
+}
+
+func (p *ProductDomain) GetViewer(ctx context.Context, userId int, productId int) (viewer *Viewer, err error) {
+	viewer = &Viewer{}
+	err = p.db.QueryRow(
+		ctx,
+		`select
+			(select coalesce(sum(qty),0) from cart_items where user_id=$1 and product_id=$2),
+			exists(select 1 from stock_subscriptions where user_id=$1 and product_id=$2 and notified_at is null),
+			exists(select 1 from order_items join orders on orders.id=order_items.order_id
+				where orders.user_id=$1 and order_items.product_id=$2),
+			exists(select 1 from reviews where user_id=$1 and product_id=$2)`,
+		userId,
+		productId,
+	).Scan(&viewer.CartQty, &viewer.NotifyMe, &viewer.Purchased, &viewer.Reviewed)
+
+	return
+}
@@ -0,0 +1,187 @@
+package promotion
+
+import (
+	"slices"
+	"sort"
+	"sypchal/money"
+)
+
+// Line is a cart line the promotions may discount, Price is its unit price.
+type Line struct {
+	Id        int
+	ProductId int
+	Category  string
+	Qty       int
+	Price     money.Money
+}
+
+// Allocation is the discount of a promotion on a line.
+type Allocation struct {
+	LineId      int         `json:"line_id"`
+	PromotionId int         `json:"promotion_id"`
+	Name        string      `json:"name"`
+	Amount      money.Money `json:"amount"`
+}
+
+// Evaluate applies the promotions to the lines in the order given, see
+// Running. A line is never discounted below zero.
+func Evaluate(promotions []*Promotion, lines []Line) (allocations []Allocation) {
+	allocations = []Allocation{}
+	remaining := map[int]money.Money{}
+	for _, line := range lines {
+		remaining[line.Id] = line.Price.Mul(line.Qty)
+	}
+
+	discounted := map[int]bool{}
+	locked := map[int]bool{}
+	for _, promotion := range promotions {
+		eligible := []Line{}
+		for _, line := range lines {
+			if locked[line.Id] || (promotion.Exclusive && discounted[line.Id]) || !promotion.appliesTo(line) {
+				continue
+			}
+			eligible = append(eligible, line)
+		}
+
+		if len(eligible) == 0 {
+			continue
+		}
+
+		amounts := promotion.discount(eligible)
+		for _, line := range eligible {
+			amount := money.Min(amounts[line.Id], remaining[line.Id])
+			if !amount.IsPositive() {
+				continue
+			}
+
+			allocations = append(allocations, Allocation{line.Id, promotion.Id, promotion.Name, amount})
+			remaining[line.Id] = remaining[line.Id].Sub(amount)
+			discounted[line.Id] = true
+			if promotion.Exclusive {
+				locked[line.Id] = true
+			}
+		}
+	}
+
+	return
+}
+
+func (promotion *Promotion) appliesTo(line Line) bool {
+	if promotion.Type == TypeBundle {
+		return slices.Contains(promotion.ProductIds, line.ProductId)
+	}
+
+	if len(promotion.Categories) == 0 && len(promotion.ProductIds) == 0 {
+		return true
+	}
+
+	return slices.Contains(promotion.Categories, line.Category) || slices.Contains(promotion.ProductIds, line.ProductId)
+}
+
+// discount returns the discount of the promotion on each of the lines it
+// applies to, by line id. Percentages round down, in favor of the store.
+func (promotion *Promotion) discount(lines []Line) map[int]money.Money {
+	amounts := map[int]money.Money{}
+
+	switch promotion.Type {
+	case TypeSale:
+		for _, line := range lines {
+			amounts[line.Id] = line.Price.Mul(line.Qty).Percent(promotion.Percent, money.RoundDown)
+		}
+
+	case TypeVolumeTier:
+		for _, line := range lines {
+			percent := 0
+			for _, tier := range promotion.Tiers {
+				if line.Qty >= tier.MinQty && tier.Percent > percent {
+					percent = tier.Percent
+				}
+			}
+			amounts[line.Id] = line.Price.Mul(line.Qty).Percent(percent, money.RoundDown)
+		}
+
+	case TypeBuyXGetY:
+		units := 0
+		for _, line := range lines {
+			units += line.Qty
+		}
+
+		free := units / (promotion.BuyQty + promotion.GetQty) * promotion.GetQty
+
+		// the cheapest units are free, ties go to the first lines
+		cheapest := slices.Clone(lines)
+		sort.SliceStable(cheapest, func(i, j int) bool { return cheapest[i].Price.LessThan(cheapest[j].Price) })
+		for _, line := range cheapest {
+			if free == 0 {
+				break
+			}
+
+			qty := min(free, line.Qty)
+			amounts[line.Id] = line.Price.Mul(qty)
+			free -= qty
+		}
+
+	case TypeBundle:
+		// lines and quantity of every product of the bundle
+		byProduct := map[int][]Line{}
+		qty := map[int]int{}
+		for _, line := range lines {
+			byProduct[line.ProductId] = append(byProduct[line.ProductId], line)
+			qty[line.ProductId] += line.Qty
+		}
+
+		bundles := -1
+		for _, productId := range promotion.ProductIds {
+			if bundles == -1 || qty[productId] < bundles {
+				bundles = qty[productId]
+			}
+		}
+
+		// units are taken from the lines in order, next is the line of each
+		// product the next bundle takes its unit from and left its units not
+		// taken yet
+		next := make([]int, len(promotion.ProductIds))
+		left := make([]int, len(promotion.ProductIds))
+		for bundles > 0 {
+			// bundles taking their units from the same lines cost the same
+			same := bundles
+			regular := money.Money{}
+			for i, productId := range promotion.ProductIds {
+				for left[i] == 0 {
+					left[i] = byProduct[productId][next[i]].Qty
+					if left[i] == 0 {
+						next[i]++
+					}
+				}
+				same = min(same, left[i])
+				regular = regular.Add(byProduct[productId][next[i]].Price)
+			}
+
+			saving := regular.Sub(promotion.BundlePrice)
+			if saving.IsPositive() {
+				// spread the saving of a bundle over its units in proportion
+				// to their price, the rounding remainder going to the first
+				// product
+				allocated := money.Money{}
+				for i, productId := range promotion.ProductIds {
+					unit := byProduct[productId][next[i]]
+					share := saving.MulRatio(unit.Price.Amount, regular.Amount, money.RoundDown)
+					amounts[unit.Id] = amounts[unit.Id].Add(share.Mul(same))
+					allocated = allocated.Add(share)
+				}
+				first := byProduct[promotion.ProductIds[0]][next[0]].Id
+				amounts[first] = amounts[first].Add(saving.Sub(allocated).Mul(same))
+			}
+
+			bundles -= same
+			for i := range left {
+				left[i] -= same
+				if left[i] == 0 {
+					next[i]++
+				}
+			}
+		}
+	}
+
+	return amounts
+}
@@ -0,0 +1,74 @@
+package promotion
+
+import (
+	"sypchal/money"
+	"testing"
+)
+
+func TestBundleDiscount(t *testing.T) {
+	bundle := &Promotion{Type: TypeBundle, ProductIds: []int{1, 2}, BundlePrice: money.New(800, "USD")}
+
+	tests := []struct {
+		name  string
+		lines []Line
+		// want is the discount by line id
+		want map[int]int64
+	}{
+		{
+			name:  "one bundle",
+			lines: []Line{{Id: 10, ProductId: 1, Qty: 1, Price: money.New(600, "USD")}, {Id: 20, ProductId: 2, Qty: 1, Price: money.New(400, "USD")}},
+			// saving 200 spread 120 and 80
+			want: map[int]int64{10: 120, 20: 80},
+		},
+		{
+			name:  "bundles are the smallest quantity",
+			lines: []Line{{Id: 10, ProductId: 1, Qty: 5, Price: money.New(600, "USD")}, {Id: 20, ProductId: 2, Qty: 3, Price: money.New(400, "USD")}},
+			want:  map[int]int64{10: 360, 20: 240},
+		},
+		{
+			name:  "remainder to the first product",
+			lines: []Line{{Id: 10, ProductId: 1, Qty: 2, Price: money.New(500, "USD")}, {Id: 20, ProductId: 2, Qty: 2, Price: money.New(501, "USD")}},
+			// saving 201 spread 100 and 100, the remaining 1 to the first
+			want: map[int]int64{10: 202, 20: 200},
+		},
+		{
+			name: "units taken from the lines in order",
+			lines: []Line{
+				{Id: 10, ProductId: 1, Qty: 2, Price: money.New(600, "USD")},
+				{Id: 11, ProductId: 1, Qty: 2, Price: money.New(500, "USD")},
+				{Id: 20, ProductId: 2, Qty: 3, Price: money.New(400, "USD")},
+			},
+			// two bundles saving 200, one saving 100
+			want: map[int]int64{10: 240, 11: 56, 20: 204},
+		},
+		{
+			name: "bundles without saving",
+			lines: []Line{
+				{Id: 10, ProductId: 1, Qty: 1, Price: money.New(300, "USD")},
+				{Id: 20, ProductId: 2, Qty: 1, Price: money.New(400, "USD")},
+			},
+			want: map[int]int64{},
+		},
+		{
+			name:  "product missing",
+			lines: []Line{{Id: 10, ProductId: 1, Qty: 3, Price: money.New(600, "USD")}},
+			want:  map[int]int64{},
+		},
+		{
+			name:  "large quantities",
+			lines: []Line{{Id: 10, ProductId: 1, Qty: 1_000_000_000, Price: money.New(600, "USD")}, {Id: 20, ProductId: 2, Qty: 1_000_000_000, Price: money.New(400, "USD")}},
+			want:  map[int]int64{10: 120_000_000_000, 20: 80_000_000_000},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			amounts := bundle.discount(tt.lines)
+			for _, line := range tt.lines {
+				if got := amounts[line.Id].Amount; got != tt.want[line.Id] {
+					t.Errorf("line %d: got %d, want %d", line.Id, got, tt.want[line.Id])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,8 @@
+package promotion
+
+import "errors"
+
+var ErrPromotionNotFound = errors.New("promotion not found")
+var ErrInvalidRule = errors.New("invalid promotion rule")
+var ErrInvalidWindow = errors.New("promotion must end after it starts")
+var ErrProductNotFound = errors.New("product not found")
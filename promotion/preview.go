@@ -0,0 +1,119 @@
+package promotion
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sypchal/money"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type PreviewItem struct {
+	ProductId int `json:"product_id" validate:"required"`
+	VariantId int `json:"variant_id"`
+	Qty       int `json:"qty" validate:"required,gt=0"`
+}
+
+type PreviewRequest struct {
+	Items []PreviewItem `json:"items" validate:"required,min=1,dive"`
+	// Draft is an unsaved promotion tried along the running ones, whatever
+	// its dates and active flag.
+	Draft *CreatePromotionRequest `json:"draft"`
+	// At is when the promotions run, now when nil.
+	At *time.Time `json:"at"`
+}
+
+type PreviewLine struct {
+	Id        int         `json:"id"`
+	ProductId int         `json:"product_id"`
+	VariantId *int        `json:"variant_id"`
+	Name      string      `json:"name"`
+	Category  string      `json:"category"`
+	Qty       int         `json:"qty"`
+	Price     money.Money `json:"price"`
+	Total     money.Money `json:"total"`
+	Discount  money.Money `json:"discount"`
+}
+
+type PreviewResult struct {
+	Lines       []*PreviewLine `json:"lines"`
+	Allocations []Allocation   `json:"allocations"`
+	Subtotal    money.Money    `json:"subtotal"`
+	Discount    money.Money    `json:"discount"`
+	Total       money.Money    `json:"total"`
+}
+
+// PreviewPromotions prices a sample cart at the catalog prices and applies the
+// promotions running at the time, and the draft promotion, to it. The draft
+// has id 0.
+func (p *PromotionDomain) PreviewPromotions(ctx context.Context, req PreviewRequest) (res *PreviewResult, err error) {
+	if err = p.validator.ValidateStruct(req); err != nil {
+		return
+	}
+
+	at := time.Now()
+	if req.At != nil {
+		at = *req.At
+	}
+
+	promotions, err := Running(ctx, p.db, at)
+	if err != nil {
+		return
+	}
+
+	if req.Draft != nil {
+		if err = req.Draft.validate(p.validator, p.currency); err != nil {
+			return
+		}
+
+		promotions = append(promotions, req.Draft.promotion())
+		sort.SliceStable(promotions, func(i, j int) bool {
+			if promotions[i].Priority != promotions[j].Priority {
+				return promotions[i].Priority < promotions[j].Priority
+			}
+			return promotions[i].Id < promotions[j].Id
+		})
+	}
+
+	res = &PreviewResult{Lines: []*PreviewLine{}, Subtotal: money.Zero(p.currency), Discount: money.Zero(p.currency)}
+	lines := make([]Line, 0, len(req.Items))
+	for i, item := range req.Items {
+		line := &PreviewLine{Id: i + 1, ProductId: item.ProductId, Qty: item.Qty}
+		if item.VariantId != 0 {
+			line.VariantId = &item.VariantId
+		}
+
+		err = p.db.QueryRow(
+			ctx,
+			`select products.name, products.category, row(coalesce(product_variants.price,products.price),products.currency)
+			from products left join product_variants on(product_variants.id=$2 and product_variants.product_id=products.id)
+			where products.id=$1 and products.deleted_at is null and ($2::int is null or product_variants.id is not null)`,
+			item.ProductId,
+			line.VariantId,
+		).Scan(&line.Name, &line.Category, &line.Price)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				err = ErrProductNotFound
+			}
+			return
+		}
+
+		line.Total = line.Price.Mul(line.Qty)
+		line.Discount = money.Zero(line.Price.Currency)
+		res.Subtotal = res.Subtotal.Add(line.Total)
+		res.Lines = append(res.Lines, line)
+		lines = append(lines, Line{line.Id, line.ProductId, line.Category, line.Qty, line.Price})
+	}
+
+	res.Allocations = Evaluate(promotions, lines)
+	for _, allocation := range res.Allocations {
+		line := res.Lines[allocation.LineId-1]
+		line.Discount = line.Discount.Add(allocation.Amount)
+		res.Discount = res.Discount.Add(allocation.Amount)
+	}
+	res.Total = res.Subtotal.Sub(res.Discount)
+
+	return
+}
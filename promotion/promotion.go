@@ -0,0 +1,336 @@
+package promotion
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sypchal/exchange"
+	"sypchal/money"
+	"sypchal/validation"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type PromotionDomain struct {
+	db        *pgx.Conn
+	validator *validation.Validator
+	// currency is the currency of the bundle prices
+	currency money.Currency
+}
+
+func NewPromotionDomain(db *pgx.Conn, validator *validation.Validator, currency money.Currency) (*PromotionDomain, error) {
+	if db == nil {
+		return nil, errors.New("db is nil")
+	}
+
+	if validator == nil {
+		return nil, errors.New("validator is nil")
+	}
+
+	return &PromotionDomain{db, validator, currency}, nil
+}
+
+var (
+	// TypeBuyXGetY gives GetQty units free for every BuyQty units bought,
+	// the cheapest units are free.
+	TypeBuyXGetY = "buy_x_get_y"
+	// TypeBundle sells one unit of each of ProductIds for BundlePrice.
+	TypeBundle = "bundle"
+	// TypeVolumeTier takes the percent of the highest tier reached by the
+	// quantity of a line off the line.
+	TypeVolumeTier = "volume_tier"
+	// TypeSale takes Percent off the items.
+	TypeSale = "sale"
+)
+
+// Tier is a volume discount from a quantity on.
+type Tier struct {
+	MinQty  int `json:"min_qty" validate:"gt=1"`
+	Percent int `json:"percent" validate:"gte=1,lte=100"`
+}
+
+// Promotion is a rule applied to every cart it matches, without a code.
+// Promotions apply by Priority, lowest first, then by id. An Exclusive
+// promotion skips the lines another promotion already discounted, and no
+// other promotion discounts the lines it discounted.
+type Promotion struct {
+	Id        int        `json:"id"`
+	Name      string     `json:"name"`
+	Type      string     `json:"type"`
+	Priority  int        `json:"priority"`
+	Exclusive bool       `json:"exclusive"`
+	StartsAt  *time.Time `json:"starts_at"`
+	EndsAt    *time.Time `json:"ends_at"`
+	Active    bool       `json:"active"`
+	// Categories and ProductIds are the items the promotion applies to, every
+	// item when both are empty. Bundles are exactly ProductIds.
+	Categories  []string    `json:"categories"`
+	ProductIds  []int       `json:"product_ids"`
+	BuyQty      int         `json:"buy_qty"`
+	GetQty      int         `json:"get_qty"`
+	BundlePrice money.Money `json:"bundle_price"`
+	Percent     int         `json:"percent"`
+	Tiers       []Tier      `json:"tiers"`
+	CreatedAt   time.Time   `json:"created_at"`
+	UpdatedAt   *time.Time  `json:"updated_at"`
+}
+
+const promotionColumns = "id,name,type,priority,exclusive,starts_at,ends_at,active,categories,product_ids," +
+	"buy_qty,get_qty,row(bundle_price,currency),percent,tiers,created_at,updated_at"
+
+// scanFields returns the destinations matching promotionColumns.
+func (promotion *Promotion) scanFields() []any {
+	return []any{
+		&promotion.Id,
+		&promotion.Name,
+		&promotion.Type,
+		&promotion.Priority,
+		&promotion.Exclusive,
+		&promotion.StartsAt,
+		&promotion.EndsAt,
+		&promotion.Active,
+		&promotion.Categories,
+		&promotion.ProductIds,
+		&promotion.BuyQty,
+		&promotion.GetQty,
+		&promotion.BundlePrice,
+		&promotion.Percent,
+		&promotion.Tiers,
+		&promotion.CreatedAt,
+		&promotion.UpdatedAt,
+	}
+}
+
+type CreatePromotionRequest struct {
+	Name        string      `json:"name" validate:"required"`
+	Type        string      `json:"type" validate:"required,oneof=buy_x_get_y bundle volume_tier sale"`
+	Priority    int         `json:"priority"`
+	Exclusive   bool        `json:"exclusive"`
+	StartsAt    *time.Time  `json:"starts_at"`
+	EndsAt      *time.Time  `json:"ends_at"`
+	Active      bool        `json:"active"`
+	Categories  []string    `json:"categories"`
+	ProductIds  []int       `json:"product_ids" validate:"unique"`
+	BuyQty      int         `json:"buy_qty" validate:"gte=0"`
+	GetQty      int         `json:"get_qty" validate:"gte=0"`
+	BundlePrice money.Money `json:"bundle_price" validate:"gte=0"`
+	Percent     int         `json:"percent" validate:"gte=0,lte=100"`
+	Tiers       []Tier      `json:"tiers" validate:"dive"`
+}
+
+func (req *CreatePromotionRequest) validate(validator *validation.Validator, currency money.Currency) (err error) {
+	if err = validator.ValidateStruct(req); err != nil {
+		return err
+	}
+
+	if req.BundlePrice, err = validation.InCurrency("bundle_price", req.BundlePrice, currency); err != nil {
+		return err
+	}
+
+	switch {
+	case req.Type == TypeBuyXGetY && (req.BuyQty < 1 || req.GetQty < 1):
+		return fmt.Errorf("%w: buy_qty and get_qty are required", ErrInvalidRule)
+	case req.Type == TypeBundle && (len(req.ProductIds) < 2 || !req.BundlePrice.IsPositive()):
+		return fmt.Errorf("%w: at least 2 product_ids and a bundle_price are required", ErrInvalidRule)
+	case req.Type == TypeVolumeTier && len(req.Tiers) == 0:
+		return fmt.Errorf("%w: tiers are required", ErrInvalidRule)
+	case req.Type == TypeSale && req.Percent < 1:
+		return fmt.Errorf("%w: percent is required", ErrInvalidRule)
+	}
+
+	if req.StartsAt != nil && req.EndsAt != nil && !req.EndsAt.After(*req.StartsAt) {
+		return ErrInvalidWindow
+	}
+
+	if req.Categories == nil {
+		req.Categories = []string{}
+	}
+	if req.ProductIds == nil {
+		req.ProductIds = []int{}
+	}
+	if req.Tiers == nil {
+		req.Tiers = []Tier{}
+	}
+
+	return nil
+}
+
+// promotion returns the promotion the request describes, unsaved.
+func (req *CreatePromotionRequest) promotion() *Promotion {
+	return &Promotion{
+		Name:        req.Name,
+		Type:        req.Type,
+		Priority:    req.Priority,
+		Exclusive:   req.Exclusive,
+		StartsAt:    req.StartsAt,
+		EndsAt:      req.EndsAt,
+		Active:      req.Active,
+		Categories:  req.Categories,
+		ProductIds:  req.ProductIds,
+		BuyQty:      req.BuyQty,
+		GetQty:      req.GetQty,
+		BundlePrice: req.BundlePrice,
+		Percent:     req.Percent,
+		Tiers:       req.Tiers,
+	}
+}
+
+func (p *PromotionDomain) CreatePromotion(ctx context.Context, req CreatePromotionRequest) (promotion *Promotion, err error) {
+	if err = req.validate(p.validator, p.currency); err != nil {
+		return
+	}
+
+	promotion = &Promotion{}
+	err = p.db.QueryRow(
+		ctx,
+		`insert into promotions(name,type,priority,exclusive,starts_at,ends_at,active,categories,product_ids,
+		buy_qty,get_qty,bundle_price,currency,percent,tiers)
+		values ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15) returning `+promotionColumns,
+		req.Name,
+		req.Type,
+		req.Priority,
+		req.Exclusive,
+		req.StartsAt,
+		req.EndsAt,
+		req.Active,
+		req.Categories,
+		req.ProductIds,
+		req.BuyQty,
+		req.GetQty,
+		req.BundlePrice.Amount,
+		req.BundlePrice.Currency,
+		req.Percent,
+		req.Tiers,
+	).Scan(promotion.scanFields()...)
+
+	return
+}
+
+type UpdatePromotionRequest CreatePromotionRequest
+
+// UpdatePromotionById replaces every field of the promotion, placed orders
+// keep their discounts.
+func (p *PromotionDomain) UpdatePromotionById(ctx context.Context, id int, req UpdatePromotionRequest) (promotion *Promotion, err error) {
+	create := CreatePromotionRequest(req)
+	if err = create.validate(p.validator, p.currency); err != nil {
+		return
+	}
+
+	promotion = &Promotion{}
+	err = p.db.QueryRow(
+		ctx,
+		`update promotions set name=$1,type=$2,priority=$3,exclusive=$4,starts_at=$5,ends_at=$6,active=$7,
+		categories=$8,product_ids=$9,buy_qty=$10,get_qty=$11,bundle_price=$12,currency=$13,percent=$14,tiers=$15,
+		updated_at=now() where id=$16 returning `+promotionColumns,
+		create.Name,
+		create.Type,
+		create.Priority,
+		create.Exclusive,
+		create.StartsAt,
+		create.EndsAt,
+		create.Active,
+		create.Categories,
+		create.ProductIds,
+		create.BuyQty,
+		create.GetQty,
+		create.BundlePrice.Amount,
+		create.BundlePrice.Currency,
+		create.Percent,
+		create.Tiers,
+		id,
+	).Scan(promotion.scanFields()...)
+	if errors.Is(err, pgx.ErrNoRows) {
+		err = ErrPromotionNotFound
+	}
+
+	return
+}
+
+type GetPromotionsRequest struct {
+	Limit  int
+	Offset int
+}
+
+type GetPromotionsResponse struct {
+	Promotions []*Promotion `json:"promotions"`
+	Total      int          `json:"total"`
+	MaxPage    int          `json:"max_page"`
+}
+
+// GetPromotions lists the promotions in the order they apply.
+func (p *PromotionDomain) GetPromotions(ctx context.Context, req GetPromotionsRequest) (res *GetPromotionsResponse, err error) {
+	rows, err := p.db.Query(
+		ctx,
+		"select count(*) over(),"+promotionColumns+" from promotions order by priority, id limit $1 offset $2",
+		req.Limit,
+		req.Offset,
+	)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	var total int
+	res = &GetPromotionsResponse{Promotions: []*Promotion{}}
+	for rows.Next() {
+		promotion := &Promotion{}
+		if err = rows.Scan(append([]any{&total}, promotion.scanFields()...)...); err != nil {
+			return
+		}
+		res.Promotions = append(res.Promotions, promotion)
+	}
+	if err = rows.Err(); err != nil {
+		return
+	}
+
+	res.Total = total
+	res.MaxPage = int(math.Ceil(float64(total) / float64(req.Limit)))
+
+	return
+}
+
+type querier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}
+
+// Running returns the active promotions running at a time, in the order they
+// apply.
+func Running(ctx context.Context, db querier, at time.Time) (promotions []*Promotion, err error) {
+	rows, err := db.Query(
+		ctx,
+		`select `+promotionColumns+` from promotions
+		where active and (starts_at is null or starts_at<=$1) and (ends_at is null or ends_at>$1)
+		order by priority, id`,
+		at,
+	)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	promotions = []*Promotion{}
+	for rows.Next() {
+		promotion := &Promotion{}
+		if err = rows.Scan(promotion.scanFields()...); err != nil {
+			return
+		}
+		promotions = append(promotions, promotion)
+	}
+
+	err = rows.Err()
+	return
+}
+
+// Localize converts the bundle prices of the promotions into currency at the
+// rates, for carts priced in another currency than the catalog.
+func Localize(promotions []*Promotion, rates *exchange.Rates, currency money.Currency) (err error) {
+	for _, promotion := range promotions {
+		if promotion.BundlePrice, err = rates.Convert(promotion.BundlePrice, currency); err != nil {
+			return
+		}
+	}
+
+	return
+}
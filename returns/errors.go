@@ -0,0 +1,15 @@
+package returns
+
+import "errors"
+
+var ErrReturnNotFound = errors.New("return not found")
+var ErrOrderNotFound = errors.New("order not found")
+var ErrOrderNotDelivered = errors.New("order is not delivered")
+var ErrReturnWindowClosed = errors.New("return window of the order has closed")
+var ErrOrderItemNotFound = errors.New("order item not found")
+var ErrReturnQty = errors.New("return quantity over the quantity left to return")
+var ErrReturnTransition = errors.New("return status can't change")
+var ErrNoPayment = errors.New("order has no payment to refund")
+var ErrRefundPending = errors.New("refund is pending at the payment gateway, retry it")
+var ErrRefundNotPending = errors.New("return has no pending refund")
+var ErrWarehouseNotFound = errors.New("warehouse not found")
@@ -0,0 +1,674 @@
+package returns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"sypchal/audit"
+	"sypchal/inventory"
+	"sypchal/invoice"
+	"sypchal/money"
+	"sypchal/payment"
+	"sypchal/validation"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+type ReturnDomain struct {
+	db        *pgx.Conn
+	validator *validation.Validator
+	gateway   payment.Gateway
+	// invoices issues the credit notes of the refunds
+	invoices *invoice.Issuer
+	// window is how long after delivery a return can be requested, forever
+	// when 0
+	window time.Duration
+}
+
+func NewReturnDomain(db *pgx.Conn, validator *validation.Validator, gateway payment.Gateway, invoices *invoice.Issuer, window time.Duration) (*ReturnDomain, error) {
+	if db == nil {
+		return nil, errors.New("db is nil")
+	}
+
+	if validator == nil {
+		return nil, errors.New("validator is nil")
+	}
+
+	if gateway == nil {
+		return nil, errors.New("payment gateway is nil")
+	}
+
+	if invoices == nil {
+		return nil, errors.New("invoice issuer is nil")
+	}
+
+	return &ReturnDomain{db, validator, gateway, invoices, window}, nil
+}
+
+var (
+	// RefundStatusPending is a refund recorded but not paid back yet by the
+	// gateway, it becomes RefundStatusCompleted once it is.
+	RefundStatusPending   = "pending"
+	RefundStatusCompleted = "completed"
+)
+
+var (
+	StatusRequested = "requested"
+	StatusApproved  = "approved"
+	StatusRejected  = "rejected"
+	StatusReceived  = "received"
+)
+
+// transitions are the statuses a return can move to from each status.
+var transitions = map[string][]string{
+	StatusRequested: {StatusApproved, StatusRejected},
+	StatusApproved:  {StatusReceived},
+}
+
+type Return struct {
+	Id      int    `json:"id"`
+	OrderId int    `json:"order_id"`
+	UserId  int    `json:"user_id"`
+	Status  string `json:"status"`
+	Reason  string `json:"reason"`
+	// RefundAmount is paid back when the return is approved, RefundId is the
+	// refund then and RefundStatus whether the gateway paid it back.
+	RefundAmount money.Money     `json:"refund_amount"`
+	RefundId     *int            `json:"refund_id"`
+	RefundStatus *string         `json:"refund_status"`
+	Items        []*ReturnItem   `json:"items"`
+	History      []*HistoryEntry `json:"history"`
+	CreatedAt    time.Time       `json:"created_at"`
+	UpdatedAt    *time.Time      `json:"updated_at"`
+}
+
+type ReturnItem struct {
+	OrderItemId int         `json:"order_item_id" validate:"required"`
+	Qty         int         `json:"qty" validate:"required,gt=0"`
+	Amount      money.Money `json:"amount"`
+	Restocked   bool        `json:"restocked"`
+}
+
+// HistoryEntry is a status a return went through.
+type HistoryEntry struct {
+	Status    string    `json:"status"`
+	Note      string    `json:"note"`
+	Actor     string    `json:"actor"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+const returnColumns = "id,order_id,user_id,status,reason,row(refund_amount,currency),refund_id," +
+	"(select status from refunds where refunds.id=returns.refund_id),created_at,updated_at"
+
+// scanFields returns the destinations matching returnColumns.
+func (ret *Return) scanFields() []any {
+	return []any{
+		&ret.Id,
+		&ret.OrderId,
+		&ret.UserId,
+		&ret.Status,
+		&ret.Reason,
+		&ret.RefundAmount,
+		&ret.RefundId,
+		&ret.RefundStatus,
+		&ret.CreatedAt,
+		&ret.UpdatedAt,
+	}
+}
+
+type CreateReturnRequest struct {
+	UserId  int
+	OrderId int
+	Reason  string       `json:"reason" validate:"required,max=1000"`
+	Items   []ReturnItem `json:"items" validate:"required,min=1,dive"`
+}
+
+// returnable is what is left to return of an order line and what it was paid
+// in total, after its discounts and with its exclusive tax.
+type returnable struct {
+	ordered int
+	left    int
+	paid    money.Money
+}
+
+// CreateReturn requests the return of some quantities of the lines of a
+// delivered order of the customer, within the return window. The refund is
+// the share of what the lines were paid.
+func (rd *ReturnDomain) CreateReturn(ctx context.Context, req CreateReturnRequest) (ret *Return, err error) {
+	if err = rd.validator.ValidateStruct(req); err != nil {
+		return
+	}
+
+	tx, err := rd.db.Begin(ctx)
+	if err != nil {
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	var status string
+	var deliveredAt *time.Time
+	err = tx.QueryRow(
+		ctx,
+		`select status,(select max(delivered_at) from shipments where order_id=orders.id and status='delivered')
+		from orders where id=$1 and user_id=$2 for update`,
+		req.OrderId,
+		req.UserId,
+	).Scan(&status, &deliveredAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			err = ErrOrderNotFound
+		}
+		return
+	}
+
+	if status != "delivered" || deliveredAt == nil {
+		err = ErrOrderNotDelivered
+		return
+	}
+
+	if rd.window > 0 && time.Since(*deliveredAt) > rd.window {
+		err = ErrReturnWindowClosed
+		return
+	}
+
+	lines, err := returnableLines(ctx, tx, req.OrderId)
+	if err != nil {
+		return
+	}
+
+	amount := money.Money{}
+	for i := range req.Items {
+		item := &req.Items[i]
+		line, ok := lines[item.OrderItemId]
+		if !ok {
+			err = fmt.Errorf("%w: order item %d", ErrOrderItemNotFound, item.OrderItemId)
+			return
+		}
+		if item.Qty > line.left {
+			err = fmt.Errorf("%w: %d of order item %d left", ErrReturnQty, line.left, item.OrderItemId)
+			return
+		}
+		line.left -= item.Qty
+
+		item.Amount = line.paid.MulRatio(int64(item.Qty), int64(line.ordered), money.RoundDown)
+		item.Restocked = false
+		amount = amount.Add(item.Amount)
+	}
+
+	ret = &Return{}
+	err = tx.QueryRow(
+		ctx,
+		`insert into returns(order_id,user_id,status,reason,refund_amount,currency) values ($1,$2,$3,$4,$5,$6)
+		returning `+returnColumns,
+		req.OrderId,
+		req.UserId,
+		StatusRequested,
+		req.Reason,
+		amount.Amount,
+		amount.Currency,
+	).Scan(ret.scanFields()...)
+	if err != nil {
+		return
+	}
+
+	_, err = tx.CopyFrom(
+		ctx,
+		pgx.Identifier{"return_items"},
+		[]string{"return_id", "order_item_id", "qty", "amount"},
+		pgx.CopyFromSlice(len(req.Items), func(i int) ([]any, error) {
+			return []any{ret.Id, req.Items[i].OrderItemId, req.Items[i].Qty, req.Items[i].Amount.Amount}, nil
+		}),
+	)
+	if err != nil {
+		return
+	}
+
+	if err = recordHistory(ctx, tx, ret.Id, StatusRequested, ""); err != nil {
+		return
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return
+	}
+
+	return rd.GetReturnById(ctx, ret.Id)
+}
+
+// returnableLines returns what is left to return of the lines of an order by
+// order item id, the lines of rejected returns can be returned again.
+func returnableLines(ctx context.Context, tx pgx.Tx, orderId int) (lines map[int]*returnable, err error) {
+	rows, err := tx.Query(
+		ctx,
+		`select
+			order_items.id,
+			order_items.qty,
+			order_items.qty-coalesce((select sum(return_items.qty) from return_items
+				inner join returns on(return_id=returns.id)
+				where order_item_id=order_items.id and returns.status<>'rejected'),0),
+			row(order_items.qty*order_items.price
+				+coalesce((select sum(amount) from order_adjustments where order_item_id=order_items.id),0)
+				+case when orders.tax_mode='exclusive' then order_items.tax_amount else 0 end,
+				orders.currency)
+		from order_items inner join orders on(order_id=orders.id)
+		where order_id=$1`,
+		orderId,
+	)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	lines = map[int]*returnable{}
+	for rows.Next() {
+		var orderItemId int
+		line := &returnable{}
+		if err = rows.Scan(&orderItemId, &line.ordered, &line.left, &line.paid); err != nil {
+			return
+		}
+		lines[orderItemId] = line
+	}
+
+	err = rows.Err()
+	return
+}
+
+type UpdateReturnStatusRequest struct {
+	ReturnId int
+	Status   string `json:"status" validate:"required,oneof=approved rejected received"`
+	Note     string `json:"note" validate:"max=1000"`
+	// Restock puts the received items back in stock, in WarehouseId or the
+	// default warehouse when 0.
+	Restock     bool `json:"restock"`
+	WarehouseId int  `json:"warehouse_id"`
+}
+
+// UpdateReturnStatus moves a return forward. Approving it refunds the
+// customer through the payment gateway once the approval is saved, receiving
+// it optionally restocks the items. A refund the gateway fails stays pending
+// and fails with ErrRefundPending, RetryRefund tries it again.
+func (rd *ReturnDomain) UpdateReturnStatus(ctx context.Context, req UpdateReturnStatusRequest) (ret *Return, err error) {
+	if err = rd.validator.ValidateStruct(req); err != nil {
+		return
+	}
+
+	tx, err := rd.db.Begin(ctx)
+	if err != nil {
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	ret = &Return{}
+	err = tx.QueryRow(ctx, "select "+returnColumns+" from returns where id=$1 for update", req.ReturnId).
+		Scan(ret.scanFields()...)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			err = ErrReturnNotFound
+		}
+		return
+	}
+
+	if !slices.Contains(transitions[ret.Status], req.Status) {
+		err = fmt.Errorf("%w: from %s to %s", ErrReturnTransition, ret.Status, req.Status)
+		return
+	}
+
+	switch req.Status {
+	case StatusApproved:
+		err = rd.refund(ctx, tx, ret, req.Note)
+	case StatusReceived:
+		if req.Restock {
+			err = restock(ctx, tx, ret.Id, req.WarehouseId)
+		}
+	}
+	if err != nil {
+		return
+	}
+
+	_, err = tx.Exec(
+		ctx,
+		"update returns set status=$1,refund_id=$2,updated_at=now() where id=$3",
+		req.Status,
+		ret.RefundId,
+		ret.Id,
+	)
+	if err != nil {
+		return
+	}
+
+	if err = recordHistory(ctx, tx, ret.Id, req.Status, req.Note); err != nil {
+		return
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return
+	}
+
+	var refundErr error
+	if req.Status == StatusApproved {
+		refundErr = rd.completeRefund(ctx, *ret.RefundId)
+	}
+
+	if ret, err = rd.GetReturnById(ctx, ret.Id); err != nil {
+		return
+	}
+
+	err = refundErr
+	return
+}
+
+// RetryRefund tries again the pending refund of an approved return.
+func (rd *ReturnDomain) RetryRefund(ctx context.Context, returnId int) (ret *Return, err error) {
+	if ret, err = rd.GetReturnById(ctx, returnId); err != nil {
+		return
+	}
+
+	if ret.RefundId == nil || *ret.RefundStatus != RefundStatusPending {
+		err = ErrRefundNotPending
+		return
+	}
+
+	refundErr := rd.completeRefund(ctx, *ret.RefundId)
+	if ret, err = rd.GetReturnById(ctx, returnId); err != nil {
+		return
+	}
+
+	err = refundErr
+	return
+}
+
+// refund records a pending refund of the refund amount of the return, paid
+// back by completeRefund once the transaction is committed. The return can
+// only be refunded once, by the key of the refund.
+func (rd *ReturnDomain) refund(ctx context.Context, tx pgx.Tx, ret *Return, note string) (err error) {
+	var paymentId int
+	err = tx.QueryRow(ctx, "select id from payments where order_id=$1", ret.OrderId).Scan(&paymentId)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			err = ErrNoPayment
+		}
+		return
+	}
+
+	var refundId int
+	err = tx.QueryRow(
+		ctx,
+		`insert into refunds(order_id,payment_id,return_id,amount,currency,status,idempotency_key,note)
+		values ($1,$2,$3,$4,$5,$6,$7,$8) returning id`,
+		ret.OrderId,
+		paymentId,
+		ret.Id,
+		ret.RefundAmount.Amount,
+		ret.RefundAmount.Currency,
+		RefundStatusPending,
+		"return:"+strconv.Itoa(ret.Id),
+		note,
+	).Scan(&refundId)
+	if err != nil {
+		return
+	}
+	ret.RefundId = &refundId
+
+	return
+}
+
+// completeRefund pays back a pending refund through the gateway, outside of
+// any transaction so a slow gateway holds no locks, then marks it completed
+// and issues its credit note. The gateway gets the key of the refund, trying
+// it again doesn't pay it twice.
+func (rd *ReturnDomain) completeRefund(ctx context.Context, refundId int) (err error) {
+	req := payment.RefundRequest{Reason: "refund " + strconv.Itoa(refundId)}
+	var returnId *int
+	err = rd.db.QueryRow(
+		ctx,
+		"select order_id,payment_id,return_id,row(amount,currency),idempotency_key from refunds where id=$1 and status=$2",
+		refundId,
+		RefundStatusPending,
+	).Scan(&req.OrderId, &req.PaymentId, &returnId, &req.Amount, &req.IdempotencyKey)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			err = ErrRefundNotPending
+		}
+		return
+	}
+	if returnId != nil {
+		req.Reason = "return " + strconv.Itoa(*returnId)
+	}
+
+	reference, err := rd.gateway.Refund(ctx, req)
+	if err != nil {
+		err = fmt.Errorf("%w: %w", ErrRefundPending, err)
+		return
+	}
+
+	tx, err := rd.db.Begin(ctx)
+	if err != nil {
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(
+		ctx,
+		"update refunds set status=$1,reference=$2,completed_at=now() where id=$3 and status=$4",
+		RefundStatusCompleted,
+		reference,
+		refundId,
+		RefundStatusPending,
+	)
+	if err != nil {
+		return
+	}
+
+	// completed meanwhile by another try, with its credit note
+	if tag.RowsAffected() == 0 {
+		return
+	}
+
+	if _, err = rd.invoices.CreditNote(ctx, tx, refundId); err != nil {
+		return
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return
+	}
+
+	return
+}
+
+// restock puts the items of the return back in stock.
+func restock(ctx context.Context, tx pgx.Tx, returnId int, warehouseId int) (err error) {
+	rows, err := tx.Query(
+		ctx,
+		`select order_items.product_id,order_items.variant_id,return_items.qty
+		from return_items inner join order_items on(order_item_id=order_items.id)
+		where return_id=$1 and order_items.product_id is not null`,
+		returnId,
+	)
+	if err != nil {
+		return
+	}
+
+	movements := []*inventory.Movement{}
+	for rows.Next() {
+		movement := &inventory.Movement{
+			WarehouseId: warehouseId,
+			Reason:      inventory.ReasonReturn,
+			Reference:   "return:" + strconv.Itoa(returnId),
+		}
+		if err = rows.Scan(&movement.ProductId, &movement.VariantId, &movement.Quantity); err != nil {
+			rows.Close()
+			return
+		}
+		movements = append(movements, movement)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return
+	}
+
+	for _, movement := range movements {
+		if err = inventory.Move(ctx, tx, movement); err != nil {
+			// foreign_key_violation, the warehouse doesn't exist
+			var pgErr *pgconn.PgError
+			if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+				err = ErrWarehouseNotFound
+			}
+			return
+		}
+	}
+
+	_, err = tx.Exec(
+		ctx,
+		`update return_items set restocked=true from order_items
+		where order_item_id=order_items.id and return_id=$1 and order_items.product_id is not null`,
+		returnId,
+	)
+	return
+}
+
+// recordHistory adds a status of a return to its history.
+func recordHistory(ctx context.Context, tx pgx.Tx, returnId int, status string, note string) (err error) {
+	_, err = tx.Exec(
+		ctx,
+		"insert into return_history(return_id,status,note,actor) values ($1,$2,$3,$4)",
+		returnId,
+		status,
+		note,
+		audit.FromContext(ctx).Name,
+	)
+	return
+}
+
+type GetReturnsRequest struct {
+	Status  string
+	UserId  int
+	OrderId int
+	Limit   int
+	Offset  int
+}
+
+type GetReturnsResponse struct {
+	Returns []*Return `json:"returns"`
+	Total   int       `json:"total"`
+	MaxPage int       `json:"max_page"`
+}
+
+// GetReturns lists the returns, newest first, of a status, a customer or an
+// order when set.
+func (rd *ReturnDomain) GetReturns(ctx context.Context, req GetReturnsRequest) (res *GetReturnsResponse, err error) {
+	rows, err := rd.db.Query(
+		ctx,
+		`select count(*) over(),`+returnColumns+` from returns
+		where ($1='' or status=$1) and ($2=0 or user_id=$2) and ($3=0 or order_id=$3)
+		order by id desc limit $4 offset $5`,
+		req.Status,
+		req.UserId,
+		req.OrderId,
+		req.Limit,
+		req.Offset,
+	)
+	if err != nil {
+		return
+	}
+
+	var total int
+	res = &GetReturnsResponse{Returns: []*Return{}}
+	for rows.Next() {
+		ret := &Return{}
+		if err = rows.Scan(append([]any{&total}, ret.scanFields()...)...); err != nil {
+			rows.Close()
+			return
+		}
+		res.Returns = append(res.Returns, ret)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return
+	}
+
+	if err = rd.populate(ctx, res.Returns); err != nil {
+		return
+	}
+
+	res.Total = total
+	res.MaxPage = int(math.Ceil(float64(total) / float64(req.Limit)))
+
+	return
+}
+
+// GetReturnById returns a return with its items and history.
+func (rd *ReturnDomain) GetReturnById(ctx context.Context, id int) (ret *Return, err error) {
+	ret = &Return{}
+	err = rd.db.QueryRow(ctx, "select "+returnColumns+" from returns where id=$1", id).Scan(ret.scanFields()...)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			err = ErrReturnNotFound
+		}
+		return
+	}
+
+	err = rd.populate(ctx, []*Return{ret})
+	return
+}
+
+// populate reads the items and the history of the returns.
+func (rd *ReturnDomain) populate(ctx context.Context, returns []*Return) (err error) {
+	byId := map[int]*Return{}
+	ids := make([]int, 0, len(returns))
+	for _, ret := range returns {
+		ret.Items = []*ReturnItem{}
+		ret.History = []*HistoryEntry{}
+		byId[ret.Id] = ret
+		ids = append(ids, ret.Id)
+	}
+
+	rows, err := rd.db.Query(
+		ctx,
+		`select return_id,order_item_id,qty,row(amount,returns.currency),restocked
+		from return_items inner join returns on(return_id=returns.id)
+		where return_id=any($1) order by return_items.id`,
+		ids,
+	)
+	if err != nil {
+		return
+	}
+	for rows.Next() {
+		var returnId int
+		item := &ReturnItem{}
+		if err = rows.Scan(&returnId, &item.OrderItemId, &item.Qty, &item.Amount, &item.Restocked); err != nil {
+			rows.Close()
+			return
+		}
+		byId[returnId].Items = append(byId[returnId].Items, item)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return
+	}
+
+	rows, err = rd.db.Query(
+		ctx,
+		"select return_id,status,note,actor,created_at from return_history where return_id=any($1) order by id",
+		ids,
+	)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var returnId int
+		entry := &HistoryEntry{}
+		if err = rows.Scan(&returnId, &entry.Status, &entry.Note, &entry.Actor, &entry.CreatedAt); err != nil {
+			return
+		}
+		byId[returnId].History = append(byId[returnId].History, entry)
+	}
+
+	err = rows.Err()
+	return
+}
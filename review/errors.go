@@ -0,0 +1,8 @@
+package review
+
+import "errors"
+
+var ErrProductNotFound = errors.New("product not found")
+var ErrReviewNotFound = errors.New("review not found")
+var ErrNotVerifiedBuyer = errors.New("only customers who received the product can review it")
+var ErrReviewExists = errors.New("product already reviewed")
@@ -0,0 +1,344 @@
+package review
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sypchal/validation"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type ReviewDomain struct {
+	db        *pgx.Conn
+	validator *validation.Validator
+}
+
+func NewReviewDomain(db *pgx.Conn, validator *validation.Validator) (*ReviewDomain, error) {
+	if db == nil {
+		return nil, errors.New("db is nil")
+	}
+
+	if validator == nil {
+		return nil, errors.New("validator is nil")
+	}
+
+	return &ReviewDomain{db, validator}, nil
+}
+
+var (
+	ReviewStatusPending  = "pending"
+	ReviewStatusApproved = "approved"
+	ReviewStatusRejected = "rejected"
+)
+
+// receivedStatuses are the statuses of the orders whose products count as
+// received by the customer.
+var receivedStatuses = []string{"delivered"}
+
+type Review struct {
+	Id             int        `json:"id"`
+	ProductId      int        `json:"product_id"`
+	UserId         int        `json:"user_id"`
+	UserName       string     `json:"user_name"`
+	OrderId        *int       `json:"order_id"`
+	Rating         int        `json:"rating"`
+	Body           string     `json:"body"`
+	Status         string     `json:"status"`
+	ModerationNote string     `json:"moderation_note,omitempty"`
+	ModeratedAt    *time.Time `json:"moderated_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      *time.Time `json:"updated_at"`
+}
+
+const reviewColumns = `reviews.id,product_id,user_id,users.full_name,order_id,rating,body,status,moderation_note,
+	moderated_at,reviews.created_at,reviews.updated_at`
+
+func scanReview(row pgx.Row, review *Review, extra ...any) error {
+	return row.Scan(append(extra,
+		&review.Id,
+		&review.ProductId,
+		&review.UserId,
+		&review.UserName,
+		&review.OrderId,
+		&review.Rating,
+		&review.Body,
+		&review.Status,
+		&review.ModerationNote,
+		&review.ModeratedAt,
+		&review.CreatedAt,
+		&review.UpdatedAt,
+	)...)
+}
+
+type CreateReviewRequest struct {
+	UserId    int
+	ProductId int
+	Rating    int    `json:"rating" validate:"required,min=1,max=5"`
+	Body      string `json:"body" validate:"required,max=5000"`
+}
+
+// CreateReview posts a review of a product the customer received. It waits
+// for moderation before it's shown.
+func (rv *ReviewDomain) CreateReview(ctx context.Context, req CreateReviewRequest) (review *Review, err error) {
+	if err = rv.validator.ValidateStruct(req); err != nil {
+		return
+	}
+
+	var count int
+	err = rv.db.QueryRow(ctx, "select count(*) from products where id=$1 and deleted_at is null", req.ProductId).
+		Scan(&count)
+	if err != nil {
+		return
+	}
+
+	if count == 0 {
+		err = ErrProductNotFound
+		return
+	}
+
+	var orderId int
+	err = rv.db.QueryRow(
+		ctx,
+		`select orders.id from order_items inner join orders on(order_id=orders.id)
+		where orders.user_id=$1 and order_items.product_id=$2 and orders.status=any($3)
+		order by orders.id limit 1`,
+		req.UserId,
+		req.ProductId,
+		receivedStatuses,
+	).Scan(&orderId)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			err = ErrNotVerifiedBuyer
+		}
+		return
+	}
+
+	var id int
+	err = rv.db.QueryRow(
+		ctx,
+		`insert into reviews(product_id,user_id,order_id,rating,body) values ($1,$2,$3,$4,$5)
+		on conflict (product_id,user_id) do nothing returning id`,
+		req.ProductId,
+		req.UserId,
+		orderId,
+		req.Rating,
+		req.Body,
+	).Scan(&id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			err = ErrReviewExists
+		}
+		return
+	}
+
+	return getReview(ctx, rv.db, id)
+}
+
+type UpdateReviewRequest struct {
+	UserId   int
+	ReviewId int
+	Rating   int    `json:"rating" validate:"required,min=1,max=5"`
+	Body     string `json:"body" validate:"required,max=5000"`
+}
+
+// UpdateReviewById replaces the customer's own review, which goes back to
+// moderation.
+func (rv *ReviewDomain) UpdateReviewById(ctx context.Context, req UpdateReviewRequest) (review *Review, err error) {
+	if err = rv.validator.ValidateStruct(req); err != nil {
+		return
+	}
+
+	tx, err := rv.db.Begin(ctx)
+	if err != nil {
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	var productId int
+	err = tx.QueryRow(
+		ctx,
+		`update reviews set rating=$1,body=$2,status=$3,moderation_note='',moderated_at=null,updated_at=now()
+		where id=$4 and user_id=$5 returning product_id`,
+		req.Rating,
+		req.Body,
+		ReviewStatusPending,
+		req.ReviewId,
+		req.UserId,
+	).Scan(&productId)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			err = ErrReviewNotFound
+		}
+		return
+	}
+
+	if err = refreshRating(ctx, tx, productId); err != nil {
+		return
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return
+	}
+
+	return getReview(ctx, rv.db, req.ReviewId)
+}
+
+func (rv *ReviewDomain) DeleteReviewById(ctx context.Context, userId int, reviewId int) (err error) {
+	tx, err := rv.db.Begin(ctx)
+	if err != nil {
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	var productId int
+	err = tx.QueryRow(ctx, "delete from reviews where id=$1 and user_id=$2 returning product_id", reviewId, userId).
+		Scan(&productId)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			err = ErrReviewNotFound
+		}
+		return
+	}
+
+	if err = refreshRating(ctx, tx, productId); err != nil {
+		return
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return
+	}
+
+	return
+}
+
+type ModerateReviewRequest struct {
+	ReviewId int
+	Status   string `json:"status" validate:"required,oneof=approved rejected"`
+	Note     string `json:"note" validate:"max=1000"`
+}
+
+// ModerateReview approves or rejects a review, only approved reviews are
+// shown and count in the product rating.
+func (rv *ReviewDomain) ModerateReview(ctx context.Context, req ModerateReviewRequest) (review *Review, err error) {
+	if err = rv.validator.ValidateStruct(req); err != nil {
+		return
+	}
+
+	tx, err := rv.db.Begin(ctx)
+	if err != nil {
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	var productId int
+	err = tx.QueryRow(
+		ctx,
+		`update reviews set status=$1,moderation_note=$2,moderated_at=now() where id=$3 returning product_id`,
+		req.Status,
+		req.Note,
+		req.ReviewId,
+	).Scan(&productId)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			err = ErrReviewNotFound
+		}
+		return
+	}
+
+	if err = refreshRating(ctx, tx, productId); err != nil {
+		return
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return
+	}
+
+	return getReview(ctx, rv.db, req.ReviewId)
+}
+
+// refreshRating recomputes the rating of the product from its approved
+// reviews. The product version changes with it, so cached products are
+// refreshed too.
+func refreshRating(ctx context.Context, tx pgx.Tx, productId int) error {
+	_, err := tx.Exec(
+		ctx,
+		`with summary as (
+			select coalesce(round(avg(rating),2),0) as average, count(*) as count
+			from reviews where product_id=$1 and status=$2
+		)
+		update products set rating_average=summary.average,rating_count=summary.count,version=version+1
+		from summary
+		where id=$1 and (rating_average,rating_count) is distinct from (summary.average,summary.count)`,
+		productId,
+		ReviewStatusApproved,
+	)
+
+	return err
+}
+
+type GetReviewsRequest struct {
+	// ProductId lists the reviews of every product when 0.
+	ProductId int
+	// Status lists the reviews in any status when empty.
+	Status string
+	Limit  int
+	Offset int
+}
+
+type GetReviewsResponse struct {
+	Reviews []*Review `json:"reviews"`
+	Total   int       `json:"total"`
+	MaxPage int       `json:"max_page"`
+}
+
+// GetReviews lists reviews, newest first.
+func (rv *ReviewDomain) GetReviews(ctx context.Context, req GetReviewsRequest) (res *GetReviewsResponse, err error) {
+	rows, err := rv.db.Query(
+		ctx,
+		`select count(*) over(),`+reviewColumns+`
+		from reviews inner join users on(user_id=users.id)
+		where ($1=0 or product_id=$1) and ($2='' or status=$2)
+		order by reviews.created_at desc, reviews.id desc limit $3 offset $4`,
+		req.ProductId,
+		req.Status,
+		req.Limit,
+		req.Offset,
+	)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	var total int
+	res = &GetReviewsResponse{Reviews: []*Review{}}
+	for rows.Next() {
+		review := &Review{}
+		if err = scanReview(rows, review, &total); err != nil {
+			return
+		}
+		res.Reviews = append(res.Reviews, review)
+	}
+
+	res.Total = total
+	res.MaxPage = int(math.Ceil(float64(total) / float64(req.Limit)))
+
+	return
+}
+
+func getReview(ctx context.Context, db *pgx.Conn, id int) (review *Review, err error) {
+	review = &Review{}
+	err = scanReview(
+		db.QueryRow(ctx, "select "+reviewColumns+" from reviews inner join users on(user_id=users.id) where reviews.id=$1", id),
+		review,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			err = ErrReviewNotFound
+		}
+		return
+	}
+
+	return
+}
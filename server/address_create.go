@@ -0,0 +1,99 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"sypchal/order"
+	"sypchal/shipping"
+	"sypchal/validation"
+
+	"github.com/go-chi/jwtauth/v5"
+	"github.com/rs/zerolog/log"
+)
+
+type AddressCreateRequest struct {
+	Label         string `json:"label"`
+	RecipientName string `json:"recipient_name"`
+	Phone         string `json:"phone"`
+	Line1         string `json:"line1"`
+	Line2         string `json:"line2"`
+	City          string `json:"city"`
+	Region        string `json:"region"`
+	PostalCode    string `json:"postal_code"`
+	Country       string `json:"country"`
+	IsDefault     bool   `json:"is_default"`
+}
+
+func (s *ServerDependency) AddressCreate(w http.ResponseWriter, r *http.Request) {
+	var requestBody AddressCreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		s.Response(w, r).Status(http.StatusBadRequest).
+			Error(http.StatusBadRequest, "invalid request body", nil)
+		return
+	}
+
+	_, payload, err := jwtauth.FromContext(r.Context())
+	if err != nil {
+		log.Error().Err(err).Msg("get jwt payload")
+		s.Response(w, r).Status(http.StatusInternalServerError).
+			Error(http.StatusInternalServerError, "internal server error", nil)
+		return
+	}
+
+	userId, err := strconv.Atoi(payload["uid"].(string))
+	if err != nil {
+		log.Error().Err(err).Msg("atoi")
+		s.Response(w, r).Status(http.StatusInternalServerError).
+			Error(http.StatusInternalServerError, "internal server error", nil)
+		return
+	}
+
+	address, err := s.shippingDomain.CreateAddress(r.Context(), userId, shipping.CreateAddressRequest(requestBody))
+	if err != nil {
+		log.Error().Err(err).Msg("create address")
+		s.shippingError(w, r, err)
+		return
+	}
+
+	s.Response(w, r).Status(http.StatusCreated).Data(address)
+}
+
+func (s *ServerDependency) shippingError(w http.ResponseWriter, r *http.Request, err error) {
+	var ve *validation.ValidationErrors
+	if errors.As(err, &ve) {
+		s.Response(w, r).Status(http.StatusBadRequest).
+			Error(http.StatusBadRequest, "validation error", ve.Transform())
+		return
+	}
+
+	for _, target := range []error{shipping.ErrAddressNotFound, shipping.ErrMethodNotFound} {
+		if errors.Is(err, target) {
+			s.Response(w, r).Status(http.StatusNotFound).
+				Error(http.StatusNotFound, target.Error(), nil)
+			return
+		}
+	}
+
+	if errors.Is(err, shipping.ErrMethodCodeTaken) {
+		s.Response(w, r).Status(http.StatusConflict).
+			Error(http.StatusConflict, err.Error(), nil)
+		return
+	}
+
+	for _, target := range []error{
+		shipping.ErrInvalidRate,
+		order.ErrNoShippingMethod,
+		order.ErrShippingMethodUnavailable,
+	} {
+		if errors.Is(err, target) {
+			s.Response(w, r).Status(http.StatusBadRequest).
+				Error(http.StatusBadRequest, target.Error(), nil)
+			return
+		}
+	}
+
+	s.Response(w, r).Status(http.StatusInternalServerError).
+		Error(http.StatusInternalServerError, "internal server error", nil)
+}
@@ -0,0 +1,38 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/jwtauth/v5"
+	"github.com/rs/zerolog/log"
+)
+
+func (s *ServerDependency) AddressDelete(w http.ResponseWriter, r *http.Request) {
+	addressId, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	_, payload, err := jwtauth.FromContext(r.Context())
+	if err != nil {
+		log.Error().Err(err).Msg("get jwt payload")
+		s.Response(w, r).Status(http.StatusInternalServerError).
+			Error(http.StatusInternalServerError, "internal server error", nil)
+		return
+	}
+
+	userId, err := strconv.Atoi(payload["uid"].(string))
+	if err != nil {
+		log.Error().Err(err).Msg("atoi")
+		s.Response(w, r).Status(http.StatusInternalServerError).
+			Error(http.StatusInternalServerError, "internal server error", nil)
+		return
+	}
+
+	if err := s.shippingDomain.DeleteAddressById(r.Context(), userId, addressId); err != nil {
+		log.Error().Err(err).Msg("delete address")
+		s.shippingError(w, r, err)
+		return
+	}
+
+	s.Response(w, r).Status(http.StatusNoContent).End()
+}
@@ -0,0 +1,37 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/jwtauth/v5"
+	"github.com/rs/zerolog/log"
+)
+
+func (s *ServerDependency) AddressList(w http.ResponseWriter, r *http.Request) {
+	_, payload, err := jwtauth.FromContext(r.Context())
+	if err != nil {
+		log.Error().Err(err).Msg("get jwt payload")
+		s.Response(w, r).Status(http.StatusInternalServerError).
+			Error(http.StatusInternalServerError, "internal server error", nil)
+		return
+	}
+
+	userId, err := strconv.Atoi(payload["uid"].(string))
+	if err != nil {
+		log.Error().Err(err).Msg("atoi")
+		s.Response(w, r).Status(http.StatusInternalServerError).
+			Error(http.StatusInternalServerError, "internal server error", nil)
+		return
+	}
+
+	addresses, err := s.shippingDomain.GetAddresses(r.Context(), userId)
+	if err != nil {
+		log.Error().Err(err).Msg("get addresses")
+		s.Response(w, r).Status(http.StatusInternalServerError).
+			Error(http.StatusInternalServerError, "internal server error", nil)
+		return
+	}
+
+	s.Response(w, r).Data(addresses)
+}
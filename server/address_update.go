@@ -0,0 +1,49 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"sypchal/shipping"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/jwtauth/v5"
+	"github.com/rs/zerolog/log"
+)
+
+type AddressUpdateRequest AddressCreateRequest
+
+func (s *ServerDependency) AddressUpdate(w http.ResponseWriter, r *http.Request) {
+	addressId, _ := strconv.Atoi(chi.URLParam(r, "id"))
+	var requestBody AddressUpdateRequest
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		s.Response(w, r).Status(http.StatusBadRequest).
+			Error(http.StatusBadRequest, "invalid request body", nil)
+		return
+	}
+
+	_, payload, err := jwtauth.FromContext(r.Context())
+	if err != nil {
+		log.Error().Err(err).Msg("get jwt payload")
+		s.Response(w, r).Status(http.StatusInternalServerError).
+			Error(http.StatusInternalServerError, "internal server error", nil)
+		return
+	}
+
+	userId, err := strconv.Atoi(payload["uid"].(string))
+	if err != nil {
+		log.Error().Err(err).Msg("atoi")
+		s.Response(w, r).Status(http.StatusInternalServerError).
+			Error(http.StatusInternalServerError, "internal server error", nil)
+		return
+	}
+
+	address, err := s.shippingDomain.UpdateAddressById(r.Context(), userId, addressId, shipping.UpdateAddressRequest(requestBody))
+	if err != nil {
+		log.Error().Err(err).Msg("update address")
+		s.shippingError(w, r, err)
+		return
+	}
+
+	s.Response(w, r).Data(address)
+}
@@ -0,0 +1,47 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"sypchal/alert"
+	"sypchal/validation"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+)
+
+type AlertSetReorderThresholdRequest struct {
+	Threshold *int `json:"threshold"`
+}
+
+func (s *ServerDependency) AlertSetReorderThreshold(w http.ResponseWriter, r *http.Request) {
+	productId, _ := strconv.Atoi(chi.URLParam(r, "id"))
+	requestBody := AlertSetReorderThresholdRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		s.Response(w, r).Status(http.StatusBadRequest).
+			Error(http.StatusBadRequest, "invalid request body", nil)
+		return
+	}
+
+	err := s.alertDomain.SetReorderThreshold(r.Context(), alert.SetReorderThresholdRequest{
+		ProductId: productId,
+		Threshold: requestBody.Threshold,
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("set reorder threshold")
+
+		var ve *validation.ValidationErrors
+		if errors.As(err, &ve) {
+			s.Response(w, r).Status(http.StatusBadRequest).
+				Error(http.StatusBadRequest, "validation error", ve.Transform())
+			return
+		}
+
+		s.alertError(w, r, err)
+		return
+	}
+
+	s.Response(w, r).Data(requestBody)
+}
@@ -0,0 +1,94 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"sypchal/alert"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/jwtauth/v5"
+	"github.com/rs/zerolog/log"
+)
+
+type AlertSubscribeRequest struct {
+	VariantId *int `json:"variant_id"`
+}
+
+func (s *ServerDependency) AlertSubscribe(w http.ResponseWriter, r *http.Request) {
+	productId, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	// the body is optional for products without variants
+	var requestBody AlertSubscribeRequest
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil && !errors.Is(err, io.EOF) {
+		s.Response(w, r).Status(http.StatusBadRequest).
+			Error(http.StatusBadRequest, "invalid request body", nil)
+		return
+	}
+
+	_, payload, err := jwtauth.FromContext(r.Context())
+	if err != nil {
+		log.Error().Err(err).Msg("get jwt payload")
+		s.Response(w, r).Status(http.StatusInternalServerError).
+			Error(http.StatusInternalServerError, "internal server error", nil)
+		return
+	}
+
+	userId, err := strconv.Atoi(payload["uid"].(string))
+	if err != nil {
+		log.Error().Err(err).Msg("atoi")
+		s.Response(w, r).Status(http.StatusInternalServerError).
+			Error(http.StatusInternalServerError, "internal server error", nil)
+		return
+	}
+
+	subscription, err := s.alertDomain.Subscribe(r.Context(), alert.SubscribeRequest{
+		UserId:    userId,
+		ProductId: productId,
+		VariantId: requestBody.VariantId,
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("subscribe to restock")
+		s.alertError(w, r, err)
+		return
+	}
+
+	s.Response(w, r).Status(http.StatusCreated).Data(subscription)
+}
+
+func (s *ServerDependency) alertError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, alert.ErrProductNotFound) {
+		s.Response(w, r).Status(http.StatusBadRequest).
+			Error(http.StatusBadRequest, "product not found", nil)
+		return
+	}
+
+	if errors.Is(err, alert.ErrVariantNotFound) {
+		s.Response(w, r).Status(http.StatusBadRequest).
+			Error(http.StatusBadRequest, "variant not found", nil)
+		return
+	}
+
+	if errors.Is(err, alert.ErrVariantRequired) {
+		s.Response(w, r).Status(http.StatusBadRequest).
+			Error(http.StatusBadRequest, "variant_id is required for products with variants", nil)
+		return
+	}
+
+	if errors.Is(err, alert.ErrInStock) {
+		s.Response(w, r).Status(http.StatusConflict).
+			Error(http.StatusConflict, "product is in stock", nil)
+		return
+	}
+
+	if errors.Is(err, alert.ErrSubscriptionNotFound) {
+		s.Response(w, r).Status(http.StatusBadRequest).
+			Error(http.StatusBadRequest, "subscription not found", nil)
+		return
+	}
+
+	s.Response(w, r).Status(http.StatusInternalServerError).
+		Error(http.StatusInternalServerError, "internal server error", nil)
+}
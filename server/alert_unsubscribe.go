@@ -0,0 +1,44 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"sypchal/alert"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/jwtauth/v5"
+	"github.com/rs/zerolog/log"
+)
+
+func (s *ServerDependency) AlertUnsubscribe(w http.ResponseWriter, r *http.Request) {
+	productId, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	_, payload, err := jwtauth.FromContext(r.Context())
+	if err != nil {
+		log.Error().Err(err).Msg("get jwt payload")
+		s.Response(w, r).Status(http.StatusInternalServerError).
+			Error(http.StatusInternalServerError, "internal server error", nil)
+		return
+	}
+
+	userId, err := strconv.Atoi(payload["uid"].(string))
+	if err != nil {
+		log.Error().Err(err).Msg("atoi")
+		s.Response(w, r).Status(http.StatusInternalServerError).
+			Error(http.StatusInternalServerError, "internal server error", nil)
+		return
+	}
+
+	req := alert.SubscribeRequest{UserId: userId, ProductId: productId}
+	if variantId, err := strconv.Atoi(r.URL.Query().Get("variant_id")); err == nil {
+		req.VariantId = &variantId
+	}
+
+	if err := s.alertDomain.Unsubscribe(r.Context(), req); err != nil {
+		log.Error().Err(err).Msg("unsubscribe from restock")
+		s.alertError(w, r, err)
+		return
+	}
+
+	s.Response(w, r).Status(http.StatusNoContent).End()
+}
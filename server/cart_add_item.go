@@ -4,16 +4,15 @@ import (
 	"encoding/json"
 	"errors"
 	"net/http"
-	"strconv"
 	"sypchal/cart"
 	"sypchal/validation"
 
-	"github.com/go-chi/jwtauth/v5"
 	"github.com/rs/zerolog/log"
 )
 
 type CartAddItemRequest struct {
 	ProductId int `json:"product_id"`
+	VariantId int `json:"variant_id"`
 	Qty       int `json:"qty"`
 }
 
@@ -25,25 +24,15 @@ func (s *ServerDependency) CartAddItem(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	_, payload, err := jwtauth.FromContext(r.Context())
-	if err != nil {
-		log.Error().Err(err).Msg("get jwt payload")
-		s.Response(w, r).Status(http.StatusInternalServerError).
-			Error(http.StatusInternalServerError, "internal server error", nil)
-		return
-	}
-
-	userId, err := strconv.Atoi(payload["uid"].(string))
-	if err != nil {
-		log.Error().Err(err).Msg("atoi")
-		s.Response(w, r).Status(http.StatusInternalServerError).
-			Error(http.StatusInternalServerError, "internal server error", nil)
+	owner, ok := s.cartOwner(w, r, true)
+	if !ok {
 		return
 	}
 
 	count, err := s.cartDomain.AddCartItem(r.Context(), cart.AddCartItemRequest{
-		UserId:    userId,
+		Owner:     owner,
 		ProductId: requestBody.ProductId,
+		VariantId: requestBody.VariantId,
 		Qty:       requestBody.Qty,
 	})
 	if err != nil {
@@ -62,6 +51,18 @@ func (s *ServerDependency) CartAddItem(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		if errors.Is(err, cart.ErrVariantNotFound) {
+			s.Response(w, r).Status(http.StatusBadRequest).
+				Error(http.StatusBadRequest, "variant not found", nil)
+			return
+		}
+
+		if errors.Is(err, cart.ErrVariantRequired) {
+			s.Response(w, r).Status(http.StatusBadRequest).
+				Error(http.StatusBadRequest, "variant is required for this product", nil)
+			return
+		}
+
 		if errors.Is(err, cart.ErrProductOutOfStock) {
 			s.Response(w, r).Status(http.StatusBadRequest).
 				Error(http.StatusBadRequest, "product out of stock", nil)
@@ -0,0 +1,53 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/jwtauth/v5"
+	"github.com/rs/zerolog/log"
+)
+
+type CartApplyCouponRequest struct {
+	Code string `json:"code"`
+}
+
+func (s *ServerDependency) CartApplyCoupon(w http.ResponseWriter, r *http.Request) {
+	var requestBody CartApplyCouponRequest
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		s.Response(w, r).Status(http.StatusBadRequest).
+			Error(http.StatusBadRequest, "invalid request body", nil)
+		return
+	}
+
+	_, payload, err := jwtauth.FromContext(r.Context())
+	if err != nil {
+		log.Error().Err(err).Msg("get jwt payload")
+		s.Response(w, r).Status(http.StatusInternalServerError).
+			Error(http.StatusInternalServerError, "internal server error", nil)
+		return
+	}
+
+	userId, err := strconv.Atoi(payload["uid"].(string))
+	if err != nil {
+		log.Error().Err(err).Msg("atoi")
+		s.Response(w, r).Status(http.StatusInternalServerError).
+			Error(http.StatusInternalServerError, "internal server error", nil)
+		return
+	}
+
+	currency, _, ok := s.currency(w, r)
+	if !ok {
+		return
+	}
+
+	checkout, err := s.orderDomain.ApplyCoupon(r.Context(), userId, currency, requestBody.Code)
+	if err != nil {
+		log.Error().Err(err).Msg("apply coupon")
+		s.couponError(w, r, err)
+		return
+	}
+
+	s.Response(w, r).Data(checkout)
+}
@@ -0,0 +1,24 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/rs/zerolog/log"
+)
+
+func (s *ServerDependency) CartConfirmPrices(w http.ResponseWriter, r *http.Request) {
+	owner, ok := s.cartOwner(w, r, false)
+	if !ok {
+		return
+	}
+
+	changes, err := s.cartDomain.ConfirmPrices(r.Context(), owner)
+	if err != nil {
+		log.Error().Err(err).Msg("confirm cart prices")
+		s.Response(w, r).Status(http.StatusInternalServerError).
+			Error(http.StatusInternalServerError, "internal server error", nil)
+		return
+	}
+
+	s.Response(w, r).Data(map[string]interface{}{"confirmed": changes})
+}
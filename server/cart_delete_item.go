@@ -7,31 +7,19 @@ import (
 	"sypchal/cart"
 
 	"github.com/go-chi/chi/v5"
-	"github.com/go-chi/jwtauth/v5"
 	"github.com/rs/zerolog/log"
 )
 
 func (s *ServerDependency) CartDeleteItem(w http.ResponseWriter, r *http.Request) {
 	itemId, _ := strconv.Atoi(chi.URLParam(r, "id"))
 
-	_, payload, err := jwtauth.FromContext(r.Context())
-	if err != nil {
-		log.Error().Err(err).Msg("get jwt payload")
-		s.Response(w, r).Status(http.StatusInternalServerError).
-			Error(http.StatusInternalServerError, "internal server error", nil)
-		return
-	}
-
-	userId, err := strconv.Atoi(payload["uid"].(string))
-	if err != nil {
-		log.Error().Err(err).Msg("atoi")
-		s.Response(w, r).Status(http.StatusInternalServerError).
-			Error(http.StatusInternalServerError, "internal server error", nil)
+	owner, ok := s.cartOwner(w, r, false)
+	if !ok {
 		return
 	}
 
 	count, err := s.cartDomain.DeleteCartItem(r.Context(), cart.DeleteCartItemRequest{
-		UserId: userId,
+		Owner:  owner,
 		ItemId: itemId,
 	})
 	if err != nil {
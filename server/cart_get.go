@@ -2,36 +2,24 @@ package server
 
 import (
 	"net/http"
-	"strconv"
 
-	"github.com/go-chi/jwtauth/v5"
 	"github.com/rs/zerolog/log"
 )
 
 func (s *ServerDependency) CartGet(w http.ResponseWriter, r *http.Request) {
-	_, payload, err := jwtauth.FromContext(r.Context())
-	if err != nil {
-		log.Error().Err(err).Msg("get jwt payload")
-
-		s.Response(w, r).
-			Status(http.StatusInternalServerError).
-			Error(http.StatusInternalServerError, "internal server error", nil)
+	owner, ok := s.cartOwner(w, r, false)
+	if !ok {
 		return
 	}
 
-	userId, err := strconv.Atoi(payload["uid"].(string))
-	if err != nil {
-		log.Error().Err(err).Msg("atoi")
-
-		s.Response(w, r).
-			Status(http.StatusInternalServerError).
-			Error(http.StatusInternalServerError, "internal server error", nil)
+	currency, _, ok := s.currency(w, r)
+	if !ok {
 		return
 	}
 
-	cart, err := s.cartDomain.GetUserCart(r.Context(), userId)
+	cart, err := s.cartDomain.GetCart(r.Context(), owner, currency)
 	if err != nil {
-		log.Error().Err(err).Msg("get user cart")
+		log.Error().Err(err).Msg("get cart")
 
 		s.Response(w, r).
 			Status(http.StatusInternalServerError).
@@ -0,0 +1,83 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"sypchal/cart"
+
+	"github.com/rs/zerolog/log"
+)
+
+// CartTokenHeader carries the guest cart token of visitors who are not signed
+// in, requests send it back and responses set it when a guest cart starts.
+const CartTokenHeader = "X-Cart-Token"
+
+// cartOwner returns whose cart the request is about, the signed in customer or
+// the guest cart of the cart token. A guest cart is started for visitors
+// without either when create is true. It responds with an error when ok is
+// false.
+func (s *ServerDependency) cartOwner(w http.ResponseWriter, r *http.Request, create bool) (owner cart.Owner, ok bool) {
+	if userId, ok := viewerId(r); ok {
+		return cart.Owner{UserId: userId}, true
+	}
+
+	token := r.Header.Get(CartTokenHeader)
+	if token == "" && create {
+		guestCartId, token, err := s.cartDomain.CreateGuestCart(r.Context())
+		if err != nil {
+			log.Error().Err(err).Msg("create guest cart")
+			s.Response(w, r).Status(http.StatusInternalServerError).
+				Error(http.StatusInternalServerError, "internal server error", nil)
+			return owner, false
+		}
+
+		w.Header().Set(CartTokenHeader, token)
+		return cart.Owner{GuestCartId: guestCartId}, true
+	}
+
+	if token == "" {
+		s.Response(w, r).Status(http.StatusUnauthorized).
+			Error(http.StatusUnauthorized, "sign in or send a cart token", nil)
+		return owner, false
+	}
+
+	guestCartId, err := s.cartDomain.ParseGuestCartToken(r.Context(), token)
+	if err != nil {
+		log.Error().Err(err).Msg("parse guest cart token")
+
+		if errors.Is(err, cart.ErrInvalidCartToken) {
+			s.Response(w, r).Status(http.StatusUnauthorized).
+				Error(http.StatusUnauthorized, "invalid cart token", nil)
+			return owner, false
+		}
+
+		s.Response(w, r).Status(http.StatusInternalServerError).
+			Error(http.StatusInternalServerError, "internal server error", nil)
+		return owner, false
+	}
+
+	return cart.Owner{GuestCartId: guestCartId}, true
+}
+
+// mergeGuestCart moves the guest cart of the request, if any, into the cart of
+// the customer who just signed in. A stale token doesn't fail the sign in.
+func (s *ServerDependency) mergeGuestCart(r *http.Request, userId int) *cart.MergeResult {
+	token := r.Header.Get(CartTokenHeader)
+	if token == "" {
+		return nil
+	}
+
+	guestCartId, err := s.cartDomain.ParseGuestCartToken(r.Context(), token)
+	if err != nil {
+		log.Error().Err(err).Msg("parse guest cart token")
+		return nil
+	}
+
+	res, err := s.cartDomain.MergeGuestCart(r.Context(), guestCartId, userId)
+	if err != nil {
+		log.Error().Err(err).Msg("merge guest cart")
+		return nil
+	}
+
+	return res
+}
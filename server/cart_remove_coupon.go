@@ -0,0 +1,45 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"sypchal/order"
+
+	"github.com/go-chi/jwtauth/v5"
+	"github.com/rs/zerolog/log"
+)
+
+func (s *ServerDependency) CartRemoveCoupon(w http.ResponseWriter, r *http.Request) {
+	_, payload, err := jwtauth.FromContext(r.Context())
+	if err != nil {
+		log.Error().Err(err).Msg("get jwt payload")
+		s.Response(w, r).Status(http.StatusInternalServerError).
+			Error(http.StatusInternalServerError, "internal server error", nil)
+		return
+	}
+
+	userId, err := strconv.Atoi(payload["uid"].(string))
+	if err != nil {
+		log.Error().Err(err).Msg("atoi")
+		s.Response(w, r).Status(http.StatusInternalServerError).
+			Error(http.StatusInternalServerError, "internal server error", nil)
+		return
+	}
+
+	if err = s.orderDomain.RemoveCoupon(r.Context(), userId); err != nil {
+		log.Error().Err(err).Msg("remove coupon")
+
+		if errors.Is(err, order.ErrNoCoupon) {
+			s.Response(w, r).Status(http.StatusBadRequest).
+				Error(http.StatusBadRequest, "cart has no coupon", nil)
+			return
+		}
+
+		s.Response(w, r).Status(http.StatusInternalServerError).
+			Error(http.StatusInternalServerError, "internal server error", nil)
+		return
+	}
+
+	s.Response(w, r).Status(http.StatusNoContent).End()
+}
@@ -0,0 +1,40 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/jwtauth/v5"
+	"github.com/rs/zerolog/log"
+)
+
+// CartSaveForLater moves a line of the cart to the saved items.
+func (s *ServerDependency) CartSaveForLater(w http.ResponseWriter, r *http.Request) {
+	itemId, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	_, payload, err := jwtauth.FromContext(r.Context())
+	if err != nil {
+		log.Error().Err(err).Msg("get jwt payload")
+		s.Response(w, r).Status(http.StatusInternalServerError).
+			Error(http.StatusInternalServerError, "internal server error", nil)
+		return
+	}
+
+	userId, err := strconv.Atoi(payload["uid"].(string))
+	if err != nil {
+		log.Error().Err(err).Msg("atoi")
+		s.Response(w, r).Status(http.StatusInternalServerError).
+			Error(http.StatusInternalServerError, "internal server error", nil)
+		return
+	}
+
+	item, err := s.wishlistDomain.SaveForLater(r.Context(), userId, itemId)
+	if err != nil {
+		log.Error().Err(err).Msg("save for later")
+		s.wishlistError(w, r, err)
+		return
+	}
+
+	s.Response(w, r).Data(item)
+}
@@ -0,0 +1,55 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"sypchal/order"
+
+	"github.com/go-chi/jwtauth/v5"
+	"github.com/rs/zerolog/log"
+)
+
+type CartSetShippingRequest struct {
+	AddressId int `json:"address_id"`
+	MethodId  int `json:"method_id"`
+}
+
+func (s *ServerDependency) CartSetShipping(w http.ResponseWriter, r *http.Request) {
+	var requestBody CartSetShippingRequest
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		s.Response(w, r).Status(http.StatusBadRequest).
+			Error(http.StatusBadRequest, "invalid request body", nil)
+		return
+	}
+
+	_, payload, err := jwtauth.FromContext(r.Context())
+	if err != nil {
+		log.Error().Err(err).Msg("get jwt payload")
+		s.Response(w, r).Status(http.StatusInternalServerError).
+			Error(http.StatusInternalServerError, "internal server error", nil)
+		return
+	}
+
+	userId, err := strconv.Atoi(payload["uid"].(string))
+	if err != nil {
+		log.Error().Err(err).Msg("atoi")
+		s.Response(w, r).Status(http.StatusInternalServerError).
+			Error(http.StatusInternalServerError, "internal server error", nil)
+		return
+	}
+
+	currency, _, ok := s.currency(w, r)
+	if !ok {
+		return
+	}
+
+	checkout, err := s.orderDomain.SetShipping(r.Context(), userId, currency, order.SetShippingRequest(requestBody))
+	if err != nil {
+		log.Error().Err(err).Msg("set cart shipping")
+		s.shippingError(w, r, err)
+		return
+	}
+
+	s.Response(w, r).Data(checkout)
+}
@@ -9,7 +9,6 @@ import (
 	"sypchal/validation"
 
 	"github.com/go-chi/chi/v5"
-	"github.com/go-chi/jwtauth/v5"
 	"github.com/rs/zerolog/log"
 )
 
@@ -27,24 +26,13 @@ func (s *ServerDependency) CartUpdateItem(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	_, payload, err := jwtauth.FromContext(r.Context())
-	if err != nil {
-		log.Error().Err(err).Msg("get jwt payload")
-		s.Response(w, r).Status(http.StatusInternalServerError).
-			Error(http.StatusInternalServerError, "internal server error", nil)
-		return
-	}
-
-	userId, err := strconv.Atoi(payload["uid"].(string))
-	if err != nil {
-		log.Error().Err(err).Msg("atoi")
-		s.Response(w, r).Status(http.StatusInternalServerError).
-			Error(http.StatusInternalServerError, "internal server error", nil)
+	owner, ok := s.cartOwner(w, r, false)
+	if !ok {
 		return
 	}
 
 	count, err := s.cartDomain.UpdateCartItem(r.Context(), cart.UpdateCartItemRequest{
-		UserId: userId,
+		Owner:  owner,
 		ItemId: itemId,
 		Qty:    requestBody.Qty,
 	})
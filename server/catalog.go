@@ -0,0 +1,47 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/jwtauth/v5"
+)
+
+// viewerId is the signed in customer on the public catalog routes, ok is
+// false for anonymous visitors.
+func viewerId(r *http.Request) (int, bool) {
+	token, claims, err := jwtauth.FromContext(r.Context())
+	if err != nil || token == nil {
+		return 0, false
+	}
+
+	uid, _ := claims["uid"].(string)
+	userId, err := strconv.Atoi(uid)
+	if err != nil {
+		return 0, false
+	}
+
+	return userId, true
+}
+
+// setCatalogCache lets browsers and CDNs cache the anonymous catalog, by
+// currency. Responses to signed in customers may be personalized, they are
+// kept out of shared caches and revalidated every time.
+func (s *ServerDependency) setCatalogCache(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Vary", "Authorization")
+	w.Header().Add("Vary", CurrencyHeader)
+
+	if _, ok := viewerId(r); ok {
+		w.Header().Set("Cache-Control", "private, no-cache")
+		return
+	}
+
+	w.Header().Set("Cache-Control", "public, max-age="+seconds(s.catalogCache.MaxAge)+
+		", s-maxage="+seconds(s.catalogCache.SharedMaxAge)+
+		", stale-while-revalidate="+seconds(s.catalogCache.StaleWhileRevalidate))
+}
+
+func seconds(d time.Duration) string {
+	return strconv.Itoa(int(d.Seconds()))
+}
@@ -0,0 +1,82 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sypchal/coupon"
+	"sypchal/money"
+	"sypchal/validation"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+type CouponCreateRequest struct {
+	Code              string      `json:"code"`
+	Type              string      `json:"type"`
+	Value             int         `json:"value"`
+	MinOrderValue     money.Money `json:"min_order_value"`
+	UsageLimit        *int        `json:"usage_limit"`
+	UsageLimitPerUser *int        `json:"usage_limit_per_user"`
+	StartsAt          *time.Time  `json:"starts_at"`
+	EndsAt            *time.Time  `json:"ends_at"`
+	Categories        []string    `json:"categories"`
+	ProductIds        []int       `json:"product_ids"`
+	Active            bool        `json:"active"`
+}
+
+func (s *ServerDependency) CouponCreate(w http.ResponseWriter, r *http.Request) {
+	requestBody := CouponCreateRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		s.Response(w, r).Status(http.StatusBadRequest).
+			Error(http.StatusBadRequest, "invalid request body", nil)
+		return
+	}
+
+	c, err := s.couponDomain.CreateCoupon(r.Context(), coupon.CreateCouponRequest(requestBody))
+	if err != nil {
+		log.Error().Err(err).Msg("create coupon")
+		s.couponError(w, r, err)
+		return
+	}
+
+	s.Response(w, r).Status(http.StatusCreated).Data(c)
+}
+
+func (s *ServerDependency) couponError(w http.ResponseWriter, r *http.Request, err error) {
+	var ve *validation.ValidationErrors
+	if errors.As(err, &ve) {
+		s.Response(w, r).Status(http.StatusBadRequest).
+			Error(http.StatusBadRequest, "validation error", ve.Transform())
+		return
+	}
+
+	if errors.Is(err, coupon.ErrCodeTaken) {
+		s.Response(w, r).Status(http.StatusConflict).
+			Error(http.StatusConflict, "coupon code already exists", nil)
+		return
+	}
+
+	// the reasons a coupon can't be used, e.g. expired, are their own message
+	for _, target := range []error{
+		coupon.ErrCouponNotFound,
+		coupon.ErrInvalidValue,
+		coupon.ErrInvalidWindow,
+		coupon.ErrCouponNotStarted,
+		coupon.ErrCouponExpired,
+		coupon.ErrCouponUsedUp,
+		coupon.ErrCouponUserLimit,
+		coupon.ErrMinOrderValue,
+		coupon.ErrCouponNotApplicable,
+	} {
+		if errors.Is(err, target) {
+			s.Response(w, r).Status(http.StatusBadRequest).
+				Error(http.StatusBadRequest, target.Error(), nil)
+			return
+		}
+	}
+
+	s.Response(w, r).Status(http.StatusInternalServerError).
+		Error(http.StatusInternalServerError, "internal server error", nil)
+}
@@ -0,0 +1,36 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"sypchal/coupon"
+
+	"github.com/rs/zerolog/log"
+)
+
+func (s *ServerDependency) CouponList(w http.ResponseWriter, r *http.Request) {
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil {
+		limit = 10
+	}
+
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil {
+		page = 1
+	}
+
+	offset := limit * (page - 1)
+
+	res, err := s.couponDomain.GetCoupons(r.Context(), coupon.GetCouponsRequest{
+		Limit:  limit,
+		Offset: offset,
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("get coupons")
+		s.Response(w, r).Status(http.StatusInternalServerError).
+			Error(http.StatusInternalServerError, "internal server error", nil)
+		return
+	}
+
+	s.Response(w, r).Data(res)
+}
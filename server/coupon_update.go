@@ -0,0 +1,32 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"sypchal/coupon"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+)
+
+type CouponUpdateRequest CouponCreateRequest
+
+func (s *ServerDependency) CouponUpdate(w http.ResponseWriter, r *http.Request) {
+	couponId, _ := strconv.Atoi(chi.URLParam(r, "id"))
+	requestBody := CouponUpdateRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		s.Response(w, r).Status(http.StatusBadRequest).
+			Error(http.StatusBadRequest, "invalid request body", nil)
+		return
+	}
+
+	c, err := s.couponDomain.UpdateCouponById(r.Context(), couponId, coupon.UpdateCouponRequest(requestBody))
+	if err != nil {
+		log.Error().Err(err).Msg("update coupon")
+		s.couponError(w, r, err)
+		return
+	}
+
+	s.Response(w, r).Data(c)
+}
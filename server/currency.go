@@ -0,0 +1,57 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"sypchal/exchange"
+	"sypchal/money"
+	"sypchal/user"
+
+	"github.com/rs/zerolog/log"
+)
+
+// CurrencyHeader chooses the currency prices are shown and charged in, over
+// the preference of the signed in customer.
+const CurrencyHeader = "X-Currency"
+
+// currency returns the currency of the request, from the currency header, the
+// preference of the signed in customer or else the base currency, and the
+// rates to price it. It responds with an error when ok is false.
+func (s *ServerDependency) currency(w http.ResponseWriter, r *http.Request) (currency money.Currency, rates *exchange.Rates, ok bool) {
+	rates, err := s.exchangeDomain.LoadRates(r.Context())
+	if err != nil {
+		log.Error().Err(err).Msg("load exchange rates")
+		s.Response(w, r).Status(http.StatusInternalServerError).
+			Error(http.StatusInternalServerError, "internal server error", nil)
+		return
+	}
+
+	if code := strings.TrimSpace(r.Header.Get(CurrencyHeader)); code != "" {
+		currency, err = money.ParseCurrency(code)
+		if err != nil || !rates.Supports(currency) {
+			s.Response(w, r).Status(http.StatusBadRequest).
+				Error(http.StatusBadRequest, "currency is not supported", map[string]any{"currencies": rates.Currencies()})
+			return
+		}
+
+		return currency, rates, true
+	}
+
+	// a preference whose rate was removed falls back to the base currency
+	if userId, signedIn := viewerId(r); signedIn {
+		currency, err = s.userDomain.GetCurrency(r.Context(), userId)
+		if err != nil && !errors.Is(err, user.ErrUserNotFound) {
+			log.Error().Err(err).Msg("get user currency")
+			s.Response(w, r).Status(http.StatusInternalServerError).
+				Error(http.StatusInternalServerError, "internal server error", nil)
+			return
+		}
+
+		if currency != "" && rates.Supports(currency) {
+			return currency, rates, true
+		}
+	}
+
+	return rates.Base, rates, true
+}
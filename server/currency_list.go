@@ -0,0 +1,27 @@
+package server
+
+import (
+	"net/http"
+	"sypchal/money"
+
+	"github.com/rs/zerolog/log"
+)
+
+type CurrencyListResponse struct {
+	Base       money.Currency   `json:"base"`
+	Currencies []money.Currency `json:"currencies"`
+}
+
+// CurrencyList lists the currencies prices can be shown and charged in.
+func (s *ServerDependency) CurrencyList(w http.ResponseWriter, r *http.Request) {
+	rates, err := s.exchangeDomain.LoadRates(r.Context())
+	if err != nil {
+		log.Error().Err(err).Msg("load exchange rates")
+
+		s.Response(w, r).Status(http.StatusInternalServerError).
+			Error(http.StatusInternalServerError, "internal server error", nil)
+		return
+	}
+
+	s.Response(w, r).Data(CurrencyListResponse{rates.Base, rates.Currencies()})
+}
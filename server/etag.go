@@ -0,0 +1,50 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	prd "sypchal/product"
+)
+
+func productETag(product *prd.Product) string {
+	return `"` + strconv.Itoa(product.Version) + `"`
+}
+
+// ifMatchVersion reads the product version out of the If-Match header,
+// "*" matches any version. It responds with 428 when the header is missing
+// and 412 when it can't be a product etag.
+func (s *ServerDependency) ifMatchVersion(w http.ResponseWriter, r *http.Request) (int, bool) {
+	header := strings.TrimSpace(r.Header.Get("If-Match"))
+	if header == "" {
+		s.Response(w, r).Status(http.StatusPreconditionRequired).
+			Error(http.StatusPreconditionRequired, "If-Match header is required", nil)
+		return 0, false
+	}
+
+	if header == "*" {
+		return prd.AnyVersion, true
+	}
+
+	// weak etags never match for If-Match
+	version, err := strconv.Atoi(strings.Trim(header, `"`))
+	if err != nil || strings.HasPrefix(header, "W/") || version < 1 {
+		s.Response(w, r).Status(http.StatusPreconditionFailed).
+			Error(http.StatusPreconditionFailed, "product has been modified", nil)
+		return 0, false
+	}
+
+	return version, true
+}
+
+// etagMatches reports whether an If-None-Match header lists etag.
+func etagMatches(header string, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,18 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+)
+
+func (s *ServerDependency) ExchangeRateDelete(w http.ResponseWriter, r *http.Request) {
+	if err := s.exchangeDomain.DeleteRate(r.Context(), chi.URLParam(r, "currency")); err != nil {
+		log.Error().Err(err).Msg("delete exchange rate")
+		s.exchangeRateError(w, r, err)
+		return
+	}
+
+	s.Response(w, r).Status(http.StatusNoContent).End()
+}
@@ -0,0 +1,49 @@
+package server
+
+import (
+	"mime"
+	"net/http"
+	"sypchal/exchange"
+	"sypchal/money"
+
+	"github.com/rs/zerolog/log"
+)
+
+func (s *ServerDependency) ExchangeRateImport(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if mediaType == "text/csv" {
+			format = "csv"
+		} else {
+			format = "json"
+		}
+	}
+
+	var rates map[string]money.Rate
+	var err error
+	switch format {
+	case "csv":
+		rates, err = exchange.ParseRatesCSV(r.Body)
+	case "json":
+		rates, err = exchange.ParseRatesJSON(r.Body, s.exchangeDomain.Base())
+	default:
+		s.Response(w, r).Status(http.StatusBadRequest).
+			Error(http.StatusBadRequest, "format must be csv or json", nil)
+		return
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("parse exchange rates")
+		s.exchangeRateError(w, r, err)
+		return
+	}
+
+	imported, err := s.exchangeDomain.ImportRates(r.Context(), rates)
+	if err != nil {
+		log.Error().Err(err).Msg("import exchange rates")
+		s.exchangeRateError(w, r, err)
+		return
+	}
+
+	s.Response(w, r).Data(imported)
+}
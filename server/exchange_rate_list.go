@@ -0,0 +1,27 @@
+package server
+
+import (
+	"net/http"
+	"sypchal/exchange"
+	"sypchal/money"
+
+	"github.com/rs/zerolog/log"
+)
+
+type ExchangeRateListResponse struct {
+	Base  money.Currency   `json:"base"`
+	Rates []*exchange.Rate `json:"rates"`
+}
+
+func (s *ServerDependency) ExchangeRateList(w http.ResponseWriter, r *http.Request) {
+	rates, err := s.exchangeDomain.GetRates(r.Context())
+	if err != nil {
+		log.Error().Err(err).Msg("get exchange rates")
+
+		s.Response(w, r).Status(http.StatusInternalServerError).
+			Error(http.StatusInternalServerError, "internal server error", nil)
+		return
+	}
+
+	s.Response(w, r).Data(ExchangeRateListResponse{s.exchangeDomain.Base(), rates})
+}
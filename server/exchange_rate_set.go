@@ -0,0 +1,61 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sypchal/exchange"
+	"sypchal/money"
+	"sypchal/validation"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+)
+
+type ExchangeRateSetRequest struct {
+	Rate money.Rate `json:"rate"`
+}
+
+func (s *ServerDependency) ExchangeRateSet(w http.ResponseWriter, r *http.Request) {
+	requestBody := ExchangeRateSetRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		s.Response(w, r).Status(http.StatusBadRequest).
+			Error(http.StatusBadRequest, "invalid request body", nil)
+		return
+	}
+
+	rate, err := s.exchangeDomain.SetRate(r.Context(), chi.URLParam(r, "currency"), exchange.SetRateRequest(requestBody))
+	if err != nil {
+		log.Error().Err(err).Msg("set exchange rate")
+		s.exchangeRateError(w, r, err)
+		return
+	}
+
+	s.Response(w, r).Data(rate)
+}
+
+func (s *ServerDependency) exchangeRateError(w http.ResponseWriter, r *http.Request, err error) {
+	var ve *validation.ValidationErrors
+	if errors.As(err, &ve) {
+		s.Response(w, r).Status(http.StatusBadRequest).
+			Error(http.StatusBadRequest, "validation error", ve.Transform())
+		return
+	}
+
+	for _, target := range []error{money.ErrUnknownCurrency, exchange.ErrBaseCurrency, exchange.ErrInvalidRateFile} {
+		if errors.Is(err, target) {
+			s.Response(w, r).Status(http.StatusBadRequest).
+				Error(http.StatusBadRequest, err.Error(), nil)
+			return
+		}
+	}
+
+	if errors.Is(err, exchange.ErrRateNotFound) {
+		s.Response(w, r).Status(http.StatusNotFound).
+			Error(http.StatusNotFound, "exchange rate not found", nil)
+		return
+	}
+
+	s.Response(w, r).Status(http.StatusInternalServerError).
+		Error(http.StatusInternalServerError, "internal server error", nil)
+}
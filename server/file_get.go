@@ -0,0 +1,39 @@
+package server
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"sypchal/upload"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+)
+
+func (s *ServerDependency) FileGet(w http.ResponseWriter, r *http.Request) {
+	key := chi.URLParam(r, "*")
+
+	body, contentType, err := s.uploadDomain.OpenPublic(r.Context(), key)
+	if err != nil {
+		if errors.Is(err, upload.ErrFileNotFound) {
+			s.Response(w, r).Status(http.StatusNotFound).
+				Error(http.StatusNotFound, "file not found", nil)
+			return
+		}
+
+		log.Error().Err(err).Msg("open file")
+		s.Response(w, r).Status(http.StatusInternalServerError).
+			Error(http.StatusInternalServerError, "internal server error", nil)
+		return
+	}
+	defer body.Close()
+
+	// product media keys are random and never overwritten, so the content
+	// never changes
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	if _, err := io.Copy(w, body); err != nil {
+		log.Error().Err(err).Msg("write file")
+	}
+}
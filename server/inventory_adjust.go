@@ -0,0 +1,88 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"sypchal/inventory"
+	"sypchal/validation"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+)
+
+type InventoryAdjustRequest struct {
+	WarehouseId int    `json:"warehouse_id"`
+	VariantId   *int   `json:"variant_id"`
+	Quantity    int    `json:"quantity"`
+	Reason      string `json:"reason"`
+	Reference   string `json:"reference"`
+	Note        string `json:"note"`
+}
+
+func (s *ServerDependency) InventoryAdjust(w http.ResponseWriter, r *http.Request) {
+	productId, _ := strconv.Atoi(chi.URLParam(r, "id"))
+	requestBody := InventoryAdjustRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		s.Response(w, r).Status(http.StatusBadRequest).
+			Error(http.StatusBadRequest, "invalid request body", nil)
+		return
+	}
+
+	movement, err := s.inventoryDomain.AdjustStock(r.Context(), inventory.AdjustStockRequest{
+		ProductId:   productId,
+		WarehouseId: requestBody.WarehouseId,
+		VariantId:   requestBody.VariantId,
+		Quantity:    requestBody.Quantity,
+		Reason:      requestBody.Reason,
+		Reference:   requestBody.Reference,
+		Note:        requestBody.Note,
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("adjust stock")
+
+		var ve *validation.ValidationErrors
+		if errors.As(err, &ve) {
+			s.Response(w, r).Status(http.StatusBadRequest).
+				Error(http.StatusBadRequest, "validation error", ve.Transform())
+			return
+		}
+
+		if errors.Is(err, inventory.ErrProductNotFound) {
+			s.Response(w, r).Status(http.StatusBadRequest).
+				Error(http.StatusBadRequest, "product not found", nil)
+			return
+		}
+
+		if errors.Is(err, inventory.ErrVariantNotFound) {
+			s.Response(w, r).Status(http.StatusBadRequest).
+				Error(http.StatusBadRequest, "variant not found", nil)
+			return
+		}
+
+		if errors.Is(err, inventory.ErrWarehouseNotFound) {
+			s.Response(w, r).Status(http.StatusBadRequest).
+				Error(http.StatusBadRequest, "warehouse not found", nil)
+			return
+		}
+
+		if errors.Is(err, inventory.ErrVariantRequired) {
+			s.Response(w, r).Status(http.StatusBadRequest).
+				Error(http.StatusBadRequest, "variant_id is required for products with variants", nil)
+			return
+		}
+
+		if errors.Is(err, inventory.ErrInsufficientStock) {
+			s.Response(w, r).Status(http.StatusConflict).
+				Error(http.StatusConflict, "stock can't become negative", nil)
+			return
+		}
+
+		s.Response(w, r).Status(http.StatusInternalServerError).
+			Error(http.StatusInternalServerError, "internal server error", nil)
+		return
+	}
+
+	s.Response(w, r).Status(http.StatusCreated).Data(movement)
+}
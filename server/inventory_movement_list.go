@@ -0,0 +1,49 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"sypchal/inventory"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+)
+
+func (s *ServerDependency) InventoryMovementList(w http.ResponseWriter, r *http.Request) {
+	productId, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil {
+		limit = 10
+	}
+
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil {
+		page = 1
+	}
+
+	offset := limit * (page - 1)
+
+	req := inventory.GetMovementsRequest{
+		ProductId: productId,
+		Limit:     limit,
+		Offset:    offset,
+	}
+	if variantId, err := strconv.Atoi(r.URL.Query().Get("variant_id")); err == nil {
+		req.VariantId = &variantId
+	}
+	if warehouseId, err := strconv.Atoi(r.URL.Query().Get("warehouse_id")); err == nil {
+		req.WarehouseId = &warehouseId
+	}
+
+	res, err := s.inventoryDomain.GetMovements(r.Context(), req)
+	if err != nil {
+		log.Error().Err(err).Msg("get stock movements")
+
+		s.Response(w, r).Status(http.StatusInternalServerError).
+			Error(http.StatusInternalServerError, "internal server error", nil)
+		return
+	}
+
+	s.Response(w, r).Data(res)
+}
@@ -0,0 +1,22 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/rs/zerolog/log"
+)
+
+// InventoryReconcile lists the stocks that don't match their ledger, POST
+// also resets them to the ledger.
+func (s *ServerDependency) InventoryReconcile(w http.ResponseWriter, r *http.Request) {
+	discrepancies, err := s.inventoryDomain.ReconcileStock(r.Context(), r.Method == http.MethodPost)
+	if err != nil {
+		log.Error().Err(err).Msg("reconcile stock")
+
+		s.Response(w, r).Status(http.StatusInternalServerError).
+			Error(http.StatusInternalServerError, "internal server error", nil)
+		return
+	}
+
+	s.Response(w, r).Data(discrepancies)
+}
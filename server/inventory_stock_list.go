@@ -0,0 +1,24 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+)
+
+func (s *ServerDependency) InventoryStockList(w http.ResponseWriter, r *http.Request) {
+	productId, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	stocks, err := s.inventoryDomain.GetProductStocks(r.Context(), productId)
+	if err != nil {
+		log.Error().Err(err).Msg("get product stocks")
+
+		s.Response(w, r).Status(http.StatusInternalServerError).
+			Error(http.StatusInternalServerError, "internal server error", nil)
+		return
+	}
+
+	s.Response(w, r).Data(stocks)
+}
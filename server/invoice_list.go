@@ -0,0 +1,43 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"sypchal/invoice"
+
+	"github.com/rs/zerolog/log"
+)
+
+// InvoiceList lists the invoices and credit notes of every order for the
+// admins.
+func (s *ServerDependency) InvoiceList(w http.ResponseWriter, r *http.Request) {
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil {
+		limit = 10
+	}
+
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil {
+		page = 1
+	}
+
+	offset := limit * (page - 1)
+
+	orderId, _ := strconv.Atoi(r.URL.Query().Get("order_id"))
+
+	res, err := s.invoiceDomain.GetInvoices(r.Context(), invoice.GetInvoicesRequest{
+		Kind:    r.URL.Query().Get("kind"),
+		OrderId: orderId,
+		Limit:   limit,
+		Offset:  offset,
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("get invoices")
+
+		s.Response(w, r).Status(http.StatusInternalServerError).
+			Error(http.StatusInternalServerError, "internal server error", nil)
+		return
+	}
+
+	s.Response(w, r).Data(res)
+}
@@ -0,0 +1,61 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"sypchal/invoice"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/jwtauth/v5"
+	"github.com/rs/zerolog/log"
+)
+
+// InvoiceOrderList lists the invoice and the credit notes of an order of the
+// customer.
+func (s *ServerDependency) InvoiceOrderList(w http.ResponseWriter, r *http.Request) {
+	orderId, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	_, payload, err := jwtauth.FromContext(r.Context())
+	if err != nil {
+		log.Error().Err(err).Msg("get jwt payload")
+		s.Response(w, r).Status(http.StatusInternalServerError).
+			Error(http.StatusInternalServerError, "internal server error", nil)
+		return
+	}
+
+	userId, err := strconv.Atoi(payload["uid"].(string))
+	if err != nil {
+		log.Error().Err(err).Msg("atoi")
+		s.Response(w, r).Status(http.StatusInternalServerError).
+			Error(http.StatusInternalServerError, "internal server error", nil)
+		return
+	}
+
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil {
+		limit = 10
+	}
+
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil {
+		page = 1
+	}
+
+	offset := limit * (page - 1)
+
+	res, err := s.invoiceDomain.GetInvoices(r.Context(), invoice.GetInvoicesRequest{
+		OrderId: orderId,
+		UserId:  userId,
+		Limit:   limit,
+		Offset:  offset,
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("get invoices")
+
+		s.Response(w, r).Status(http.StatusInternalServerError).
+			Error(http.StatusInternalServerError, "internal server error", nil)
+		return
+	}
+
+	s.Response(w, r).Data(res)
+}
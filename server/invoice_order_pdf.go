@@ -0,0 +1,74 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"sypchal/invoice"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/jwtauth/v5"
+	"github.com/rs/zerolog/log"
+)
+
+// InvoiceOrderPdf downloads the invoice of an order of the customer, or one of
+// its credit notes by invoice_id.
+func (s *ServerDependency) InvoiceOrderPdf(w http.ResponseWriter, r *http.Request) {
+	orderId, _ := strconv.Atoi(chi.URLParam(r, "id"))
+	invoiceId, _ := strconv.Atoi(chi.URLParam(r, "invoice_id"))
+
+	_, payload, err := jwtauth.FromContext(r.Context())
+	if err != nil {
+		log.Error().Err(err).Msg("get jwt payload")
+		s.Response(w, r).Status(http.StatusInternalServerError).
+			Error(http.StatusInternalServerError, "internal server error", nil)
+		return
+	}
+
+	userId, err := strconv.Atoi(payload["uid"].(string))
+	if err != nil {
+		log.Error().Err(err).Msg("atoi")
+		s.Response(w, r).Status(http.StatusInternalServerError).
+			Error(http.StatusInternalServerError, "internal server error", nil)
+		return
+	}
+
+	var inv *invoice.Invoice
+	if invoiceId == 0 {
+		inv, err = s.invoiceDomain.GetOrderInvoice(r.Context(), userId, orderId)
+	} else {
+		inv, err = s.invoiceDomain.GetInvoiceById(r.Context(), userId, invoiceId)
+		if err == nil && inv.OrderId != orderId {
+			err = invoice.ErrInvoiceNotFound
+		}
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("get invoice")
+		s.invoiceError(w, r, err)
+		return
+	}
+
+	s.invoicePdf(w, r, inv)
+}
+
+// invoicePdf writes the PDF of an invoice inline, named after its number.
+func (s *ServerDependency) invoicePdf(w http.ResponseWriter, r *http.Request, inv *invoice.Invoice) {
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", `inline; filename="`+inv.Number+`.pdf"`)
+
+	// the response is already partially written, all we can do is log it
+	if err := inv.WritePDF(w); err != nil {
+		log.Error().Err(err).Msg("write invoice pdf")
+	}
+}
+
+func (s *ServerDependency) invoiceError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, invoice.ErrInvoiceNotFound) {
+		s.Response(w, r).Status(http.StatusNotFound).
+			Error(http.StatusNotFound, "invoice not found", nil)
+		return
+	}
+
+	s.Response(w, r).Status(http.StatusInternalServerError).
+		Error(http.StatusInternalServerError, "internal server error", nil)
+}
@@ -0,0 +1,22 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+)
+
+func (s *ServerDependency) InvoicePdf(w http.ResponseWriter, r *http.Request) {
+	invoiceId, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	inv, err := s.invoiceDomain.GetInvoiceById(r.Context(), 0, invoiceId)
+	if err != nil {
+		log.Error().Err(err).Msg("get invoice by id")
+		s.invoiceError(w, r, err)
+		return
+	}
+
+	s.invoicePdf(w, r, inv)
+}
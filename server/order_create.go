@@ -31,10 +31,22 @@ func (s *ServerDependency) OrderCreate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	userOrder, err := s.orderDomain.PlaceOrder(r.Context(), userId)
+	currency, _, ok := s.currency(w, r)
+	if !ok {
+		return
+	}
+
+	userOrder, err := s.orderDomain.PlaceOrder(r.Context(), userId, currency)
 	if err != nil {
 		log.Error().Err(err).Msg("place order")
 
+		if errors.Is(err, order.ErrCartEmpty) {
+			s.Response(w, r).
+				Status(http.StatusBadRequest).
+				Error(http.StatusBadRequest, "cart is empty", nil)
+			return
+		}
+
 		if errors.Is(err, order.ErrItemOutOfStock) {
 			s.Response(w, r).
 				Status(http.StatusBadRequest).
@@ -42,6 +54,41 @@ func (s *ServerDependency) OrderCreate(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		if errors.Is(err, order.ErrItemQtyLimit) {
+			s.Response(w, r).
+				Status(http.StatusBadRequest).
+				Error(http.StatusBadRequest, "item quantity over the order limit", nil)
+			return
+		}
+
+		var pe *order.PriceChangedError
+		if errors.As(err, &pe) {
+			s.Response(w, r).
+				Status(http.StatusConflict).
+				Error(http.StatusConflict, "cart prices changed, confirm them before ordering", pe.Changes)
+			return
+		}
+
+		for _, target := range []error{
+			order.ErrNoShippingAddress,
+			order.ErrNoShippingMethod,
+			order.ErrShippingMethodUnavailable,
+		} {
+			if errors.Is(err, target) {
+				s.Response(w, r).
+					Status(http.StatusBadRequest).
+					Error(http.StatusBadRequest, target.Error(), nil)
+				return
+			}
+		}
+
+		if errors.Is(err, order.ErrSplitShipment) {
+			s.Response(w, r).
+				Status(http.StatusConflict).
+				Error(http.StatusConflict, "order can't be shipped from a single warehouse", nil)
+			return
+		}
+
 		s.Response(w, r).
 			Status(http.StatusInternalServerError).
 			Error(http.StatusInternalServerError, "internal server error", nil)
@@ -0,0 +1,49 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"sypchal/order"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/jwtauth/v5"
+	"github.com/rs/zerolog/log"
+)
+
+func (s *ServerDependency) OrderGet(w http.ResponseWriter, r *http.Request) {
+	orderId, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	_, payload, err := jwtauth.FromContext(r.Context())
+	if err != nil {
+		log.Error().Err(err).Msg("get jwt payload")
+		s.Response(w, r).Status(http.StatusInternalServerError).
+			Error(http.StatusInternalServerError, "internal server error", nil)
+		return
+	}
+
+	userId, err := strconv.Atoi(payload["uid"].(string))
+	if err != nil {
+		log.Error().Err(err).Msg("atoi")
+		s.Response(w, r).Status(http.StatusInternalServerError).
+			Error(http.StatusInternalServerError, "internal server error", nil)
+		return
+	}
+
+	detail, err := s.orderDomain.GetUserOrderById(r.Context(), userId, orderId)
+	if err != nil {
+		log.Error().Err(err).Msg("get user order by id")
+
+		if errors.Is(err, order.ErrOrderNotFound) {
+			s.Response(w, r).Status(http.StatusNotFound).
+				Error(http.StatusNotFound, "order not found", nil)
+			return
+		}
+
+		s.Response(w, r).Status(http.StatusInternalServerError).
+			Error(http.StatusInternalServerError, "internal server error", nil)
+		return
+	}
+
+	s.Response(w, r).Data(detail)
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"net/http"
 	"strconv"
+	"sypchal/money"
 	"sypchal/order"
 	"sypchal/validation"
 
@@ -14,10 +15,10 @@ import (
 )
 
 type OrderPayRequest struct {
-	OrderId  int    `json:"order_id"`
-	ProofUrl string `json:"proof_url"`
-	Amount   int    `json:"amount"`
-	Method   string `json:"method"`
+	OrderId  int         `json:"order_id"`
+	ProofUrl string      `json:"proof_url"`
+	Amount   money.Money `json:"amount"`
+	Method   string      `json:"method"`
 }
 
 func (s *ServerDependency) OrderPay(w http.ResponseWriter, r *http.Request) {
@@ -81,6 +82,12 @@ func (s *ServerDependency) OrderPay(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		if errors.Is(err, order.ErrPayCurrencyMismatch) {
+			s.Response(w, r).Status(http.StatusBadRequest).
+				Error(http.StatusBadRequest, "pay currency doesn't match the order currency", nil)
+			return
+		}
+
 		if errors.Is(err, order.ErrPayAmountNotMatch) {
 			s.Response(w, r).Status(http.StatusBadRequest).
 				Error(http.StatusBadRequest, "pay amount not match", nil)
@@ -0,0 +1,74 @@
+package server
+
+import (
+	"crypto/subtle"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"sypchal/upload"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+)
+
+// PaymentProofGet serves a payment proof, or its thumbnail, to the customer it
+// belongs to or to the admin.
+func (s *ServerDependency) PaymentProofGet(w http.ResponseWriter, r *http.Request) {
+	userId, err := strconv.Atoi(chi.URLParam(r, "user_id"))
+	if err != nil {
+		s.Response(w, r).Status(http.StatusNotFound).
+			Error(http.StatusNotFound, "file not found", nil)
+		return
+	}
+
+	viewer, signedIn := viewerId(r)
+	if !(signedIn && viewer == userId) && !s.isAdmin(r) {
+		// other customers can't tell whether the proof exists
+		if signedIn {
+			s.Response(w, r).Status(http.StatusNotFound).
+				Error(http.StatusNotFound, "file not found", nil)
+			return
+		}
+
+		w.Header().Set("WWW-Authenticate", `Basic realm="admin area"`)
+		s.Response(w, r).Status(http.StatusUnauthorized).
+			Error(http.StatusUnauthorized, "sign in or use the admin credentials", nil)
+		return
+	}
+
+	body, contentType, err := s.uploadDomain.OpenPaymentProof(r.Context(), userId, chi.URLParam(r, "*"))
+	if err != nil {
+		if errors.Is(err, upload.ErrFileNotFound) {
+			s.Response(w, r).Status(http.StatusNotFound).
+				Error(http.StatusNotFound, "file not found", nil)
+			return
+		}
+
+		log.Error().Err(err).Msg("open payment proof")
+		s.Response(w, r).Status(http.StatusInternalServerError).
+			Error(http.StatusInternalServerError, "internal server error", nil)
+		return
+	}
+	defer body.Close()
+
+	// receipts hold bank details, keep them out of every cache
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", "private, no-store")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	if _, err := io.Copy(w, body); err != nil {
+		log.Error().Err(err).Msg("write payment proof")
+	}
+}
+
+// isAdmin tells whether the request has the basic auth credentials of the
+// admin area.
+func (s *ServerDependency) isAdmin(r *http.Request) bool {
+	username, password, ok := r.BasicAuth()
+	if !ok {
+		return false
+	}
+
+	expected, ok := s.admin[username]
+	return ok && subtle.ConstantTimeCompare([]byte(password), []byte(expected)) == 1
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"errors"
 	"net/http"
+	"sypchal/money"
 	prd "sypchal/product"
 	"sypchal/validation"
 
@@ -11,12 +12,18 @@ import (
 )
 
 type ProductCreateRequest struct {
-	Name        string `json:"name"`
-	Description string `json:"description"`
-	ImageUrl    string `json:"image_url"`
-	Category    string `json:"category"`
-	Stock       int    `json:"stock"`
-	Price       int    `json:"price"`
+	Sku         string      `json:"sku"`
+	Name        string      `json:"name"`
+	Description string      `json:"description"`
+	ImageUrl    string      `json:"image_url"`
+	Category    string      `json:"category"`
+	TaxClass    string      `json:"tax_class"`
+	Stock       int         `json:"stock"`
+	Price       money.Money `json:"price"`
+	Weight      int         `json:"weight"`
+	Length      int         `json:"length"`
+	Width       int         `json:"width"`
+	Height      int         `json:"height"`
 }
 
 func (s *ServerDependency) ProductCreate(w http.ResponseWriter, r *http.Request) {
@@ -39,11 +46,18 @@ func (s *ServerDependency) ProductCreate(w http.ResponseWriter, r *http.Request)
 			return
 		}
 
+		if errors.Is(err, prd.ErrSkuAlreadyExists) {
+			s.Response(w, r).Status(http.StatusBadRequest).
+				Error(http.StatusBadRequest, "sku already exists", nil)
+			return
+		}
+
 		s.Response(w, r).Status(http.StatusInternalServerError).
 			Error(http.StatusInternalServerError, "internal server error", nil)
 		return
 	}
 
+	w.Header().Set("ETag", productETag(product))
 	s.Response(w, r).Status(http.StatusCreated).
 		Data(product)
 }
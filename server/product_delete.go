@@ -13,9 +13,15 @@ import (
 func (s *ServerDependency) ProductDelete(w http.ResponseWriter, r *http.Request) {
 	productId, _ := strconv.Atoi(chi.URLParam(r, "id"))
 
+	version, ok := s.ifMatchVersion(w, r)
+	if !ok {
+		return
+	}
+
 	err := s.productDomain.DeleteProductById(
 		r.Context(),
 		productId,
+		version,
 	)
 	if err != nil {
 		log.Error().Err(err).Msg("delete product by id")
@@ -26,6 +32,12 @@ func (s *ServerDependency) ProductDelete(w http.ResponseWriter, r *http.Request)
 			return
 		}
 
+		if errors.Is(err, prd.ErrVersionMismatch) {
+			s.Response(w, r).Status(http.StatusPreconditionFailed).
+				Error(http.StatusPreconditionFailed, "product has been modified", nil)
+			return
+		}
+
 		s.Response(w, r).Status(http.StatusInternalServerError).
 			Error(http.StatusInternalServerError, "internal server error", nil)
 		return
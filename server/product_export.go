@@ -0,0 +1,30 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/rs/zerolog/log"
+)
+
+func (s *ServerDependency) ProductExport(w http.ResponseWriter, r *http.Request) {
+	var err error
+	switch format := r.URL.Query().Get("format"); format {
+	case "", "csv":
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", `attachment; filename="products.csv"`)
+		err = s.productDomain.ExportProductsCSV(r.Context(), w)
+	case "json":
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Disposition", `attachment; filename="products.json"`)
+		err = s.productDomain.ExportProductsJSON(r.Context(), w)
+	default:
+		s.Response(w, r).Status(http.StatusBadRequest).
+			Error(http.StatusBadRequest, "format must be csv or json", nil)
+		return
+	}
+
+	// the response is already partially written, all we can do is log it
+	if err != nil {
+		log.Error().Err(err).Msg("export products")
+	}
+}
@@ -13,6 +13,11 @@ import (
 func (s *ServerDependency) ProductGet(w http.ResponseWriter, r *http.Request) {
 	productId, _ := strconv.Atoi(chi.URLParam(r, "id"))
 
+	currency, rates, ok := s.currency(w, r)
+	if !ok {
+		return
+	}
+
 	product, err := s.productDomain.GetProductById(
 		r.Context(),
 		productId,
@@ -31,5 +36,42 @@ func (s *ServerDependency) ProductGet(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err = s.productDomain.Localize(r.Context(), rates, currency, product); err != nil {
+		log.Error().Err(err).Msg("localize prices")
+		s.Response(w, r).Status(http.StatusInternalServerError).
+			Error(http.StatusInternalServerError, "internal server error", nil)
+		return
+	}
+
+	s.setCatalogCache(w, r)
+
+	// the viewer and the currency are not part of the version, personalized
+	// and localized responses have no etag
+	if userId, ok := viewerId(r); ok {
+		product.Viewer, err = s.productDomain.GetViewer(r.Context(), userId, productId)
+		if err != nil {
+			log.Error().Err(err).Msg("get product viewer")
+			s.Response(w, r).Status(http.StatusInternalServerError).
+				Error(http.StatusInternalServerError, "internal server error", nil)
+			return
+		}
+
+		s.Response(w, r).Data(product)
+		return
+	}
+
+	if product.LocalPrice != nil {
+		s.Response(w, r).Data(product)
+		return
+	}
+
+	etag := productETag(product)
+	w.Header().Set("ETag", etag)
+
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		s.Response(w, r).Status(http.StatusNotModified).End()
+		return
+	}
+
 	s.Response(w, r).Data(product)
 }
@@ -0,0 +1,41 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"sypchal/product"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+)
+
+func (s *ServerDependency) ProductHistory(w http.ResponseWriter, r *http.Request) {
+	productId, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil {
+		limit = 10
+	}
+
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil {
+		page = 1
+	}
+
+	offset := limit * (page - 1)
+
+	res, err := s.productDomain.GetProductHistory(r.Context(), product.GetProductHistoryRequest{
+		ProductId: productId,
+		Limit:     limit,
+		Offset:    offset,
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("get product history")
+
+		s.Response(w, r).Status(http.StatusInternalServerError).
+			Error(http.StatusInternalServerError, "internal server error", nil)
+		return
+	}
+
+	s.Response(w, r).Data(res)
+}
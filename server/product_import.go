@@ -0,0 +1,71 @@
+package server
+
+import (
+	"errors"
+	"mime"
+	"net/http"
+	"strconv"
+	prd "sypchal/product"
+
+	"github.com/rs/zerolog/log"
+)
+
+func (s *ServerDependency) ProductImport(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if mediaType == "text/csv" {
+			format = "csv"
+		} else {
+			format = "json"
+		}
+	}
+
+	dryRun, _ := strconv.ParseBool(r.URL.Query().Get("dry_run"))
+	atomic, _ := strconv.ParseBool(r.URL.Query().Get("atomic"))
+
+	var rows []prd.ImportProductRow
+	var err error
+	switch format {
+	case "csv":
+		rows, err = prd.ParseProductsCSV(r.Body)
+	case "json":
+		rows, err = prd.ParseProductsJSON(r.Body)
+	default:
+		s.Response(w, r).Status(http.StatusBadRequest).
+			Error(http.StatusBadRequest, "format must be csv or json", nil)
+		return
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("parse products")
+
+		if errors.Is(err, prd.ErrInvalidImportFile) {
+			s.Response(w, r).Status(http.StatusBadRequest).
+				Error(http.StatusBadRequest, err.Error(), nil)
+			return
+		}
+
+		s.Response(w, r).Status(http.StatusInternalServerError).
+			Error(http.StatusInternalServerError, "internal server error", nil)
+		return
+	}
+
+	res, err := s.productDomain.ImportProducts(r.Context(), rows, prd.ImportOptions{
+		DryRun: dryRun,
+		Atomic: atomic,
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("import products")
+
+		s.Response(w, r).Status(http.StatusInternalServerError).
+			Error(http.StatusInternalServerError, "internal server error", nil)
+		return
+	}
+
+	if res.Failed > 0 && !res.Applied && !res.DryRun {
+		s.Response(w, r).Status(http.StatusUnprocessableEntity).Data(res)
+		return
+	}
+
+	s.Response(w, r).Data(res)
+}
@@ -1,6 +1,7 @@
 package server
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 	"sypchal/product"
@@ -21,17 +22,37 @@ func (s *ServerDependency) ProductList(w http.ResponseWriter, r *http.Request) {
 
 	offset := limit * (page - 1)
 
+	currency, rates, ok := s.currency(w, r)
+	if !ok {
+		return
+	}
+
 	res, err := s.productDomain.GetProducts(r.Context(), product.GetProductsRequest{
+		Sort:   r.URL.Query().Get("sort"),
 		Limit:  limit,
 		Offset: offset,
 	})
 	if err != nil {
 		log.Error().Err(err).Msg("get products")
 
+		if errors.Is(err, product.ErrInvalidSort) {
+			s.Response(w, r).Status(http.StatusBadRequest).
+				Error(http.StatusBadRequest, "sort must be empty or rating", nil)
+			return
+		}
+
+		s.Response(w, r).Status(http.StatusInternalServerError).
+			Error(http.StatusInternalServerError, "internal server error", nil)
+		return
+	}
+
+	if err = s.productDomain.Localize(r.Context(), rates, currency, res.Products...); err != nil {
+		log.Error().Err(err).Msg("localize prices")
 		s.Response(w, r).Status(http.StatusInternalServerError).
 			Error(http.StatusInternalServerError, "internal server error", nil)
 		return
 	}
 
+	s.setCatalogCache(w, r)
 	s.Response(w, r).Data(res)
 }
@@ -0,0 +1,40 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"sypchal/product"
+
+	"github.com/rs/zerolog/log"
+)
+
+func (s *ServerDependency) ProductListArchived(w http.ResponseWriter, r *http.Request) {
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil {
+		limit = 10
+	}
+
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil {
+		page = 1
+	}
+
+	offset := limit * (page - 1)
+
+	res, err := s.productDomain.GetProducts(r.Context(), product.GetProductsRequest{
+		Limit:  limit,
+		Offset: offset,
+		Filter: &product.GetProductFilter{
+			Archived: true,
+		},
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("get archived products")
+
+		s.Response(w, r).Status(http.StatusInternalServerError).
+			Error(http.StatusInternalServerError, "internal server error", nil)
+		return
+	}
+
+	s.Response(w, r).Data(res)
+}
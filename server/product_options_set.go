@@ -0,0 +1,67 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	prd "sypchal/product"
+	"sypchal/validation"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+)
+
+type ProductOptionsSetRequest struct {
+	Options []prd.ProductOptionInput `json:"options"`
+}
+
+func (s *ServerDependency) ProductOptionsSet(w http.ResponseWriter, r *http.Request) {
+	productId, _ := strconv.Atoi(chi.URLParam(r, "id"))
+	requestBody := ProductOptionsSetRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		s.Response(w, r).Status(http.StatusBadRequest).
+			Error(http.StatusBadRequest, "invalid request body", nil)
+		return
+	}
+
+	options, err := s.productDomain.SetProductOptions(
+		r.Context(),
+		productId,
+		prd.SetProductOptionsRequest(requestBody),
+	)
+	if err != nil {
+		log.Error().Err(err).Msg("set product options")
+
+		var ve *validation.ValidationErrors
+		if errors.As(err, &ve) {
+			s.Response(w, r).Status(http.StatusBadRequest).
+				Error(http.StatusBadRequest, "validation error", ve.Transform())
+			return
+		}
+
+		if errors.Is(err, prd.ErrProductNotFound) {
+			s.Response(w, r).Status(http.StatusBadRequest).
+				Error(http.StatusBadRequest, "product not found", nil)
+			return
+		}
+
+		if errors.Is(err, prd.ErrInvalidVariantOptions) {
+			s.Response(w, r).Status(http.StatusBadRequest).
+				Error(http.StatusBadRequest, "option names must be unique", nil)
+			return
+		}
+
+		if errors.Is(err, prd.ErrOptionsInUse) {
+			s.Response(w, r).Status(http.StatusConflict).
+				Error(http.StatusConflict, "options are used by existing variants", nil)
+			return
+		}
+
+		s.Response(w, r).Status(http.StatusInternalServerError).
+			Error(http.StatusInternalServerError, "internal server error", nil)
+		return
+	}
+
+	s.Response(w, r).Data(options)
+}
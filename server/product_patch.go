@@ -0,0 +1,86 @@
+package server
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	prd "sypchal/product"
+	"sypchal/validation"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+)
+
+func (s *ServerDependency) ProductPatch(w http.ResponseWriter, r *http.Request) {
+	productId, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	version, ok := s.ifMatchVersion(w, r)
+	if !ok {
+		return
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "application/merge-patch+json" && mediaType != "application/json" {
+		s.Response(w, r).Status(http.StatusUnsupportedMediaType).
+			Error(http.StatusUnsupportedMediaType, "content type must be application/merge-patch+json", nil)
+		return
+	}
+
+	patch, err := io.ReadAll(http.MaxBytesReader(w, r.Body, 1<<20))
+	if err != nil {
+		s.Response(w, r).Status(http.StatusBadRequest).
+			Error(http.StatusBadRequest, "invalid request body", nil)
+		return
+	}
+
+	product, err := s.productDomain.PatchProductById(r.Context(), productId, version, patch)
+	if err != nil {
+		log.Error().Err(err).Msg("patch product")
+
+		var ve *validation.ValidationErrors
+		if errors.As(err, &ve) {
+			s.Response(w, r).Status(http.StatusBadRequest).
+				Error(http.StatusBadRequest, "validation error", ve.Transform())
+			return
+		}
+
+		if errors.Is(err, prd.ErrInvalidPatch) {
+			s.Response(w, r).Status(http.StatusBadRequest).
+				Error(http.StatusBadRequest, "patch must be a json object", nil)
+			return
+		}
+
+		if errors.Is(err, prd.ErrProductNotFound) {
+			s.Response(w, r).Status(http.StatusBadRequest).
+				Error(http.StatusBadRequest, "product not found", nil)
+			return
+		}
+
+		if errors.Is(err, prd.ErrSkuAlreadyExists) {
+			s.Response(w, r).Status(http.StatusBadRequest).
+				Error(http.StatusBadRequest, "sku already exists", nil)
+			return
+		}
+
+		if errors.Is(err, prd.ErrDefaultWarehouseStock) {
+			s.Response(w, r).Status(http.StatusConflict).
+				Error(http.StatusConflict, "the default warehouse doesn't have enough stock to remove", nil)
+			return
+		}
+
+		if errors.Is(err, prd.ErrVersionMismatch) {
+			s.Response(w, r).Status(http.StatusPreconditionFailed).
+				Error(http.StatusPreconditionFailed, "product has been modified", nil)
+			return
+		}
+
+		s.Response(w, r).Status(http.StatusInternalServerError).
+			Error(http.StatusInternalServerError, "internal server error", nil)
+		return
+	}
+
+	w.Header().Set("ETag", productETag(product))
+	s.Response(w, r).Data(product)
+}
@@ -0,0 +1,22 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+)
+
+func (s *ServerDependency) ProductPriceDelete(w http.ResponseWriter, r *http.Request) {
+	productId, _ := strconv.Atoi(chi.URLParam(r, "id"))
+	priceId, _ := strconv.Atoi(chi.URLParam(r, "price_id"))
+
+	if err := s.productDomain.DeleteListPrice(r.Context(), productId, priceId); err != nil {
+		log.Error().Err(err).Msg("delete list price")
+		s.productPriceError(w, r, err)
+		return
+	}
+
+	s.Response(w, r).Status(http.StatusNoContent).End()
+}
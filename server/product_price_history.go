@@ -0,0 +1,42 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"sypchal/product"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+)
+
+func (s *ServerDependency) ProductPriceHistory(w http.ResponseWriter, r *http.Request) {
+	productId, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	req := product.GetPriceHistoryRequest{ProductId: productId}
+	if value := r.URL.Query().Get("at"); value != "" {
+		at, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			s.Response(w, r).Status(http.StatusBadRequest).
+				Error(http.StatusBadRequest, "validation error", map[string]string{
+					"at": "at must be a RFC 3339 time",
+				})
+			return
+		}
+
+		// timestamps are stored in utc without a time zone
+		at = at.UTC()
+		req.At = &at
+	}
+
+	periods, err := s.productDomain.GetPriceHistory(r.Context(), req)
+	if err != nil {
+		log.Error().Err(err).Msg("get product price history")
+
+		s.Response(w, r).Status(http.StatusInternalServerError).
+			Error(http.StatusInternalServerError, "internal server error", nil)
+		return
+	}
+
+	s.Response(w, r).Data(periods)
+}
@@ -0,0 +1,47 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	prd "sypchal/product"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+)
+
+func (s *ServerDependency) ProductPriceList(w http.ResponseWriter, r *http.Request) {
+	productId, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	if !s.productDomain.IsProductExists(r.Context(), productId) {
+		s.productPriceError(w, r, prd.ErrProductNotFound)
+		return
+	}
+
+	prices, err := s.productDomain.GetListPrices(r.Context(), productId)
+	if err != nil {
+		log.Error().Err(err).Msg("get list prices")
+		s.productPriceError(w, r, err)
+		return
+	}
+
+	s.Response(w, r).Data(prices)
+}
+
+func (s *ServerDependency) productPriceError(w http.ResponseWriter, r *http.Request, err error) {
+	for _, target := range []error{prd.ErrProductNotFound, prd.ErrListPriceNotFound} {
+		if errors.Is(err, target) {
+			s.Response(w, r).Status(http.StatusNotFound).
+				Error(http.StatusNotFound, target.Error(), nil)
+			return
+		}
+	}
+
+	if errors.Is(err, prd.ErrVariantNotFound) || errors.Is(err, prd.ErrListPriceCurrency) {
+		s.Response(w, r).Status(http.StatusBadRequest).
+			Error(http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	s.variantError(w, r, err)
+}
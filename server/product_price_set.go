@@ -0,0 +1,36 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"sypchal/money"
+	prd "sypchal/product"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+)
+
+type ProductPriceSetRequest struct {
+	VariantId *int        `json:"variant_id"`
+	Price     money.Money `json:"price"`
+}
+
+func (s *ServerDependency) ProductPriceSet(w http.ResponseWriter, r *http.Request) {
+	productId, _ := strconv.Atoi(chi.URLParam(r, "id"))
+	requestBody := ProductPriceSetRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		s.Response(w, r).Status(http.StatusBadRequest).
+			Error(http.StatusBadRequest, "invalid request body", nil)
+		return
+	}
+
+	price, err := s.productDomain.SetListPrice(r.Context(), productId, prd.SetListPriceRequest(requestBody))
+	if err != nil {
+		log.Error().Err(err).Msg("set list price")
+		s.productPriceError(w, r, err)
+		return
+	}
+
+	s.Response(w, r).Data(price)
+}
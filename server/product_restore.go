@@ -0,0 +1,32 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	prd "sypchal/product"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+)
+
+func (s *ServerDependency) ProductRestore(w http.ResponseWriter, r *http.Request) {
+	productId, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	product, err := s.productDomain.RestoreProductById(r.Context(), productId)
+	if err != nil {
+		log.Error().Err(err).Msg("restore product by id")
+
+		if errors.Is(err, prd.ErrProductNotArchived) {
+			s.Response(w, r).Status(http.StatusBadRequest).
+				Error(http.StatusBadRequest, "product not found or not archived", nil)
+			return
+		}
+
+		s.Response(w, r).Status(http.StatusInternalServerError).
+			Error(http.StatusInternalServerError, "internal server error", nil)
+		return
+	}
+
+	s.Response(w, r).Data(product)
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"net/http"
 	"strconv"
+	"sypchal/money"
 	prd "sypchal/product"
 	"sypchal/validation"
 
@@ -13,16 +14,28 @@ import (
 )
 
 type ProductUpdateRequest struct {
-	Name        string `json:"name,omitempty"`
-	Description string `json:"description,omitempty"`
-	ImageUrl    string `json:"image_url,omitempty"`
-	Category    string `json:"category,omitempty"`
-	Stock       int    `json:"stock,omitempty"`
-	Price       int    `json:"price,omitempty"`
+	Sku         string       `json:"sku"`
+	Name        string       `json:"name"`
+	Description string       `json:"description"`
+	ImageUrl    string       `json:"image_url"`
+	Category    string       `json:"category"`
+	TaxClass    string       `json:"tax_class"`
+	Stock       *int         `json:"stock"`
+	Price       *money.Money `json:"price"`
+	Weight      int          `json:"weight"`
+	Length      int          `json:"length"`
+	Width       int          `json:"width"`
+	Height      int          `json:"height"`
 }
 
 func (s *ServerDependency) ProductUpdate(w http.ResponseWriter, r *http.Request) {
 	productId, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	version, ok := s.ifMatchVersion(w, r)
+	if !ok {
+		return
+	}
+
 	requestBody := ProductUpdateRequest{}
 	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
 		s.Response(w, r).Status(http.StatusBadRequest).
@@ -33,6 +46,7 @@ func (s *ServerDependency) ProductUpdate(w http.ResponseWriter, r *http.Request)
 	product, err := s.productDomain.UpdateProductById(
 		r.Context(),
 		productId,
+		version,
 		prd.UpdateProductRequest(requestBody),
 	)
 	if err != nil {
@@ -51,10 +65,29 @@ func (s *ServerDependency) ProductUpdate(w http.ResponseWriter, r *http.Request)
 			return
 		}
 
+		if errors.Is(err, prd.ErrSkuAlreadyExists) {
+			s.Response(w, r).Status(http.StatusBadRequest).
+				Error(http.StatusBadRequest, "sku already exists", nil)
+			return
+		}
+
+		if errors.Is(err, prd.ErrDefaultWarehouseStock) {
+			s.Response(w, r).Status(http.StatusConflict).
+				Error(http.StatusConflict, "the default warehouse doesn't have enough stock to remove", nil)
+			return
+		}
+
+		if errors.Is(err, prd.ErrVersionMismatch) {
+			s.Response(w, r).Status(http.StatusPreconditionFailed).
+				Error(http.StatusPreconditionFailed, "product has been modified", nil)
+			return
+		}
+
 		s.Response(w, r).Status(http.StatusInternalServerError).
 			Error(http.StatusInternalServerError, "internal server error", nil)
 		return
 	}
 
+	w.Header().Set("ETag", productETag(product))
 	s.Response(w, r).Data(product)
 }
@@ -0,0 +1,93 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"sypchal/money"
+	prd "sypchal/product"
+	"sypchal/validation"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+)
+
+type ProductVariantCreateRequest struct {
+	Sku      string            `json:"sku"`
+	Options  map[string]string `json:"options"`
+	Stock    int               `json:"stock"`
+	Price    *money.Money      `json:"price"`
+	ImageUrl string            `json:"image_url"`
+}
+
+func (s *ServerDependency) ProductVariantCreate(w http.ResponseWriter, r *http.Request) {
+	productId, _ := strconv.Atoi(chi.URLParam(r, "id"))
+	requestBody := ProductVariantCreateRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		s.Response(w, r).Status(http.StatusBadRequest).
+			Error(http.StatusBadRequest, "invalid request body", nil)
+		return
+	}
+
+	variant, err := s.productDomain.CreateVariant(
+		r.Context(),
+		productId,
+		prd.CreateVariantRequest(requestBody),
+	)
+	if err != nil {
+		log.Error().Err(err).Msg("create variant")
+		s.variantError(w, r, err)
+		return
+	}
+
+	s.Response(w, r).Status(http.StatusCreated).Data(variant)
+}
+
+func (s *ServerDependency) variantError(w http.ResponseWriter, r *http.Request, err error) {
+	var ve *validation.ValidationErrors
+	if errors.As(err, &ve) {
+		s.Response(w, r).Status(http.StatusBadRequest).
+			Error(http.StatusBadRequest, "validation error", ve.Transform())
+		return
+	}
+
+	if errors.Is(err, prd.ErrProductNotFound) {
+		s.Response(w, r).Status(http.StatusBadRequest).
+			Error(http.StatusBadRequest, "product not found", nil)
+		return
+	}
+
+	if errors.Is(err, prd.ErrVariantNotFound) {
+		s.Response(w, r).Status(http.StatusBadRequest).
+			Error(http.StatusBadRequest, "variant not found", nil)
+		return
+	}
+
+	if errors.Is(err, prd.ErrSkuAlreadyExists) {
+		s.Response(w, r).Status(http.StatusBadRequest).
+			Error(http.StatusBadRequest, "sku already exists", nil)
+		return
+	}
+
+	if errors.Is(err, prd.ErrInvalidVariantOptions) {
+		s.Response(w, r).Status(http.StatusBadRequest).
+			Error(http.StatusBadRequest, "options must have one valid value for every product option", nil)
+		return
+	}
+
+	if errors.Is(err, prd.ErrDefaultWarehouseStock) {
+		s.Response(w, r).Status(http.StatusConflict).
+			Error(http.StatusConflict, "the default warehouse doesn't have enough stock to remove", nil)
+		return
+	}
+
+	if errors.Is(err, prd.ErrDuplicateVariantOptions) {
+		s.Response(w, r).Status(http.StatusBadRequest).
+			Error(http.StatusBadRequest, "options already used by another variant", nil)
+		return
+	}
+
+	s.Response(w, r).Status(http.StatusInternalServerError).
+		Error(http.StatusInternalServerError, "internal server error", nil)
+}
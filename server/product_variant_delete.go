@@ -0,0 +1,23 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+)
+
+func (s *ServerDependency) ProductVariantDelete(w http.ResponseWriter, r *http.Request) {
+	productId, _ := strconv.Atoi(chi.URLParam(r, "id"))
+	variantId, _ := strconv.Atoi(chi.URLParam(r, "variant_id"))
+
+	err := s.productDomain.DeleteVariantById(r.Context(), productId, variantId)
+	if err != nil {
+		log.Error().Err(err).Msg("delete variant")
+		s.variantError(w, r, err)
+		return
+	}
+
+	s.Response(w, r).Status(http.StatusNoContent).End()
+}
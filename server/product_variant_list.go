@@ -0,0 +1,45 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	prd "sypchal/product"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+)
+
+func (s *ServerDependency) ProductVariantList(w http.ResponseWriter, r *http.Request) {
+	productId, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	currency, rates, ok := s.currency(w, r)
+	if !ok {
+		return
+	}
+
+	variants, err := s.productDomain.GetProductVariants(r.Context(), productId)
+	if err != nil {
+		log.Error().Err(err).Msg("get product variants")
+
+		if errors.Is(err, prd.ErrProductNotFound) {
+			s.Response(w, r).Status(http.StatusBadRequest).
+				Error(http.StatusBadRequest, "product not found", nil)
+			return
+		}
+
+		s.Response(w, r).Status(http.StatusInternalServerError).
+			Error(http.StatusInternalServerError, "internal server error", nil)
+		return
+	}
+
+	if err = s.productDomain.LocalizeVariants(r.Context(), rates, currency, productId, variants); err != nil {
+		log.Error().Err(err).Msg("localize prices")
+		s.Response(w, r).Status(http.StatusInternalServerError).
+			Error(http.StatusInternalServerError, "internal server error", nil)
+		return
+	}
+
+	s.setCatalogCache(w, r)
+	s.Response(w, r).Data(variants)
+}
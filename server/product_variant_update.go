@@ -0,0 +1,38 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	prd "sypchal/product"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+)
+
+type ProductVariantUpdateRequest ProductVariantCreateRequest
+
+func (s *ServerDependency) ProductVariantUpdate(w http.ResponseWriter, r *http.Request) {
+	productId, _ := strconv.Atoi(chi.URLParam(r, "id"))
+	variantId, _ := strconv.Atoi(chi.URLParam(r, "variant_id"))
+	requestBody := ProductVariantUpdateRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		s.Response(w, r).Status(http.StatusBadRequest).
+			Error(http.StatusBadRequest, "invalid request body", nil)
+		return
+	}
+
+	variant, err := s.productDomain.UpdateVariantById(
+		r.Context(),
+		productId,
+		variantId,
+		prd.UpdateVariantRequest(requestBody),
+	)
+	if err != nil {
+		log.Error().Err(err).Msg("update variant")
+		s.variantError(w, r, err)
+		return
+	}
+
+	s.Response(w, r).Data(variant)
+}
@@ -0,0 +1,78 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sypchal/money"
+	"sypchal/promotion"
+	"sypchal/validation"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+type PromotionCreateRequest struct {
+	Name        string           `json:"name"`
+	Type        string           `json:"type"`
+	Priority    int              `json:"priority"`
+	Exclusive   bool             `json:"exclusive"`
+	StartsAt    *time.Time       `json:"starts_at"`
+	EndsAt      *time.Time       `json:"ends_at"`
+	Active      bool             `json:"active"`
+	Categories  []string         `json:"categories"`
+	ProductIds  []int            `json:"product_ids"`
+	BuyQty      int              `json:"buy_qty"`
+	GetQty      int              `json:"get_qty"`
+	BundlePrice money.Money      `json:"bundle_price"`
+	Percent     int              `json:"percent"`
+	Tiers       []promotion.Tier `json:"tiers"`
+}
+
+func (s *ServerDependency) PromotionCreate(w http.ResponseWriter, r *http.Request) {
+	requestBody := PromotionCreateRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		s.Response(w, r).Status(http.StatusBadRequest).
+			Error(http.StatusBadRequest, "invalid request body", nil)
+		return
+	}
+
+	p, err := s.promotionDomain.CreatePromotion(r.Context(), promotion.CreatePromotionRequest(requestBody))
+	if err != nil {
+		log.Error().Err(err).Msg("create promotion")
+		s.promotionError(w, r, err)
+		return
+	}
+
+	s.Response(w, r).Status(http.StatusCreated).Data(p)
+}
+
+func (s *ServerDependency) promotionError(w http.ResponseWriter, r *http.Request, err error) {
+	var ve *validation.ValidationErrors
+	if errors.As(err, &ve) {
+		s.Response(w, r).Status(http.StatusBadRequest).
+			Error(http.StatusBadRequest, "validation error", ve.Transform())
+		return
+	}
+
+	if errors.Is(err, promotion.ErrPromotionNotFound) {
+		s.Response(w, r).Status(http.StatusNotFound).
+			Error(http.StatusNotFound, "promotion not found", nil)
+		return
+	}
+
+	for _, target := range []error{
+		promotion.ErrInvalidRule,
+		promotion.ErrInvalidWindow,
+		promotion.ErrProductNotFound,
+	} {
+		if errors.Is(err, target) {
+			s.Response(w, r).Status(http.StatusBadRequest).
+				Error(http.StatusBadRequest, err.Error(), nil)
+			return
+		}
+	}
+
+	s.Response(w, r).Status(http.StatusInternalServerError).
+		Error(http.StatusInternalServerError, "internal server error", nil)
+}
@@ -0,0 +1,36 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"sypchal/promotion"
+
+	"github.com/rs/zerolog/log"
+)
+
+func (s *ServerDependency) PromotionList(w http.ResponseWriter, r *http.Request) {
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil {
+		limit = 10
+	}
+
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil {
+		page = 1
+	}
+
+	offset := limit * (page - 1)
+
+	res, err := s.promotionDomain.GetPromotions(r.Context(), promotion.GetPromotionsRequest{
+		Limit:  limit,
+		Offset: offset,
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("get promotions")
+		s.Response(w, r).Status(http.StatusInternalServerError).
+			Error(http.StatusInternalServerError, "internal server error", nil)
+		return
+	}
+
+	s.Response(w, r).Data(res)
+}
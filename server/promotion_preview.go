@@ -0,0 +1,43 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"sypchal/promotion"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+type PromotionPreviewRequest struct {
+	Items []promotion.PreviewItem `json:"items"`
+	Draft *PromotionCreateRequest `json:"draft"`
+	At    *time.Time              `json:"at"`
+}
+
+func (s *ServerDependency) PromotionPreview(w http.ResponseWriter, r *http.Request) {
+	requestBody := PromotionPreviewRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		s.Response(w, r).Status(http.StatusBadRequest).
+			Error(http.StatusBadRequest, "invalid request body", nil)
+		return
+	}
+
+	req := promotion.PreviewRequest{
+		Items: requestBody.Items,
+		At:    requestBody.At,
+	}
+	if requestBody.Draft != nil {
+		draft := promotion.CreatePromotionRequest(*requestBody.Draft)
+		req.Draft = &draft
+	}
+
+	res, err := s.promotionDomain.PreviewPromotions(r.Context(), req)
+	if err != nil {
+		log.Error().Err(err).Msg("preview promotions")
+		s.promotionError(w, r, err)
+		return
+	}
+
+	s.Response(w, r).Data(res)
+}
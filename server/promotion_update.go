@@ -0,0 +1,32 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"sypchal/promotion"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+)
+
+type PromotionUpdateRequest PromotionCreateRequest
+
+func (s *ServerDependency) PromotionUpdate(w http.ResponseWriter, r *http.Request) {
+	promotionId, _ := strconv.Atoi(chi.URLParam(r, "id"))
+	requestBody := PromotionUpdateRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		s.Response(w, r).Status(http.StatusBadRequest).
+			Error(http.StatusBadRequest, "invalid request body", nil)
+		return
+	}
+
+	p, err := s.promotionDomain.UpdatePromotionById(r.Context(), promotionId, promotion.UpdatePromotionRequest(requestBody))
+	if err != nil {
+		log.Error().Err(err).Msg("update promotion")
+		s.promotionError(w, r, err)
+		return
+	}
+
+	s.Response(w, r).Data(p)
+}
@@ -0,0 +1,109 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"sypchal/returns"
+	"sypchal/validation"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/jwtauth/v5"
+	"github.com/rs/zerolog/log"
+)
+
+type ReturnCreateRequest struct {
+	Reason string               `json:"reason"`
+	Items  []returns.ReturnItem `json:"items"`
+}
+
+func (s *ServerDependency) ReturnCreate(w http.ResponseWriter, r *http.Request) {
+	orderId, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	_, payload, err := jwtauth.FromContext(r.Context())
+	if err != nil {
+		log.Error().Err(err).Msg("get jwt payload")
+		s.Response(w, r).Status(http.StatusInternalServerError).
+			Error(http.StatusInternalServerError, "internal server error", nil)
+		return
+	}
+
+	userId, err := strconv.Atoi(payload["uid"].(string))
+	if err != nil {
+		log.Error().Err(err).Msg("atoi")
+		s.Response(w, r).Status(http.StatusInternalServerError).
+			Error(http.StatusInternalServerError, "internal server error", nil)
+		return
+	}
+
+	requestBody := ReturnCreateRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		s.Response(w, r).Status(http.StatusBadRequest).
+			Error(http.StatusBadRequest, "invalid request body", nil)
+		return
+	}
+
+	ret, err := s.returnDomain.CreateReturn(r.Context(), returns.CreateReturnRequest{
+		UserId:  userId,
+		OrderId: orderId,
+		Reason:  requestBody.Reason,
+		Items:   requestBody.Items,
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("create return")
+		s.returnError(w, r, err)
+		return
+	}
+
+	s.Response(w, r).Status(http.StatusCreated).Data(ret)
+}
+
+func (s *ServerDependency) returnError(w http.ResponseWriter, r *http.Request, err error) {
+	var ve *validation.ValidationErrors
+	if errors.As(err, &ve) {
+		s.Response(w, r).Status(http.StatusBadRequest).
+			Error(http.StatusBadRequest, "validation error", ve.Transform())
+		return
+	}
+
+	for _, target := range []error{returns.ErrOrderNotFound, returns.ErrReturnNotFound} {
+		if errors.Is(err, target) {
+			s.Response(w, r).Status(http.StatusNotFound).
+				Error(http.StatusNotFound, target.Error(), nil)
+			return
+		}
+	}
+
+	for _, target := range []error{
+		returns.ErrOrderNotDelivered,
+		returns.ErrReturnWindowClosed,
+		returns.ErrReturnTransition,
+		returns.ErrNoPayment,
+		returns.ErrRefundNotPending,
+	} {
+		if errors.Is(err, target) {
+			s.Response(w, r).Status(http.StatusConflict).
+				Error(http.StatusConflict, err.Error(), nil)
+			return
+		}
+	}
+
+	// the return is saved, its refund is left pending
+	if errors.Is(err, returns.ErrRefundPending) {
+		s.Response(w, r).Status(http.StatusBadGateway).
+			Error(http.StatusBadGateway, returns.ErrRefundPending.Error(), nil)
+		return
+	}
+
+	for _, target := range []error{returns.ErrOrderItemNotFound, returns.ErrReturnQty, returns.ErrWarehouseNotFound} {
+		if errors.Is(err, target) {
+			s.Response(w, r).Status(http.StatusBadRequest).
+				Error(http.StatusBadRequest, err.Error(), nil)
+			return
+		}
+	}
+
+	s.Response(w, r).Status(http.StatusInternalServerError).
+		Error(http.StatusInternalServerError, "internal server error", nil)
+}
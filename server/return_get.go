@@ -0,0 +1,22 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+)
+
+func (s *ServerDependency) ReturnGet(w http.ResponseWriter, r *http.Request) {
+	returnId, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	ret, err := s.returnDomain.GetReturnById(r.Context(), returnId)
+	if err != nil {
+		log.Error().Err(err).Msg("get return by id")
+		s.returnError(w, r, err)
+		return
+	}
+
+	s.Response(w, r).Data(ret)
+}
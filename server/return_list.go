@@ -0,0 +1,48 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"sypchal/returns"
+
+	"github.com/rs/zerolog/log"
+)
+
+// ReturnList lists the returns of every order for the admins, requested ones
+// by default.
+func (s *ServerDependency) ReturnList(w http.ResponseWriter, r *http.Request) {
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil {
+		limit = 10
+	}
+
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil {
+		page = 1
+	}
+
+	offset := limit * (page - 1)
+
+	status := returns.StatusRequested
+	if r.URL.Query().Has("status") {
+		status = r.URL.Query().Get("status")
+	}
+
+	orderId, _ := strconv.Atoi(r.URL.Query().Get("order_id"))
+
+	res, err := s.returnDomain.GetReturns(r.Context(), returns.GetReturnsRequest{
+		Status:  status,
+		OrderId: orderId,
+		Limit:   limit,
+		Offset:  offset,
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("get returns")
+
+		s.Response(w, r).Status(http.StatusInternalServerError).
+			Error(http.StatusInternalServerError, "internal server error", nil)
+		return
+	}
+
+	s.Response(w, r).Data(res)
+}
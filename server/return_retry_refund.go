@@ -0,0 +1,22 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+)
+
+func (s *ServerDependency) ReturnRetryRefund(w http.ResponseWriter, r *http.Request) {
+	returnId, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	ret, err := s.returnDomain.RetryRefund(r.Context(), returnId)
+	if err != nil {
+		log.Error().Err(err).Msg("retry refund")
+		s.returnError(w, r, err)
+		return
+	}
+
+	s.Response(w, r).Data(ret)
+}
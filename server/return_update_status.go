@@ -0,0 +1,43 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"sypchal/returns"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+)
+
+type ReturnUpdateStatusRequest struct {
+	Status      string `json:"status"`
+	Note        string `json:"note"`
+	Restock     bool   `json:"restock"`
+	WarehouseId int    `json:"warehouse_id"`
+}
+
+func (s *ServerDependency) ReturnUpdateStatus(w http.ResponseWriter, r *http.Request) {
+	returnId, _ := strconv.Atoi(chi.URLParam(r, "id"))
+	var requestBody ReturnUpdateStatusRequest
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		s.Response(w, r).Status(http.StatusBadRequest).
+			Error(http.StatusBadRequest, "invalid request body", nil)
+		return
+	}
+
+	ret, err := s.returnDomain.UpdateReturnStatus(r.Context(), returns.UpdateReturnStatusRequest{
+		ReturnId:    returnId,
+		Status:      requestBody.Status,
+		Note:        requestBody.Note,
+		Restock:     requestBody.Restock,
+		WarehouseId: requestBody.WarehouseId,
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("update return status")
+		s.returnError(w, r, err)
+		return
+	}
+
+	s.Response(w, r).Data(ret)
+}
@@ -0,0 +1,95 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"sypchal/review"
+	"sypchal/validation"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/jwtauth/v5"
+	"github.com/rs/zerolog/log"
+)
+
+type ReviewCreateRequest struct {
+	Rating int    `json:"rating"`
+	Body   string `json:"body"`
+}
+
+func (s *ServerDependency) ReviewCreate(w http.ResponseWriter, r *http.Request) {
+	productId, _ := strconv.Atoi(chi.URLParam(r, "id"))
+	var requestBody ReviewCreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		s.Response(w, r).Status(http.StatusBadRequest).
+			Error(http.StatusBadRequest, "invalid request body", nil)
+		return
+	}
+
+	_, payload, err := jwtauth.FromContext(r.Context())
+	if err != nil {
+		log.Error().Err(err).Msg("get jwt payload")
+		s.Response(w, r).Status(http.StatusInternalServerError).
+			Error(http.StatusInternalServerError, "internal server error", nil)
+		return
+	}
+
+	userId, err := strconv.Atoi(payload["uid"].(string))
+	if err != nil {
+		log.Error().Err(err).Msg("atoi")
+		s.Response(w, r).Status(http.StatusInternalServerError).
+			Error(http.StatusInternalServerError, "internal server error", nil)
+		return
+	}
+
+	rv, err := s.reviewDomain.CreateReview(r.Context(), review.CreateReviewRequest{
+		UserId:    userId,
+		ProductId: productId,
+		Rating:    requestBody.Rating,
+		Body:      requestBody.Body,
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("create review")
+		s.reviewError(w, r, err)
+		return
+	}
+
+	s.Response(w, r).Status(http.StatusCreated).Data(rv)
+}
+
+func (s *ServerDependency) reviewError(w http.ResponseWriter, r *http.Request, err error) {
+	var ve *validation.ValidationErrors
+	if errors.As(err, &ve) {
+		s.Response(w, r).Status(http.StatusBadRequest).
+			Error(http.StatusBadRequest, "validation error", ve.Transform())
+		return
+	}
+
+	if errors.Is(err, review.ErrProductNotFound) {
+		s.Response(w, r).Status(http.StatusBadRequest).
+			Error(http.StatusBadRequest, "product not found", nil)
+		return
+	}
+
+	if errors.Is(err, review.ErrReviewNotFound) {
+		s.Response(w, r).Status(http.StatusBadRequest).
+			Error(http.StatusBadRequest, "review not found", nil)
+		return
+	}
+
+	if errors.Is(err, review.ErrNotVerifiedBuyer) {
+		s.Response(w, r).Status(http.StatusForbidden).
+			Error(http.StatusForbidden, "only customers who received the product can review it", nil)
+		return
+	}
+
+	if errors.Is(err, review.ErrReviewExists) {
+		s.Response(w, r).Status(http.StatusConflict).
+			Error(http.StatusConflict, "product already reviewed, update the review instead", nil)
+		return
+	}
+
+	s.Response(w, r).Status(http.StatusInternalServerError).
+		Error(http.StatusInternalServerError, "internal server error", nil)
+}
@@ -0,0 +1,44 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"sypchal/review"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+)
+
+// ReviewList lists the approved reviews of a product.
+func (s *ServerDependency) ReviewList(w http.ResponseWriter, r *http.Request) {
+	productId, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil {
+		limit = 10
+	}
+
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil {
+		page = 1
+	}
+
+	offset := limit * (page - 1)
+
+	res, err := s.reviewDomain.GetReviews(r.Context(), review.GetReviewsRequest{
+		ProductId: productId,
+		Status:    review.ReviewStatusApproved,
+		Limit:     limit,
+		Offset:    offset,
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("get reviews")
+
+		s.Response(w, r).Status(http.StatusInternalServerError).
+			Error(http.StatusInternalServerError, "internal server error", nil)
+		return
+	}
+
+	s.setCatalogCache(w, r)
+	s.Response(w, r).Data(res)
+}
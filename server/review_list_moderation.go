@@ -0,0 +1,48 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"sypchal/review"
+
+	"github.com/rs/zerolog/log"
+)
+
+// ReviewListModeration lists the reviews of every product for the admins,
+// pending ones by default.
+func (s *ServerDependency) ReviewListModeration(w http.ResponseWriter, r *http.Request) {
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil {
+		limit = 10
+	}
+
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil {
+		page = 1
+	}
+
+	offset := limit * (page - 1)
+
+	status := review.ReviewStatusPending
+	if r.URL.Query().Has("status") {
+		status = r.URL.Query().Get("status")
+	}
+
+	productId, _ := strconv.Atoi(r.URL.Query().Get("product_id"))
+
+	res, err := s.reviewDomain.GetReviews(r.Context(), review.GetReviewsRequest{
+		ProductId: productId,
+		Status:    status,
+		Limit:     limit,
+		Offset:    offset,
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("get reviews")
+
+		s.Response(w, r).Status(http.StatusInternalServerError).
+			Error(http.StatusInternalServerError, "internal server error", nil)
+		return
+	}
+
+	s.Response(w, r).Data(res)
+}
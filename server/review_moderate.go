@@ -0,0 +1,39 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"sypchal/review"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+)
+
+type ReviewModerateRequest struct {
+	Status string `json:"status"`
+	Note   string `json:"note"`
+}
+
+func (s *ServerDependency) ReviewModerate(w http.ResponseWriter, r *http.Request) {
+	reviewId, _ := strconv.Atoi(chi.URLParam(r, "id"))
+	var requestBody ReviewModerateRequest
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		s.Response(w, r).Status(http.StatusBadRequest).
+			Error(http.StatusBadRequest, "invalid request body", nil)
+		return
+	}
+
+	rv, err := s.reviewDomain.ModerateReview(r.Context(), review.ModerateReviewRequest{
+		ReviewId: reviewId,
+		Status:   requestBody.Status,
+		Note:     requestBody.Note,
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("moderate review")
+		s.reviewError(w, r, err)
+		return
+	}
+
+	s.Response(w, r).Data(rv)
+}
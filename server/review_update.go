@@ -0,0 +1,54 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"sypchal/review"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/jwtauth/v5"
+	"github.com/rs/zerolog/log"
+)
+
+type ReviewUpdateRequest ReviewCreateRequest
+
+func (s *ServerDependency) ReviewUpdate(w http.ResponseWriter, r *http.Request) {
+	reviewId, _ := strconv.Atoi(chi.URLParam(r, "id"))
+	var requestBody ReviewUpdateRequest
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		s.Response(w, r).Status(http.StatusBadRequest).
+			Error(http.StatusBadRequest, "invalid request body", nil)
+		return
+	}
+
+	_, payload, err := jwtauth.FromContext(r.Context())
+	if err != nil {
+		log.Error().Err(err).Msg("get jwt payload")
+		s.Response(w, r).Status(http.StatusInternalServerError).
+			Error(http.StatusInternalServerError, "internal server error", nil)
+		return
+	}
+
+	userId, err := strconv.Atoi(payload["uid"].(string))
+	if err != nil {
+		log.Error().Err(err).Msg("atoi")
+		s.Response(w, r).Status(http.StatusInternalServerError).
+			Error(http.StatusInternalServerError, "internal server error", nil)
+		return
+	}
+
+	rv, err := s.reviewDomain.UpdateReviewById(r.Context(), review.UpdateReviewRequest{
+		UserId:   userId,
+		ReviewId: reviewId,
+		Rating:   requestBody.Rating,
+		Body:     requestBody.Body,
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("update review")
+		s.reviewError(w, r, err)
+		return
+	}
+
+	s.Response(w, r).Data(rv)
+}
@@ -0,0 +1,41 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/jwtauth/v5"
+	"github.com/rs/zerolog/log"
+)
+
+func (s *ServerDependency) SavedItemList(w http.ResponseWriter, r *http.Request) {
+	_, payload, err := jwtauth.FromContext(r.Context())
+	if err != nil {
+		log.Error().Err(err).Msg("get jwt payload")
+		s.Response(w, r).Status(http.StatusInternalServerError).
+			Error(http.StatusInternalServerError, "internal server error", nil)
+		return
+	}
+
+	userId, err := strconv.Atoi(payload["uid"].(string))
+	if err != nil {
+		log.Error().Err(err).Msg("atoi")
+		s.Response(w, r).Status(http.StatusInternalServerError).
+			Error(http.StatusInternalServerError, "internal server error", nil)
+		return
+	}
+
+	currency, _, ok := s.currency(w, r)
+	if !ok {
+		return
+	}
+
+	items, err := s.wishlistDomain.GetSavedItems(r.Context(), userId, currency)
+	if err != nil {
+		log.Error().Err(err).Msg("get saved items")
+		s.wishlistError(w, r, err)
+		return
+	}
+
+	s.Response(w, r).Data(items)
+}
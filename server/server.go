@@ -3,10 +3,23 @@ package server
 import (
 	"net"
 	"net/http"
+	"sypchal/alert"
 	"sypchal/cart"
+	"sypchal/coupon"
+	"sypchal/exchange"
+	"sypchal/inventory"
+	"sypchal/invoice"
 	"sypchal/order"
 	"sypchal/product"
+	"sypchal/promotion"
+	"sypchal/returns"
+	"sypchal/review"
+	"sypchal/shipping"
+	"sypchal/tax"
+	"sypchal/upload"
 	"sypchal/user"
+	"sypchal/wishlist"
+	"time"
 
 	mdw "sypchal/middleware"
 
@@ -16,33 +29,98 @@ import (
 	"github.com/rs/zerolog/log"
 )
 
+// CatalogCacheConfig is how long the anonymous catalog is cached, MaxAge by
+// browsers and SharedMaxAge by CDNs and proxies.
+type CatalogCacheConfig struct {
+	MaxAge               time.Duration
+	SharedMaxAge         time.Duration
+	StaleWhileRevalidate time.Duration
+}
+
+// RateLimitConfig is the number of requests allowed per Window, by ip address
+// for anonymous clients and by user for signed in customers. Zero is unlimited.
+type RateLimitConfig struct {
+	Anonymous     int
+	Authenticated int
+	Window        time.Duration
+}
+
 type ServerConfig struct {
 	Hostname    string
 	Port        string
 	Environment string
-	Admin       struct {
+	// TrustProxy takes the client ip address from the X-Forwarded-For or
+	// X-Real-IP headers, only set it behind a proxy that sets them.
+	TrustProxy bool
+	Admin      struct {
 		Username string
 		Password string
 	}
-	UserDomain    *user.UserDomain
-	ProductDomain *product.ProductDomain
-	CartDomain    *cart.CartDomain
-	OrderDomain   *order.OrderDomain
+	CatalogCache    CatalogCacheConfig
+	RateLimit       RateLimitConfig
+	UserDomain      *user.UserDomain
+	ProductDomain   *product.ProductDomain
+	CartDomain      *cart.CartDomain
+	OrderDomain     *order.OrderDomain
+	UploadDomain    *upload.UploadDomain
+	InventoryDomain *inventory.InventoryDomain
+	AlertDomain     *alert.AlertDomain
+	ReviewDomain    *review.ReviewDomain
+	CouponDomain    *coupon.CouponDomain
+	PromotionDomain *promotion.PromotionDomain
+	TaxDomain       *tax.TaxDomain
+	ShippingDomain  *shipping.ShippingDomain
+	ReturnDomain    *returns.ReturnDomain
+	InvoiceDomain   *invoice.InvoiceDomain
+	ExchangeDomain  *exchange.ExchangeDomain
+	WishlistDomain  *wishlist.WishlistDomain
 }
 
 type ServerDependency struct {
-	userDomain    *user.UserDomain
-	productDomain *product.ProductDomain
-	cartDomain    *cart.CartDomain
-	orderDomain   *order.OrderDomain
+	userDomain      *user.UserDomain
+	productDomain   *product.ProductDomain
+	cartDomain      *cart.CartDomain
+	orderDomain     *order.OrderDomain
+	uploadDomain    *upload.UploadDomain
+	inventoryDomain *inventory.InventoryDomain
+	alertDomain     *alert.AlertDomain
+	reviewDomain    *review.ReviewDomain
+	couponDomain    *coupon.CouponDomain
+	promotionDomain *promotion.PromotionDomain
+	taxDomain       *tax.TaxDomain
+	shippingDomain  *shipping.ShippingDomain
+	returnDomain    *returns.ReturnDomain
+	invoiceDomain   *invoice.InvoiceDomain
+	exchangeDomain  *exchange.ExchangeDomain
+	wishlistDomain  *wishlist.WishlistDomain
+	catalogCache    CatalogCacheConfig
+	// admin holds the basic auth credentials of the admin area, also checked
+	// by the routes shared with the customers
+	admin map[string]string
 }
 
 func NewServer(config ServerConfig) (*http.Server, error) {
 	dependencies := &ServerDependency{
-		userDomain:    config.UserDomain,
-		productDomain: config.ProductDomain,
-		cartDomain:    config.CartDomain,
-		orderDomain:   config.OrderDomain,
+		userDomain:      config.UserDomain,
+		productDomain:   config.ProductDomain,
+		cartDomain:      config.CartDomain,
+		orderDomain:     config.OrderDomain,
+		uploadDomain:    config.UploadDomain,
+		inventoryDomain: config.InventoryDomain,
+		alertDomain:     config.AlertDomain,
+		reviewDomain:    config.ReviewDomain,
+		couponDomain:    config.CouponDomain,
+		promotionDomain: config.PromotionDomain,
+		taxDomain:       config.TaxDomain,
+		shippingDomain:  config.ShippingDomain,
+		returnDomain:    config.ReturnDomain,
+		invoiceDomain:   config.InvoiceDomain,
+		exchangeDomain:  config.ExchangeDomain,
+		wishlistDomain:  config.WishlistDomain,
+		catalogCache:    config.CatalogCache,
+		admin: map[string]string{
+			config.Admin.Username: config.Admin.Password,
+		},
 	}
 
 	r := chi.NewRouter()
@@ -52,35 +130,164 @@ func NewServer(config ServerConfig) (*http.Server, error) {
 		r.Use(mdw.Logger(log.Logger))
 	}
 
+	if config.TrustProxy {
+		r.Use(middleware.RealIP)
+	}
+
 	r.Use(middleware.RequestID)
 	r.Use(middleware.Recoverer)
 
+	var anonymousLimiter, authenticatedLimiter *mdw.RateLimiter
+	if config.RateLimit.Anonymous > 0 {
+		anonymousLimiter = mdw.NewRateLimiter(config.RateLimit.Anonymous, config.RateLimit.Window)
+	}
+	if config.RateLimit.Authenticated > 0 {
+		authenticatedLimiter = mdw.NewRateLimiter(config.RateLimit.Authenticated, config.RateLimit.Window)
+	}
+	rateLimit := mdw.RateLimit(anonymousLimiter, authenticatedLimiter)
+
 	r.Post("/api/register", dependencies.UserRegister)
 	r.Post("/api/login", dependencies.UserLogin)
+	r.Get("/files/*", dependencies.FileGet)
+	r.Get("/api/currencies", dependencies.CurrencyList)
 
+	// the catalog and the cart are public, a token personalizes the catalog
+	// and guests use a cart token instead
 	r.Group(func(r chi.Router) {
 		r.Use(jwtauth.Verifier(dependencies.userDomain.Jwt))
-		r.Use(jwtauth.Authenticator(dependencies.userDomain.Jwt))
+		r.Use(mdw.OptionalAuthenticator)
+		r.Use(rateLimit)
 
 		r.Get("/api/products", dependencies.ProductList)
 		r.Get("/api/products/{id:^[0-9]*$}", dependencies.ProductGet)
+		r.Get("/api/products/{id:^[0-9]*$}/variants", dependencies.ProductVariantList)
 		r.Get("/api/category/{category}", dependencies.ProductListByCategory)
+		r.Get("/api/products/{id:^[0-9]*$}/reviews", dependencies.ReviewList)
 		r.Get("/api/cart", dependencies.CartGet)
 		r.Post("/api/cart", dependencies.CartAddItem)
 		r.Delete("/api/cart/{id:^[0-9]*$}", dependencies.CartDeleteItem)
 		r.Put("/api/cart/{id:^[0-9]*$}", dependencies.CartUpdateItem)
+		r.Post("/api/cart/confirm-prices", dependencies.CartConfirmPrices)
+		r.Get("/api/shared-wishlists/{token}", dependencies.WishlistSharedGet)
+	})
+
+	// payment proofs are private, the handler lets in their customer or the
+	// admin with basic auth
+	r.Group(func(r chi.Router) {
+		r.Use(jwtauth.Verifier(dependencies.userDomain.Jwt))
+		r.Use(mdw.OptionalAuthenticator)
+		r.Use(rateLimit)
+
+		r.Get("/api/payment-proofs/{user_id:^[0-9]+$}/*", dependencies.PaymentProofGet)
+	})
+
+	r.Group(func(r chi.Router) {
+		r.Use(jwtauth.Verifier(dependencies.userDomain.Jwt))
+		r.Use(jwtauth.Authenticator(dependencies.userDomain.Jwt))
+		r.Use(rateLimit)
+		r.Use(mdw.UserActor)
+
+		r.Get("/api/cart/checkout-preview", dependencies.CartCheckoutPreview)
+		r.Post("/api/cart/coupon", dependencies.CartApplyCoupon)
+		r.Delete("/api/cart/coupon", dependencies.CartRemoveCoupon)
+		r.Put("/api/cart/shipping", dependencies.CartSetShipping)
+		r.Post("/api/cart/{id:^[0-9]*$}/save-for-later", dependencies.CartSaveForLater)
+		r.Get("/api/saved-items", dependencies.SavedItemList)
+		r.Post("/api/saved-items/{id:^[0-9]*$}/move-to-cart", dependencies.SavedItemMoveToCart)
+		r.Delete("/api/saved-items/{id:^[0-9]*$}", dependencies.SavedItemDelete)
+		r.Get("/api/wishlists", dependencies.WishlistList)
+		r.Post("/api/wishlists", dependencies.WishlistCreate)
+		r.Get("/api/wishlists/{id:^[0-9]*$}", dependencies.WishlistGet)
+		r.Put("/api/wishlists/{id:^[0-9]*$}", dependencies.WishlistUpdate)
+		r.Delete("/api/wishlists/{id:^[0-9]*$}", dependencies.WishlistDelete)
+		r.Post("/api/wishlists/{id:^[0-9]*$}/share", dependencies.WishlistShare)
+		r.Delete("/api/wishlists/{id:^[0-9]*$}/share", dependencies.WishlistUnshare)
+		r.Post("/api/wishlists/{id:^[0-9]*$}/items", dependencies.WishlistAddItem)
+		r.Delete("/api/wishlists/{id:^[0-9]*$}/items/{item_id:^[0-9]*$}", dependencies.WishlistRemoveItem)
+		r.Put("/api/users/me/currency", dependencies.UserSetCurrency)
+		r.Get("/api/addresses", dependencies.AddressList)
+		r.Post("/api/addresses", dependencies.AddressCreate)
+		r.Put("/api/addresses/{id:^[0-9]*$}", dependencies.AddressUpdate)
+		r.Delete("/api/addresses/{id:^[0-9]*$}", dependencies.AddressDelete)
 		r.Post("/api/order", dependencies.OrderCreate)
 		r.Post("/api/order/pay/{pay_id:^[a-zA-Z]+$}", dependencies.OrderPay)
+		r.Get("/api/orders", dependencies.OrderList)
+		r.Get("/api/orders/{id:^[0-9]*$}", dependencies.OrderGet)
+		r.Get("/api/orders/{id:^[0-9]*$}/returns", dependencies.ReturnOrderList)
+		r.Post("/api/orders/{id:^[0-9]*$}/returns", dependencies.ReturnCreate)
+		r.Get("/api/orders/{id:^[0-9]*$}/invoice.pdf", dependencies.InvoiceOrderPdf)
+		r.Get("/api/orders/{id:^[0-9]*$}/invoices", dependencies.InvoiceOrderList)
+		r.Get("/api/orders/{id:^[0-9]*$}/invoices/{invoice_id:^[0-9]*$}.pdf", dependencies.InvoiceOrderPdf)
+		r.Post("/api/uploads/payment-proof", dependencies.UploadPaymentProof)
+		r.Post("/api/products/{id:^[0-9]*$}/notify-me", dependencies.AlertSubscribe)
+		r.Delete("/api/products/{id:^[0-9]*$}/notify-me", dependencies.AlertUnsubscribe)
+		r.Post("/api/products/{id:^[0-9]*$}/reviews", dependencies.ReviewCreate)
+		r.Put("/api/reviews/{id:^[0-9]*$}", dependencies.ReviewUpdate)
+		r.Delete("/api/reviews/{id:^[0-9]*$}", dependencies.ReviewDelete)
 	})
 
 	r.Group(func(r chi.Router) {
-		r.Use(middleware.BasicAuth("admin area", map[string]string{
-			config.Admin.Username: config.Admin.Password,
-		}))
+		r.Use(middleware.BasicAuth("admin area", dependencies.admin))
+		r.Use(mdw.AdminActor)
 
 		r.Post("/api/products", dependencies.ProductCreate)
 		r.Put("/api/products/{id:^[0-9]*$}", dependencies.ProductUpdate)
+		r.Patch("/api/products/{id:^[0-9]*$}", dependencies.ProductPatch)
 		r.Delete("/api/products/{id:^[0-9]*$}", dependencies.ProductDelete)
+		r.Get("/api/products/archived", dependencies.ProductListArchived)
+		r.Post("/api/products/{id:^[0-9]*$}/restore", dependencies.ProductRestore)
+		r.Put("/api/products/{id:^[0-9]*$}/options", dependencies.ProductOptionsSet)
+		r.Post("/api/products/{id:^[0-9]*$}/variants", dependencies.ProductVariantCreate)
+		r.Put("/api/products/{id:^[0-9]*$}/variants/{variant_id:^[0-9]*$}", dependencies.ProductVariantUpdate)
+		r.Delete("/api/products/{id:^[0-9]*$}/variants/{variant_id:^[0-9]*$}", dependencies.ProductVariantDelete)
+		r.Post("/api/uploads/product-image", dependencies.UploadProductImage)
+		r.Post("/api/products/import", dependencies.ProductImport)
+		r.Get("/api/products/export", dependencies.ProductExport)
+		r.Get("/api/products/{id:^[0-9]*$}/history", dependencies.ProductHistory)
+		r.Get("/api/products/{id:^[0-9]*$}/price-history", dependencies.ProductPriceHistory)
+		r.Get("/api/products/{id:^[0-9]*$}/prices", dependencies.ProductPriceList)
+		r.Put("/api/products/{id:^[0-9]*$}/prices", dependencies.ProductPriceSet)
+		r.Delete("/api/products/{id:^[0-9]*$}/prices/{price_id:^[0-9]*$}", dependencies.ProductPriceDelete)
+		r.Post("/api/products/{id:^[0-9]*$}/stock-movements", dependencies.InventoryAdjust)
+		r.Get("/api/products/{id:^[0-9]*$}/stock-movements", dependencies.InventoryMovementList)
+		r.Get("/api/inventory/reconcile", dependencies.InventoryReconcile)
+		r.Post("/api/inventory/reconcile", dependencies.InventoryReconcile)
+		r.Get("/api/products/{id:^[0-9]*$}/stocks", dependencies.InventoryStockList)
+		r.Get("/api/warehouses", dependencies.WarehouseList)
+		r.Post("/api/warehouses", dependencies.WarehouseCreate)
+		r.Put("/api/warehouses/{id:^[0-9]*$}", dependencies.WarehouseUpdate)
+		r.Delete("/api/warehouses/{id:^[0-9]*$}", dependencies.WarehouseDelete)
+		r.Put("/api/products/{id:^[0-9]*$}/reorder-threshold", dependencies.AlertSetReorderThreshold)
+		r.Get("/api/inventory/low-stock", dependencies.AlertLowStock)
+		r.Get("/api/reviews", dependencies.ReviewListModeration)
+		r.Put("/api/reviews/{id:^[0-9]*$}/status", dependencies.ReviewModerate)
+		r.Get("/api/coupons", dependencies.CouponList)
+		r.Post("/api/coupons", dependencies.CouponCreate)
+		r.Put("/api/coupons/{id:^[0-9]*$}", dependencies.CouponUpdate)
+		r.Get("/api/promotions", dependencies.PromotionList)
+		r.Post("/api/promotions", dependencies.PromotionCreate)
+		r.Put("/api/promotions/{id:^[0-9]*$}", dependencies.PromotionUpdate)
+		r.Post("/api/promotions/preview", dependencies.PromotionPreview)
+		r.Get("/api/tax-rates", dependencies.TaxRateList)
+		r.Post("/api/tax-rates", dependencies.TaxRateCreate)
+		r.Put("/api/tax-rates/{id:^[0-9]*$}", dependencies.TaxRateUpdate)
+		r.Delete("/api/tax-rates/{id:^[0-9]*$}", dependencies.TaxRateDelete)
+		r.Get("/api/shipping-methods", dependencies.ShippingMethodList)
+		r.Post("/api/shipping-methods", dependencies.ShippingMethodCreate)
+		r.Put("/api/shipping-methods/{id:^[0-9]*$}", dependencies.ShippingMethodUpdate)
+		r.Get("/api/orders/{id:^[0-9]*$}/shipments", dependencies.ShipmentList)
+		r.Post("/api/orders/{id:^[0-9]*$}/shipments", dependencies.ShipmentCreate)
+		r.Put("/api/shipments/{id:^[0-9]*$}", dependencies.ShipmentUpdate)
+		r.Get("/api/returns", dependencies.ReturnList)
+		r.Get("/api/returns/{id:^[0-9]*$}", dependencies.ReturnGet)
+		r.Put("/api/returns/{id:^[0-9]*$}/status", dependencies.ReturnUpdateStatus)
+		r.Post("/api/returns/{id:^[0-9]*$}/refund/retry", dependencies.ReturnRetryRefund)
+		r.Get("/api/invoices", dependencies.InvoiceList)
+		r.Get("/api/invoices/{id:^[0-9]*$}.pdf", dependencies.InvoicePdf)
+		r.Get("/api/exchange-rates", dependencies.ExchangeRateList)
+		r.Post("/api/exchange-rates/import", dependencies.ExchangeRateImport)
+		r.Put("/api/exchange-rates/{currency:^[a-zA-Z]{3}$}", dependencies.ExchangeRateSet)
+		r.Delete("/api/exchange-rates/{currency:^[a-zA-Z]{3}$}", dependencies.ExchangeRateDelete)
 	})
 
 	httpServer := &http.Server{
@@ -0,0 +1,76 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"sypchal/order"
+	"sypchal/validation"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+)
+
+type ShipmentCreateRequest struct {
+	Carrier        string               `json:"carrier"`
+	TrackingNumber string               `json:"tracking_number"`
+	TrackingUrl    string               `json:"tracking_url"`
+	Status         string               `json:"status"`
+	Items          []order.ShipmentItem `json:"items"`
+}
+
+func (s *ServerDependency) ShipmentCreate(w http.ResponseWriter, r *http.Request) {
+	orderId, _ := strconv.Atoi(chi.URLParam(r, "id"))
+	requestBody := ShipmentCreateRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		s.Response(w, r).Status(http.StatusBadRequest).
+			Error(http.StatusBadRequest, "invalid request body", nil)
+		return
+	}
+
+	shipment, err := s.orderDomain.CreateShipment(r.Context(), orderId, order.CreateShipmentRequest(requestBody))
+	if err != nil {
+		log.Error().Err(err).Msg("create shipment")
+		s.shipmentError(w, r, err)
+		return
+	}
+
+	s.Response(w, r).Status(http.StatusCreated).Data(shipment)
+}
+
+func (s *ServerDependency) shipmentError(w http.ResponseWriter, r *http.Request, err error) {
+	var ve *validation.ValidationErrors
+	if errors.As(err, &ve) {
+		s.Response(w, r).Status(http.StatusBadRequest).
+			Error(http.StatusBadRequest, "validation error", ve.Transform())
+		return
+	}
+
+	for _, target := range []error{order.ErrOrderNotFound, order.ErrShipmentNotFound} {
+		if errors.Is(err, target) {
+			s.Response(w, r).Status(http.StatusNotFound).
+				Error(http.StatusNotFound, target.Error(), nil)
+			return
+		}
+	}
+
+	for _, target := range []error{order.ErrOrderNotPaid, order.ErrNothingToShip, order.ErrShipmentTransition} {
+		if errors.Is(err, target) {
+			s.Response(w, r).Status(http.StatusConflict).
+				Error(http.StatusConflict, err.Error(), nil)
+			return
+		}
+	}
+
+	for _, target := range []error{order.ErrOrderItemNotFound, order.ErrShipmentQty} {
+		if errors.Is(err, target) {
+			s.Response(w, r).Status(http.StatusBadRequest).
+				Error(http.StatusBadRequest, err.Error(), nil)
+			return
+		}
+	}
+
+	s.Response(w, r).Status(http.StatusInternalServerError).
+		Error(http.StatusInternalServerError, "internal server error", nil)
+}
@@ -0,0 +1,37 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"sypchal/order"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+)
+
+type ShipmentUpdateRequest struct {
+	Carrier        string `json:"carrier"`
+	TrackingNumber string `json:"tracking_number"`
+	TrackingUrl    string `json:"tracking_url"`
+	Status         string `json:"status"`
+}
+
+func (s *ServerDependency) ShipmentUpdate(w http.ResponseWriter, r *http.Request) {
+	shipmentId, _ := strconv.Atoi(chi.URLParam(r, "id"))
+	requestBody := ShipmentUpdateRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		s.Response(w, r).Status(http.StatusBadRequest).
+			Error(http.StatusBadRequest, "invalid request body", nil)
+		return
+	}
+
+	shipment, err := s.orderDomain.UpdateShipmentById(r.Context(), shipmentId, order.UpdateShipmentRequest(requestBody))
+	if err != nil {
+		log.Error().Err(err).Msg("update shipment")
+		s.shipmentError(w, r, err)
+		return
+	}
+
+	s.Response(w, r).Data(shipment)
+}
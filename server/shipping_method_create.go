@@ -0,0 +1,40 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"sypchal/money"
+	"sypchal/shipping"
+
+	"github.com/rs/zerolog/log"
+)
+
+type ShippingMethodCreateRequest struct {
+	Code     string             `json:"code"`
+	Name     string             `json:"name"`
+	Zones    []string           `json:"zones"`
+	RateType string             `json:"rate_type"`
+	BaseRate money.Money        `json:"base_rate"`
+	Brackets []shipping.Bracket `json:"brackets"`
+	FreeOver *money.Money       `json:"free_over"`
+	Position int                `json:"position"`
+	Active   bool               `json:"active"`
+}
+
+func (s *ServerDependency) ShippingMethodCreate(w http.ResponseWriter, r *http.Request) {
+	requestBody := ShippingMethodCreateRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		s.Response(w, r).Status(http.StatusBadRequest).
+			Error(http.StatusBadRequest, "invalid request body", nil)
+		return
+	}
+
+	method, err := s.shippingDomain.CreateMethod(r.Context(), shipping.CreateMethodRequest(requestBody))
+	if err != nil {
+		log.Error().Err(err).Msg("create shipping method")
+		s.shippingError(w, r, err)
+		return
+	}
+
+	s.Response(w, r).Status(http.StatusCreated).Data(method)
+}
@@ -0,0 +1,20 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/rs/zerolog/log"
+)
+
+func (s *ServerDependency) ShippingMethodList(w http.ResponseWriter, r *http.Request) {
+	methods, err := s.shippingDomain.GetMethods(r.Context())
+	if err != nil {
+		log.Error().Err(err).Msg("get shipping methods")
+
+		s.Response(w, r).Status(http.StatusInternalServerError).
+			Error(http.StatusInternalServerError, "internal server error", nil)
+		return
+	}
+
+	s.Response(w, r).Data(methods)
+}
@@ -0,0 +1,32 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"sypchal/shipping"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+)
+
+type ShippingMethodUpdateRequest ShippingMethodCreateRequest
+
+func (s *ServerDependency) ShippingMethodUpdate(w http.ResponseWriter, r *http.Request) {
+	methodId, _ := strconv.Atoi(chi.URLParam(r, "id"))
+	requestBody := ShippingMethodUpdateRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		s.Response(w, r).Status(http.StatusBadRequest).
+			Error(http.StatusBadRequest, "invalid request body", nil)
+		return
+	}
+
+	method, err := s.shippingDomain.UpdateMethodById(r.Context(), methodId, shipping.UpdateMethodRequest(requestBody))
+	if err != nil {
+		log.Error().Err(err).Msg("update shipping method")
+		s.shippingError(w, r, err)
+		return
+	}
+
+	s.Response(w, r).Data(method)
+}
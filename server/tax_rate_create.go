@@ -0,0 +1,60 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sypchal/tax"
+	"sypchal/validation"
+
+	"github.com/rs/zerolog/log"
+)
+
+type TaxRateCreateRequest struct {
+	Region   string `json:"region"`
+	TaxClass string `json:"tax_class"`
+	Name     string `json:"name"`
+	Rate     int    `json:"rate"`
+}
+
+func (s *ServerDependency) TaxRateCreate(w http.ResponseWriter, r *http.Request) {
+	requestBody := TaxRateCreateRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		s.Response(w, r).Status(http.StatusBadRequest).
+			Error(http.StatusBadRequest, "invalid request body", nil)
+		return
+	}
+
+	rate, err := s.taxDomain.CreateRate(r.Context(), tax.CreateRateRequest(requestBody))
+	if err != nil {
+		log.Error().Err(err).Msg("create tax rate")
+		s.taxRateError(w, r, err)
+		return
+	}
+
+	s.Response(w, r).Status(http.StatusCreated).Data(rate)
+}
+
+func (s *ServerDependency) taxRateError(w http.ResponseWriter, r *http.Request, err error) {
+	var ve *validation.ValidationErrors
+	if errors.As(err, &ve) {
+		s.Response(w, r).Status(http.StatusBadRequest).
+			Error(http.StatusBadRequest, "validation error", ve.Transform())
+		return
+	}
+
+	if errors.Is(err, tax.ErrTaxRateNotFound) {
+		s.Response(w, r).Status(http.StatusNotFound).
+			Error(http.StatusNotFound, "tax rate not found", nil)
+		return
+	}
+
+	if errors.Is(err, tax.ErrTaxRateExists) {
+		s.Response(w, r).Status(http.StatusConflict).
+			Error(http.StatusConflict, err.Error(), nil)
+		return
+	}
+
+	s.Response(w, r).Status(http.StatusInternalServerError).
+		Error(http.StatusInternalServerError, "internal server error", nil)
+}
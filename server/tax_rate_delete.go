@@ -0,0 +1,21 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+)
+
+func (s *ServerDependency) TaxRateDelete(w http.ResponseWriter, r *http.Request) {
+	rateId, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	if err := s.taxDomain.DeleteRateById(r.Context(), rateId); err != nil {
+		log.Error().Err(err).Msg("delete tax rate")
+		s.taxRateError(w, r, err)
+		return
+	}
+
+	s.Response(w, r).Status(http.StatusNoContent).End()
+}
@@ -0,0 +1,20 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/rs/zerolog/log"
+)
+
+func (s *ServerDependency) TaxRateList(w http.ResponseWriter, r *http.Request) {
+	rates, err := s.taxDomain.GetRates(r.Context())
+	if err != nil {
+		log.Error().Err(err).Msg("get tax rates")
+
+		s.Response(w, r).Status(http.StatusInternalServerError).
+			Error(http.StatusInternalServerError, "internal server error", nil)
+		return
+	}
+
+	s.Response(w, r).Data(rates)
+}
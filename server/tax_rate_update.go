@@ -0,0 +1,32 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"sypchal/tax"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+)
+
+type TaxRateUpdateRequest TaxRateCreateRequest
+
+func (s *ServerDependency) TaxRateUpdate(w http.ResponseWriter, r *http.Request) {
+	rateId, _ := strconv.Atoi(chi.URLParam(r, "id"))
+	requestBody := TaxRateUpdateRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		s.Response(w, r).Status(http.StatusBadRequest).
+			Error(http.StatusBadRequest, "invalid request body", nil)
+		return
+	}
+
+	rate, err := s.taxDomain.UpdateRateById(r.Context(), rateId, tax.UpdateRateRequest(requestBody))
+	if err != nil {
+		log.Error().Err(err).Msg("update tax rate")
+		s.taxRateError(w, r, err)
+		return
+	}
+
+	s.Response(w, r).Data(rate)
+}
@@ -0,0 +1,87 @@
+package server
+
+import (
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"sypchal/upload"
+
+	"github.com/rs/zerolog/log"
+)
+
+func (s *ServerDependency) UploadProductImage(w http.ResponseWriter, r *http.Request) {
+	file, ok := s.formFile(w, r)
+	if !ok {
+		return
+	}
+	defer file.Close()
+
+	res, err := s.uploadDomain.UploadProductImage(r.Context(), file)
+	if err != nil {
+		log.Error().Err(err).Msg("upload product image")
+		s.uploadError(w, r, err)
+		return
+	}
+
+	s.Response(w, r).Status(http.StatusCreated).Data(res)
+}
+
+// formFile reads the "file" field of a multipart request, responding with an
+// error when it is missing or bigger than the upload limit.
+func (s *ServerDependency) formFile(w http.ResponseWriter, r *http.Request) (multipart.File, bool) {
+	maxSize := s.uploadDomain.MaxSize()
+	// leave some room for the multipart boundaries and other fields
+	r.Body = http.MaxBytesReader(w, r.Body, maxSize+1<<20)
+
+	if err := r.ParseMultipartForm(maxSize); err != nil {
+		var mbe *http.MaxBytesError
+		if errors.As(err, &mbe) {
+			s.Response(w, r).Status(http.StatusRequestEntityTooLarge).
+				Error(http.StatusRequestEntityTooLarge, "file too large", nil)
+			return nil, false
+		}
+
+		s.Response(w, r).Status(http.StatusBadRequest).
+			Error(http.StatusBadRequest, "invalid multipart form", nil)
+		return nil, false
+	}
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		s.Response(w, r).Status(http.StatusBadRequest).
+			Error(http.StatusBadRequest, "file is required", nil)
+		return nil, false
+	}
+
+	if header.Size > maxSize {
+		file.Close()
+		s.Response(w, r).Status(http.StatusRequestEntityTooLarge).
+			Error(http.StatusRequestEntityTooLarge, "file too large", nil)
+		return nil, false
+	}
+
+	return file, true
+}
+
+func (s *ServerDependency) uploadError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, upload.ErrFileTooLarge) {
+		s.Response(w, r).Status(http.StatusRequestEntityTooLarge).
+			Error(http.StatusRequestEntityTooLarge, "file too large", nil)
+		return
+	}
+
+	if errors.Is(err, upload.ErrImageTooLarge) {
+		s.Response(w, r).Status(http.StatusRequestEntityTooLarge).
+			Error(http.StatusRequestEntityTooLarge, "image too large", nil)
+		return
+	}
+
+	if errors.Is(err, upload.ErrUnsupportedFileType) {
+		s.Response(w, r).Status(http.StatusUnsupportedMediaType).
+			Error(http.StatusUnsupportedMediaType, "unsupported file type", nil)
+		return
+	}
+
+	s.Response(w, r).Status(http.StatusInternalServerError).
+		Error(http.StatusInternalServerError, "internal server error", nil)
+}
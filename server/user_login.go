@@ -24,7 +24,7 @@ func (s *ServerDependency) UserLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	accessToken, err := s.userDomain.Authenticate(r.Context(), user.AuthenticateRequest{
+	accessToken, userId, err := s.userDomain.Authenticate(r.Context(), user.AuthenticateRequest{
 		Email:    requestBody.Email,
 		Password: requestBody.Password,
 	})
@@ -49,5 +49,10 @@ func (s *ServerDependency) UserLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	s.Response(w, r).Data(map[string]string{"access_token": accessToken})
+	res := map[string]interface{}{"access_token": accessToken}
+	if merge := s.mergeGuestCart(r, userId); merge != nil {
+		res["cart_merge"] = merge
+	}
+
+	s.Response(w, r).Data(res)
 }
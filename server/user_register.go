@@ -25,7 +25,7 @@ func (s *ServerDependency) UserRegister(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	err := s.userDomain.CreateUser(r.Context(), user.CreateUserRequest{
+	userId, err := s.userDomain.CreateUser(r.Context(), user.CreateUserRequest{
 		Email:    requestBody.Email,
 		Password: requestBody.Password,
 		FullName: requestBody.FullName,
@@ -51,5 +51,11 @@ func (s *ServerDependency) UserRegister(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	if merge := s.mergeGuestCart(r, userId); merge != nil {
+		s.Response(w, r).Status(http.StatusCreated).
+			Data(map[string]interface{}{"cart_merge": merge})
+		return
+	}
+
 	s.Response(w, r).Status(http.StatusCreated).End()
 }
@@ -0,0 +1,82 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sypchal/money"
+	"sypchal/user"
+
+	"github.com/go-chi/jwtauth/v5"
+	"github.com/rs/zerolog/log"
+)
+
+type UserSetCurrencyRequest struct {
+	// Currency is the currency to see prices in, empty clears the preference.
+	Currency string `json:"currency"`
+}
+
+type UserSetCurrencyResponse struct {
+	Currency money.Currency `json:"currency"`
+}
+
+func (s *ServerDependency) UserSetCurrency(w http.ResponseWriter, r *http.Request) {
+	var requestBody UserSetCurrencyRequest
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		s.Response(w, r).Status(http.StatusBadRequest).
+			Error(http.StatusBadRequest, "invalid request body", nil)
+		return
+	}
+
+	_, payload, err := jwtauth.FromContext(r.Context())
+	if err != nil {
+		log.Error().Err(err).Msg("get jwt payload")
+		s.Response(w, r).Status(http.StatusInternalServerError).
+			Error(http.StatusInternalServerError, "internal server error", nil)
+		return
+	}
+
+	userId, err := strconv.Atoi(payload["uid"].(string))
+	if err != nil {
+		log.Error().Err(err).Msg("atoi")
+		s.Response(w, r).Status(http.StatusInternalServerError).
+			Error(http.StatusInternalServerError, "internal server error", nil)
+		return
+	}
+
+	var currency money.Currency
+	if code := strings.TrimSpace(requestBody.Currency); code != "" {
+		rates, err := s.exchangeDomain.LoadRates(r.Context())
+		if err != nil {
+			log.Error().Err(err).Msg("load exchange rates")
+			s.Response(w, r).Status(http.StatusInternalServerError).
+				Error(http.StatusInternalServerError, "internal server error", nil)
+			return
+		}
+
+		currency, err = money.ParseCurrency(code)
+		if err != nil || !rates.Supports(currency) {
+			s.Response(w, r).Status(http.StatusBadRequest).
+				Error(http.StatusBadRequest, "currency is not supported", map[string]any{"currencies": rates.Currencies()})
+			return
+		}
+	}
+
+	if err = s.userDomain.SetCurrency(r.Context(), userId, currency); err != nil {
+		log.Error().Err(err).Msg("set user currency")
+
+		if errors.Is(err, user.ErrUserNotFound) {
+			s.Response(w, r).Status(http.StatusNotFound).
+				Error(http.StatusNotFound, "user not found", nil)
+			return
+		}
+
+		s.Response(w, r).Status(http.StatusInternalServerError).
+			Error(http.StatusInternalServerError, "internal server error", nil)
+		return
+	}
+
+	s.Response(w, r).Data(UserSetCurrencyResponse{currency})
+}
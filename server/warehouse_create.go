@@ -0,0 +1,73 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sypchal/inventory"
+	"sypchal/validation"
+
+	"github.com/rs/zerolog/log"
+)
+
+type WarehouseCreateRequest struct {
+	Code      string `json:"code"`
+	Name      string `json:"name"`
+	Address   string `json:"address"`
+	Priority  int    `json:"priority"`
+	IsDefault bool   `json:"is_default"`
+}
+
+func (s *ServerDependency) WarehouseCreate(w http.ResponseWriter, r *http.Request) {
+	requestBody := WarehouseCreateRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		s.Response(w, r).Status(http.StatusBadRequest).
+			Error(http.StatusBadRequest, "invalid request body", nil)
+		return
+	}
+
+	warehouse, err := s.inventoryDomain.CreateWarehouse(r.Context(), inventory.CreateWarehouseRequest(requestBody))
+	if err != nil {
+		log.Error().Err(err).Msg("create warehouse")
+		s.warehouseError(w, r, err)
+		return
+	}
+
+	s.Response(w, r).Status(http.StatusCreated).Data(warehouse)
+}
+
+func (s *ServerDependency) warehouseError(w http.ResponseWriter, r *http.Request, err error) {
+	var ve *validation.ValidationErrors
+	if errors.As(err, &ve) {
+		s.Response(w, r).Status(http.StatusBadRequest).
+			Error(http.StatusBadRequest, "validation error", ve.Transform())
+		return
+	}
+
+	if errors.Is(err, inventory.ErrWarehouseNotFound) {
+		s.Response(w, r).Status(http.StatusBadRequest).
+			Error(http.StatusBadRequest, "warehouse not found", nil)
+		return
+	}
+
+	if errors.Is(err, inventory.ErrWarehouseCodeTaken) {
+		s.Response(w, r).Status(http.StatusBadRequest).
+			Error(http.StatusBadRequest, "warehouse code already exists", nil)
+		return
+	}
+
+	if errors.Is(err, inventory.ErrDefaultWarehouse) {
+		s.Response(w, r).Status(http.StatusConflict).
+			Error(http.StatusConflict, "make another warehouse the default first", nil)
+		return
+	}
+
+	if errors.Is(err, inventory.ErrWarehouseInUse) {
+		s.Response(w, r).Status(http.StatusConflict).
+			Error(http.StatusConflict, "warehouse has stock or orders", nil)
+		return
+	}
+
+	s.Response(w, r).Status(http.StatusInternalServerError).
+		Error(http.StatusInternalServerError, "internal server error", nil)
+}
@@ -0,0 +1,21 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+)
+
+func (s *ServerDependency) WarehouseDelete(w http.ResponseWriter, r *http.Request) {
+	warehouseId, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	if err := s.inventoryDomain.DeleteWarehouseById(r.Context(), warehouseId); err != nil {
+		log.Error().Err(err).Msg("delete warehouse")
+		s.warehouseError(w, r, err)
+		return
+	}
+
+	s.Response(w, r).Status(http.StatusNoContent).End()
+}
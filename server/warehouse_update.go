@@ -0,0 +1,36 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"sypchal/inventory"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+)
+
+type WarehouseUpdateRequest WarehouseCreateRequest
+
+func (s *ServerDependency) WarehouseUpdate(w http.ResponseWriter, r *http.Request) {
+	warehouseId, _ := strconv.Atoi(chi.URLParam(r, "id"))
+	requestBody := WarehouseUpdateRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		s.Response(w, r).Status(http.StatusBadRequest).
+			Error(http.StatusBadRequest, "invalid request body", nil)
+		return
+	}
+
+	warehouse, err := s.inventoryDomain.UpdateWarehouseById(
+		r.Context(),
+		warehouseId,
+		inventory.UpdateWarehouseRequest(requestBody),
+	)
+	if err != nil {
+		log.Error().Err(err).Msg("update warehouse")
+		s.warehouseError(w, r, err)
+		return
+	}
+
+	s.Response(w, r).Data(warehouse)
+}
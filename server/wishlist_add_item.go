@@ -0,0 +1,52 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"sypchal/wishlist"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/jwtauth/v5"
+	"github.com/rs/zerolog/log"
+)
+
+type WishlistAddItemRequest struct {
+	ProductId int `json:"product_id"`
+	VariantId int `json:"variant_id"`
+}
+
+func (s *ServerDependency) WishlistAddItem(w http.ResponseWriter, r *http.Request) {
+	wishlistId, _ := strconv.Atoi(chi.URLParam(r, "id"))
+	var requestBody WishlistAddItemRequest
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		s.Response(w, r).Status(http.StatusBadRequest).
+			Error(http.StatusBadRequest, "invalid request body", nil)
+		return
+	}
+
+	_, payload, err := jwtauth.FromContext(r.Context())
+	if err != nil {
+		log.Error().Err(err).Msg("get jwt payload")
+		s.Response(w, r).Status(http.StatusInternalServerError).
+			Error(http.StatusInternalServerError, "internal server error", nil)
+		return
+	}
+
+	userId, err := strconv.Atoi(payload["uid"].(string))
+	if err != nil {
+		log.Error().Err(err).Msg("atoi")
+		s.Response(w, r).Status(http.StatusInternalServerError).
+			Error(http.StatusInternalServerError, "internal server error", nil)
+		return
+	}
+
+	item, err := s.wishlistDomain.AddItem(r.Context(), userId, wishlistId, wishlist.AddItemRequest(requestBody))
+	if err != nil {
+		log.Error().Err(err).Msg("add wishlist item")
+		s.wishlistError(w, r, err)
+		return
+	}
+
+	s.Response(w, r).Status(http.StatusCreated).Data(item)
+}
@@ -0,0 +1,96 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"sypchal/validation"
+	"sypchal/wishlist"
+
+	"github.com/go-chi/jwtauth/v5"
+	"github.com/rs/zerolog/log"
+)
+
+type WishlistCreateRequest struct {
+	Name string `json:"name"`
+}
+
+func (s *ServerDependency) WishlistCreate(w http.ResponseWriter, r *http.Request) {
+	var requestBody WishlistCreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		s.Response(w, r).Status(http.StatusBadRequest).
+			Error(http.StatusBadRequest, "invalid request body", nil)
+		return
+	}
+
+	_, payload, err := jwtauth.FromContext(r.Context())
+	if err != nil {
+		log.Error().Err(err).Msg("get jwt payload")
+		s.Response(w, r).Status(http.StatusInternalServerError).
+			Error(http.StatusInternalServerError, "internal server error", nil)
+		return
+	}
+
+	userId, err := strconv.Atoi(payload["uid"].(string))
+	if err != nil {
+		log.Error().Err(err).Msg("atoi")
+		s.Response(w, r).Status(http.StatusInternalServerError).
+			Error(http.StatusInternalServerError, "internal server error", nil)
+		return
+	}
+
+	list, err := s.wishlistDomain.CreateWishlist(r.Context(), userId, wishlist.CreateWishlistRequest(requestBody))
+	if err != nil {
+		log.Error().Err(err).Msg("create wishlist")
+		s.wishlistError(w, r, err)
+		return
+	}
+
+	s.Response(w, r).Status(http.StatusCreated).Data(list)
+}
+
+func (s *ServerDependency) wishlistError(w http.ResponseWriter, r *http.Request, err error) {
+	var ve *validation.ValidationErrors
+	if errors.As(err, &ve) {
+		s.Response(w, r).Status(http.StatusBadRequest).
+			Error(http.StatusBadRequest, "validation error", ve.Transform())
+		return
+	}
+
+	for _, target := range []error{
+		wishlist.ErrWishlistNotFound,
+		wishlist.ErrItemNotFound,
+		wishlist.ErrSavedItemNotFound,
+		wishlist.ErrCartItemNotFound,
+	} {
+		if errors.Is(err, target) {
+			s.Response(w, r).Status(http.StatusNotFound).
+				Error(http.StatusNotFound, target.Error(), nil)
+			return
+		}
+	}
+
+	for _, target := range []error{wishlist.ErrWishlistExists, wishlist.ErrItemExists} {
+		if errors.Is(err, target) {
+			s.Response(w, r).Status(http.StatusConflict).
+				Error(http.StatusConflict, target.Error(), nil)
+			return
+		}
+	}
+
+	for _, target := range []error{
+		wishlist.ErrProductNotFound,
+		wishlist.ErrVariantNotFound,
+		wishlist.ErrProductOutOfStock,
+	} {
+		if errors.Is(err, target) {
+			s.Response(w, r).Status(http.StatusBadRequest).
+				Error(http.StatusBadRequest, target.Error(), nil)
+			return
+		}
+	}
+
+	s.Response(w, r).Status(http.StatusInternalServerError).
+		Error(http.StatusInternalServerError, "internal server error", nil)
+}
@@ -0,0 +1,39 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/jwtauth/v5"
+	"github.com/rs/zerolog/log"
+)
+
+func (s *ServerDependency) WishlistRemoveItem(w http.ResponseWriter, r *http.Request) {
+	wishlistId, _ := strconv.Atoi(chi.URLParam(r, "id"))
+	itemId, _ := strconv.Atoi(chi.URLParam(r, "item_id"))
+
+	_, payload, err := jwtauth.FromContext(r.Context())
+	if err != nil {
+		log.Error().Err(err).Msg("get jwt payload")
+		s.Response(w, r).Status(http.StatusInternalServerError).
+			Error(http.StatusInternalServerError, "internal server error", nil)
+		return
+	}
+
+	userId, err := strconv.Atoi(payload["uid"].(string))
+	if err != nil {
+		log.Error().Err(err).Msg("atoi")
+		s.Response(w, r).Status(http.StatusInternalServerError).
+			Error(http.StatusInternalServerError, "internal server error", nil)
+		return
+	}
+
+	if err := s.wishlistDomain.RemoveItem(r.Context(), userId, wishlistId, itemId); err != nil {
+		log.Error().Err(err).Msg("remove wishlist item")
+		s.wishlistError(w, r, err)
+		return
+	}
+
+	s.Response(w, r).Status(http.StatusNoContent).End()
+}
@@ -0,0 +1,70 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/jwtauth/v5"
+	"github.com/rs/zerolog/log"
+)
+
+// WishlistShare turns on the read-only link of a wishlist, see
+// WishlistSharedGet.
+func (s *ServerDependency) WishlistShare(w http.ResponseWriter, r *http.Request) {
+	wishlistId, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	_, payload, err := jwtauth.FromContext(r.Context())
+	if err != nil {
+		log.Error().Err(err).Msg("get jwt payload")
+		s.Response(w, r).Status(http.StatusInternalServerError).
+			Error(http.StatusInternalServerError, "internal server error", nil)
+		return
+	}
+
+	userId, err := strconv.Atoi(payload["uid"].(string))
+	if err != nil {
+		log.Error().Err(err).Msg("atoi")
+		s.Response(w, r).Status(http.StatusInternalServerError).
+			Error(http.StatusInternalServerError, "internal server error", nil)
+		return
+	}
+
+	list, err := s.wishlistDomain.ShareWishlist(r.Context(), userId, wishlistId)
+	if err != nil {
+		log.Error().Err(err).Msg("share wishlist")
+		s.wishlistError(w, r, err)
+		return
+	}
+
+	s.Response(w, r).Data(list)
+}
+
+func (s *ServerDependency) WishlistUnshare(w http.ResponseWriter, r *http.Request) {
+	wishlistId, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	_, payload, err := jwtauth.FromContext(r.Context())
+	if err != nil {
+		log.Error().Err(err).Msg("get jwt payload")
+		s.Response(w, r).Status(http.StatusInternalServerError).
+			Error(http.StatusInternalServerError, "internal server error", nil)
+		return
+	}
+
+	userId, err := strconv.Atoi(payload["uid"].(string))
+	if err != nil {
+		log.Error().Err(err).Msg("atoi")
+		s.Response(w, r).Status(http.StatusInternalServerError).
+			Error(http.StatusInternalServerError, "internal server error", nil)
+		return
+	}
+
+	list, err := s.wishlistDomain.UnshareWishlist(r.Context(), userId, wishlistId)
+	if err != nil {
+		log.Error().Err(err).Msg("unshare wishlist")
+		s.wishlistError(w, r, err)
+		return
+	}
+
+	s.Response(w, r).Data(list)
+}
@@ -0,0 +1,27 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+)
+
+// WishlistSharedGet shows a shared wishlist to anyone with its link, read-only
+// and without its owner.
+func (s *ServerDependency) WishlistSharedGet(w http.ResponseWriter, r *http.Request) {
+	currency, _, ok := s.currency(w, r)
+	if !ok {
+		return
+	}
+
+	list, err := s.wishlistDomain.GetSharedWishlist(r.Context(), chi.URLParam(r, "token"), currency)
+	if err != nil {
+		log.Error().Err(err).Msg("get shared wishlist")
+		s.wishlistError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "private, no-cache")
+	s.Response(w, r).Data(list)
+}
@@ -0,0 +1,300 @@
+package shipping
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sypchal/money"
+	"sypchal/validation"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type ShippingDomain struct {
+	db        *pgx.Conn
+	validator *validation.Validator
+	// currency is the currency of the shipping rates
+	currency money.Currency
+}
+
+func NewShippingDomain(db *pgx.Conn, validator *validation.Validator, currency money.Currency) (*ShippingDomain, error) {
+	if db == nil {
+		return nil, errors.New("db is nil")
+	}
+
+	if validator == nil {
+		return nil, errors.New("validator is nil")
+	}
+
+	return &ShippingDomain{db, validator, currency}, nil
+}
+
+// Address is an address of the address book of a customer. Orders keep a copy
+// of the address they ship to.
+type Address struct {
+	Id            int    `json:"id"`
+	UserId        int    `json:"user_id"`
+	Label         string `json:"label"`
+	RecipientName string `json:"recipient_name"`
+	Phone         string `json:"phone"`
+	Line1         string `json:"line1"`
+	Line2         string `json:"line2"`
+	City          string `json:"city"`
+	// Region is a subdivision code like "ID-JK", Country an ISO 3166-1 alpha-2
+	// code like "ID".
+	Region     string     `json:"region"`
+	PostalCode string     `json:"postal_code"`
+	Country    string     `json:"country"`
+	IsDefault  bool       `json:"is_default"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  *time.Time `json:"updated_at"`
+}
+
+// Zone is the most precise place of the address the shipping and tax rates
+// know, its region or else its country.
+func (address *Address) Zone() string {
+	if address.Region != "" {
+		return address.Region
+	}
+
+	return address.Country
+}
+
+const addressColumns = "id,user_id,label,recipient_name,phone,line1,line2,city,region,postal_code,country,is_default," +
+	"created_at,updated_at"
+
+// scanFields returns the destinations matching addressColumns.
+func (address *Address) scanFields() []any {
+	return []any{
+		&address.Id,
+		&address.UserId,
+		&address.Label,
+		&address.RecipientName,
+		&address.Phone,
+		&address.Line1,
+		&address.Line2,
+		&address.City,
+		&address.Region,
+		&address.PostalCode,
+		&address.Country,
+		&address.IsDefault,
+		&address.CreatedAt,
+		&address.UpdatedAt,
+	}
+}
+
+type CreateAddressRequest struct {
+	Label         string `json:"label" validate:"max=64"`
+	RecipientName string `json:"recipient_name" validate:"required"`
+	Phone         string `json:"phone" validate:"required,max=32"`
+	Line1         string `json:"line1" validate:"required"`
+	Line2         string `json:"line2"`
+	City          string `json:"city" validate:"required"`
+	Region        string `json:"region" validate:"max=16"`
+	PostalCode    string `json:"postal_code" validate:"max=16"`
+	Country       string `json:"country" validate:"required,len=2"`
+	IsDefault     bool   `json:"is_default"`
+}
+
+func (req *CreateAddressRequest) validate(validator *validation.Validator) error {
+	if err := validator.ValidateStruct(req); err != nil {
+		return err
+	}
+
+	req.Region = strings.ToUpper(req.Region)
+	req.Country = strings.ToUpper(req.Country)
+
+	return nil
+}
+
+// CreateAddress adds an address to the address book of the customer, the
+// first one is the default address.
+func (s *ShippingDomain) CreateAddress(ctx context.Context, userId int, req CreateAddressRequest) (address *Address, err error) {
+	if err = req.validate(s.validator); err != nil {
+		return
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	if req.IsDefault {
+		if _, err = tx.Exec(ctx, "update addresses set is_default=false where user_id=$1 and is_default", userId); err != nil {
+			return
+		}
+	}
+
+	address = &Address{}
+	err = tx.QueryRow(
+		ctx,
+		`insert into addresses(user_id,label,recipient_name,phone,line1,line2,city,region,postal_code,country,is_default)
+		values ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11 or not exists(select 1 from addresses where user_id=$1))
+		returning `+addressColumns,
+		userId,
+		req.Label,
+		req.RecipientName,
+		req.Phone,
+		req.Line1,
+		req.Line2,
+		req.City,
+		req.Region,
+		req.PostalCode,
+		req.Country,
+		req.IsDefault,
+	).Scan(address.scanFields()...)
+	if err != nil {
+		return
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return
+	}
+
+	return
+}
+
+type UpdateAddressRequest CreateAddressRequest
+
+// UpdateAddressById replaces every field of an address of the customer,
+// placed orders keep the address they shipped to. The default address stays
+// the default when IsDefault is false.
+func (s *ShippingDomain) UpdateAddressById(ctx context.Context, userId int, id int, req UpdateAddressRequest) (address *Address, err error) {
+	create := CreateAddressRequest(req)
+	if err = create.validate(s.validator); err != nil {
+		return
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	if create.IsDefault {
+		_, err = tx.Exec(ctx, "update addresses set is_default=false where user_id=$1 and is_default and id<>$2", userId, id)
+		if err != nil {
+			return
+		}
+	}
+
+	address = &Address{}
+	err = tx.QueryRow(
+		ctx,
+		`update addresses set label=$1,recipient_name=$2,phone=$3,line1=$4,line2=$5,city=$6,region=$7,postal_code=$8,
+		country=$9,is_default=is_default or $10,updated_at=now()
+		where id=$11 and user_id=$12 returning `+addressColumns,
+		create.Label,
+		create.RecipientName,
+		create.Phone,
+		create.Line1,
+		create.Line2,
+		create.City,
+		create.Region,
+		create.PostalCode,
+		create.Country,
+		create.IsDefault,
+		id,
+		userId,
+	).Scan(address.scanFields()...)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			err = ErrAddressNotFound
+		}
+		return
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return
+	}
+
+	return
+}
+
+// DeleteAddressById removes an address of the customer, the newest remaining
+// address becomes the default when it was the default.
+func (s *ShippingDomain) DeleteAddressById(ctx context.Context, userId int, id int) (err error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	var isDefault bool
+	err = tx.QueryRow(ctx, "delete from addresses where id=$1 and user_id=$2 returning is_default", id, userId).Scan(&isDefault)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			err = ErrAddressNotFound
+		}
+		return
+	}
+
+	if isDefault {
+		_, err = tx.Exec(
+			ctx,
+			`update addresses set is_default=true
+			where id=(select id from addresses where user_id=$1 order by id desc limit 1)`,
+			userId,
+		)
+		if err != nil {
+			return
+		}
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return
+	}
+
+	return
+}
+
+// GetAddresses lists the address book of the customer, the default address
+// first.
+func (s *ShippingDomain) GetAddresses(ctx context.Context, userId int) (addresses []*Address, err error) {
+	rows, err := s.db.Query(
+		ctx,
+		"select "+addressColumns+" from addresses where user_id=$1 order by is_default desc,id",
+		userId,
+	)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	addresses = []*Address{}
+	for rows.Next() {
+		address := &Address{}
+		if err = rows.Scan(address.scanFields()...); err != nil {
+			return
+		}
+		addresses = append(addresses, address)
+	}
+	if err = rows.Err(); err != nil {
+		return
+	}
+
+	return
+}
+
+// LoadAddress returns an address of the customer, the default address when id
+// is 0. It fails with ErrAddressNotFound when there is none.
+func LoadAddress(ctx context.Context, db querier, userId int, id int) (address *Address, err error) {
+	address = &Address{}
+	err = db.QueryRow(
+		ctx,
+		`select `+addressColumns+` from addresses
+		where user_id=$1 and (id=$2 or ($2=0 and is_default))`,
+		userId,
+		id,
+	).Scan(address.scanFields()...)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			err = ErrAddressNotFound
+		}
+		return
+	}
+
+	return
+}
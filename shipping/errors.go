@@ -0,0 +1,8 @@
+package shipping
+
+import "errors"
+
+var ErrAddressNotFound = errors.New("address not found")
+var ErrMethodNotFound = errors.New("shipping method not found")
+var ErrMethodCodeTaken = errors.New("shipping method code already exists")
+var ErrInvalidRate = errors.New("weight and order_value rates need brackets")
@@ -0,0 +1,354 @@
+package shipping
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sort"
+	"strings"
+	"sypchal/exchange"
+	"sypchal/money"
+	"sypchal/validation"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+var (
+	// RateFlat charges BaseRate.
+	RateFlat = "flat"
+	// RateWeight charges BaseRate plus the bracket of the weight, in grams.
+	RateWeight = "weight"
+	// RateOrderValue charges BaseRate plus the bracket of the order value.
+	RateOrderValue = "order_value"
+)
+
+// Bracket is the price of the weights or order values from Min up to the
+// next bracket, order values being in minor units.
+type Bracket struct {
+	Min   int         `json:"min" validate:"gte=0"`
+	Price money.Money `json:"price" validate:"gte=0"`
+}
+
+type Method struct {
+	Id   int    `json:"id"`
+	Code string `json:"code"`
+	Name string `json:"name"`
+	// Zones are the regions and countries the method ships to, everywhere
+	// when empty.
+	Zones    []string    `json:"zones"`
+	RateType string      `json:"rate_type"`
+	BaseRate money.Money `json:"base_rate"`
+	Brackets []Bracket   `json:"brackets"`
+	// FreeOver is the order value from which shipping is free, never when
+	// nil.
+	FreeOver  *money.Money `json:"free_over"`
+	Position  int          `json:"position"`
+	Active    bool         `json:"active"`
+	CreatedAt time.Time    `json:"created_at"`
+	UpdatedAt *time.Time   `json:"updated_at"`
+}
+
+const methodColumns = "id,code,name,zones,rate_type,row(base_rate,currency),brackets," +
+	"case when free_over is null then null else row(free_over,currency) end,position,active,created_at,updated_at"
+
+// scanFields returns the destinations matching methodColumns.
+func (method *Method) scanFields() []any {
+	return []any{
+		&method.Id,
+		&method.Code,
+		&method.Name,
+		&method.Zones,
+		&method.RateType,
+		&method.BaseRate,
+		&method.Brackets,
+		&method.FreeOver,
+		&method.Position,
+		&method.Active,
+		&method.CreatedAt,
+		&method.UpdatedAt,
+	}
+}
+
+// ShipsTo reports whether the method ships to the address.
+func (method *Method) ShipsTo(address *Address) bool {
+	return len(method.Zones) == 0 ||
+		slices.Contains(method.Zones, address.Country) ||
+		(address.Region != "" && slices.Contains(method.Zones, address.Region))
+}
+
+// Price is the shipping cost of a parcel of weight grams for an order of
+// value.
+func (method *Method) Price(weight int, value money.Money) money.Money {
+	if method.FreeOver != nil && !value.LessThan(*method.FreeOver) {
+		return money.Zero(method.BaseRate.Currency)
+	}
+
+	measure := int64(weight)
+	switch method.RateType {
+	case RateFlat:
+		return method.BaseRate
+	case RateOrderValue:
+		measure = value.Amount
+	}
+
+	// brackets are sorted by Min, the last one reached applies
+	price := money.Zero(method.BaseRate.Currency)
+	for _, bracket := range method.Brackets {
+		if measure >= int64(bracket.Min) {
+			price = bracket.Price
+		}
+	}
+
+	return method.BaseRate.Add(price)
+}
+
+// localize converts the rates of the method into currency, and the order
+// values of its brackets.
+func (method *Method) localize(rates *exchange.Rates, currency money.Currency) (err error) {
+	from := method.BaseRate.Currency
+	if method.BaseRate, err = rates.Convert(method.BaseRate, currency); err != nil {
+		return
+	}
+
+	if method.FreeOver != nil {
+		freeOver, err := rates.Convert(*method.FreeOver, currency)
+		if err != nil {
+			return err
+		}
+		method.FreeOver = &freeOver
+	}
+
+	for i, bracket := range method.Brackets {
+		if method.Brackets[i].Price, err = rates.Convert(bracket.Price, currency); err != nil {
+			return
+		}
+
+		if method.RateType == RateOrderValue {
+			var value money.Money
+			if value, err = rates.Convert(money.New(int64(bracket.Min), from), currency); err != nil {
+				return
+			}
+			method.Brackets[i].Min = int(value.Amount)
+		}
+	}
+
+	return
+}
+
+type CreateMethodRequest struct {
+	Code     string       `json:"code" validate:"required,max=32"`
+	Name     string       `json:"name" validate:"required"`
+	Zones    []string     `json:"zones" validate:"dive,max=16"`
+	RateType string       `json:"rate_type" validate:"required,oneof=flat weight order_value"`
+	BaseRate money.Money  `json:"base_rate" validate:"gte=0"`
+	Brackets []Bracket    `json:"brackets" validate:"dive"`
+	FreeOver *money.Money `json:"free_over" validate:"omitempty,gte=0"`
+	Position int          `json:"position"`
+	Active   bool         `json:"active"`
+}
+
+func (req *CreateMethodRequest) validate(validator *validation.Validator, currency money.Currency) (err error) {
+	if err = validator.ValidateStruct(req); err != nil {
+		return err
+	}
+
+	if req.RateType != RateFlat && len(req.Brackets) == 0 {
+		return ErrInvalidRate
+	}
+
+	if req.BaseRate, err = validation.InCurrency("base_rate", req.BaseRate, currency); err != nil {
+		return err
+	}
+	for i := range req.Brackets {
+		if req.Brackets[i].Price, err = validation.InCurrency("brackets", req.Brackets[i].Price, currency); err != nil {
+			return err
+		}
+	}
+	if req.FreeOver != nil {
+		freeOver, err := validation.InCurrency("free_over", *req.FreeOver, currency)
+		if err != nil {
+			return err
+		}
+		req.FreeOver = &freeOver
+	}
+
+	req.Code = strings.ToLower(req.Code)
+	for i, zone := range req.Zones {
+		req.Zones[i] = strings.ToUpper(zone)
+	}
+	if req.Zones == nil {
+		req.Zones = []string{}
+	}
+	if req.Brackets == nil {
+		req.Brackets = []Bracket{}
+	}
+	sort.SliceStable(req.Brackets, func(i, j int) bool { return req.Brackets[i].Min < req.Brackets[j].Min })
+
+	return nil
+}
+
+func (s *ShippingDomain) CreateMethod(ctx context.Context, req CreateMethodRequest) (method *Method, err error) {
+	if err = req.validate(s.validator, s.currency); err != nil {
+		return
+	}
+
+	method = &Method{}
+	err = s.db.QueryRow(
+		ctx,
+		`insert into shipping_methods(code,name,zones,rate_type,base_rate,currency,brackets,free_over,position,active)
+		values ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10) returning `+methodColumns,
+		req.Code,
+		req.Name,
+		req.Zones,
+		req.RateType,
+		req.BaseRate.Amount,
+		req.BaseRate.Currency,
+		req.Brackets,
+		amountOf(req.FreeOver),
+		req.Position,
+		req.Active,
+	).Scan(method.scanFields()...)
+	if err != nil {
+		err = methodCodeTaken(err)
+		return
+	}
+
+	return
+}
+
+type UpdateMethodRequest CreateMethodRequest
+
+// UpdateMethodById replaces every field of the shipping method, placed orders
+// keep the shipping they were charged.
+func (s *ShippingDomain) UpdateMethodById(ctx context.Context, id int, req UpdateMethodRequest) (method *Method, err error) {
+	create := CreateMethodRequest(req)
+	if err = create.validate(s.validator, s.currency); err != nil {
+		return
+	}
+
+	method = &Method{}
+	err = s.db.QueryRow(
+		ctx,
+		`update shipping_methods set code=$1,name=$2,zones=$3,rate_type=$4,base_rate=$5,currency=$6,brackets=$7,
+		free_over=$8,position=$9,active=$10,updated_at=now()
+		where id=$11 returning `+methodColumns,
+		create.Code,
+		create.Name,
+		create.Zones,
+		create.RateType,
+		create.BaseRate.Amount,
+		create.BaseRate.Currency,
+		create.Brackets,
+		amountOf(create.FreeOver),
+		create.Position,
+		create.Active,
+		id,
+	).Scan(method.scanFields()...)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			err = ErrMethodNotFound
+			return
+		}
+		err = methodCodeTaken(err)
+		return
+	}
+
+	return
+}
+
+// amountOf is the amount of an optional price, nil when there is none.
+func amountOf(price *money.Money) *int64 {
+	if price == nil {
+		return nil
+	}
+
+	return &price.Amount
+}
+
+// methodCodeTaken maps the unique_violation of the method code to
+// ErrMethodCodeTaken.
+func methodCodeTaken(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return ErrMethodCodeTaken
+	}
+
+	return err
+}
+
+// GetMethods lists every shipping method by position.
+func (s *ShippingDomain) GetMethods(ctx context.Context) (methods []*Method, err error) {
+	return getMethods(ctx, s.db, false)
+}
+
+type querier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+func getMethods(ctx context.Context, db querier, activeOnly bool) (methods []*Method, err error) {
+	rows, err := db.Query(
+		ctx,
+		"select "+methodColumns+" from shipping_methods where active or not $1 order by position,id",
+		activeOnly,
+	)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	methods = []*Method{}
+	for rows.Next() {
+		method := &Method{}
+		if err = rows.Scan(method.scanFields()...); err != nil {
+			return
+		}
+		methods = append(methods, method)
+	}
+	if err = rows.Err(); err != nil {
+		return
+	}
+
+	return
+}
+
+// Quote is what a shipping method charges for an order.
+type Quote struct {
+	MethodId int         `json:"method_id"`
+	Code     string      `json:"code"`
+	Name     string      `json:"name"`
+	Price    money.Money `json:"price"`
+}
+
+// Quotes prices the active methods shipping to the address for a parcel of
+// weight grams and an order of value, cheapest first, in the currency of value
+// converted at the rates.
+func Quotes(ctx context.Context, db querier, address *Address, weight int, value money.Money, rates *exchange.Rates) (quotes []Quote, err error) {
+	methods, err := getMethods(ctx, db, true)
+	if err != nil {
+		return
+	}
+
+	quotes = []Quote{}
+	for _, method := range methods {
+		if !method.ShipsTo(address) {
+			continue
+		}
+
+		if err = method.localize(rates, value.Currency); err != nil {
+			return
+		}
+
+		quotes = append(quotes, Quote{
+			MethodId: method.Id,
+			Code:     method.Code,
+			Name:     method.Name,
+			Price:    method.Price(weight, value),
+		})
+	}
+	sort.SliceStable(quotes, func(i, j int) bool { return quotes[i].Price.LessThan(quotes[j].Price) })
+
+	return
+}
@@ -0,0 +1,177 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type s3Object struct {
+	body        []byte
+	contentType string
+}
+
+// fakeS3 is an in-memory S3 bucket that checks the requests are path-style
+// and signed.
+type fakeS3 struct {
+	t       *testing.T
+	bucket  string
+	mu      sync.Mutex
+	objects map[string]s3Object
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	key, ok := strings.CutPrefix(r.URL.Path, "/"+f.bucket+"/")
+	if !ok {
+		http.Error(w, "<Error><Code>NoSuchBucket</Code></Error>", http.StatusNotFound)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		f.t.Errorf("read body: %v", err)
+		return
+	}
+
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=access/") {
+		f.t.Errorf("%s %s: unexpected authorization %q", r.Method, key, r.Header.Get("Authorization"))
+	}
+	if got := r.Header.Get("X-Amz-Content-Sha256"); got != sha256Hex(body) {
+		f.t.Errorf("%s %s: payload hash %q, want %q", r.Method, key, got, sha256Hex(body))
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch r.Method {
+	case http.MethodPut:
+		f.objects[key] = s3Object{body, r.Header.Get("Content-Type")}
+	case http.MethodGet:
+		object, ok := f.objects[key]
+		if !ok {
+			w.Header().Set("Content-Type", "application/xml")
+			w.WriteHeader(http.StatusNotFound)
+			io.WriteString(w, "<Error><Code>NoSuchKey</Code></Error>")
+			return
+		}
+		w.Header().Set("Content-Type", object.contentType)
+		w.Write(object.body)
+	case http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func newTestS3Store(t *testing.T) (*S3Store, *fakeS3) {
+	fake := &fakeS3{t: t, bucket: "sypchal", objects: map[string]s3Object{}}
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+
+	store, err := NewS3Store(S3Config{
+		Endpoint:  srv.URL + "/",
+		Bucket:    "sypchal",
+		AccessKey: "access",
+		SecretKey: "secret",
+	})
+	if err != nil {
+		t.Fatalf("new s3 store: %v", err)
+	}
+
+	return store, fake
+}
+
+func TestS3Store(t *testing.T) {
+	ctx := context.Background()
+	store, fake := newTestS3Store(t)
+
+	tests := []struct {
+		key         string
+		body        string
+		contentType string
+	}{
+		{"products/5f2b.jpg", "jpeg data", "image/jpeg"},
+		{"proofs/7/thumbs/a1 b2.png", "png data", "image/png"},
+		{"proofs/7/c3.pdf", "", "application/pdf"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			if err := store.Put(ctx, tt.key, strings.NewReader(tt.body), tt.contentType); err != nil {
+				t.Fatalf("put: %v", err)
+			}
+
+			fake.mu.Lock()
+			object, ok := fake.objects[tt.key]
+			fake.mu.Unlock()
+			if !ok {
+				t.Fatalf("put stored no object at %q", tt.key)
+			}
+			if object.contentType != tt.contentType {
+				t.Errorf("stored content type %q, want %q", object.contentType, tt.contentType)
+			}
+
+			body, err := store.Get(ctx, tt.key)
+			if err != nil {
+				t.Fatalf("get: %v", err)
+			}
+			data, err := io.ReadAll(body)
+			body.Close()
+			if err != nil {
+				t.Fatalf("read: %v", err)
+			}
+			if string(data) != tt.body {
+				t.Errorf("got body %q, want %q", data, tt.body)
+			}
+
+			if err := store.Delete(ctx, tt.key); err != nil {
+				t.Fatalf("delete: %v", err)
+			}
+
+			if _, err := store.Get(ctx, tt.key); !errors.Is(err, ErrBlobNotFound) {
+				t.Errorf("get after delete: got %v, want ErrBlobNotFound", err)
+			}
+		})
+	}
+}
+
+func TestS3StoreGetNotFound(t *testing.T) {
+	store, _ := newTestS3Store(t)
+
+	body, err := store.Get(context.Background(), "products/missing.jpg")
+	if !errors.Is(err, ErrBlobNotFound) {
+		t.Fatalf("got %v, want ErrBlobNotFound", err)
+	}
+	if body != nil {
+		t.Errorf("got a body for a missing blob")
+	}
+}
+
+func TestS3StoreError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "<Error><Code>AccessDenied</Code></Error>", http.StatusForbidden)
+	}))
+	defer srv.Close()
+
+	store, err := NewS3Store(S3Config{Endpoint: srv.URL, Bucket: "sypchal"})
+	if err != nil {
+		t.Fatalf("new s3 store: %v", err)
+	}
+
+	ctx := context.Background()
+	if err := store.Put(ctx, "products/a.jpg", strings.NewReader("a"), "image/jpeg"); err == nil || !strings.Contains(err.Error(), "AccessDenied") {
+		t.Errorf("put: got %v, want the access denied error", err)
+	}
+	if _, err := store.Get(ctx, "products/a.jpg"); err == nil || errors.Is(err, ErrBlobNotFound) {
+		t.Errorf("get: got %v, want the access denied error", err)
+	}
+	if err := store.Delete(ctx, "products/a.jpg"); err == nil {
+		t.Errorf("delete: got no error")
+	}
+}
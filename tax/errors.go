@@ -0,0 +1,7 @@
+package tax
+
+import "errors"
+
+var ErrTaxRateNotFound = errors.New("tax rate not found")
+var ErrTaxRateExists = errors.New("region already has a rate for the tax class")
+var ErrInvalidMode = errors.New("tax mode must be inclusive or exclusive")
@@ -0,0 +1,179 @@
+package tax
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sypchal/validation"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+type TaxDomain struct {
+	db        *pgx.Conn
+	validator *validation.Validator
+}
+
+func NewTaxDomain(db *pgx.Conn, validator *validation.Validator) (*TaxDomain, error) {
+	if db == nil {
+		return nil, errors.New("db is nil")
+	}
+
+	if validator == nil {
+		return nil, errors.New("validator is nil")
+	}
+
+	return &TaxDomain{db, validator}, nil
+}
+
+// Rate is the tax of a tax class in a region, a country code like "ID" or a
+// subdivision like "ID-JK".
+type Rate struct {
+	Id        int        `json:"id"`
+	Region    string     `json:"region"`
+	TaxClass  string     `json:"tax_class"`
+	Name      string     `json:"name"`
+	Rate      int        `json:"rate"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt *time.Time `json:"updated_at"`
+}
+
+const rateColumns = "id,region,tax_class,name,rate,created_at,updated_at"
+
+// scanFields returns the destinations matching rateColumns.
+func (rate *Rate) scanFields() []any {
+	return []any{
+		&rate.Id,
+		&rate.Region,
+		&rate.TaxClass,
+		&rate.Name,
+		&rate.Rate,
+		&rate.CreatedAt,
+		&rate.UpdatedAt,
+	}
+}
+
+type CreateRateRequest struct {
+	Region   string `json:"region" validate:"required,max=16"`
+	TaxClass string `json:"tax_class" validate:"max=32"`
+	Name     string `json:"name" validate:"required"`
+	Rate     int    `json:"rate" validate:"gte=0,lte=10000"` // basis points, 1000 is 10%
+}
+
+func (req *CreateRateRequest) validate(validator *validation.Validator) error {
+	if err := validator.ValidateStruct(req); err != nil {
+		return err
+	}
+
+	req.Region = strings.ToUpper(req.Region)
+	if req.TaxClass == "" {
+		req.TaxClass = ClassStandard
+	}
+
+	return nil
+}
+
+func (t *TaxDomain) CreateRate(ctx context.Context, req CreateRateRequest) (rate *Rate, err error) {
+	if err = req.validate(t.validator); err != nil {
+		return
+	}
+
+	rate = &Rate{}
+	err = t.db.QueryRow(
+		ctx,
+		"insert into tax_rates(region,tax_class,name,rate) values ($1,$2,$3,$4) returning "+rateColumns,
+		req.Region,
+		req.TaxClass,
+		req.Name,
+		req.Rate,
+	).Scan(rate.scanFields()...)
+	if err != nil {
+		err = rateExists(err)
+		return
+	}
+
+	return
+}
+
+type UpdateRateRequest CreateRateRequest
+
+// UpdateRateById replaces every field of the rate, placed orders keep the tax
+// they were charged.
+func (t *TaxDomain) UpdateRateById(ctx context.Context, id int, req UpdateRateRequest) (rate *Rate, err error) {
+	create := CreateRateRequest(req)
+	if err = create.validate(t.validator); err != nil {
+		return
+	}
+
+	rate = &Rate{}
+	err = t.db.QueryRow(
+		ctx,
+		`update tax_rates set region=$1,tax_class=$2,name=$3,rate=$4,updated_at=now()
+		where id=$5 returning `+rateColumns,
+		create.Region,
+		create.TaxClass,
+		create.Name,
+		create.Rate,
+		id,
+	).Scan(rate.scanFields()...)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			err = ErrTaxRateNotFound
+			return
+		}
+		err = rateExists(err)
+		return
+	}
+
+	return
+}
+
+func (t *TaxDomain) DeleteRateById(ctx context.Context, id int) (err error) {
+	tag, err := t.db.Exec(ctx, "delete from tax_rates where id=$1", id)
+	if err != nil {
+		return
+	}
+
+	if tag.RowsAffected() == 0 {
+		err = ErrTaxRateNotFound
+		return
+	}
+
+	return
+}
+
+// rateExists maps the unique_violation of the region and tax class to
+// ErrTaxRateExists.
+func rateExists(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return ErrTaxRateExists
+	}
+
+	return err
+}
+
+// GetRates lists every rate by region and tax class.
+func (t *TaxDomain) GetRates(ctx context.Context) (rates []*Rate, err error) {
+	rows, err := t.db.Query(ctx, "select "+rateColumns+" from tax_rates order by region,tax_class")
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	rates = []*Rate{}
+	for rows.Next() {
+		rate := &Rate{}
+		if err = rows.Scan(rate.scanFields()...); err != nil {
+			return
+		}
+		rates = append(rates, rate)
+	}
+	if err = rows.Err(); err != nil {
+		return
+	}
+
+	return
+}
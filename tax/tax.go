@@ -0,0 +1,179 @@
+package tax
+
+import (
+	"context"
+	"strings"
+	"sypchal/money"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	// ModeExclusive adds the tax to the prices.
+	ModeExclusive = "exclusive"
+	// ModeInclusive takes the tax out of the prices, they already include it.
+	ModeInclusive = "inclusive"
+)
+
+// ClassStandard is the tax class of the products without one.
+const ClassStandard = "standard"
+
+// ValidateMode fails with ErrInvalidMode unless mode is a known mode.
+func ValidateMode(mode string) error {
+	if mode != ModeExclusive && mode != ModeInclusive {
+		return ErrInvalidMode
+	}
+
+	return nil
+}
+
+// Line is an amount to tax, the total of an order line after its discounts.
+type Line struct {
+	Id       int
+	TaxClass string
+	Amount   money.Money
+}
+
+// LineTax is the tax of a line, Rate is in basis points, 1000 is 10%.
+type LineTax struct {
+	Id     int         `json:"id"`
+	Rate   int         `json:"rate"`
+	Amount money.Money `json:"amount"`
+}
+
+type Request struct {
+	Region string
+	Mode   string
+	Lines  []Line
+	// Rates are the local rates of the region by tax class, see LoadRates.
+	// Calculators with rates of their own ignore them.
+	Rates map[string]int
+}
+
+type Result struct {
+	Lines []LineTax   `json:"lines"`
+	Total money.Money `json:"total"`
+}
+
+// TaxCalculator finds the tax of the lines of an order, e.g. from the local
+// rates or an external tax service.
+type TaxCalculator interface {
+	Calculate(ctx context.Context, req Request) (*Result, error)
+}
+
+type querier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}
+
+// LoadRates reads the local rates of region by tax class, a region like
+// "ID-JK" falls back to the rates of its country "ID". The checkout loads them
+// in its transaction so the rates are read with the rest of the order.
+func LoadRates(ctx context.Context, db querier, region string) (rates map[string]int, err error) {
+	regions := regions(region)
+
+	rows, err := db.Query(
+		ctx,
+		"select region,tax_class,rate from tax_rates where region=any($1)",
+		regions,
+	)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	byRegion := map[string]map[string]int{}
+	for rows.Next() {
+		var region, class string
+		var rate int
+		if err = rows.Scan(&region, &class, &rate); err != nil {
+			return
+		}
+		if byRegion[region] == nil {
+			byRegion[region] = map[string]int{}
+		}
+		byRegion[region][class] = rate
+	}
+	if err = rows.Err(); err != nil {
+		return
+	}
+
+	// the country first, so the rates of the region override it
+	rates = map[string]int{}
+	for i := len(regions) - 1; i >= 0; i-- {
+		for class, rate := range byRegion[regions[i]] {
+			rates[class] = rate
+		}
+	}
+
+	return
+}
+
+// Amount is the tax of amount at rate, rounded half up. In inclusive mode the
+// tax is the part of amount it already includes.
+func Amount(mode string, amount money.Money, rate int) money.Money {
+	if mode == ModeInclusive {
+		return amount.MulRatio(int64(rate), int64(10000+rate), money.RoundHalfUp)
+	}
+
+	return amount.BasisPoints(rate, money.RoundHalfUp)
+}
+
+// calculate taxes the lines with the rate of their class.
+func calculate(req Request, rate func(class string) int) *Result {
+	res := &Result{Lines: make([]LineTax, 0, len(req.Lines))}
+	for _, line := range req.Lines {
+		r := rate(line.TaxClass)
+		tax := LineTax{Id: line.Id, Rate: r, Amount: Amount(req.Mode, line.Amount, r)}
+		res.Lines = append(res.Lines, tax)
+		res.Total = res.Total.Add(tax.Amount)
+	}
+
+	return res
+}
+
+// regions is the region followed by its country, a region like "ID-JK" falls
+// back to the rates of "ID".
+func regions(region string) []string {
+	regions := []string{region}
+	if country, _, ok := strings.Cut(region, "-"); ok {
+		regions = append(regions, country)
+	}
+
+	return regions
+}
+
+// LocalCalculator taxes with the rates of the tax_rates table.
+type LocalCalculator struct{}
+
+func NewLocalCalculator() *LocalCalculator {
+	return &LocalCalculator{}
+}
+
+// Calculate uses the rate of the tax class of each line in the rates of the
+// request, lines without a rate are not taxed.
+func (c *LocalCalculator) Calculate(ctx context.Context, req Request) (*Result, error) {
+	return calculate(req, func(class string) int { return req.Rates[class] }), nil
+}
+
+// StubCalculator taxes every line of a region at its rate in Rates, whatever
+// the tax class, for tests. Regions fall back to their country like the local
+// rates, lines without a rate are not taxed.
+type StubCalculator struct {
+	Rates map[string]int
+}
+
+func NewStubCalculator(rates map[string]int) *StubCalculator {
+	return &StubCalculator{rates}
+}
+
+func (c *StubCalculator) Calculate(ctx context.Context, req Request) (*Result, error) {
+	rate := 0
+	for _, region := range regions(req.Region) {
+		if r, ok := c.Rates[region]; ok {
+			rate = r
+			break
+		}
+	}
+
+	return calculate(req, func(string) int { return rate }), nil
+}
@@ -0,0 +1,8 @@
+package upload
+
+import "errors"
+
+var ErrFileTooLarge = errors.New("file too large")
+var ErrUnsupportedFileType = errors.New("unsupported file type")
+var ErrImageTooLarge = errors.New("image too large")
+var ErrFileNotFound = errors.New("file not found")
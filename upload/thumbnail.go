@@ -0,0 +1,82 @@
+package upload
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+)
+
+const thumbnailSize = 256
+
+// thumbnail scales the image down so its longest side is thumbnailSize,
+// averaging the source pixels covered by each thumbnail pixel. Png keeps its
+// transparency, everything else becomes a jpeg. Images of more than maxPixels
+// are rejected with ErrImageTooLarge before being decoded, a small file can
+// hold a huge image.
+func thumbnail(data []byte, maxPixels int64) (thumb []byte, contentType string, err error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return
+	}
+
+	if int64(config.Width)*int64(config.Height) > maxPixels {
+		err = ErrImageTooLarge
+		return
+	}
+
+	src, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return
+	}
+
+	bounds := src.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	tw, th := w, h
+	if w > thumbnailSize || h > thumbnailSize {
+		if w >= h {
+			tw, th = thumbnailSize, max(1, h*thumbnailSize/w)
+		} else {
+			tw, th = max(1, w*thumbnailSize/h), thumbnailSize
+		}
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, tw, th))
+	for y := 0; y < th; y++ {
+		y0, y1 := bounds.Min.Y+y*h/th, bounds.Min.Y+max((y+1)*h/th, y*h/th+1)
+		for x := 0; x < tw; x++ {
+			x0, x1 := bounds.Min.X+x*w/tw, bounds.Min.X+max((x+1)*w/tw, x*w/tw+1)
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					c := color.NRGBAModel.Convert(src.At(sx, sy)).(color.NRGBA)
+					r += uint64(c.R)
+					g += uint64(c.G)
+					b += uint64(c.B)
+					a += uint64(c.A)
+					n++
+				}
+			}
+
+			dst.SetNRGBA(x, y, color.NRGBA{uint8(r / n), uint8(g / n), uint8(b / n), uint8(a / n)})
+		}
+	}
+
+	buf := &bytes.Buffer{}
+	if format == "png" {
+		err = png.Encode(buf, dst)
+		contentType = "image/png"
+	} else {
+		err = jpeg.Encode(buf, dst, &jpeg.Options{Quality: 80})
+		contentType = "image/jpeg"
+	}
+	if err != nil {
+		return
+	}
+
+	thumb = buf.Bytes()
+	return
+}
@@ -0,0 +1,197 @@
+package upload
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+	"mime"
+	"path"
+	"strconv"
+	"strings"
+	"sypchal/storage"
+
+	"github.com/gabriel-vasile/mimetype"
+)
+
+type UploadDomain struct {
+	store   storage.BlobStore
+	maxSize int64
+	// maxPixels is the largest width times height of the uploaded images
+	maxPixels int64
+	publicUrl string
+}
+
+func NewUploadDomain(store storage.BlobStore, maxSize int64, maxPixels int64, publicUrl string) (*UploadDomain, error) {
+	if store == nil {
+		return nil, errors.New("store is nil")
+	}
+
+	if maxSize < 1 {
+		return nil, errors.New("maxSize must be positive")
+	}
+
+	if maxPixels < 1 {
+		return nil, errors.New("maxPixels must be positive")
+	}
+
+	return &UploadDomain{store, maxSize, maxPixels, strings.TrimRight(publicUrl, "/")}, nil
+}
+
+type Upload struct {
+	Key          string `json:"key"`
+	Url          string `json:"url"`
+	ThumbnailUrl string `json:"thumbnail_url,omitempty"`
+	ContentType  string `json:"content_type"`
+	Size         int64  `json:"size"`
+}
+
+// productDir holds the public product media, proofDir the private payment
+// proofs by customer.
+const (
+	productDir = "products"
+	proofDir   = "proofs"
+)
+
+var imageTypes = []string{"image/jpeg", "image/png", "image/gif"}
+var proofTypes = append([]string{"application/pdf"}, imageTypes...)
+
+func (u *UploadDomain) MaxSize() int64 {
+	return u.maxSize
+}
+
+// UploadProductImage stores a product image along with a thumbnail of it.
+func (u *UploadDomain) UploadProductImage(ctx context.Context, file io.Reader) (*Upload, error) {
+	return u.upload(ctx, productDir, file, imageTypes)
+}
+
+// UploadPaymentProof stores a transfer receipt, either an image or a pdf. It
+// is private to the customer and the admins, see OpenPaymentProof.
+func (u *UploadDomain) UploadPaymentProof(ctx context.Context, userId int, file io.Reader) (*Upload, error) {
+	return u.upload(ctx, path.Join(proofDir, strconv.Itoa(userId)), file, proofTypes)
+}
+
+func (u *UploadDomain) upload(ctx context.Context, dir string, file io.Reader, allowed []string) (upload *Upload, err error) {
+	data, err := io.ReadAll(io.LimitReader(file, u.maxSize+1))
+	if err != nil {
+		return
+	}
+
+	if int64(len(data)) > u.maxSize {
+		err = ErrFileTooLarge
+		return
+	}
+
+	// trust the content, not the client provided filename or header
+	mtype := mimetype.Detect(data)
+	if !mimetype.EqualsAny(mtype.String(), allowed...) {
+		err = ErrUnsupportedFileType
+		return
+	}
+
+	name, err := randomName()
+	if err != nil {
+		return
+	}
+
+	contentType := mtype.String()
+	upload = &Upload{
+		Key:         path.Join(dir, name+mtype.Extension()),
+		ContentType: contentType,
+		Size:        int64(len(data)),
+	}
+
+	var thumb []byte
+	var thumbType string
+	if strings.HasPrefix(contentType, "image/") {
+		var terr error
+		thumb, thumbType, terr = thumbnail(data, u.maxPixels)
+		if errors.Is(terr, ErrImageTooLarge) {
+			err = terr
+			return
+		}
+		// otherwise the original is still usable without a thumbnail
+	}
+
+	if err = u.store.Put(ctx, upload.Key, bytes.NewReader(data), contentType); err != nil {
+		return
+	}
+	upload.Url = u.Url(upload.Key)
+
+	if thumb != nil {
+		ext := ".jpg"
+		if thumbType == "image/png" {
+			ext = ".png"
+		}
+
+		thumbKey := path.Join(dir, "thumbs", name+ext)
+		if err = u.store.Put(ctx, thumbKey, bytes.NewReader(thumb), thumbType); err != nil {
+			return
+		}
+		upload.ThumbnailUrl = u.Url(thumbKey)
+	}
+
+	return
+}
+
+// Url returns the url a stored key is served from, the payment proofs are
+// served by an authenticated route.
+func (u *UploadDomain) Url(key string) string {
+	if name, ok := strings.CutPrefix(key, proofDir+"/"); ok {
+		return u.publicUrl + "/api/payment-proofs/" + name
+	}
+
+	return u.publicUrl + "/files/" + key
+}
+
+// OpenPublic returns the content of a public file, the product media, and its
+// content type. Other keys are not found.
+func (u *UploadDomain) OpenPublic(ctx context.Context, key string) (io.ReadCloser, string, error) {
+	key = path.Clean("/" + key)[1:]
+	if !strings.HasPrefix(key, productDir+"/") {
+		return nil, "", ErrFileNotFound
+	}
+
+	return u.open(ctx, key)
+}
+
+// OpenPaymentProof returns the content of a payment proof of the customer, or
+// its thumbnail, by its name in the url, and its content type.
+func (u *UploadDomain) OpenPaymentProof(ctx context.Context, userId int, name string) (io.ReadCloser, string, error) {
+	name = path.Clean("/" + name)[1:]
+	if name == "" {
+		return nil, "", ErrFileNotFound
+	}
+
+	return u.open(ctx, path.Join(proofDir, strconv.Itoa(userId), name))
+}
+
+// open returns the content of a stored file and its content type.
+func (u *UploadDomain) open(ctx context.Context, key string) (body io.ReadCloser, contentType string, err error) {
+	body, err = u.store.Get(ctx, key)
+	if err != nil {
+		if errors.Is(err, storage.ErrBlobNotFound) {
+			err = ErrFileNotFound
+		}
+
+		return
+	}
+
+	contentType = mime.TypeByExtension(path.Ext(key))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	return
+}
+
+func randomName() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
@@ -4,3 +4,4 @@ import "errors"
 
 var ErrEmailAlreadyExists = errors.New("email already exists")
 var ErrWrongEmailOrPassword = errors.New("wrong email or password")
+var ErrUserNotFound = errors.New("user not found")
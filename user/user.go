@@ -4,6 +4,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strconv"
+	"sypchal/money"
 	"sypchal/validation"
 	"time"
 
@@ -47,38 +49,40 @@ func (r CreateUserRequest) validate(validator *validation.Validator) error {
 	return validator.ValidateStruct(r)
 }
 
-func (u *UserDomain) CreateUser(ctx context.Context, req CreateUserRequest) error {
+// CreateUser registers a customer, returning the new user id.
+func (u *UserDomain) CreateUser(ctx context.Context, req CreateUserRequest) (int, error) {
 	if err := req.validate(u.validator); err != nil {
-		return err
+		return 0, err
 	}
 
 	var exists int
 	err := u.db.QueryRow(ctx, "select count(*) from users where email = $1", req.Email).Scan(&exists)
 	if err != nil {
-		return err
+		return 0, err
 	}
 
 	if exists > 0 {
-		return ErrEmailAlreadyExists
+		return 0, ErrEmailAlreadyExists
 	}
 
 	hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
 	if err != nil {
-		return err
+		return 0, err
 	}
 
-	_, err = u.db.Exec(
+	var userId int
+	err = u.db.QueryRow(
 		ctx,
-		"insert into users(email, password, full_name) values($1, $2, $3)",
+		"insert into users(email, password, full_name) values($1, $2, $3) returning id",
 		req.Email,
 		string(hash),
 		req.FullName,
-	)
+	).Scan(&userId)
 	if err != nil {
-		return err
+		return 0, err
 	}
 
-	return nil
+	return userId, nil
 }
 
 type AuthenticateRequest struct {
@@ -86,7 +90,7 @@ type AuthenticateRequest struct {
 	Password string `json:"password" validate:"required"`
 }
 
-func (u *UserDomain) Authenticate(ctx context.Context, req AuthenticateRequest) (accessToken string, err error) {
+func (u *UserDomain) Authenticate(ctx context.Context, req AuthenticateRequest) (accessToken string, userId int, err error) {
 	user := User{}
 	err = u.db.QueryRow(
 		ctx,
@@ -108,6 +112,11 @@ func (u *UserDomain) Authenticate(ctx context.Context, req AuthenticateRequest)
 		return
 	}
 
+	userId, err = strconv.Atoi(user.Id)
+	if err != nil {
+		return
+	}
+
 	_, accessToken, err = u.Jwt.Encode(map[string]interface{}{
 		"uid": user.Id,
 		"exp": time.Now().Add(time.Hour * 1).Unix(), // an hour
@@ -119,3 +128,29 @@ func (u *UserDomain) Authenticate(ctx context.Context, req AuthenticateRequest)
 
 	return
 }
+
+// GetCurrency returns the currency the customer prefers to see prices in, ""
+// when they have no preference.
+func (u *UserDomain) GetCurrency(ctx context.Context, userId int) (currency money.Currency, err error) {
+	err = u.db.QueryRow(ctx, "select coalesce(currency,'') from users where id=$1", userId).Scan(&currency)
+	if errors.Is(err, pgx.ErrNoRows) {
+		err = ErrUserNotFound
+	}
+
+	return
+}
+
+// SetCurrency saves the currency the customer prefers, "" clears it.
+func (u *UserDomain) SetCurrency(ctx context.Context, userId int, currency money.Currency) (err error) {
+	tag, err := u.db.Exec(ctx, "update users set currency=nullif($1,''),updated_at=now() where id=$2", currency, userId)
+	if err != nil {
+		return
+	}
+
+	if tag.RowsAffected() == 0 {
+		err = ErrUserNotFound
+		return
+	}
+
+	return
+}
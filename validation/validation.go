@@ -3,7 +3,9 @@ package validation
 import (
 	"fmt"
 	"reflect"
+	"sort"
 	"strings"
+	"sypchal/money"
 
 	"github.com/go-playground/validator/v10"
 )
@@ -14,11 +16,42 @@ type Validator struct {
 
 type ValidationErrors struct {
 	*validator.ValidationErrors
+	// Fields holds errors found outside of the validator, e.g. while decoding
+	// a field, keyed by the json field name.
+	Fields map[string]string
+}
+
+// NewFieldErrors reports errors found outside of the validator the same way
+// validator errors are reported.
+func NewFieldErrors(fields map[string]string) *ValidationErrors {
+	return &ValidationErrors{Fields: fields}
+}
+
+func (e ValidationErrors) Error() string {
+	if e.ValidationErrors != nil {
+		return e.ValidationErrors.Error()
+	}
+
+	msgs := make([]string, 0, len(e.Fields))
+	for _, msg := range e.Fields {
+		msgs = append(msgs, msg)
+	}
+	sort.Strings(msgs)
+
+	return strings.Join(msgs, "\n")
 }
 
 func (e ValidationErrors) Transform() map[string]string {
 	errors := map[string]string{}
 
+	for field, msg := range e.Fields {
+		errors[field] = msg
+	}
+
+	if e.ValidationErrors == nil {
+		return errors
+	}
+
 	for _, err := range *e.ValidationErrors {
 		if err.Tag() == "required" {
 			errors[err.Field()] = fmt.Sprintf("%s is required", err.Field())
@@ -60,6 +93,11 @@ func NewValidator() *Validator {
 		return name
 	})
 
+	// money is validated by its amount, e.g. gt=0
+	validate.RegisterCustomTypeFunc(func(field reflect.Value) interface{} {
+		return field.Interface().(money.Money).Amount
+	}, money.Money{})
+
 	return &Validator{validate}
 }
 
@@ -76,3 +114,30 @@ func (v *Validator) ValidateStruct(s interface{}) error {
 
 	return nil
 }
+
+// ValidatePartial validates only the given fields of a struct, named after the
+// go struct fields, e.g. "Name".
+func (v *Validator) ValidatePartial(s interface{}, fields ...string) error {
+	if err := v.validate.StructPartial(s, fields...); err != nil {
+		if _, ok := err.(*validator.InvalidValidationError); ok {
+			return err
+		}
+
+		if errs, ok := err.(validator.ValidationErrors); ok {
+			return &ValidationErrors{ValidationErrors: &errs}
+		}
+	}
+
+	return nil
+}
+
+// InCurrency returns amount in currency, an amount without a currency taking
+// it, and reports field when the amount is in another currency.
+func InCurrency(field string, amount money.Money, currency money.Currency) (money.Money, error) {
+	m, err := amount.As(currency)
+	if err != nil {
+		return m, NewFieldErrors(map[string]string{field: field + " must be in " + string(currency)})
+	}
+
+	return m, nil
+}
@@ -0,0 +1,13 @@
+package wishlist
+
+import "errors"
+
+var ErrWishlistNotFound = errors.New("wishlist not found")
+var ErrWishlistExists = errors.New("a wishlist with this name already exists")
+var ErrItemNotFound = errors.New("wishlist item not found")
+var ErrItemExists = errors.New("product already in the wishlist")
+var ErrSavedItemNotFound = errors.New("saved item not found")
+var ErrCartItemNotFound = errors.New("cart item not found")
+var ErrProductNotFound = errors.New("product not found")
+var ErrVariantNotFound = errors.New("variant not found")
+var ErrProductOutOfStock = errors.New("product out of stock")
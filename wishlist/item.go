@@ -0,0 +1,177 @@
+package wishlist
+
+import (
+	"context"
+	"errors"
+	"sypchal/exchange"
+	"sypchal/money"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+type WishlistItem struct {
+	Id         int         `json:"id"`
+	WishlistId int         `json:"wishlist_id"`
+	ProductId  int         `json:"product_id"`
+	VariantId  *int        `json:"variant_id"`
+	Price      money.Money `json:"price"`
+	CreatedAt  time.Time   `json:"created_at"`
+}
+
+type WishlistItemPopulated struct {
+	Id int `json:"id"`
+	Listing
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type AddItemRequest struct {
+	ProductId int `json:"product_id" validate:"required"`
+	// VariantId is optional, a product with variants can be kept before
+	// choosing one.
+	VariantId int `json:"variant_id"`
+}
+
+// AddItem keeps a product, or one of its variants, in a wishlist of the
+// customer, with its current price to tell how the price changes.
+func (w *WishlistDomain) AddItem(ctx context.Context, userId int, wishlistId int, req AddItemRequest) (item *WishlistItem, err error) {
+	if err = w.validator.ValidateStruct(req); err != nil {
+		return
+	}
+
+	tx, err := w.db.Begin(ctx)
+	if err != nil {
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	if err = ownWishlist(ctx, tx, userId, wishlistId); err != nil {
+		return
+	}
+
+	var price money.Money
+	err = tx.QueryRow(
+		ctx,
+		`select row(coalesce(product_variants.price,products.price),products.currency)
+		from products left join product_variants on(product_variants.id=$2 and product_variants.product_id=products.id)
+		where products.id=$1 and products.deleted_at is null and ($2=0 or product_variants.id is not null)`,
+		req.ProductId,
+		req.VariantId,
+	).Scan(&price)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			err = ErrProductNotFound
+			if req.VariantId != 0 {
+				err = ErrVariantNotFound
+			}
+		}
+		return
+	}
+
+	item = &WishlistItem{}
+	err = tx.QueryRow(
+		ctx,
+		`insert into wishlist_items(wishlist_id,product_id,variant_id,price,currency) values ($1,$2,nullif($3,0),$4,$5)
+		returning id,wishlist_id,product_id,variant_id,row(price,currency),created_at`,
+		wishlistId,
+		req.ProductId,
+		req.VariantId,
+		price.Amount,
+		price.Currency,
+	).Scan(&item.Id, &item.WishlistId, &item.ProductId, &item.VariantId, &item.Price, &item.CreatedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			err = ErrItemExists
+		}
+		return
+	}
+
+	if _, err = tx.Exec(ctx, "update wishlists set updated_at=now() where id=$1", wishlistId); err != nil {
+		return
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return
+	}
+
+	return
+}
+
+// RemoveItem takes an item out of a wishlist of the customer.
+func (w *WishlistDomain) RemoveItem(ctx context.Context, userId int, wishlistId int, itemId int) (err error) {
+	tx, err := w.db.Begin(ctx)
+	if err != nil {
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	if err = ownWishlist(ctx, tx, userId, wishlistId); err != nil {
+		return
+	}
+
+	tag, err := tx.Exec(ctx, "delete from wishlist_items where id=$1 and wishlist_id=$2", itemId, wishlistId)
+	if err != nil {
+		return
+	}
+
+	if tag.RowsAffected() == 0 {
+		err = ErrItemNotFound
+		return
+	}
+
+	if _, err = tx.Exec(ctx, "update wishlists set updated_at=now() where id=$1", wishlistId); err != nil {
+		return
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return
+	}
+
+	return
+}
+
+// getItems lists the items of a wishlist priced in currency, the last added
+// first. Items of archived products stay, marked archived.
+func (w *WishlistDomain) getItems(ctx context.Context, wishlistId int, currency money.Currency) (items []*WishlistItemPopulated, err error) {
+	rates, err := exchange.LoadRates(ctx, w.db, w.currency)
+	if err != nil {
+		return
+	}
+
+	rows, err := w.db.Query(
+		ctx,
+		`select wishlist_items.id,row(wishlist_items.price,wishlist_items.currency),wishlist_items.created_at,`+listingColumns+`
+		from wishlist_items inner join products on(wishlist_items.product_id=products.id)
+		left join product_variants on(wishlist_items.variant_id=product_variants.id)
+		where wishlist_items.wishlist_id=$1
+		order by wishlist_items.created_at desc,wishlist_items.id desc`,
+		wishlistId,
+		currency,
+	)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	items = []*WishlistItemPopulated{}
+	for rows.Next() {
+		item := &WishlistItemPopulated{}
+		var added money.Money
+		row := &listingRow{}
+		if err = rows.Scan(append([]any{&item.Id, &added, &item.CreatedAt}, row.scanFields()...)...); err != nil {
+			return
+		}
+
+		if item.Listing, err = row.price(rates, currency, added); err != nil {
+			return
+		}
+		items = append(items, item)
+	}
+	if err = rows.Err(); err != nil {
+		return
+	}
+
+	return
+}
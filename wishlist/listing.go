@@ -0,0 +1,111 @@
+package wishlist
+
+import (
+	"sypchal/cart"
+	"sypchal/exchange"
+	"sypchal/money"
+)
+
+var (
+	AvailabilityInStock    = "in_stock"
+	AvailabilityOutOfStock = "out_of_stock"
+	// AvailabilityArchived is a product no longer sold.
+	AvailabilityArchived = "archived"
+)
+
+// Listing is the product of a wishlist or saved item, whether it can be
+// ordered and how its price moved since it was added. The prices are in the
+// currency of the customer, the changes are those of the catalog prices.
+type Listing struct {
+	Product      ListingProduct  `json:"product"`
+	Variant      *ListingVariant `json:"variant"`
+	Availability string          `json:"availability"`
+	Stock        int             `json:"stock"`
+	// AddedPrice is the price when the item was added, CurrentPrice the
+	// catalog price.
+	AddedPrice   money.Money `json:"added_price"`
+	CurrentPrice money.Money `json:"current_price"`
+	PriceChanged bool        `json:"price_changed"`
+	PriceDropped bool        `json:"price_dropped"`
+}
+
+type ListingProduct struct {
+	Id       int    `json:"id"`
+	Name     string `json:"name"`
+	ImageUrl string `json:"image_url"`
+	Category string `json:"category"`
+}
+
+type ListingVariant struct {
+	Id       int               `json:"id"`
+	Sku      string            `json:"sku"`
+	Options  map[string]string `json:"options"`
+	ImageUrl string            `json:"image_url"`
+}
+
+// listingColumns selects the product of an item, the query joins products and
+// product_variants and takes the currency as $2.
+const listingColumns = `products.id,products.name,coalesce(products.image_url,''),coalesce(products.category,''),
+	products.deleted_at is not null,product_variants.id,product_variants.sku,product_variants.options,
+	coalesce(product_variants.image_url,''),coalesce(product_variants.stock,products.stock),
+	row(coalesce(product_variants.price,products.price),products.currency),(` + cart.ListPriceSQL + `)`
+
+// listingRow is a row of listingColumns.
+type listingRow struct {
+	listing   Listing
+	archived  bool
+	variantId *int
+	sku       *string
+	options   map[string]string
+	imageUrl  string
+	current   money.Money
+	listPrice *int64
+}
+
+// scanFields returns the destinations matching listingColumns.
+func (row *listingRow) scanFields() []any {
+	return []any{
+		&row.listing.Product.Id,
+		&row.listing.Product.Name,
+		&row.listing.Product.ImageUrl,
+		&row.listing.Product.Category,
+		&row.archived,
+		&row.variantId,
+		&row.sku,
+		&row.options,
+		&row.imageUrl,
+		&row.listing.Stock,
+		&row.current,
+		&row.listPrice,
+	}
+}
+
+// price completes the listing of an item added at price added, in currency.
+func (row *listingRow) price(rates *exchange.Rates, currency money.Currency, added money.Money) (listing Listing, err error) {
+	listing = row.listing
+	if row.variantId != nil {
+		listing.Variant = &ListingVariant{*row.variantId, *row.sku, row.options, row.imageUrl}
+	}
+
+	switch {
+	case row.archived:
+		listing.Availability = AvailabilityArchived
+	case listing.Stock > 0:
+		listing.Availability = AvailabilityInStock
+	default:
+		listing.Availability = AvailabilityOutOfStock
+	}
+
+	listing.PriceChanged = added != row.current
+	listing.PriceDropped = added.Currency == row.current.Currency && row.current.LessThan(added)
+
+	local := cart.Localizer(rates, currency, row.current, row.listPrice)
+	if listing.AddedPrice, err = local(added); err != nil {
+		return
+	}
+	if listing.CurrentPrice, err = local(row.current); err != nil {
+		return
+	}
+
+	return
+}
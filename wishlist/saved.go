@@ -0,0 +1,231 @@
+package wishlist
+
+import (
+	"context"
+	"errors"
+	"sypchal/exchange"
+	"sypchal/money"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// SavedItem is a cart line saved for later. It keeps its quantity and the
+// price it was added to the cart at, moving it back to the cart restores
+// them.
+type SavedItem struct {
+	Id        int         `json:"id"`
+	UserId    int         `json:"user_id"`
+	ProductId int         `json:"product_id"`
+	VariantId *int        `json:"variant_id"`
+	Qty       int         `json:"qty"`
+	Price     money.Money `json:"price"`
+	PricedAt  time.Time   `json:"priced_at"`
+	CreatedAt time.Time   `json:"created_at"`
+}
+
+const savedItemColumns = "id,user_id,product_id,variant_id,qty,row(price,currency),priced_at,created_at"
+
+// scanFields returns the destinations matching savedItemColumns.
+func (item *SavedItem) scanFields() []any {
+	return []any{
+		&item.Id,
+		&item.UserId,
+		&item.ProductId,
+		&item.VariantId,
+		&item.Qty,
+		&item.Price,
+		&item.PricedAt,
+		&item.CreatedAt,
+	}
+}
+
+type SavedItemPopulated struct {
+	Id  int `json:"id"`
+	Qty int `json:"qty"`
+	Listing
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// SaveForLater moves a line of the cart of the customer to the saved items,
+// adding its quantity to the same product already saved.
+func (w *WishlistDomain) SaveForLater(ctx context.Context, userId int, cartItemId int) (item *SavedItem, err error) {
+	tx, err := w.db.Begin(ctx)
+	if err != nil {
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	line := &SavedItem{}
+	err = tx.QueryRow(
+		ctx,
+		`delete from cart_items where id=$1 and user_id=$2
+		returning product_id,variant_id,qty,row(price,currency),priced_at`,
+		cartItemId,
+		userId,
+	).Scan(&line.ProductId, &line.VariantId, &line.Qty, &line.Price, &line.PricedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			err = ErrCartItemNotFound
+		}
+		return
+	}
+
+	item = &SavedItem{}
+	err = tx.QueryRow(
+		ctx,
+		`insert into saved_items(user_id,product_id,variant_id,qty,price,currency,priced_at) values ($1,$2,$3,$4,$5,$6,$7)
+		on conflict (user_id,product_id,variant_id) do update set qty=saved_items.qty+excluded.qty
+		returning `+savedItemColumns,
+		userId,
+		line.ProductId,
+		line.VariantId,
+		line.Qty,
+		line.Price.Amount,
+		line.Price.Currency,
+		line.PricedAt,
+	).Scan(item.scanFields()...)
+	if err != nil {
+		return
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return
+	}
+
+	return
+}
+
+// MoveToCart moves a saved item back to the cart of the customer with its
+// quantity and price, adding the quantity to the same line already in the
+// cart. It returns the quantity in the cart.
+func (w *WishlistDomain) MoveToCart(ctx context.Context, userId int, savedItemId int) (count int, err error) {
+	tx, err := w.db.Begin(ctx)
+	if err != nil {
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	item := &SavedItem{}
+	err = tx.QueryRow(
+		ctx,
+		"delete from saved_items where id=$1 and user_id=$2 returning "+savedItemColumns,
+		savedItemId,
+		userId,
+	).Scan(item.scanFields()...)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			err = ErrSavedItemNotFound
+		}
+		return
+	}
+
+	var stock, inCart int
+	err = tx.QueryRow(
+		ctx,
+		`select coalesce(product_variants.stock,products.stock),
+			(select coalesce(sum(qty),0) from cart_items
+			where user_id=$3 and product_id=products.id and variant_id is not distinct from $2)
+		from products left join product_variants on(product_variants.id=$2)
+		where products.id=$1 and products.deleted_at is null`,
+		item.ProductId,
+		item.VariantId,
+		userId,
+	).Scan(&stock, &inCart)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			err = ErrProductNotFound
+		}
+		return
+	}
+
+	if inCart+item.Qty > stock {
+		err = ErrProductOutOfStock
+		return
+	}
+
+	_, err = tx.Exec(
+		ctx,
+		`insert into cart_items(user_id,product_id,variant_id,qty,price,currency,priced_at) values ($1,$2,$3,$4,$5,$6,$7)
+		on conflict (user_id,guest_cart_id,product_id,variant_id) do update set qty=cart_items.qty+excluded.qty,updated_at=now()`,
+		userId,
+		item.ProductId,
+		item.VariantId,
+		item.Qty,
+		item.Price.Amount,
+		item.Price.Currency,
+		item.PricedAt,
+	)
+	if err != nil {
+		return
+	}
+
+	err = tx.QueryRow(ctx, "select coalesce(sum(qty),0) from cart_items where user_id=$1", userId).Scan(&count)
+	if err != nil {
+		return
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return
+	}
+
+	return
+}
+
+func (w *WishlistDomain) DeleteSavedItem(ctx context.Context, userId int, savedItemId int) (err error) {
+	tag, err := w.db.Exec(ctx, "delete from saved_items where id=$1 and user_id=$2", savedItemId, userId)
+	if err != nil {
+		return
+	}
+
+	if tag.RowsAffected() == 0 {
+		err = ErrSavedItemNotFound
+		return
+	}
+
+	return
+}
+
+// GetSavedItems lists the saved items of the customer priced in currency, the
+// last saved first.
+func (w *WishlistDomain) GetSavedItems(ctx context.Context, userId int, currency money.Currency) (items []*SavedItemPopulated, err error) {
+	rates, err := exchange.LoadRates(ctx, w.db, w.currency)
+	if err != nil {
+		return
+	}
+
+	rows, err := w.db.Query(
+		ctx,
+		`select saved_items.id,saved_items.qty,row(saved_items.price,saved_items.currency),saved_items.created_at,`+listingColumns+`
+		from saved_items inner join products on(saved_items.product_id=products.id)
+		left join product_variants on(saved_items.variant_id=product_variants.id)
+		where saved_items.user_id=$1
+		order by saved_items.created_at desc,saved_items.id desc`,
+		userId,
+		currency,
+	)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	items = []*SavedItemPopulated{}
+	for rows.Next() {
+		item := &SavedItemPopulated{}
+		var added money.Money
+		row := &listingRow{}
+		if err = rows.Scan(append([]any{&item.Id, &item.Qty, &added, &item.CreatedAt}, row.scanFields()...)...); err != nil {
+			return
+		}
+
+		if item.Listing, err = row.price(rates, currency, added); err != nil {
+			return
+		}
+		items = append(items, item)
+	}
+	if err = rows.Err(); err != nil {
+		return
+	}
+
+	return
+}
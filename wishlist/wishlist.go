@@ -0,0 +1,284 @@
+package wishlist
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"sypchal/money"
+	"sypchal/validation"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+type WishlistDomain struct {
+	db        *pgx.Conn
+	validator *validation.Validator
+	// currency is the currency of the catalog prices
+	currency money.Currency
+}
+
+func NewWishlistDomain(db *pgx.Conn, validator *validation.Validator, currency money.Currency) (*WishlistDomain, error) {
+	if db == nil {
+		return nil, errors.New("db is nil")
+	}
+
+	if validator == nil {
+		return nil, errors.New("validator is nil")
+	}
+
+	return &WishlistDomain{db, validator, currency}, nil
+}
+
+// Wishlist is a named list of products a customer keeps without putting them
+// in the cart. Anyone with its ShareToken can read it, nil when it isn't
+// shared.
+type Wishlist struct {
+	Id         int                      `json:"id"`
+	UserId     int                      `json:"user_id"`
+	Name       string                   `json:"name"`
+	ShareToken *string                  `json:"share_token"`
+	ItemCount  int                      `json:"item_count"`
+	Currency   money.Currency           `json:"currency,omitempty"`
+	Items      []*WishlistItemPopulated `json:"items,omitempty"`
+	CreatedAt  time.Time                `json:"created_at"`
+	UpdatedAt  *time.Time               `json:"updated_at"`
+}
+
+const wishlistColumns = "id,user_id,name,share_token," +
+	"(select count(*) from wishlist_items where wishlist_id=wishlists.id),created_at,updated_at"
+
+// scanFields returns the destinations matching wishlistColumns.
+func (wishlist *Wishlist) scanFields() []any {
+	return []any{
+		&wishlist.Id,
+		&wishlist.UserId,
+		&wishlist.Name,
+		&wishlist.ShareToken,
+		&wishlist.ItemCount,
+		&wishlist.CreatedAt,
+		&wishlist.UpdatedAt,
+	}
+}
+
+// SharedWishlist is the read-only view of a shared wishlist, without its
+// owner.
+type SharedWishlist struct {
+	Name      string                   `json:"name"`
+	Currency  money.Currency           `json:"currency"`
+	Items     []*WishlistItemPopulated `json:"items"`
+	CreatedAt time.Time                `json:"created_at"`
+}
+
+type CreateWishlistRequest struct {
+	Name string `json:"name" validate:"required,max=100"`
+}
+
+func (w *WishlistDomain) CreateWishlist(ctx context.Context, userId int, req CreateWishlistRequest) (wishlist *Wishlist, err error) {
+	if err = w.validator.ValidateStruct(req); err != nil {
+		return
+	}
+
+	wishlist = &Wishlist{}
+	err = w.db.QueryRow(
+		ctx,
+		"insert into wishlists(user_id,name) values ($1,$2) returning "+wishlistColumns,
+		userId,
+		req.Name,
+	).Scan(wishlist.scanFields()...)
+	if err != nil {
+		err = wishlistErr(err)
+		return
+	}
+
+	return
+}
+
+type UpdateWishlistRequest CreateWishlistRequest
+
+// UpdateWishlist renames a wishlist of the customer.
+func (w *WishlistDomain) UpdateWishlist(ctx context.Context, userId int, wishlistId int, req UpdateWishlistRequest) (wishlist *Wishlist, err error) {
+	if err = w.validator.ValidateStruct(req); err != nil {
+		return
+	}
+
+	wishlist = &Wishlist{}
+	err = w.db.QueryRow(
+		ctx,
+		"update wishlists set name=$1,updated_at=now() where id=$2 and user_id=$3 returning "+wishlistColumns,
+		req.Name,
+		wishlistId,
+		userId,
+	).Scan(wishlist.scanFields()...)
+	if err != nil {
+		err = wishlistErr(err)
+		return
+	}
+
+	return
+}
+
+// DeleteWishlist deletes a wishlist of the customer with its items, its link
+// stops working.
+func (w *WishlistDomain) DeleteWishlist(ctx context.Context, userId int, wishlistId int) (err error) {
+	tag, err := w.db.Exec(ctx, "delete from wishlists where id=$1 and user_id=$2", wishlistId, userId)
+	if err != nil {
+		return
+	}
+
+	if tag.RowsAffected() == 0 {
+		err = ErrWishlistNotFound
+		return
+	}
+
+	return
+}
+
+// GetWishlists lists the wishlists of the customer by name, without their
+// items.
+func (w *WishlistDomain) GetWishlists(ctx context.Context, userId int) (wishlists []*Wishlist, err error) {
+	rows, err := w.db.Query(ctx, "select "+wishlistColumns+" from wishlists where user_id=$1 order by name", userId)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	wishlists = []*Wishlist{}
+	for rows.Next() {
+		wishlist := &Wishlist{}
+		if err = rows.Scan(wishlist.scanFields()...); err != nil {
+			return
+		}
+		wishlists = append(wishlists, wishlist)
+	}
+	if err = rows.Err(); err != nil {
+		return
+	}
+
+	return
+}
+
+// GetWishlist returns a wishlist of the customer with its items priced in
+// currency.
+func (w *WishlistDomain) GetWishlist(ctx context.Context, userId int, wishlistId int, currency money.Currency) (wishlist *Wishlist, err error) {
+	wishlist = &Wishlist{}
+	err = w.db.QueryRow(
+		ctx,
+		"select "+wishlistColumns+" from wishlists where id=$1 and user_id=$2",
+		wishlistId,
+		userId,
+	).Scan(wishlist.scanFields()...)
+	if err != nil {
+		err = wishlistErr(err)
+		return
+	}
+
+	wishlist.Currency = currency
+	wishlist.Items, err = w.getItems(ctx, wishlist.Id, currency)
+	return
+}
+
+// ShareWishlist turns on the read-only link of a wishlist of the customer, a
+// shared wishlist keeps its token.
+func (w *WishlistDomain) ShareWishlist(ctx context.Context, userId int, wishlistId int) (wishlist *Wishlist, err error) {
+	token, err := shareToken()
+	if err != nil {
+		return
+	}
+
+	wishlist = &Wishlist{}
+	err = w.db.QueryRow(
+		ctx,
+		`update wishlists set share_token=coalesce(share_token,$1),updated_at=now() where id=$2 and user_id=$3
+		returning `+wishlistColumns,
+		token,
+		wishlistId,
+		userId,
+	).Scan(wishlist.scanFields()...)
+	if err != nil {
+		err = wishlistErr(err)
+		return
+	}
+
+	return
+}
+
+// UnshareWishlist turns off the link of a wishlist of the customer, sharing it
+// again makes a new link.
+func (w *WishlistDomain) UnshareWishlist(ctx context.Context, userId int, wishlistId int) (wishlist *Wishlist, err error) {
+	wishlist = &Wishlist{}
+	err = w.db.QueryRow(
+		ctx,
+		"update wishlists set share_token=null,updated_at=now() where id=$1 and user_id=$2 returning "+wishlistColumns,
+		wishlistId,
+		userId,
+	).Scan(wishlist.scanFields()...)
+	if err != nil {
+		err = wishlistErr(err)
+		return
+	}
+
+	return
+}
+
+// GetSharedWishlist returns the wishlist of a share token with its items
+// priced in currency.
+func (w *WishlistDomain) GetSharedWishlist(ctx context.Context, token string, currency money.Currency) (wishlist *SharedWishlist, err error) {
+	var wishlistId int
+	wishlist = &SharedWishlist{Currency: currency}
+	err = w.db.QueryRow(ctx, "select id,name,created_at from wishlists where share_token=$1", token).
+		Scan(&wishlistId, &wishlist.Name, &wishlist.CreatedAt)
+	if err != nil {
+		err = wishlistErr(err)
+		return
+	}
+
+	wishlist.Items, err = w.getItems(ctx, wishlistId, currency)
+	return
+}
+
+// ownWishlist checks the wishlist belongs to the customer.
+func ownWishlist(ctx context.Context, tx pgx.Tx, userId int, wishlistId int) (err error) {
+	var exists bool
+	err = tx.QueryRow(
+		ctx,
+		"select exists(select 1 from wishlists where id=$1 and user_id=$2)",
+		wishlistId,
+		userId,
+	).Scan(&exists)
+	if err != nil {
+		return
+	}
+
+	if !exists {
+		err = ErrWishlistNotFound
+	}
+
+	return
+}
+
+// wishlistErr maps the errors of the wishlist queries.
+func wishlistErr(err error) error {
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrWishlistNotFound
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return ErrWishlistExists
+	}
+
+	return err
+}
+
+// shareToken is a random token for the link of a wishlist.
+func shareToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}